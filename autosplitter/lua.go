@@ -0,0 +1,318 @@
+package autosplitter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/procmem"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+// luaScript runs an autosplitter written in Lua on an embedded, pure Go VM.
+//
+// Scripts define any of these global functions, all optional:
+//
+//	startup()    called once after the script is loaded
+//	update()     called every tick before anything else, return false to skip the rest of the tick
+//	start()      return true to start a run (only called while the run is idle)
+//	split()      return true to split (only called while a run is in progress)
+//	reset()      return true to reset the run
+//	isLoading()  return true while the game is loading, shown next to the timer (only called while a run is in progress)
+//	gameTime()   return the in game time in milliseconds, shown next to the timer
+//
+// Setting the global refresh_rate (ticks per second) in the script body or startup() changes how often hooks run.
+// The global process table exposes memory reading for the game, see luaScript.processLibrary.  The VM runs with the
+// Runtime's context, so a script stuck in a loop is stopped when it is unloaded.
+type luaScript struct {
+	name    string
+	source  string
+	state   *lua.LState
+	process *procmem.Process
+	attach  func(string) (*procmem.Process, error)
+	status  session.GameStatus
+}
+
+func newLuaScript(path string) (*luaScript, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newLuaScriptFromSource(path, string(source)), nil
+}
+
+func newLuaScriptFromSource(name string, source string) *luaScript {
+	return &luaScript{
+		name:   name,
+		source: source,
+		attach: procmem.Attach,
+	}
+}
+
+// Startup creates the VM, runs the script body and then the startup hook.  Both must finish within startupTimeout.
+func (l *luaScript) Startup(ctx context.Context) error {
+	l.state = lua.NewState(lua.Options{SkipOpenLibs: true})
+	startupCtx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
+	l.state.SetContext(startupCtx)
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		l.state.Push(l.state.NewFunction(lib.open))
+		l.state.Push(lua.LString(lib.name))
+		l.state.Call(1, 0)
+	}
+
+	l.state.SetGlobal("print", l.state.NewFunction(l.print))
+	l.state.SetGlobal("process", l.processLibrary())
+
+	if err := l.state.DoString(l.source); err != nil {
+		return fmt.Errorf("%s: %w", l.name, err)
+	}
+
+	if _, _, err := l.call("startup"); err != nil {
+		return err
+	}
+	l.state.SetContext(ctx)
+	return nil
+}
+
+// RefreshRate reads the refresh_rate global set by the script
+func (l *luaScript) RefreshRate() time.Duration {
+	if l.state == nil {
+		return defaultRefreshRate
+	}
+	if rate, ok := l.state.GetGlobal("refresh_rate").(lua.LNumber); ok && rate > 0 {
+		return time.Duration(float64(time.Second) / float64(rate))
+	}
+	return defaultRefreshRate
+}
+
+// Tick runs the script hooks that apply to the current run state and converts their results to commands.
+func (l *luaScript) Tick(state session.State) []dispatcher.Command {
+	if l.process != nil && !l.process.Alive() {
+		logger.Infof(logModule, "attached process %s exited", l.process.Name())
+		_ = l.process.Close()
+		l.process = nil
+	}
+
+	if result, defined, err := l.call("update"); err != nil || (defined && result == lua.LFalse) {
+		return nil
+	}
+
+	commands := commandsFor(state, l)
+	l.status = gameStatusFor(state, l)
+	return commands
+}
+
+// GameStatus is the load state and game time read by the last Tick
+func (l *luaScript) GameStatus() session.GameStatus { return l.status }
+
+func (l *luaScript) start() bool     { return l.callBool("start") }
+func (l *luaScript) split() bool     { return l.callBool("split") }
+func (l *luaScript) reset() bool     { return l.callBool("reset") }
+func (l *luaScript) isLoading() bool { return l.callBool("isLoading") }
+
+func (l *luaScript) gameTime() (time.Duration, bool) {
+	result, _, err := l.call("gameTime")
+	ms, ok := result.(lua.LNumber)
+	if err != nil || !ok {
+		return 0, false
+	}
+	return time.Duration(float64(ms) * float64(time.Millisecond)), true
+}
+
+// Close shuts down the VM and detaches from the game
+func (l *luaScript) Close() {
+	if l.process != nil {
+		_ = l.process.Close()
+		l.process = nil
+	}
+	if l.state != nil {
+		l.state.Close()
+		l.state = nil
+	}
+}
+
+// call invokes the global function name if the script defined it.
+func (l *luaScript) call(name string) (lua.LValue, bool, error) {
+	fn, ok := l.state.GetGlobal(name).(*lua.LFunction)
+	if !ok {
+		return lua.LNil, false, nil
+	}
+
+	err := l.state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true})
+	if err != nil {
+		logger.Errorf(logModule, "%s: %s() failed: %s", l.name, name, err)
+		return lua.LNil, true, err
+	}
+	result := l.state.Get(-1)
+	l.state.Pop(1)
+	return result, true, nil
+}
+
+func (l *luaScript) callBool(name string) bool {
+	result, _, err := l.call(name)
+	return err == nil && lua.LVAsBool(result)
+}
+
+func (l *luaScript) print(L *lua.LState) int {
+	parts := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+	}
+	logger.Infof(logModule, "[%s] %s", l.name, strings.Join(parts, "\t"))
+	return 0
+}
+
+// processLibrary builds the process table:
+//
+//	process.attach(name)              attach to a running process by name, returns true on success
+//	process.attached()                true while attached to a running process
+//	process.pid()                     pid of the attached process or nil
+//	process.module_base(name)         lowest mapped address of a module (e.g. "game.x86_64", "libunity.so") or nil
+//	process.read_u8/u16/u32/u64(addr) unsigned little endian integers
+//	process.read_i8/i16/i32/i64(addr) signed little endian integers
+//	process.read_f32/f64(addr)        floats
+//	process.read_string(addr, max)    NUL terminated string of at most max bytes
+//	process.pointer_path(base, ...)   follow 8 byte pointers through the offsets, returns the final address
+//	process.pointer_path32(base, ...) same with 4 byte pointers
+//
+// All reads return nil when not attached or the address can't be read.
+func (l *luaScript) processLibrary() *lua.LTable {
+	table := l.state.NewTable()
+	l.state.SetFuncs(table, map[string]lua.LGFunction{
+		"attach": func(L *lua.LState) int {
+			name := L.CheckString(1)
+			if l.process != nil {
+				_ = l.process.Close()
+				l.process = nil
+			}
+			p, err := l.attach(name)
+			if err != nil {
+				logger.Debugf(logModule, "attach to %s failed: %s", name, err)
+				L.Push(lua.LFalse)
+				return 1
+			}
+			l.process = p
+			logger.Infof(logModule, "attached to %s (pid %d)", name, p.PID())
+			L.Push(lua.LTrue)
+			return 1
+		},
+		"attached": func(L *lua.LState) int {
+			L.Push(lua.LBool(l.process != nil))
+			return 1
+		},
+		"pid": func(L *lua.LState) int {
+			if l.process == nil {
+				L.Push(lua.LNil)
+				return 1
+			}
+			L.Push(lua.LNumber(l.process.PID()))
+			return 1
+		},
+		"module_base": func(L *lua.LState) int {
+			name := L.CheckString(1)
+			if l.process == nil {
+				L.Push(lua.LNil)
+				return 1
+			}
+			base, ok := l.process.ModuleBase(name)
+			if !ok {
+				L.Push(lua.LNil)
+				return 1
+			}
+			L.Push(lua.LNumber(base))
+			return 1
+		},
+		"read_u8":  l.reader(func(p *procmem.Process, a uint64) (float64, error) { v, err := p.ReadU8(a); return float64(v), err }),
+		"read_u16": l.reader(func(p *procmem.Process, a uint64) (float64, error) { v, err := p.ReadU16(a); return float64(v), err }),
+		"read_u32": l.reader(func(p *procmem.Process, a uint64) (float64, error) { v, err := p.ReadU32(a); return float64(v), err }),
+		"read_u64": l.reader(func(p *procmem.Process, a uint64) (float64, error) { v, err := p.ReadU64(a); return float64(v), err }),
+		"read_i8": l.reader(func(p *procmem.Process, a uint64) (float64, error) {
+			v, err := p.ReadU8(a)
+			return float64(int8(v)), err
+		}),
+		"read_i16": l.reader(func(p *procmem.Process, a uint64) (float64, error) {
+			v, err := p.ReadU16(a)
+			return float64(int16(v)), err
+		}),
+		"read_i32": l.reader(func(p *procmem.Process, a uint64) (float64, error) {
+			v, err := p.ReadU32(a)
+			return float64(int32(v)), err
+		}),
+		"read_i64": l.reader(func(p *procmem.Process, a uint64) (float64, error) {
+			v, err := p.ReadU64(a)
+			return float64(int64(v)), err
+		}),
+		"read_f32": l.reader(func(p *procmem.Process, a uint64) (float64, error) { v, err := p.ReadF32(a); return float64(v), err }),
+		"read_f64": l.reader(func(p *procmem.Process, a uint64) (float64, error) { return p.ReadF64(a) }),
+		"read_string": func(L *lua.LState) int {
+			addr := uint64(L.CheckNumber(1))
+			maxLength := L.OptInt(2, 64)
+			if l.process == nil {
+				L.Push(lua.LNil)
+				return 1
+			}
+			s, err := l.process.ReadString(addr, maxLength)
+			if err != nil {
+				L.Push(lua.LNil)
+				return 1
+			}
+			L.Push(lua.LString(s))
+			return 1
+		},
+		"pointer_path":   l.pointerPath(8),
+		"pointer_path32": l.pointerPath(4),
+	})
+	return table
+}
+
+func (l *luaScript) reader(read func(*procmem.Process, uint64) (float64, error)) lua.LGFunction {
+	return func(L *lua.LState) int {
+		addr := uint64(L.CheckNumber(1))
+		if l.process == nil {
+			L.Push(lua.LNil)
+			return 1
+		}
+		v, err := read(l.process, addr)
+		if err != nil {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(lua.LNumber(v))
+		return 1
+	}
+}
+
+func (l *luaScript) pointerPath(pointerSize int) lua.LGFunction {
+	return func(L *lua.LState) int {
+		base := uint64(L.CheckNumber(1))
+		offsets := make([]int64, 0, L.GetTop()-1)
+		for i := 2; i <= L.GetTop(); i++ {
+			offsets = append(offsets, int64(L.CheckNumber(i)))
+		}
+		if l.process == nil {
+			L.Push(lua.LNil)
+			return 1
+		}
+		addr, err := l.process.ResolvePointerPath(base, offsets, pointerSize)
+		if err != nil {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(lua.LNumber(addr))
+		return 1
+	}
+}
@@ -0,0 +1,221 @@
+package autosplitter
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/procmem"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+type fakeMemory struct {
+	base uint64
+	data []byte
+}
+
+func (f *fakeMemory) ReadAt(p []byte, off int64) (int, error) {
+	start := uint64(off)
+	if start < f.base || start >= f.base+uint64(len(f.data)) {
+		return 0, io.EOF
+	}
+	return copy(p, f.data[start-f.base:]), nil
+}
+
+type mockDispatcher struct {
+	mu       sync.Mutex
+	commands []dispatcher.Command
+	signal   chan dispatcher.Command
+}
+
+func (m *mockDispatcher) DispatchContext(
+	_ context.Context, _ dispatcher.Source, command dispatcher.Command, _ *string,
+) (dispatcher.DispatchReply, error) {
+	m.mu.Lock()
+	m.commands = append(m.commands, command)
+	m.mu.Unlock()
+	select {
+	case m.signal <- command:
+	default:
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+type mockStateProvider struct {
+	state  session.State
+	mu     sync.Mutex
+	status session.GameStatus
+}
+
+func (m *mockStateProvider) State() session.State { return m.state }
+
+func (m *mockStateProvider) SetGameStatus(status session.GameStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+}
+
+const levelScript = `
+refresh_rate = 120
+local level = 0
+local old = 0
+
+function startup()
+	print("starting", "up")
+end
+
+function update()
+	if not process.attached() then
+		if not process.attach("game") then
+			return false
+		end
+	end
+	local base = process.module_base("game.x86_64")
+	old = level
+	level = process.read_u32(process.pointer_path(base, 0x10, 0x4)) or 0
+end
+
+function start() return old == 0 and level == 1 end
+function split() return level > old end
+function reset() return old > 0 and level == 0 end
+function isLoading() return process.read_u8(process.module_base("game.x86_64") + 0x20) == 1 end
+function gameTime() return process.read_u32(process.module_base("game.x86_64") + 0x24) end
+`
+
+func TestLuaScript(t *testing.T) {
+	mem := &fakeMemory{base: 0x1000, data: make([]byte, 0x100)}
+	binary.LittleEndian.PutUint64(mem.data[0x10:], 0x1040)
+	setLevel := func(level uint32) { binary.LittleEndian.PutUint32(mem.data[0x44:], level) }
+
+	script := newLuaScriptFromSource("test.lua", levelScript)
+	attachCalls := 0
+	script.attach = func(name string) (*procmem.Process, error) {
+		attachCalls++
+		if name != "game" {
+			t.Fatalf("attach() name want %q, got %q", "game", name)
+		}
+		return procmem.NewProcess(42, name, mem, []procmem.Mapping{{Start: 0x1000, End: 0x1100, Path: "/games/game.x86_64"}}), nil
+	}
+
+	if err := script.Startup(context.Background()); err != nil {
+		t.Fatalf("Startup() returned error: %s", err)
+	}
+	defer script.Close()
+
+	if script.RefreshRate() != time.Second/120 {
+		t.Fatalf("RefreshRate() want %s, got %s", time.Second/120, script.RefreshRate())
+	}
+
+	expect := func(state session.State, want ...dispatcher.Command) {
+		t.Helper()
+		got := script.Tick(state)
+		if len(got) != len(want) {
+			t.Fatalf("Tick(%d) want %v, got %v", state, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Tick(%d) want %v, got %v", state, want, got)
+			}
+		}
+	}
+
+	expect(session.Idle)
+	setLevel(1)
+	expect(session.Idle, dispatcher.SPLIT)
+	expect(session.Running)
+	setLevel(2)
+	expect(session.Running, dispatcher.SPLIT)
+
+	// loads are reported, never turned into pauses
+	mem.data[0x20] = 1
+	binary.LittleEndian.PutUint32(mem.data[0x24:], 61500)
+	expect(session.Running)
+	want := session.GameStatus{Loading: true, GameTime: 61500 * time.Millisecond, HasGameTime: true}
+	if script.GameStatus() != want {
+		t.Fatalf("GameStatus() want %+v, got %+v", want, script.GameStatus())
+	}
+	mem.data[0x20] = 0
+	expect(session.Paused)
+	if script.GameStatus().Loading {
+		t.Fatal("GameStatus() still loading after isLoading() returned false")
+	}
+
+	setLevel(0)
+	expect(session.Running, dispatcher.RESET)
+	expect(session.Idle)
+	if script.GameStatus() != (session.GameStatus{}) {
+		t.Fatalf("GameStatus() while idle want empty, got %+v", script.GameStatus())
+	}
+
+	if attachCalls != 1 {
+		t.Fatalf("attach() calls want %d, got %d", 1, attachCalls)
+	}
+}
+
+func TestLuaScriptSyntaxError(t *testing.T) {
+	script := newLuaScriptFromSource("broken.lua", "function start( return true end")
+	defer script.Close()
+	if err := script.Startup(context.Background()); err == nil {
+		t.Fatal("Startup() with invalid Lua should return an error")
+	}
+}
+
+func TestLuaScriptCancelled(t *testing.T) {
+	script := newLuaScriptFromSource("loop.lua", "function split() while true do end end")
+	defer script.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := script.Startup(ctx); err != nil {
+		t.Fatalf("Startup() returned error: %s", err)
+	}
+
+	ticked := make(chan []dispatcher.Command)
+	go func() { ticked <- script.Tick(session.Running) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case commands := <-ticked:
+		if len(commands) != 0 {
+			t.Fatalf("Tick() of a cancelled script want no commands, got %v", commands)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Tick() didn't return after the script's context was cancelled")
+	}
+}
+
+func TestRuntimeLoad(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "start.lua"), []byte("refresh_rate = 200\nfunction start() return true end"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &mockDispatcher{signal: make(chan dispatcher.Command, 1)}
+	r := NewRuntime(d, &mockStateProvider{state: session.Idle}, dir)
+	if err = r.Load("start.lua"); err != nil {
+		t.Fatalf("Load() returned error: %s", err)
+	}
+	defer r.Unload()
+
+	select {
+	case command := <-d.signal:
+		if command != dispatcher.SPLIT {
+			t.Fatalf("Runtime dispatched %d, want %d", command, dispatcher.SPLIT)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("timed out waiting for autosplitter to dispatch")
+	}
+
+	if err = r.Load("missing.lua"); err == nil {
+		t.Fatal("Load() of a missing file should return an error")
+	}
+	if err = r.Load("start.txt"); err == nil {
+		t.Fatal("Load() of an unsupported file type should return an error")
+	}
+}
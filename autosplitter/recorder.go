@@ -0,0 +1,60 @@
+package autosplitter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// RecordedPacket is a single packet received by the Socket, as stored in a recording.
+type RecordedPacket struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Data   []byte    `json:"data"`
+}
+
+// Recorder writes every packet handed to it as a line of JSON, so a recording can be inspected by hand, replayed
+// with Replay, and checked in as a test fixture.
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewRecorder creates a Recorder that appends packets to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes a packet received at t from source.  A nil source is recorded as an empty string.
+func (r *Recorder) Record(t time.Time, source net.Addr, data []byte) error {
+	packet := RecordedPacket{
+		Time: t,
+		Data: append([]byte(nil), data...),
+	}
+	packet.Source = addrString(source)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.encoder.Encode(packet)
+}
+
+// ReadRecording parses a recording written by a Recorder
+func ReadRecording(r io.Reader) ([]RecordedPacket, error) {
+	var packets []RecordedPacket
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var packet RecordedPacket
+		err := decoder.Decode(&packet)
+		if errors.Is(err, io.EOF) {
+			return packets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+	}
+}
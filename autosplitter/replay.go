@@ -0,0 +1,210 @@
+package autosplitter
+
+import (
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/platform"
+	"github.com/zellydev-games/opensplit/repo"
+	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/statemachine"
+)
+
+// ReplayResult is the state of the session after every packet in a recording has been handled.
+type ReplayResult struct {
+	// Run is the run in progress (or just finished) when the recording ended, nil if there isn't one
+	Run *session.Run
+	// SplitFile is the replayed split file, including any runs completed during the recording
+	SplitFile session.SplitFile
+	State     session.State
+	// Dropped counts packets the socket rejected as malformed
+	Dropped int
+}
+
+// Replay feeds a recording through a Socket and dispatcher into a state machine running the given split file.
+//
+// The packets drive the real statemachine.Service, so the Running state's guards (split debounce, reset confirmation,
+// minimum segment times) apply exactly as they do in OpenSplit with a default config.  Dialogs are answered "No", so
+// partial runs are never added to the split file on RESET, and files are kept in memory.  Time is driven by the
+// packet timestamps instead of the wall clock: the session's timer reads a fake clock that jumps to each packet's
+// time before it is handled, so the same recording always produces the same Run regardless of how fast the replay
+// runs.
+//
+// Replay replaces the state machine singleton, so it's meant for tools and tests, never for a running OpenSplit.
+func Replay(packets []RecordedPacket, sf session.SplitFile) ReplayResult {
+	clock := &replayClock{}
+	if len(packets) > 0 {
+		clock.now = packets[0].Time
+	}
+
+	sessionService, _ := session.NewService(&replayTimer{clock: clock})
+	sessionService.SetClock(clock.Now)
+	result := ReplayResult{}
+	machine, err := startReplayMachine(sessionService, sf)
+	if err != nil {
+		logger.Errorf(logModule, "failed to load split file for replay: %s", err)
+		result.SplitFile = sf
+		return result
+	}
+	socket := NewSocket(dispatcher.NewService(machine, nil, ""), 0)
+
+	for _, packet := range packets {
+		clock.advance(packet.Time)
+		if _, _, valid := socket.handlePacket(packet.Source, packet.Data, packet.Time); !valid {
+			result.Dropped++
+		}
+	}
+
+	if run, ok := sessionService.Run(); ok {
+		result.Run = &run
+	}
+	result.SplitFile, _ = sessionService.SplitFile()
+	result.State = sessionService.State()
+	return result
+}
+
+// replaySplitFilePath is where startReplayMachine keeps the replayed split file in memory
+const replaySplitFilePath = "replay.osf"
+
+// startReplayMachine builds a state machine around sessionService and loads sf into the Running state, the way
+// opensplit-headless does with its -splitfile flag
+func startReplayMachine(sessionService *session.Service, sf session.SplitFile) (*statemachine.Service, error) {
+	data, err := adapters.SplitFileToFrontEnd(adapters.DomainSplitFileToDTO(sf))
+	if err != nil {
+		return nil, err
+	}
+	files := &replayFiles{files: map[string][]byte{replaySplitFilePath: data}}
+	runtimeProvider := platform.NewHeadlessRuntime(platform.HeadlessOptions{
+		SplitFile:     replaySplitFilePath,
+		DefaultAnswer: "No",
+	})
+	configService, _ := config.NewService()
+	machine := statemachine.InitMachine(runtimeProvider, repo.NewService(repo.NewJsonFile(runtimeProvider, files)),
+		sessionService, configService)
+	machine.Startup(context.Background())
+
+	loadSource := dispatcher.Source{Kind: dispatcher.SourceFrontend}
+	if _, err = machine.ReceiveDispatch(loadSource, dispatcher.LOAD, nil); err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
+
+// NewReplayPacket builds a version 1 command packet as sent by autosplitters, for building recordings in tests
+func NewReplayPacket(t time.Time, source net.Addr, command dispatcher.Command) RecordedPacket {
+	packet := RecordedPacket{
+		Time: t,
+		Data: []byte{magic0, magic1, magic2, magic3, 1, 0, byte(command)},
+	}
+	packet.Source = addrString(source)
+	return packet
+}
+
+// NewTimestampedReplayPacket builds a version 2 command packet for an event seen at sent and received at t
+func NewTimestampedReplayPacket(t, sent time.Time, source net.Addr, command dispatcher.Command) RecordedPacket {
+	packet := NewReplayPacket(t, source, command)
+	packet.Data[4] = 2
+	packet.Data = binary.BigEndian.AppendUint64(packet.Data, uint64(sent.UnixNano()))
+	return packet
+}
+
+// replayFiles is an in memory repo.FileProvider, so replays never touch the user's OpenSplit folder
+type replayFiles struct {
+	files map[string][]byte
+}
+
+func (f *replayFiles) WriteFile(name string, data []byte, _ os.FileMode) error {
+	f.files[name] = slices.Clone(data)
+	return nil
+}
+
+func (f *replayFiles) ReadFile(name string) ([]byte, error) {
+	data, ok := f.files[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (f *replayFiles) MkdirAll(string, os.FileMode) error { return nil }
+func (f *replayFiles) UserHomeDir() (string, error)       { return "", nil }
+
+// replayClock only moves when advance is called
+type replayClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *replayClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *replayClock) advance(to time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if to.After(c.now) {
+		c.now = to
+	}
+}
+
+// replayTimer implements session.Timer against a replayClock
+type replayTimer struct {
+	clock     *replayClock
+	running   bool
+	startTime time.Time
+	elapsed   time.Duration
+}
+
+func (t *replayTimer) Startup(context.Context) {}
+func (t *replayTimer) Run()                    {}
+func (t *replayTimer) IsRunning() bool         { return t.running }
+
+func (t *replayTimer) Start() {
+	t.StartAt(t.clock.Now())
+}
+
+func (t *replayTimer) StartAt(at time.Time) {
+	if !t.running {
+		t.startTime = at.Add(-t.elapsed)
+		t.running = true
+	}
+}
+
+func (t *replayTimer) Pause() {
+	if t.running {
+		t.elapsed = t.clock.Now().Sub(t.startTime)
+		t.running = false
+	}
+}
+
+func (t *replayTimer) Reset() {
+	t.running = false
+	t.elapsed = 0
+}
+
+func (t *replayTimer) GetCurrentTime() time.Duration {
+	return t.TimeAt(t.clock.Now())
+}
+
+func (t *replayTimer) TimeAt(at time.Time) time.Duration {
+	if t.running {
+		return at.Sub(t.startTime)
+	}
+	return t.elapsed
+}
+
+func (t *replayTimer) SubtractTime(d time.Duration) {
+	t.elapsed -= d
+}
@@ -0,0 +1,152 @@
+package autosplitter
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+var seg1 = uuid.MustParse("c9bc9698-0f39-488d-80c6-06308f12b03e")
+var seg2 = uuid.MustParse("05151851-9132-498e-b70a-344ee03c9384")
+
+func getSplitFile() session.SplitFile {
+	return session.SplitFile{
+		ID:       uuid.MustParse("9a268f11-1c89-49af-ae00-a9e2246ec82d"),
+		GameName: "Replay Game",
+		Segments: []session.Segment{{ID: seg1, Name: "Level 1"}, {ID: seg2, Name: "Level 2"}},
+		Runs:     []session.Run{},
+	}
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+
+	if err := r.Record(t0, addr, []byte("OSRC\x01\x00\x09")); err != nil {
+		t.Fatalf("Record() returned error: %s", err)
+	}
+	if err := r.Record(t0.Add(time.Second), nil, []byte("bad")); err != nil {
+		t.Fatalf("Record() returned error: %s", err)
+	}
+
+	packets, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() returned error: %s", err)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("ReadRecording() packets want %d, got %d", 2, len(packets))
+	}
+	if !packets[0].Time.Equal(t0) || packets[0].Source != "127.0.0.1:50000" || string(packets[0].Data) != "OSRC\x01\x00\x09" {
+		t.Fatalf("ReadRecording() unexpected first packet %#v", packets[0])
+	}
+	if packets[1].Source != "" {
+		t.Fatalf("ReadRecording() nil source want empty, got %q", packets[1].Source)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	packets := []RecordedPacket{
+		NewReplayPacket(t0, nil, dispatcher.SPLIT),
+		// duplicate packet inside the debounce window must be ignored
+		NewReplayPacket(t0.Add(50*time.Millisecond), nil, dispatcher.SPLIT),
+		NewReplayPacket(t0.Add(10*time.Second), nil, dispatcher.SPLIT),
+		{Time: t0.Add(11 * time.Second), Data: []byte("junk")},
+		NewReplayPacket(t0.Add(20*time.Second), nil, dispatcher.PAUSE),
+		NewReplayPacket(t0.Add(30*time.Second), nil, dispatcher.PAUSE),
+		NewReplayPacket(t0.Add(35*time.Second), nil, dispatcher.SPLIT),
+	}
+
+	result := Replay(packets, getSplitFile())
+	if result.Dropped != 1 {
+		t.Fatalf("Replay() dropped want %d, got %d", 1, result.Dropped)
+	}
+	if result.State != session.Finished {
+		t.Fatalf("Replay() state want %d, got %d", session.Finished, result.State)
+	}
+	if result.Run == nil || !result.Run.Completed {
+		t.Fatalf("Replay() expected a completed run, got %#v", result.Run)
+	}
+
+	if source := result.Run.Splits[seg1].Source; source.Kind != string(dispatcher.SourceAutosplitter) {
+		t.Fatalf("Replay() split source want %s, got %v", dispatcher.SourceAutosplitter, source)
+	}
+
+	// 10s for the first segment, 35s - 10s - 10s paused for the second
+	if got := result.Run.Splits[seg1].CurrentDuration; got != 10*time.Second {
+		t.Fatalf("Replay() segment 1 want %s, got %s", 10*time.Second, got)
+	}
+	if got := result.Run.Splits[seg2].CurrentDuration; got != 15*time.Second {
+		t.Fatalf("Replay() segment 2 want %s, got %s", 15*time.Second, got)
+	}
+	if result.Run.TotalTime != 25*time.Second {
+		t.Fatalf("Replay() total time want %s, got %s", 25*time.Second, result.Run.TotalTime)
+	}
+	if len(result.SplitFile.Runs) != 1 || result.SplitFile.Attempts != 1 {
+		t.Fatalf("Replay() split file want 1 run and 1 attempt, got %d runs and %d attempts",
+			len(result.SplitFile.Runs), result.SplitFile.Attempts)
+	}
+
+	// The same recording must always produce the same result
+	again := Replay(packets, getSplitFile())
+	if again.Run.TotalTime != result.Run.TotalTime {
+		t.Fatalf("Replay() not deterministic: %s != %s", again.Run.TotalTime, result.Run.TotalTime)
+	}
+}
+
+func TestReplayTimestamped(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	truncated := NewTimestampedReplayPacket(t0.Add(time.Second), t0, nil, dispatcher.SPLIT)
+	truncated.Data = truncated.Data[:10]
+	packets := []RecordedPacket{
+		// each packet arrives a little later than the autosplitter saw its event
+		NewTimestampedReplayPacket(t0.Add(40*time.Millisecond), t0, nil, dispatcher.SPLIT),
+		truncated,
+		NewTimestampedReplayPacket(t0.Add(10*time.Second+300*time.Millisecond), t0.Add(10*time.Second), nil,
+			dispatcher.SPLIT),
+		// a clock running ahead of ours is timed from the packet's arrival
+		NewTimestampedReplayPacket(t0.Add(20*time.Second), t0.Add(20*time.Second+time.Millisecond), nil,
+			dispatcher.SPLIT),
+	}
+
+	result := Replay(packets, getSplitFile())
+	if result.Dropped != 1 {
+		t.Fatalf("Replay() dropped want %d, got %d", 1, result.Dropped)
+	}
+	if result.Run == nil || !result.Run.Completed {
+		t.Fatalf("Replay() expected a completed run, got %#v", result.Run)
+	}
+	if split := result.Run.Splits[seg1]; split.CurrentCumulative != 10*time.Second {
+		t.Fatalf("Replay() first split want %s, got %s", 10*time.Second, split.CurrentCumulative)
+	}
+	if total := result.Run.TotalTime; total != 20*time.Second {
+		t.Fatalf("Replay() total want %s, got %s", 20*time.Second, total)
+	}
+}
+
+func TestReplayMinDuration(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	sf := getSplitFile()
+	sf.Segments[0].MinDuration = 5 * time.Second
+	packets := []RecordedPacket{
+		NewReplayPacket(t0, nil, dispatcher.SPLIT),
+		// the Running state's guards ignore a split faster than the segment's minimum
+		NewReplayPacket(t0.Add(2*time.Second), nil, dispatcher.SPLIT),
+		NewReplayPacket(t0.Add(6*time.Second), nil, dispatcher.SPLIT),
+	}
+
+	result := Replay(packets, sf)
+	if result.Run == nil || result.State != session.Running {
+		t.Fatalf("Replay() want a run in progress, got state %d", result.State)
+	}
+	if split := result.Run.Splits[seg1]; split.CurrentCumulative != 6*time.Second {
+		t.Fatalf("Replay() first split want %s, got %s", 6*time.Second, split.CurrentCumulative)
+	}
+}
@@ -0,0 +1,244 @@
+package autosplitter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+const defaultRefreshRate = time.Second / 60
+
+// startupTimeout bounds how long a script's startup may run, it runs while the state machine holds the dispatcher
+const startupTimeout = 5 * time.Second
+
+// Dispatcher sends commands to the state machine, in production this is *dispatcher.Service
+type Dispatcher interface {
+	DispatchFrom(dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error)
+}
+
+// ScriptDispatcher is the Dispatcher used by the Runtime, it drops a command once its context is cancelled
+type ScriptDispatcher interface {
+	DispatchContext(context.Context, dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error)
+}
+
+// StateProvider reports the state of the current run so scripts know whether to check for start, split or reset, and
+// records the game status scripts read.
+type StateProvider interface {
+	State() session.State
+	SetGameStatus(session.GameStatus)
+}
+
+// Script is an autosplitter program loaded from a split file's AutosplitterFile.
+//
+// Tick is called RefreshRate times a second with the state of the current run and returns the commands that should be
+// dispatched as a result, GameStatus then reports what that tick read from the game.  All methods are called from the
+// Runtime's goroutine, so implementations don't need to be safe for concurrent use.  The context passed to Startup is
+// cancelled when the script is unloaded, scripts must abandon any work in progress when it is.
+type Script interface {
+	Startup(ctx context.Context) error
+	Tick(state session.State) []dispatcher.Command
+	GameStatus() session.GameStatus
+	RefreshRate() time.Duration
+	Close()
+}
+
+// Runtime runs the autosplitter Script attached to the loaded split file.
+//
+// The state machine loads a script when a split file enters the Running state and unloads it when leaving.  Commands
+// produced by the script go through the Dispatcher exactly like hotkeys and UDP packets.
+type Runtime struct {
+	mu         sync.Mutex
+	dispatcher ScriptDispatcher
+	state      StateProvider
+	directory  string
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewRuntime creates a Runtime.  Relative autosplitter file paths are resolved against directory.
+func NewRuntime(d ScriptDispatcher, state StateProvider, directory string) *Runtime {
+	return &Runtime{
+		dispatcher: d,
+		state:      state,
+		directory:  directory,
+	}
+}
+
+// Load stops any running script, then loads and starts the autosplitter file at path.
+func (r *Runtime) Load(path string) error {
+	r.Unload()
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.directory, path)
+	}
+
+	script, err := newScript(path)
+	if err != nil {
+		logger.Errorf(logModule, "failed to load autosplitter %s: %s", path, err)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err = script.Startup(ctx); err != nil {
+		cancel()
+		script.Close()
+		logger.Errorf(logModule, "autosplitter %s startup failed: %s", path, err)
+		return err
+	}
+
+	r.start(ctx, cancel, script, dispatcher.Source{Kind: dispatcher.SourceScript, Detail: filepath.Base(path)})
+	logger.Infof(logModule, "autosplitter %s loaded", path)
+	return nil
+}
+
+// start runs a script that has been started up with ctx on its own goroutine, cancel stops it
+func (r *Runtime) start(ctx context.Context, cancel context.CancelFunc, script Script, source dispatcher.Source) {
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.cancel = cancel
+	r.done = done
+	r.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		r.run(ctx, script, source)
+	}()
+}
+
+// Unload stops the running script, if any, and waits for it to exit so the script is closed and detached from the
+// game when Unload returns.
+func (r *Runtime) Unload() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	r.state.SetGameStatus(session.GameStatus{})
+	logger.Info(logModule, "autosplitter unloaded")
+}
+
+func (r *Runtime) run(ctx context.Context, script Script, source dispatcher.Source) {
+	defer script.Close()
+
+	refreshRate := script.RefreshRate()
+	if refreshRate <= 0 {
+		refreshRate = defaultRefreshRate
+	}
+	ticker := time.NewTicker(refreshRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// commands are timed from when the script read the game, not from when the dispatcher got to them
+		tickSource := source
+		tickSource.At = time.Now()
+		commands := script.Tick(r.state.State())
+		if ctx.Err() != nil {
+			return
+		}
+		r.state.SetGameStatus(script.GameStatus())
+
+		for _, command := range commands {
+			logger.Debugf(logModule, "autosplitter dispatching command %d", command)
+			if !r.dispatch(ctx, tickSource, command) {
+				return
+			}
+		}
+	}
+}
+
+// dispatch sends command to the dispatcher, it reports false if the script was stopped before the dispatcher took it.
+//
+// The state machine unloads the script while the dispatcher is locked, so the run loop can't block on the dispatcher
+// or Unload would never see it exit.  The dispatch waits on its own goroutine instead, and the dispatcher drops the
+// command once ctx is cancelled so it can't reach the state machine after the script was unloaded.
+func (r *Runtime) dispatch(ctx context.Context, source dispatcher.Source, command dispatcher.Command) bool {
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		_, err := r.dispatcher.DispatchContext(ctx, source, command, nil)
+		if err != nil && ctx.Err() == nil {
+			logger.Warnf(logModule, "autosplitter command %d failed: %s", command, err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-dispatched:
+		return true
+	}
+}
+
+// hooks are the questions every autosplitter answers on each tick, regardless of how it's written
+type hooks interface {
+	start() bool
+	split() bool
+	reset() bool
+	isLoading() bool
+	gameTime() (time.Duration, bool)
+}
+
+// commandsFor asks h the questions that apply to the current run state and converts the answers to commands.
+func commandsFor(state session.State, h hooks) []dispatcher.Command {
+	var commands []dispatcher.Command
+	switch state {
+	case session.Idle:
+		if h.start() {
+			commands = append(commands, dispatcher.SPLIT)
+		}
+	case session.Running, session.Paused:
+		if h.reset() {
+			return append(commands, dispatcher.RESET)
+		}
+
+		if h.split() {
+			commands = append(commands, dispatcher.SPLIT)
+		}
+	case session.Finished:
+		if h.reset() {
+			commands = append(commands, dispatcher.RESET)
+		}
+	}
+	return commands
+}
+
+// gameStatusFor asks h for the load state and game time while a run is in progress.
+//
+// Loads never pause the timer: OpenSplit has no game time clock yet, and pausing the real time timer would fight with
+// the runner's own pauses.  The status is recorded on the session for the frontend instead.
+func gameStatusFor(state session.State, h hooks) session.GameStatus {
+	if state != session.Running && state != session.Paused {
+		return session.GameStatus{}
+	}
+	status := session.GameStatus{Loading: h.isLoading()}
+	status.GameTime, status.HasGameTime = h.gameTime()
+	return status
+}
+
+func newScript(path string) (Script, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".lua":
+		return newLuaScript(path)
+	case ".yaml", ".yml", ".json":
+		return newWatcherScript(path)
+	default:
+		return nil, fmt.Errorf("unsupported autosplitter file type: %s", filepath.Ext(path))
+	}
+}
@@ -0,0 +1,121 @@
+package autosplitter
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+type blockingDispatcher struct {
+	dispatched chan struct{}
+	release    chan struct{}
+}
+
+func (d *blockingDispatcher) DispatchContext(
+	context.Context, dispatcher.Source, dispatcher.Command, *string,
+) (dispatcher.DispatchReply, error) {
+	d.dispatched <- struct{}{}
+	<-d.release
+	return dispatcher.DispatchReply{}, nil
+}
+
+type idleState struct{}
+
+func (idleState) State() session.State             { return session.Idle }
+func (idleState) SetGameStatus(session.GameStatus) {}
+
+type splittingScript struct {
+	closed atomic.Bool
+}
+
+func (s *splittingScript) Startup(context.Context) error { return nil }
+func (s *splittingScript) Tick(session.State) []dispatcher.Command {
+	return []dispatcher.Command{dispatcher.SPLIT}
+}
+func (s *splittingScript) GameStatus() session.GameStatus { return session.GameStatus{} }
+func (s *splittingScript) RefreshRate() time.Duration     { return time.Millisecond }
+func (s *splittingScript) Close()                         { s.closed.Store(true) }
+
+func TestUnloadWaitsForScript(t *testing.T) {
+	d := &blockingDispatcher{dispatched: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(d.release)
+	r := NewRuntime(d, idleState{}, "")
+	script := &splittingScript{}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.start(ctx, cancel, script, dispatcher.Source{Kind: dispatcher.SourceScript})
+
+	// the state machine unloads while the dispatcher is busy, the script must still exit
+	<-d.dispatched
+	unloaded := make(chan struct{})
+	go func() {
+		r.Unload()
+		close(unloaded)
+	}()
+
+	select {
+	case <-unloaded:
+	case <-time.After(time.Second):
+		t.Fatal("Unload() didn't return while a dispatch was blocked")
+	}
+	if !script.closed.Load() {
+		t.Fatal("Unload() returned before the script was closed")
+	}
+}
+
+type unloadingReceiver struct {
+	mu       sync.Mutex
+	commands []dispatcher.Command
+	onClose  func()
+}
+
+func (u *unloadingReceiver) ReceiveDispatch(
+	_ dispatcher.Source, command dispatcher.Command, _ *string,
+) (dispatcher.DispatchReply, error) {
+	u.mu.Lock()
+	u.commands = append(u.commands, command)
+	u.mu.Unlock()
+	if command == dispatcher.CLOSE {
+		u.onClose()
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+func (u *unloadingReceiver) received() []dispatcher.Command {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return slices.Clone(u.commands)
+}
+
+func TestUnloadDropsPendingCommands(t *testing.T) {
+	receiver := &unloadingReceiver{}
+	d := dispatcher.NewService(receiver, nil, "")
+	r := NewRuntime(d, idleState{}, "")
+	// the state machine unloads while it holds the dispatcher, the script's next SPLIT is already waiting for it
+	receiver.onClose = func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Unload()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.start(ctx, cancel, &splittingScript{}, dispatcher.Source{Kind: dispatcher.SourceScript})
+
+	deadline := time.Now().Add(time.Second)
+	for len(receiver.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the script to dispatch")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = d.Dispatch(dispatcher.CLOSE, nil)
+	time.Sleep(20 * time.Millisecond)
+
+	commands := receiver.received()
+	if last := commands[len(commands)-1]; last != dispatcher.CLOSE {
+		t.Fatalf("script command %d reached the receiver after the script was unloaded: %v", last, commands)
+	}
+}
@@ -1,10 +1,12 @@
 package autosplitter
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/zellydev-games/opensplit/dispatcher"
 	"github.com/zellydev-games/opensplit/logger"
@@ -13,16 +15,24 @@ import (
 const logModule = "autosplitter"
 const magic0, magic1, magic2, magic3 = 'O', 'S', 'R', 'C'
 
+// Packet layouts.  Version 1 is the magic, version, ack flag and command.  Version 2 appends when the autosplitter saw
+// the event as big endian Unix nanoseconds, so the split is timed from the game rather than from the packet's arrival.
+const (
+	packetSizeV1 = 7
+	packetSizeV2 = 15
+)
+
 type Socket struct {
-	dispatcher *dispatcher.Service
+	dispatcher Dispatcher
 	port       uint16
 	mu         sync.Mutex
 	conn       net.PacketConn
 	closeOnce  sync.Once
 	closed     chan struct{}
+	recorder   *Recorder
 }
 
-func NewSocket(d *dispatcher.Service, port uint16) *Socket {
+func NewSocket(d Dispatcher, port uint16) *Socket {
 	return &Socket{
 		dispatcher: d,
 		port:       port,
@@ -47,6 +57,13 @@ func (s *Socket) Close() error {
 	return err
 }
 
+// Record captures every packet the socket receives to r, for debugging and replaying autosplitter integrations
+func (s *Socket) Record(r *Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = r
+}
+
 func (s *Socket) Listen() {
 	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", s.port))
 	if err != nil {
@@ -62,7 +79,7 @@ func (s *Socket) Listen() {
 		_ = s.Close()
 	}()
 
-	buf := make([]byte, 7)
+	buf := make([]byte, packetSizeV2)
 	for {
 		n, addr, err := conn.ReadFrom(buf)
 		if err != nil {
@@ -82,41 +99,82 @@ func (s *Socket) Listen() {
 			continue
 		}
 
-		if n < 7 {
-			logger.Warnf(logModule, "short packet: %d bytes", n)
-			continue
+		received := time.Now()
+		s.mu.Lock()
+		recorder := s.recorder
+		s.mu.Unlock()
+		if recorder != nil {
+			if err = recorder.Record(received, addr, buf[:n]); err != nil {
+				logger.Errorf(logModule, "failed to record packet: %s", err)
+			}
 		}
 
-		packet := buf[:n]
-		if packet[0] != magic0 || packet[1] != magic1 || packet[2] != magic2 || packet[3] != magic3 {
-			logger.Warnf(logModule, "invalid magic header")
-			continue
+		status, ackRequested, valid := s.handlePacket(addrString(addr), buf[:n], received)
+		if valid && ackRequested {
+			sendAck(conn, addr, status)
 		}
+	}
+}
 
-		version := int(packet[4])
-		ackRequested := int(packet[5]) == 1
-		command := dispatcher.Command(packet[6])
+// handlePacket validates and dispatches a single packet received at the given time.
+//
+// It returns the ack status to send back, whether the sender requested an ack, and false if the packet was malformed
+// and should be dropped without an ack.
+func (s *Socket) handlePacket(sender string, packet []byte, received time.Time) (byte, bool, bool) {
+	if len(packet) < packetSizeV1 {
+		logger.Warnf(logModule, "short packet: %d bytes", len(packet))
+		return 0, false, false
+	}
 
-		if version != 1 {
-			logger.Errorf(logModule, "invalid version: %d", version)
-			if ackRequested {
-				sendAck(conn, addr, 1)
-			}
-			continue
-		}
+	if packet[0] != magic0 || packet[1] != magic1 || packet[2] != magic2 || packet[3] != magic3 {
+		logger.Warnf(logModule, "invalid magic header")
+		return 0, false, false
+	}
 
-		_, err = s.dispatcher.Dispatch(command, nil)
-		if err != nil {
-			if ackRequested {
-				sendAck(conn, addr, 2)
-			}
-			continue
+	version := int(packet[4])
+	ackRequested := int(packet[5]) == 1
+	command := dispatcher.Command(packet[6])
+
+	at := received
+	switch version {
+	case 1:
+	case 2:
+		if len(packet) < packetSizeV2 {
+			logger.Warnf(logModule, "short version 2 packet: %d bytes", len(packet))
+			return 0, false, false
 		}
+		at = packetTime(received, int64(binary.BigEndian.Uint64(packet[7:])))
+	default:
+		logger.Errorf(logModule, "invalid version: %d", version)
+		return 1, ackRequested, true
+	}
 
-		if ackRequested {
-			sendAck(conn, addr, 0)
-		}
+	source := dispatcher.Source{Kind: dispatcher.SourceAutosplitter, Detail: sender, At: at}
+	_, err := s.dispatcher.DispatchFrom(source, command, nil)
+	if err != nil {
+		return 2, ackRequested, true
+	}
+
+	return 0, ackRequested, true
+}
+
+// packetTime moves a version 2 packet's timestamp onto the monotonic clock by how long before the packet was received
+// it is.  The autosplitter's clock may run slightly ahead of ours, so a timestamp after the packet arrived is taken as
+// the arrival.
+func packetTime(received time.Time, unixNano int64) time.Time {
+	age := received.Sub(time.Unix(0, unixNano))
+	if age < 0 {
+		return received
+	}
+	return received.Add(-age)
+}
+
+// addrString formats a remote address for split attribution, a nil address is an empty string
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
 	}
+	return addr.String()
 }
 
 func sendAck(conn net.PacketConn, addr net.Addr, status byte) {
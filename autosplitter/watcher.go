@@ -0,0 +1,436 @@
+package autosplitter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/procmem"
+	"github.com/zellydev-games/opensplit/session"
+	"gopkg.in/yaml.v3"
+)
+
+const attachRetryInterval = time.Second
+
+// WatcherFile is the declarative autosplitter format, stored as YAML or JSON (*.yaml, *.yml, *.json).
+//
+// Watchers read values out of the game's memory every tick, and the start, split, reset and loading conditions are
+// evaluated against them.  Each of those is a list of conditions where any match counts, use "all" inside a
+// condition to require several things at once:
+//
+//	process: game.x86_64
+//	refresh_rate: 60
+//	watchers:
+//	  level: {module: game.x86_64, path: [0x1f2e30, 0x10, 0x4], type: u32}
+//	  loading: {path: [0x1f2e40], type: bool}
+//	start: [{watcher: level, op: changed_to, value: 1}]
+//	split: [{watcher: level, op: increased}]
+//	reset: [{watcher: level, op: changed_to, value: 0}]
+//	loading: [{watcher: loading, op: eq, value: true}]
+type WatcherFile struct {
+	Process     string             `yaml:"process"`
+	RefreshRate float64            `yaml:"refresh_rate"`
+	PointerSize int                `yaml:"pointer_size"`
+	Watchers    map[string]Watcher `yaml:"watchers"`
+	Start       []Condition        `yaml:"start"`
+	Split       []Condition        `yaml:"split"`
+	Reset       []Condition        `yaml:"reset"`
+	Loading     []Condition        `yaml:"loading"`
+}
+
+// Watcher describes a value in the game's memory.
+//
+// Path is a pointer path relative to the base address of Module (or the process executable when Module is empty),
+// see procmem.Process.ResolvePointerPath.  Type is one of u8, u16, u32, u64, i8, i16, i32, i64, f32, f64, bool or
+// string, with Length setting the maximum length of strings.
+type Watcher struct {
+	Module string   `yaml:"module"`
+	Path   []Offset `yaml:"path"`
+	Type   string   `yaml:"type"`
+	Length int      `yaml:"length"`
+}
+
+// Condition compares a watcher's current (and previous) value.
+//
+// Op is one of eq, ne, gt, gte, lt, lte (compare the current value to Value), changed, increased, decreased
+// (compare the current value to the previous one), changed_to and changed_from (the value just became, or just
+// stopped being, Value).  A condition with All or Any set ignores the other fields and combines its children.
+type Condition struct {
+	Watcher string      `yaml:"watcher"`
+	Op      string      `yaml:"op"`
+	Value   any         `yaml:"value"`
+	All     []Condition `yaml:"all"`
+	Any     []Condition `yaml:"any"`
+}
+
+// Offset is a pointer path offset that can be written as a number or a hex string (JSON has no hex literals)
+type Offset int64
+
+func (o *Offset) UnmarshalYAML(node *yaml.Node) error {
+	v, err := strconv.ParseInt(node.Value, 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid offset %q on line %d", node.Value, node.Line)
+	}
+	*o = Offset(v)
+	return nil
+}
+
+// watchedValue is a value read from memory, either numeric or a string
+type watchedValue struct {
+	valid bool
+	num   float64
+	str   string
+	isStr bool
+}
+
+type watcherState struct {
+	old     watchedValue
+	current watchedValue
+}
+
+// watcherScript evaluates a WatcherFile against the game's memory.
+type watcherScript struct {
+	name        string
+	file        WatcherFile
+	process     *procmem.Process
+	attach      func(string) (*procmem.Process, error)
+	lastAttach  time.Time
+	values      map[string]*watcherState
+	pointerSize int
+	status      session.GameStatus
+}
+
+func newWatcherScript(path string) (*watcherScript, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newWatcherScriptFromSource(path, source)
+}
+
+func newWatcherScriptFromSource(name string, source []byte) (*watcherScript, error) {
+	var file WatcherFile
+	if err := yaml.Unmarshal(source, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	pointerSize := file.PointerSize
+	if pointerSize == 0 {
+		pointerSize = 8
+	}
+
+	values := make(map[string]*watcherState, len(file.Watchers))
+	for watcherName := range file.Watchers {
+		values[watcherName] = &watcherState{}
+	}
+
+	return &watcherScript{
+		name:        name,
+		file:        file,
+		attach:      procmem.Attach,
+		values:      values,
+		pointerSize: pointerSize,
+	}, nil
+}
+
+// Validate checks that the file names a process, that watchers have known types, and that conditions only
+// reference watchers that exist with known operators.
+func (w WatcherFile) Validate() error {
+	if w.Process == "" {
+		return fmt.Errorf("process is required")
+	}
+	if w.PointerSize != 0 && w.PointerSize != 4 && w.PointerSize != 8 {
+		return fmt.Errorf("pointer_size must be 4 or 8, got %d", w.PointerSize)
+	}
+	for name, watcher := range w.Watchers {
+		if len(watcher.Path) == 0 {
+			return fmt.Errorf("watcher %s has no path", name)
+		}
+		if _, ok := watcherTypeSizes[watcher.Type]; !ok {
+			return fmt.Errorf("watcher %s has unknown type %q", name, watcher.Type)
+		}
+	}
+
+	for section, conditions := range map[string][]Condition{
+		"start": w.Start, "split": w.Split, "reset": w.Reset, "loading": w.Loading,
+	} {
+		for _, c := range conditions {
+			if err := c.validate(w.Watchers); err != nil {
+				return fmt.Errorf("%s: %w", section, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate(watchers map[string]Watcher) error {
+	if len(c.All) > 0 || len(c.Any) > 0 {
+		for _, child := range slices.Concat(c.All, c.Any) {
+			if err := child.validate(watchers); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, ok := watchers[c.Watcher]; !ok {
+		return fmt.Errorf("condition references unknown watcher %q", c.Watcher)
+	}
+	switch c.Op {
+	case "changed", "increased", "decreased":
+	case "eq", "ne", "gt", "gte", "lt", "lte", "changed_to", "changed_from":
+		if c.Value == nil {
+			return fmt.Errorf("condition %s on %s requires a value", c.Op, c.Watcher)
+		}
+	default:
+		return fmt.Errorf("unknown condition op %q", c.Op)
+	}
+	return nil
+}
+
+var watcherTypeSizes = map[string]int{
+	"u8": 1, "i8": 1, "bool": 1,
+	"u16": 2, "i16": 2,
+	"u32": 4, "i32": 4, "f32": 4,
+	"u64": 8, "i64": 8, "f64": 8,
+	"string": 0,
+}
+
+func (w *watcherScript) Startup(context.Context) error { return nil }
+
+func (w *watcherScript) RefreshRate() time.Duration {
+	if w.file.RefreshRate <= 0 {
+		return defaultRefreshRate
+	}
+	return time.Duration(float64(time.Second) / w.file.RefreshRate)
+}
+
+func (w *watcherScript) Close() {
+	if w.process != nil {
+		_ = w.process.Close()
+		w.process = nil
+	}
+}
+
+// Tick refreshes every watcher from memory then evaluates the conditions for the current run state
+func (w *watcherScript) Tick(state session.State) []dispatcher.Command {
+	if w.process != nil && !w.process.Alive() {
+		logger.Infof(logModule, "attached process %s exited", w.process.Name())
+		_ = w.process.Close()
+		w.process = nil
+	}
+
+	if w.process == nil {
+		w.status = session.GameStatus{}
+		if time.Since(w.lastAttach) < attachRetryInterval {
+			return nil
+		}
+		w.lastAttach = time.Now()
+		p, err := w.attach(w.file.Process)
+		if err != nil {
+			logger.Debugf(logModule, "attach to %s failed: %s", w.file.Process, err)
+			return nil
+		}
+		w.process = p
+		logger.Infof(logModule, "attached to %s (pid %d)", w.file.Process, p.PID())
+	}
+
+	for name, watcher := range w.file.Watchers {
+		v := w.values[name]
+		v.old = v.current
+		v.current = w.read(watcher)
+	}
+
+	commands := commandsFor(state, w)
+	w.status = gameStatusFor(state, w)
+	return commands
+}
+
+// GameStatus is the load state read by the last Tick, watcher files have no game time
+func (w *watcherScript) GameStatus() session.GameStatus { return w.status }
+
+func (w *watcherScript) start() bool                     { return w.any(w.file.Start) }
+func (w *watcherScript) split() bool                     { return w.any(w.file.Split) }
+func (w *watcherScript) reset() bool                     { return w.any(w.file.Reset) }
+func (w *watcherScript) isLoading() bool                 { return w.any(w.file.Loading) }
+func (w *watcherScript) gameTime() (time.Duration, bool) { return 0, false }
+
+func (w *watcherScript) read(watcher Watcher) watchedValue {
+	module := watcher.Module
+	if module == "" {
+		module = w.file.Process
+	}
+	base, ok := w.process.ModuleBase(module)
+	if !ok {
+		return watchedValue{}
+	}
+
+	offsets := make([]int64, len(watcher.Path))
+	for i, o := range watcher.Path {
+		offsets[i] = int64(o)
+	}
+	addr, err := w.process.ResolvePointerPath(base, offsets, w.pointerSize)
+	if err != nil {
+		return watchedValue{}
+	}
+
+	if watcher.Type == "string" {
+		length := watcher.Length
+		if length <= 0 {
+			length = 64
+		}
+		s, err := w.process.ReadString(addr, length)
+		if err != nil {
+			return watchedValue{}
+		}
+		return watchedValue{valid: true, str: s, isStr: true}
+	}
+
+	b, err := w.process.Read(addr, watcherTypeSizes[watcher.Type])
+	if err != nil {
+		return watchedValue{}
+	}
+	return watchedValue{valid: true, num: decodeNumber(watcher.Type, b)}
+}
+
+func decodeNumber(valueType string, b []byte) float64 {
+	var u uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	switch valueType {
+	case "i8":
+		return float64(int8(u))
+	case "i16":
+		return float64(int16(u))
+	case "i32":
+		return float64(int32(u))
+	case "i64":
+		return float64(int64(u))
+	case "f32":
+		return float64(math.Float32frombits(uint32(u)))
+	case "f64":
+		return math.Float64frombits(u)
+	case "bool":
+		if u != 0 {
+			return 1
+		}
+		return 0
+	default:
+		return float64(u)
+	}
+}
+
+func (w *watcherScript) any(conditions []Condition) bool {
+	for _, c := range conditions {
+		if w.eval(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watcherScript) eval(c Condition) bool {
+	if len(c.All) > 0 {
+		for _, child := range c.All {
+			if !w.eval(child) {
+				return false
+			}
+		}
+		return true
+	}
+	if len(c.Any) > 0 {
+		return w.any(c.Any)
+	}
+
+	v, ok := w.values[c.Watcher]
+	if !ok || !v.current.valid {
+		return false
+	}
+	cur := v.current
+
+	switch c.Op {
+	case "changed":
+		return v.old.valid && compare(cur, v.old) != 0
+	case "increased":
+		return v.old.valid && compare(cur, v.old) > 0
+	case "decreased":
+		return v.old.valid && compare(cur, v.old) < 0
+	}
+
+	target, ok := toWatchedValue(c.Value, cur.isStr)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case "eq":
+		return compare(cur, target) == 0
+	case "ne":
+		return compare(cur, target) != 0
+	case "gt":
+		return compare(cur, target) > 0
+	case "gte":
+		return compare(cur, target) >= 0
+	case "lt":
+		return compare(cur, target) < 0
+	case "lte":
+		return compare(cur, target) <= 0
+	case "changed_to":
+		return v.old.valid && compare(cur, target) == 0 && compare(v.old, target) != 0
+	case "changed_from":
+		return v.old.valid && compare(v.old, target) == 0 && compare(cur, target) != 0
+	}
+	return false
+}
+
+func compare(a, b watchedValue) int {
+	if a.isStr || b.isStr {
+		switch {
+		case a.str < b.str:
+			return -1
+		case a.str > b.str:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case a.num < b.num:
+		return -1
+	case a.num > b.num:
+		return 1
+	}
+	return 0
+}
+
+func toWatchedValue(v any, asString bool) (watchedValue, bool) {
+	switch value := v.(type) {
+	case string:
+		if asString {
+			return watchedValue{valid: true, str: value, isStr: true}, true
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return watchedValue{}, false
+		}
+		return watchedValue{valid: true, num: n}, true
+	case bool:
+		if value {
+			return watchedValue{valid: true, num: 1}, true
+		}
+		return watchedValue{valid: true, num: 0}, true
+	case int:
+		return watchedValue{valid: true, num: float64(value)}, true
+	case float64:
+		return watchedValue{valid: true, num: value}, true
+	}
+	return watchedValue{}, false
+}
@@ -0,0 +1,122 @@
+package autosplitter
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/procmem"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+const levelWatcherYAML = `
+process: game.x86_64
+refresh_rate: 30
+watchers:
+  level: {path: [0x10, 0x4], type: u32}
+  loading: {module: libengine.so, path: [0x0], type: bool}
+  area: {path: [0x30], type: string, length: 16}
+start:
+  - {watcher: level, op: changed_to, value: 1}
+split:
+  - all:
+      - {watcher: level, op: increased}
+      - {watcher: area, op: ne, value: "menu"}
+reset:
+  - {watcher: level, op: changed_to, value: 0}
+loading:
+  - {watcher: loading, op: eq, value: true}
+`
+
+const levelWatcherJSON = `{
+  "process": "game.x86_64",
+  "watchers": {"level": {"path": ["0x10", "0x4"], "type": "u32"}},
+  "split": [{"watcher": "level", "op": "increased"}]
+}`
+
+func TestWatcherScript(t *testing.T) {
+	mem := &fakeMemory{base: 0x1000, data: make([]byte, 0x200)}
+	binary.LittleEndian.PutUint64(mem.data[0x10:], 0x1040)
+	copy(mem.data[0x30:], "castle\x00")
+	setLevel := func(level uint32) { binary.LittleEndian.PutUint32(mem.data[0x44:], level) }
+
+	script, err := newWatcherScriptFromSource("test.yaml", []byte(levelWatcherYAML))
+	if err != nil {
+		t.Fatalf("newWatcherScriptFromSource() returned error: %s", err)
+	}
+	script.attach = func(name string) (*procmem.Process, error) {
+		return procmem.NewProcess(42, name, mem, []procmem.Mapping{
+			{Start: 0x1000, End: 0x1100, Path: "/games/game.x86_64"},
+			{Start: 0x1100, End: 0x1200, Path: "/games/libengine.so"},
+		}), nil
+	}
+	defer script.Close()
+
+	expect := func(state session.State, want ...dispatcher.Command) {
+		t.Helper()
+		got := script.Tick(state)
+		if len(got) != len(want) {
+			t.Fatalf("Tick(%d) want %v, got %v", state, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Tick(%d) want %v, got %v", state, want, got)
+			}
+		}
+	}
+
+	expect(session.Idle)
+	setLevel(1)
+	expect(session.Idle, dispatcher.SPLIT)
+	setLevel(2)
+	expect(session.Running, dispatcher.SPLIT)
+
+	// area must not be "menu" for a split to count
+	copy(mem.data[0x30:], "menu\x00")
+	setLevel(3)
+	expect(session.Running)
+	copy(mem.data[0x30:], "castle\x00")
+
+	// loads are reported, never turned into pauses
+	mem.data[0x100] = 1
+	expect(session.Running)
+	if !script.GameStatus().Loading {
+		t.Fatal("GameStatus() want loading while the loading condition matches")
+	}
+	mem.data[0x100] = 0
+	expect(session.Paused)
+	if script.GameStatus() != (session.GameStatus{}) {
+		t.Fatalf("GameStatus() after the load want empty, got %+v", script.GameStatus())
+	}
+
+	setLevel(0)
+	expect(session.Running, dispatcher.RESET)
+}
+
+func TestWatcherScriptJSON(t *testing.T) {
+	script, err := newWatcherScriptFromSource("test.json", []byte(levelWatcherJSON))
+	if err != nil {
+		t.Fatalf("newWatcherScriptFromSource() returned error: %s", err)
+	}
+	if len(script.file.Watchers["level"].Path) != 2 || script.file.Watchers["level"].Path[1] != 4 {
+		t.Fatalf("hex string offsets not parsed: %#v", script.file.Watchers["level"].Path)
+	}
+	if script.RefreshRate() != defaultRefreshRate {
+		t.Fatalf("RefreshRate() want default %s, got %s", defaultRefreshRate, script.RefreshRate())
+	}
+}
+
+func TestWatcherFileValidate(t *testing.T) {
+	cases := map[string]string{
+		"missing process": `watchers: {}`,
+		"unknown type":    "process: g\nwatchers: {a: {path: [0], type: u128}}",
+		"unknown watcher": "process: g\nsplit: [{watcher: nope, op: changed}]",
+		"unknown op":      "process: g\nwatchers: {a: {path: [0], type: u8}}\nsplit: [{watcher: a, op: wobble}]",
+		"missing value":   "process: g\nwatchers: {a: {path: [0], type: u8}}\nsplit: [{watcher: a, op: eq}]",
+	}
+	for name, source := range cases {
+		if _, err := newWatcherScriptFromSource(name, []byte(source)); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+const ghostEventName = "ghost:update"
+
+// GhostSource finds where the ghost run was at a given time, in production this is *session.Service
+type GhostSource interface {
+	Ghost(elapsed time.Duration) (session.Ghost, bool)
+}
+
+// Ghost emits the ghost run's position alongside every timer update
+type Ghost struct {
+	timerUpdateChannel chan time.Duration
+	source             GhostSource
+	runtimeProvider    RuntimeProvider
+}
+
+func NewGhost(timerUpdateChannel chan time.Duration, source GhostSource, runtimeProvider RuntimeProvider) *Ghost {
+	return &Ghost{
+		timerUpdateChannel: timerUpdateChannel,
+		source:             source,
+		runtimeProvider:    runtimeProvider,
+	}
+}
+
+// StartUIPump emits a dto.Ghost for every timer update, or nil when the split file has no run to race
+func (g *Ghost) StartUIPump() {
+	go func() {
+		for currentTime := range g.timerUpdateChannel {
+			ghost, ok := g.source.Ghost(max(currentTime, 0))
+			if !ok {
+				g.runtimeProvider.EventsEmit(ghostEventName, nil)
+				continue
+			}
+			payload := adapters.DomainGhostToDTO(ghost)
+			g.runtimeProvider.EventsEmit(ghostEventName, &payload)
+		}
+	}()
+	logger.Debug(logModule, "started ghost UI pump")
+}
@@ -0,0 +1,40 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+type mockGhostSource struct{ run *session.Run }
+
+func (g mockGhostSource) Ghost(elapsed time.Duration) (session.Ghost, bool) {
+	if g.run == nil {
+		return session.Ghost{}, false
+	}
+	return g.run.GhostAt(elapsed), true
+}
+
+func TestGhostUIPump(t *testing.T) {
+	rp := newMockRuntimeProvider()
+	updates := make(chan time.Duration, 1)
+	segmentID := uuid.New()
+	run := &session.Run{ID: uuid.New(), LeafSegments: []session.Segment{{ID: segmentID}}, Splits: map[uuid.UUID]session.Split{
+		segmentID: {SplitSegmentID: segmentID, CurrentCumulative: 2 * time.Second},
+	}}
+	NewGhost(updates, mockGhostSource{run}, rp).StartUIPump()
+
+	updates <- time.Second
+	select {
+	case call := <-rp.signal:
+		ghost, ok := call.args[0].(*dto.Ghost)
+		if call.event != "ghost:update" || !ok || ghost.SegmentID != segmentID.String() || ghost.Progress != 0.5 {
+			t.Fatalf("expected ghost:update halfway through the segment, got %s %#v", call.event, call.args[0])
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("timed out waiting for EventsEmit")
+	}
+}
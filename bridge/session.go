@@ -57,6 +57,9 @@ type AppViewModel struct {
 
 	// Only set for settings
 	Config *config.Service `json:"config,omitempty"`
+
+	// ValidCommands names the commands the state machine accepts in the state that sent this model
+	ValidCommands []string `json:"validCommands"`
 }
 
 // EmitUIEvent informs the frontend of a state change
@@ -112,7 +112,7 @@ func TestEmitUIEvent(t *testing.T) {
 			t.Fatalf("expected AppViewModel arg, got %T", call.args[0])
 		}
 
-		if got != model {
+		if !reflect.DeepEqual(got, model) {
 			t.Fatalf("expected model %#v, got %#v", model, got)
 		}
 
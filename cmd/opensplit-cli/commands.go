@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+func runSummary(args []string, out io.Writer) error {
+	path, err := parseFlags(flag.NewFlagSet("summary", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+	sf.BuildStats()
+
+	completed := 0
+	for _, run := range sf.Runs {
+		if run.Completed {
+			completed++
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Game:\t%s\n", sf.GameName)
+	_, _ = fmt.Fprintf(w, "Category:\t%s\n", sf.GameCategory)
+	_, _ = fmt.Fprintf(w, "ID:\t%s\n", sf.ID)
+	_, _ = fmt.Fprintf(w, "Version:\t%d\n", sf.Version)
+	_, _ = fmt.Fprintf(w, "Attempts:\t%d\n", sf.Attempts)
+	_, _ = fmt.Fprintf(w, "Segments:\t%d (%d timed)\n", len(sf.Segments), len(sf.DeepCopyLeafSegments()))
+	_, _ = fmt.Fprintf(w, "Runs:\t%d (%d completed)\n", len(sf.Runs), completed)
+	if sf.PracticeAttempts > 0 {
+		_, _ = fmt.Fprintf(w, "Practice:\t%d attempts (%d recorded)\n", sf.PracticeAttempts, len(sf.PracticeRuns))
+	}
+	if sf.PB != nil {
+		_, _ = fmt.Fprintf(w, "PB:\t%s\n", timer.FormatTimeToString(sf.PB.TotalTime))
+	} else {
+		_, _ = fmt.Fprintf(w, "PB:\t-\n")
+	}
+	_, _ = fmt.Fprintf(w, "Sum of Best:\t%s\n", timer.FormatTimeToString(sf.SOB))
+	if sf.Offset != 0 {
+		_, _ = fmt.Fprintf(w, "Offset:\t%s\n", timer.FormatTimeToString(sf.Offset))
+	}
+	for i, game := range sf.Marathon {
+		_, _ = fmt.Fprintf(w, "Game %d:\t%s\n", i+1, game.Path)
+	}
+	if sf.AutosplitterFile != "" {
+		_, _ = fmt.Fprintf(w, "Autosplitter:\t%s\n", sf.AutosplitterFile)
+	}
+	counts := sf.SplitSourceCounts()
+	for _, source := range slices.Sorted(maps.Keys(counts)) {
+		_, _ = fmt.Fprintf(w, "Splits from %s:\t%d\n", source, counts[source])
+	}
+	return w.Flush()
+}
+
+func runRuns(args []string, out io.Writer) error {
+	path, err := parseFlags(flag.NewFlagSet("runs", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+	sf.BuildStats()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "#\tID\tCOMPLETED\tSPLITS\tTIME\t")
+	for i, run := range sf.Runs {
+		total := "-"
+		if run.Completed {
+			total = timer.FormatTimeToString(run.TotalTime)
+		}
+		pb := ""
+		if sf.PB != nil && sf.PB.ID == run.ID {
+			pb = "PB"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%t\t%d\t%s\t%s\n", i+1, run.ID, run.Completed, len(run.Splits), total, pb)
+	}
+	return w.Flush()
+}
+
+func runStats(args []string, out io.Writer) error {
+	path, err := parseFlags(flag.NewFlagSet("stats", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+	sf.BuildStats()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SEGMENT\tPB\tGOLD\tAVERAGE\t")
+	for _, segment := range sf.DeepCopyLeafSegments() {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", segment.Name,
+			formatStat(segment.PB), formatStat(segment.Gold), formatStat(segment.Average))
+	}
+	pb := time.Duration(-1)
+	if sf.PB != nil {
+		pb = sf.PB.TotalTime
+	}
+	_, _ = fmt.Fprintf(w, "Total\t%s\t%s\t\t\n", formatStat(pb), formatStat(sf.SOB))
+	return w.Flush()
+}
+
+func runValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "fix problems that have an obvious fix and write the file")
+	output := fs.String("o", "", "write the repaired file here instead of over FILE")
+	path, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	sf, err := readDTO(path)
+	if err != nil {
+		return err
+	}
+
+	problems := validate(&sf, *repair)
+	remaining := 0
+	for _, p := range problems {
+		if p.repaired {
+			_, _ = fmt.Fprintf(out, "repaired: %s\n", p.message)
+		} else {
+			remaining++
+			_, _ = fmt.Fprintf(out, "problem: %s\n", p.message)
+		}
+	}
+
+	if *repair && remaining < len(problems) {
+		domain, err := adapters.DTOSplitFileToDomain(sf)
+		if err != nil {
+			return err
+		}
+		domain.BuildStats()
+		if err = writeSplitFile(outputPath(*output, path), domain); err != nil {
+			return err
+		}
+	}
+
+	if remaining > 0 {
+		return errProblemsFound
+	}
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintln(out, "ok")
+	}
+	return nil
+}
+
+func runConvert(args []string, _ io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write, the format is taken from its extension")
+	path, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("-o is required")
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+	return writeSplitFile(*output, sf)
+}
+
+func runPrune(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	keep := fs.Int("keep", 0, "keep only the most recent N runs (the PB is always kept), 0 keeps all")
+	incomplete := fs.Bool("incomplete", false, "remove runs that weren't completed")
+	output := fs.String("o", "", "write the pruned file here instead of over FILE")
+	path, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *keep < 0 {
+		return errors.New("-keep must not be negative")
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+	sf.BuildStats()
+
+	before := len(sf.Runs)
+	sf.Runs = pruneRuns(sf.Runs, sf.PB, *keep, *incomplete)
+	sf.BuildStats()
+	_, _ = fmt.Fprintf(out, "removed %d of %d runs\n", before-len(sf.Runs), before)
+	return writeSplitFile(outputPath(*output, path), sf)
+}
+
+// pruneRuns returns the runs to keep, in their original order.  The PB is never removed.
+func pruneRuns(runs []session.Run, pb *session.Run, keep int, incomplete bool) []session.Run {
+	isPB := func(run session.Run) bool {
+		return pb != nil && run.ID == pb.ID
+	}
+
+	var kept []session.Run
+	for _, run := range runs {
+		if incomplete && !run.Completed && !isPB(run) {
+			continue
+		}
+		kept = append(kept, run)
+	}
+
+	if keep == 0 || len(kept) <= keep {
+		return kept
+	}
+
+	// walk back from the newest run, the PB doesn't count towards keep
+	var recent []session.Run
+	for i := len(kept) - 1; i >= 0; i-- {
+		if isPB(kept[i]) || len(recent) < keep {
+			recent = append(recent, kept[i])
+		}
+	}
+	slices.Reverse(recent)
+	return recent
+}
+
+func runRename(args []string, _ io.Writer) error {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	game := fs.String("game", "", "new game name")
+	category := fs.String("category", "", "new category name")
+	output := fs.String("o", "", "write the renamed file here instead of over FILE")
+	path, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *game == "" && *category == "" {
+		return errors.New("at least one of -game or -category is required")
+	}
+	sf, err := readSplitFile(path)
+	if err != nil {
+		return err
+	}
+
+	if *game != "" {
+		sf.GameName = *game
+	}
+	if *category != "" {
+		sf.GameCategory = *category
+	}
+	return writeSplitFile(outputPath(*output, path), sf)
+}
+
+func runMarathon(args []string, _ io.Writer) error {
+	fs := flag.NewFlagSet("marathon", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	game := fs.String("game", "", "marathon name")
+	category := fs.String("category", "", "marathon category")
+	output := fs.String("o", "", "write the marathon split file here")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *game == "" || *output == "" {
+		return errors.New("-game and -o are required")
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("a marathon needs at least two split files, got %d", fs.NArg())
+	}
+
+	marathon := session.SplitFile{ID: uuid.New(), GameName: *game, GameCategory: *category}
+	games := make([]session.SplitFile, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		sf, err := readSplitFile(path)
+		if err != nil {
+			return err
+		}
+		games = append(games, sf)
+		marathon.Marathon = append(marathon.Marathon, session.MarathonGame{Path: marathonGamePath(*output, path)})
+	}
+	if err := marathon.AttachMarathonGames(games); err != nil {
+		return err
+	}
+	return writeSplitFile(*output, marathon)
+}
+
+// marathonGamePath stores a game's path relative to the marathon file when it can, so the files can be moved together
+func marathonGamePath(marathonPath string, gamePath string) string {
+	absMarathon, err := filepath.Abs(marathonPath)
+	if err != nil {
+		return gamePath
+	}
+	absGame, err := filepath.Abs(gamePath)
+	if err != nil {
+		return gamePath
+	}
+	if rel, err := filepath.Rel(filepath.Dir(absMarathon), absGame); err == nil {
+		return rel
+	}
+	return absGame
+}
+
+// formatStat formats a stat, BuildStats uses -1 for stats without data
+func formatStat(d time.Duration) string {
+	if d < 0 {
+		return "-"
+	}
+	return timer.FormatTimeToString(d)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readDTO reads a split file without validating it, so validate can report problems the adapters would trip over
+func readDTO(path string) (dto.SplitFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dto.SplitFile{}, err
+	}
+
+	if isYAML(path) {
+		// dto only has json tags, so go through a generic value to keep the same field names in both formats
+		var v any
+		if err = yaml.Unmarshal(data, &v); err != nil {
+			return dto.SplitFile{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return dto.SplitFile{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	var sf dto.SplitFile
+	if err = json.Unmarshal(data, &sf); err != nil {
+		return dto.SplitFile{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return sf, nil
+}
+
+// readSplitFile reads a split file into the session model, failing if it doesn't validate
+func readSplitFile(path string) (session.SplitFile, error) {
+	sf, err := readDTO(path)
+	if err != nil {
+		return session.SplitFile{}, err
+	}
+	if problems := validate(&sf, false); len(problems) > 0 {
+		return session.SplitFile{}, fmt.Errorf("%s is invalid (%s), run validate -repair", path, problems[0].message)
+	}
+	return adapters.DTOSplitFileToDomain(sf)
+}
+
+// writeSplitFile writes sf to path in the format matching its extension
+func writeSplitFile(path string, sf session.SplitFile) error {
+	return writeDTO(path, adapters.DomainSplitFileToDTO(sf))
+}
+
+func writeDTO(path string, sf dto.SplitFile) error {
+	data, err := adapters.SplitFileToFrontEnd(sf)
+	if err != nil {
+		return err
+	}
+
+	if isYAML(path) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var v any
+		if err = decoder.Decode(&v); err != nil {
+			return err
+		}
+		if data, err = yaml.Marshal(integers(v)); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// integers replaces json.Numbers with int64s where possible, so times are written to YAML as plain integers
+func integers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = integers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = integers(value)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// outputPath is -o if given, otherwise the input file is rewritten
+func outputPath(out string, in string) string {
+	if out != "" {
+		return out
+	}
+	return in
+}
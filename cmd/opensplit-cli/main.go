@@ -0,0 +1,82 @@
+// opensplit-cli inspects and maintains OpenSplit split files.
+//
+//	opensplit-cli summary FILE
+//	opensplit-cli runs FILE
+//	opensplit-cli stats FILE
+//	opensplit-cli validate [-repair] [-o OUT] FILE
+//	opensplit-cli convert -o OUT FILE
+//	opensplit-cli prune [-keep N] [-incomplete] [-o OUT] FILE
+//	opensplit-cli rename [-game NAME] [-category NAME] [-o OUT] FILE
+//	opensplit-cli marathon -game NAME [-category NAME] -o OUT FILE FILE...
+//
+// Files ending in .yaml or .yml are read and written as YAML, anything else as the JSON .osf format.
+// Commands that change a file write it back in place unless -o is given.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"summary":  {"summary FILE", runSummary},
+	"runs":     {"runs FILE", runRuns},
+	"stats":    {"stats FILE", runStats},
+	"validate": {"validate [-repair] [-o OUT] FILE", runValidate},
+	"convert":  {"convert -o OUT FILE", runConvert},
+	"prune":    {"prune [-keep N] [-incomplete] [-o OUT] FILE", runPrune},
+	"rename":   {"rename [-game NAME] [-category NAME] [-o OUT] FILE", runRename},
+	"marathon": {"marathon -game NAME [-category NAME] -o OUT FILE FILE...", runMarathon},
+}
+
+// errProblemsFound makes validate exit non-zero without printing anything else
+var errProblemsFound = errors.New("split file has problems")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(os.Args[2:], os.Stdout)
+	if errors.Is(err, errProblemsFound) {
+		os.Exit(1)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	_, _ = fmt.Fprintln(os.Stderr, "usage: opensplit-cli COMMAND [flags] FILE")
+	for _, name := range []string{"summary", "runs", "stats", "validate", "convert", "prune", "rename", "marathon"} {
+		_, _ = fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
+
+// parseFlags parses a subcommand's flags and returns its one FILE argument
+func parseFlags(fs *flag.FlagSet, args []string) (string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("expected exactly one split file, got %d", fs.NArg())
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/zellydev-games/opensplit/dto"
+)
+
+// problem is something wrong with a split file, repaired is true when validate(sf, true) fixed it
+type problem struct {
+	message  string
+	repaired bool
+}
+
+// validate checks the things DTOSplitFileToDomain and BuildStats assume about a split file.
+//
+// With repair set, problems are fixed in place where there is an obvious fix: missing or malformed IDs are
+// regenerated, and everything that referenced a segment by its old ID follows it to the new one.  Runs left behind
+// with a nil ID are dropped, splits for segments that no longer exist are dropped and attempts is raised to the
+// number of runs.
+func validate(sf *dto.SplitFile, repair bool) []problem {
+	var problems []problem
+	report := func(fixable bool, format string, args ...any) {
+		problems = append(problems, problem{message: fmt.Sprintf(format, args...), repaired: repair && fixable})
+	}
+
+	if _, err := uuid.Parse(sf.ID); err != nil {
+		report(true, "split file ID %q is not a UUID", sf.ID)
+		if repair {
+			sf.ID = uuid.NewString()
+		}
+	}
+
+	leafIDs := map[string]bool{}
+	seenSegments := map[string]bool{}
+	renamed := map[string]string{}
+	var checkSegments func(segments []dto.Segment)
+	checkSegments = func(segments []dto.Segment) {
+		for i := range segments {
+			segment := &segments[i]
+			if _, err := uuid.Parse(segment.ID); err != nil || seenSegments[segment.ID] {
+				report(true, "segment %q has a missing, malformed or duplicate ID %q", segment.Name, segment.ID)
+				if repair {
+					// a duplicate's splits belong to the segment that had the ID first, so only those move
+					id := uuid.NewString()
+					if !seenSegments[segment.ID] {
+						renamed[segment.ID] = id
+					}
+					segment.ID = id
+				}
+			}
+			seenSegments[segment.ID] = true
+
+			if len(segment.Children) > 0 {
+				checkSegments(segment.Children)
+			} else {
+				leafIDs[segment.ID] = true
+			}
+		}
+	}
+	checkSegments(sf.Segments)
+	if len(renamed) > 0 {
+		renameSegments(sf, renamed)
+	}
+
+	seenRuns := map[string]bool{}
+	var runs []dto.Run
+	for i, run := range sf.Runs {
+		id, err := uuid.Parse(run.ID)
+		if err == nil && id == uuid.Nil && len(run.Splits) == 0 {
+			report(true, "run %d is empty and has a nil ID", i+1)
+			if repair {
+				continue
+			}
+		} else if err != nil || seenRuns[run.ID] {
+			report(true, "run %d has a malformed or duplicate ID %q", i+1, run.ID)
+			if repair {
+				run.ID = uuid.NewString()
+			}
+		}
+		seenRuns[run.ID] = true
+
+		for segmentID, split := range run.Splits {
+			if !leafIDs[segmentID] {
+				report(true, "run %d has a split for unknown segment %s", i+1, segmentID)
+				if repair {
+					delete(run.Splits, segmentID)
+				}
+				continue
+			}
+			if split.SplitSegmentID != segmentID {
+				report(true, "run %d split %s is labelled with segment %q", i+1, segmentID, split.SplitSegmentID)
+				if repair {
+					split.SplitSegmentID = segmentID
+					run.Splits[segmentID] = split
+				}
+			}
+			if split.CurrentDuration < 0 || split.CurrentCumulative < 0 {
+				report(false, "run %d has a negative split time for segment %s", i+1, segmentID)
+			}
+		}
+
+		if run.Completed && len(run.Splits) == 0 {
+			report(false, "run %d is marked completed but has no splits", i+1)
+		}
+		runs = append(runs, run)
+	}
+	if repair {
+		sf.Runs = runs
+	}
+
+	if sf.Attempts < len(sf.Runs) {
+		report(true, "attempts (%d) is less than the number of runs (%d)", sf.Attempts, len(sf.Runs))
+		if repair {
+			sf.Attempts = len(sf.Runs)
+		}
+	}
+
+	if sf.PB != nil && !slices.ContainsFunc(sf.Runs, func(r dto.Run) bool { return r.ID == sf.PB.ID }) {
+		report(true, "PB %q is not one of the split file's runs", sf.PB.ID)
+		if repair {
+			// BuildStats picks the PB again when the repaired file is written
+			sf.PB = nil
+		}
+	}
+
+	return problems
+}
+
+// renameSegments points everything in sf that references a segment by an old ID in renamed to its new ID
+func renameSegments(sf *dto.SplitFile, renamed map[string]string) {
+	rename := func(id *string) {
+		if newID, ok := renamed[*id]; ok {
+			*id = newID
+		}
+	}
+	var renameTree func(segments []dto.Segment)
+	renameTree = func(segments []dto.Segment) {
+		for i := range segments {
+			rename(&segments[i].ID)
+			renameTree(segments[i].Children)
+		}
+	}
+	renameRun := func(run *dto.Run) {
+		if run.Splits != nil {
+			splits := make(map[string]dto.Split, len(run.Splits))
+			for segmentID, split := range run.Splits {
+				rename(&segmentID)
+				rename(&split.SplitSegmentID)
+				splits[segmentID] = split
+			}
+			run.Splits = splits
+		}
+		renameTree(run.LeafSegments)
+	}
+
+	for i := range sf.Runs {
+		renameRun(&sf.Runs[i])
+	}
+	if sf.PB != nil {
+		renameRun(sf.PB)
+	}
+	for i := range sf.PracticeRuns {
+		renameRun(&sf.PracticeRuns[i].Run)
+		rename(&sf.PracticeRuns[i].StartSegmentID)
+		rename(&sf.PracticeRuns[i].EndSegmentID)
+	}
+	for i := range sf.PracticeLog {
+		rename(&sf.PracticeLog[i].SegmentID)
+	}
+	for i := range sf.Marathon {
+		rename(&sf.Marathon[i].SegmentID)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+const seg1 = "c9bc9698-0f39-488d-80c6-06308f12b03e"
+const run1 = "fc498ab0-91c6-4d32-a2bf-d8ee51056531"
+
+func getSplitFile() dto.SplitFile {
+	return dto.SplitFile{
+		ID:       "9a268f11-1c89-49af-ae00-a9e2246ec82d",
+		Attempts: 1,
+		Segments: []dto.Segment{{ID: seg1, Name: "Level 1"}},
+		Runs: []dto.Run{{
+			ID:        run1,
+			Completed: true,
+			Splits: map[string]dto.Split{
+				seg1: {SplitSegmentID: seg1, CurrentCumulative: 1000, CurrentDuration: 1000},
+			},
+		}},
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	sf := getSplitFile()
+	if problems := validate(&sf, false); len(problems) != 0 {
+		t.Fatalf("validate() want no problems, got %v", problems)
+	}
+}
+
+func TestValidateRepair(t *testing.T) {
+	sf := getSplitFile()
+	sf.Segments = append(sf.Segments, dto.Segment{ID: "", Name: "Level 2"})
+	sf.Runs = append(sf.Runs,
+		dto.Run{ID: uuid.Nil.String()},
+		dto.Run{ID: run1, Splits: map[string]dto.Split{"missing": {SplitSegmentID: "missing"}}},
+	)
+	sf.PB = &dto.Run{ID: "not-a-run"}
+
+	problems := validate(&sf, false)
+	if len(problems) != 6 {
+		t.Fatalf("validate() want %d problems, got %d: %v", 6, len(problems), problems)
+	}
+	for _, p := range problems {
+		if p.repaired {
+			t.Fatalf("validate() without repair reported a repair: %v", p)
+		}
+	}
+
+	problems = validate(&sf, true)
+	for _, p := range problems {
+		if !p.repaired {
+			t.Fatalf("validate() with repair left a problem: %v", p)
+		}
+	}
+	if _, err := uuid.Parse(sf.Segments[1].ID); err != nil {
+		t.Fatalf("validate() didn't regenerate segment ID: %q", sf.Segments[1].ID)
+	}
+	if len(sf.Runs) != 2 || sf.Runs[1].ID == run1 || len(sf.Runs[1].Splits) != 0 {
+		t.Fatalf("validate() didn't repair runs: %#v", sf.Runs)
+	}
+	if sf.Attempts != 2 || sf.PB != nil {
+		t.Fatalf("validate() attempts want %d and no PB, got %d and %v", 2, sf.Attempts, sf.PB)
+	}
+
+	if problems = validate(&sf, false); len(problems) != 0 {
+		t.Fatalf("validate() after repair want no problems, got %v", problems)
+	}
+}
+
+func TestValidateRepairRenamesSegments(t *testing.T) {
+	sf := getSplitFile()
+	malformed := "level-2"
+	level2 := dto.Segment{ID: malformed, Name: "Level 2"}
+	sf.Segments = append(sf.Segments, level2)
+	sf.Runs[0].LeafSegments = []dto.Segment{sf.Segments[0], level2}
+	sf.Runs[0].Splits[malformed] = dto.Split{SplitSegmentID: malformed, CurrentCumulative: 3000, CurrentDuration: 2000}
+	pb := sf.Runs[0]
+	pb.Splits = map[string]dto.Split{seg1: sf.Runs[0].Splits[seg1], malformed: sf.Runs[0].Splits[malformed]}
+	sf.PB = &pb
+
+	problems := validate(&sf, true)
+	if len(problems) != 1 || !problems[0].repaired {
+		t.Fatalf("validate() want the segment ID repaired, got %v", problems)
+	}
+	newID := sf.Segments[1].ID
+	if newID == malformed {
+		t.Fatalf("validate() didn't regenerate segment ID %q", malformed)
+	}
+	for _, run := range []dto.Run{sf.Runs[0], *sf.PB} {
+		split, ok := run.Splits[newID]
+		if !ok || split.SplitSegmentID != newID || split.CurrentCumulative != 3000 || len(run.Splits) != 2 {
+			t.Fatalf("validate() didn't move run %s's split to the new segment ID: %v", run.ID, run.Splits)
+		}
+		if run.LeafSegments[1].ID != newID {
+			t.Fatalf("validate() didn't rename run %s's leaf segment: %q", run.ID, run.LeafSegments[1].ID)
+		}
+	}
+
+	if problems = validate(&sf, false); len(problems) != 0 {
+		t.Fatalf("validate() after repair want no problems, got %v", problems)
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	runs := make([]session.Run, 5)
+	for i := range runs {
+		runs[i] = session.Run{ID: uuid.New(), Completed: i%2 == 0}
+	}
+	pb := &runs[0]
+
+	kept := pruneRuns(runs, pb, 2, false)
+	if len(kept) != 3 || kept[0].ID != runs[0].ID || kept[1].ID != runs[3].ID || kept[2].ID != runs[4].ID {
+		t.Fatalf("pruneRuns(keep 2) want PB and the last 2 runs, got %v", kept)
+	}
+
+	kept = pruneRuns(runs, pb, 0, true)
+	if len(kept) != 3 {
+		t.Fatalf("pruneRuns(incomplete) want %d runs, got %d", 3, len(kept))
+	}
+	for _, run := range kept {
+		if !run.Completed {
+			t.Fatalf("pruneRuns(incomplete) kept incomplete run %s", run.ID)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// opensplit-headless runs OpenSplit without a window, for streaming boxes and CI.
+//
+// The state machine, session, repo and dispatcher are the same as the desktop app.  Dialogs are answered from flags,
+// and OpenSplit is controlled through the autosplitter socket and a local HTTP control API (see package control):
+//
+//	opensplit-headless -splitfile "My Game-Any%.osf" -answer "Save New Run Data?=Yes"
+//	curl -X POST -H "Authorization: Bearer $TOKEN" http://127.0.0.1:6768/commands/split
+//
+// The control API token is control.token in os-config.json, one is generated and saved on first run.  Text outputs
+// are written when text_output.enabled is set in the config.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zellydev-games/opensplit/autosplitter"
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/control"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/fanout"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/platform"
+	"github.com/zellydev-games/opensplit/repo"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/statemachine"
+	"github.com/zellydev-games/opensplit/textoutput"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+const logModule = "headless"
+
+// answers collects repeated -answer "Dialog Title=Button" flags
+type answers map[string]string
+
+func (a answers) String() string {
+	var pairs []string
+	for title, button := range a {
+		pairs = append(pairs, title+"="+button)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (a answers) Set(value string) error {
+	title, button, ok := strings.Cut(value, "=")
+	if !ok || title == "" || button == "" {
+		return fmt.Errorf("answer must look like \"Dialog Title=Button\", got %q", value)
+	}
+	a[title] = button
+	return nil
+}
+
+func main() {
+	dialogAnswers := answers{}
+	splitFile := flag.String("splitfile", "", "split file to load on startup and to save to")
+	autosplitterFile := flag.String("autosplitter", "", "file returned when an autosplitter file is picked")
+	defaultAnswer := flag.String("default-answer", "", "button chosen for dialogs without an -answer, defaults to each dialog's default button")
+	flag.Var(dialogAnswers, "answer", "answer a dialog by title, e.g. \"Save New Run Data?=Yes\" (repeatable)")
+	socketPort := flag.Uint("port", 6767, "UDP port for the autosplitter socket")
+	controlAddress := flag.String("control", "127.0.0.1:6768", "address for the HTTP control API, empty to disable")
+	debug := flag.Bool("debug", false, "log debug messages")
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger.AddHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, options{
+		runtime: platform.HeadlessOptions{
+			SplitFile:        *splitFile,
+			AutosplitterFile: *autosplitterFile,
+			Answers:          dialogAnswers,
+			DefaultAnswer:    *defaultAnswer,
+		},
+		socketPort:     uint16(*socketPort),
+		controlAddress: *controlAddress,
+	}); err != nil {
+		logger.Error(logModule, err.Error())
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	runtime        platform.HeadlessOptions
+	socketPort     uint16
+	controlAddress string
+}
+
+func run(ctx context.Context, opts options) error {
+	runtimeProvider := platform.NewHeadlessRuntime(opts.runtime)
+	fileProvider := platform.NewFileRuntime()
+
+	home, err := fileProvider.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	appDir := filepath.Join(home, "OpenSplit")
+	autoSplittersDir := filepath.Join(appDir, "Autosplitters")
+	if err = os.MkdirAll(autoSplittersDir, 0755); err != nil {
+		return err
+	}
+
+	jsonRepo := repo.NewJsonFile(runtimeProvider, fileProvider)
+	timerService, timerUpdateChannel := timer.NewStopwatch(timer.NewTicker(time.Millisecond * 20))
+	repoService := repo.NewService(jsonRepo)
+	configService, configUpdateChannel := config.NewService()
+	sessionService, sessionUpdateChannel := session.NewService(timerService)
+	machine := statemachine.InitMachine(runtimeProvider, repoService, sessionService, configService)
+
+	commandDispatcher := dispatcher.NewService(machine, runtimeProvider, autoSplittersDir)
+	machine.AttachAutosplitterRuntime(autosplitter.NewRuntime(commandDispatcher, sessionService, autoSplittersDir))
+
+	timerService.Startup(ctx)
+	runtimeProvider.Startup(ctx)
+	machine.Startup(ctx)
+
+	// the sink only writes while text outputs are enabled in the config
+	timerUpdateChannels := fanout.Tee(timerUpdateChannel, 2, 1)
+	sessionUpdateChannels := fanout.Tee(sessionUpdateChannel, 2, 128)
+	timerUpdateChannel, sessionUpdateChannel = timerUpdateChannels[0], sessionUpdateChannels[0]
+	textoutput.NewSink(timerUpdateChannels[1], sessionUpdateChannels[1],
+		configService, fileProvider, filepath.Join(appDir, "Text Output")).Start(ctx)
+	// The bridges emit into the headless runtime, which keeps the latest payloads for the control API
+	bridge.NewTimer(timerUpdateChannel, runtimeProvider).StartUIPump()
+	bridge.NewSession(sessionUpdateChannel, runtimeProvider).StartUIPump()
+	bridge.NewConfig(configUpdateChannel, runtimeProvider).StartUIPump()
+
+	remoteControl := autosplitter.NewSocket(commandDispatcher, opts.socketPort)
+	go remoteControl.Listen()
+	defer func() {
+		_ = remoteControl.Close()
+	}()
+
+	if opts.controlAddress != "" {
+		token, err := controlToken(configService, repoService)
+		if err != nil {
+			return err
+		}
+		controlServer := control.NewServer(commandDispatcher, runtimeProvider, opts.controlAddress, token)
+		go func() {
+			if err := controlServer.Listen(); err != nil {
+				logger.Errorf(logModule, "control API stopped: %s", err)
+			}
+		}()
+		defer func() {
+			_ = controlServer.Close()
+		}()
+	}
+
+	startupSource := dispatcher.Source{Kind: dispatcher.SourceControl, Detail: "command line"}
+	if opts.runtime.SplitFile != "" {
+		if _, err = commandDispatcher.DispatchFrom(startupSource, dispatcher.LOAD, nil); err != nil {
+			return fmt.Errorf("failed to load %s: %w", opts.runtime.SplitFile, err)
+		}
+	}
+
+	logger.Info(logModule, "headless startup complete")
+	select {
+	case <-ctx.Done():
+		logger.Info(logModule, "received exit signal")
+		_, _ = commandDispatcher.DispatchFrom(startupSource, dispatcher.QUIT, nil)
+	case <-runtimeProvider.Done():
+	}
+
+	logger.Info(logModule, "shutdown complete")
+	return nil
+}
+
+// controlToken returns the config's control API token, generating and saving one if the config has none
+func controlToken(configService *config.Service, repoService *repo.Service) (string, error) {
+	if token := configService.ControlToken(); token != "" {
+		return token, nil
+	}
+
+	token, err := control.NewToken()
+	if err != nil {
+		return "", err
+	}
+	configService.SetControlToken(token)
+	if err = repoService.SaveConfig(configService); err != nil {
+		return "", fmt.Errorf("failed to save control API token: %w", err)
+	}
+	logger.Info(logModule, "generated a control API token, it is control.token in os-config.json")
+	return token, nil
+}
@@ -0,0 +1,39 @@
+// opensplit-race hosts races between OpenSplit instances.
+//
+// Start a coordinator, then have each racer RACE it from the Running view and ready up:
+//
+//	go run ./cmd/opensplit-race -listen 0.0.0.0:6769 -countdown 15s
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/race"
+)
+
+func main() {
+	address := flag.String("listen", race.DefaultAddress, "address to accept racers on")
+	countdown := flag.Duration("countdown", race.DefaultCountdown, "countdown between everyone readying up and the start")
+	flag.Parse()
+
+	logger.AddHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	coordinator := race.NewCoordinator(*countdown)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		_ = coordinator.Close()
+	}()
+
+	if err := coordinator.Listen(*address); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
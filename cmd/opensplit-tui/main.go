@@ -0,0 +1,113 @@
+// opensplit-tui runs OpenSplit in a terminal.
+//
+// The backend is the same as the desktop app, with package tui standing in for the Wails window.  Logs go to
+// ~/OpenSplit/logs/OpenSplit-tui.log since the terminal is busy drawing the timer.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/zellydev-games/opensplit/autosplitter"
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/platform"
+	"github.com/zellydev-games/opensplit/repo"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/statemachine"
+	"github.com/zellydev-games/opensplit/timer"
+	"github.com/zellydev-games/opensplit/tui"
+)
+
+func main() {
+	if err := run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("opensplit-tui must be run in a terminal")
+	}
+
+	fileProvider := platform.NewFileRuntime()
+	home, err := fileProvider.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	appDir := filepath.Join(home, "OpenSplit")
+	logDir := filepath.Join(appDir, "logs")
+	autoSplittersDir := filepath.Join(appDir, "Autosplitters")
+	for _, dir := range []string{logDir, autoSplittersDir} {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(logDir, "OpenSplit-tui.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = logFile.Close()
+	}()
+	logger.AddHandler(slog.NewTextHandler(logFile, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = term.Restore(fd, oldState)
+	}()
+
+	app := tui.NewApp(os.Stdin, os.Stdout, func() int {
+		width, _, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			return 80
+		}
+		return width
+	})
+
+	jsonRepo := repo.NewJsonFile(app, fileProvider)
+	timerService, timerUpdateChannel := timer.NewStopwatch(timer.NewTicker(time.Millisecond * 20))
+	repoService := repo.NewService(jsonRepo)
+	configService, configUpdateChannel := config.NewService()
+	sessionService, sessionUpdateChannel := session.NewService(timerService)
+	machine := statemachine.InitMachine(app, repoService, sessionService, configService)
+
+	commandDispatcher := dispatcher.NewService(machine, app, autoSplittersDir)
+	app.SetDispatcher(commandDispatcher)
+	machine.AttachAutosplitterRuntime(autosplitter.NewRuntime(commandDispatcher, sessionService, autoSplittersDir))
+
+	remoteControl := autosplitter.NewSocket(commandDispatcher, 6767)
+	go remoteControl.Listen()
+	defer func() {
+		_ = remoteControl.Close()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	bridge.NewTimer(timerUpdateChannel, app).StartUIPump()
+	bridge.NewSession(sessionUpdateChannel, app).StartUIPump()
+	bridge.NewConfig(configUpdateChannel, app).StartUIPump()
+
+	timerService.Startup(ctx)
+	go machine.Startup(ctx)
+
+	app.Run(ctx)
+	return nil
+}
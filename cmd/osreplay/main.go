@@ -0,0 +1,82 @@
+// osreplay replays a recording of autosplitter packets against a split file and prints the resulting run.
+//
+// Record packets by starting OpenSplit with OPENSPLIT_AUTOSPLITTER_RECORDING=/path/to/recording.jsonl, then:
+//
+//	go run ./cmd/osreplay -recording recording.jsonl -splitfile "My Game-Any%.osf"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zellydev-games/opensplit/autosplitter"
+	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+func main() {
+	recordingPath := flag.String("recording", "", "path to a packet recording")
+	splitFilePath := flag.String("splitfile", "", "path to the .osf split file to replay against")
+	flag.Parse()
+
+	if *recordingPath == "" || *splitFilePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*recordingPath, *splitFilePath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(recordingPath string, splitFilePath string) error {
+	recording, err := os.Open(recordingPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = recording.Close()
+	}()
+
+	packets, err := autosplitter.ReadRecording(recording)
+	if err != nil {
+		return fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	splitFileBytes, err := os.ReadFile(splitFilePath)
+	if err != nil {
+		return err
+	}
+	splitFileDTO, err := adapters.JSONSplitFileToDTO(string(splitFileBytes))
+	if err != nil {
+		return fmt.Errorf("failed to parse split file: %w", err)
+	}
+	splitFile, err := adapters.DTOSplitFileToDomain(splitFileDTO)
+	if err != nil {
+		return fmt.Errorf("failed to parse split file: %w", err)
+	}
+
+	result := autosplitter.Replay(packets, splitFile)
+	fmt.Printf("%s %s: %d packets replayed, %d dropped\n",
+		splitFile.GameName, splitFile.GameCategory, len(packets), result.Dropped)
+
+	if result.Run == nil {
+		fmt.Println("no run in progress at the end of the recording")
+		return nil
+	}
+
+	fmt.Printf("run %s (completed: %t)\n", result.Run.ID, result.Run.Completed)
+	for _, segment := range result.Run.LeafSegments {
+		split, ok := result.Run.Splits[segment.ID]
+		if !ok {
+			fmt.Printf("  %-32s %11s %11s\n", segment.Name, "-", "-")
+			continue
+		}
+		fmt.Printf("  %-32s %11s %11s\n", segment.Name,
+			timer.FormatTimeToString(split.CurrentDuration), timer.FormatTimeToString(split.CurrentCumulative))
+	}
+	fmt.Printf("  %-32s %11s %11s\n", "Total", "", timer.FormatTimeToString(result.Run.TotalTime))
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/gesture"
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+// Bindings are the hotkeys that send a command, pressing any of them sends it
+type Bindings []keyinfo.KeyData
+
+// BindingConflict is the error for a chord that is already bound to something else
+type BindingConflict struct {
+	Key keyinfo.KeyData
+	// BoundTo describes what the chord already sends, e.g. "SPLIT" or "pinned split file Any%"
+	BoundTo string
+}
+
+func (c *BindingConflict) Error() string {
+	return fmt.Sprintf("%s is already bound to %s", c.Key, c.BoundTo)
+}
+
+// findBindingLocked returns what key is bound to, leaving out the binding of the pinned split file at skipPin.  It
+// must be called under lock.
+func (s *Service) findBindingLocked(key keyinfo.KeyData, skipPin int) (string, bool) {
+	if command, ok := s.matchCommandLocked(key); ok {
+		return command.String(), true
+	}
+	for i, pinned := range s.PinnedSplitFiles {
+		if i != skipPin && pinned.Key.Bound() && pinned.Key.Matches(key) {
+			return "pinned split file " + pinned.Name, true
+		}
+	}
+	return "", false
+}
+
+// Conflicts returns an error for every chord bound more than once, which a hand edited config can contain.  Each
+// duplicate is reported against the binding that wins when the chord is pressed.
+func (s *Service) Conflicts() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type binding struct {
+		key     keyinfo.KeyData
+		boundTo string
+	}
+	var seen []binding
+	var conflicts []error
+	check := func(key keyinfo.KeyData, boundTo string) {
+		if !key.Bound() {
+			return
+		}
+		for _, other := range seen {
+			if other.key.Matches(key) {
+				conflicts = append(conflicts, &BindingConflict{Key: key, BoundTo: other.boundTo})
+				return
+			}
+		}
+		seen = append(seen, binding{key: key, boundTo: boundTo})
+	}
+
+	for _, command := range slices.Sorted(maps.Keys(s.KeyConfig)) {
+		for _, key := range s.KeyConfig[command] {
+			check(key, command.String())
+		}
+	}
+	for _, pinned := range s.PinnedSplitFiles {
+		check(pinned.Key, "pinned split file "+pinned.Name)
+	}
+	return conflicts
+}
+
+// MatchCommand returns the command a key press is bound to.
+//
+// Commands are checked in order and then each command's bindings in order, so a chord bound twice in a hand edited
+// config always resolves to the same command rather than depending on map iteration order.  Commands win over pinned
+// split files, see MatchPinnedSplitFile.
+func (s *Service) MatchCommand(key keyinfo.KeyData) (dispatcher.Command, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.matchCommandLocked(key)
+}
+
+func (s *Service) matchCommandLocked(key keyinfo.KeyData) (dispatcher.Command, bool) {
+	for _, command := range slices.Sorted(maps.Keys(s.KeyConfig)) {
+		for _, bound := range s.KeyConfig[command] {
+			if bound.Bound() && bound.Matches(key) {
+				return command, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// CommandGestures returns the gesture of every command that has a valid one other than gesture.Press.  Invalid gestures are
+// left out, so their command is sent on a press, and reported by GestureErrors.
+func (s *Service) CommandGestures() map[dispatcher.Command]gesture.Gesture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gestures := make(map[dispatcher.Command]gesture.Gesture, len(s.Gestures))
+	for command, g := range s.Gestures {
+		if g.Kind != gesture.Press && g.Validate() == nil {
+			gestures[command] = g
+		}
+	}
+	return gestures
+}
+
+// GestureErrors returns an error for every gesture in the config that can never complete
+func (s *Service) GestureErrors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, command := range slices.Sorted(maps.Keys(s.Gestures)) {
+		if err := s.Gestures[command].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", command, err))
+		}
+	}
+	return errs
+}
+
+// MatchPinnedSplitFile returns the first pinned split file whose hotkey is key
+func (s *Service) MatchPinnedSplitFile(key keyinfo.KeyData) (PinnedSplitFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pinned := range s.PinnedSplitFiles {
+		if pinned.Key.Bound() && pinned.Key.Matches(key) {
+			return pinned, true
+		}
+	}
+	return PinnedSplitFile{}, false
+}
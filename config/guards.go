@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// GuardConfig holds the safety guards that keep a stray input from ruining a run.  Each split file segment can also
+// set a minimum time, see session.Segment.MinDuration.
+type GuardConfig struct {
+	// DebounceMS ignores a command sent again within this many milliseconds of the last time it was accepted
+	DebounceMS map[dispatcher.Command]int `json:"debounce_ms"`
+	// ResetConfirmAfterMS makes RESET ask to be sent again to confirm once a run is this many milliseconds in, 0 never
+	// asks
+	ResetConfirmAfterMS int `json:"reset_confirm_after_ms"`
+}
+
+// DefaultSplitDebounce is the SPLIT debounce a new config starts with, so a key that bounces doesn't split twice
+const DefaultSplitDebounce = 120 * time.Millisecond
+
+// Validate reports a negative duration
+func (g GuardConfig) Validate() error {
+	for command, ms := range g.DebounceMS {
+		if ms < 0 {
+			return fmt.Errorf("%v: negative debounce %dms", command, ms)
+		}
+	}
+	if g.ResetConfirmAfterMS < 0 {
+		return fmt.Errorf("negative reset confirmation threshold %dms", g.ResetConfirmAfterMS)
+	}
+	return nil
+}
+
+// Debounce returns how soon after command was last accepted it is ignored, 0 when it isn't debounced
+func (s *Service) Debounce(command dispatcher.Command) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(max(s.Guards.DebounceMS[command], 0)) * time.Millisecond
+}
+
+// ResetConfirmAfter returns how far into a run RESET has to be confirmed, 0 when it never does
+func (s *Service) ResetConfirmAfter() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(max(s.Guards.ResetConfirmAfterMS, 0)) * time.Millisecond
+}
+
+// SetGuards replaces the safety guards, commands without a debounce are dropped
+func (s *Service) SetGuards(guards GuardConfig) error {
+	if err := guards.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Guards = GuardConfig{DebounceMS: map[dispatcher.Command]int{}, ResetConfirmAfterMS: guards.ResetConfirmAfterMS}
+	for command, ms := range guards.DebounceMS {
+		if ms > 0 {
+			s.Guards.DebounceMS[command] = ms
+		}
+	}
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "updated guards: %d debounced commands, reset confirmation after %dms",
+		len(s.Guards.DebounceMS), s.Guards.ResetConfirmAfterMS)
+	return nil
+}
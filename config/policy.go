@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// HotkeyPolicy is when a command's hotkeys are listened to
+type HotkeyPolicy string
+
+const (
+	// PolicyDefault follows GlobalHotkeysActive, it is the zero value
+	PolicyDefault HotkeyPolicy = ""
+	// PolicyGlobal listens whether or not OpenSplit has focus
+	PolicyGlobal HotkeyPolicy = "global"
+	// PolicyFocused only listens while OpenSplit has focus
+	PolicyFocused HotkeyPolicy = "focused"
+	// PolicyDisabled never sends the command from a hotkey, it can still be sent from the menu or an autosplitter
+	PolicyDisabled HotkeyPolicy = "disabled"
+)
+
+// Validate reports a policy that isn't one of the known values
+func (p HotkeyPolicy) Validate() error {
+	switch p {
+	case PolicyDefault, PolicyGlobal, PolicyFocused, PolicyDisabled:
+		return nil
+	}
+	return fmt.Errorf("unknown hotkey policy %q", p)
+}
+
+// HotkeyPolicy returns when the command's hotkeys are listened to, resolving PolicyDefault against
+// GlobalHotkeysActive so the result is never PolicyDefault.
+func (s *Service) HotkeyPolicy(command dispatcher.Command) HotkeyPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy := s.HotkeyPolicies[command]
+	if policy == PolicyDefault || policy.Validate() != nil {
+		if s.GlobalHotkeysActive {
+			return PolicyGlobal
+		}
+		return PolicyFocused
+	}
+	return policy
+}
+
+// SetHotkeyPolicies replaces every command's policy, commands left out follow GlobalHotkeysActive
+func (s *Service) SetHotkeyPolicies(policies map[dispatcher.Command]HotkeyPolicy) error {
+	for command, policy := range policies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("%v: %w", command, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.HotkeyPolicies = map[dispatcher.Command]HotkeyPolicy{}
+	for command, policy := range policies {
+		if policy != PolicyDefault {
+			s.HotkeyPolicies[command] = policy
+		}
+	}
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "updated hotkey policies for %d commands", len(s.HotkeyPolicies))
+	return nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/gesture"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// Profile is a named set of the settings that change from game to game, e.g. keyboard bindings for one game and
+// controller bindings for another.  Profiles are stored apart from the config by repo.Service, and a split file can
+// name the profile to use while it's loaded.
+type Profile struct {
+	Name           string                                 `json:"name"`
+	KeyConfig      map[dispatcher.Command]Bindings        `json:"key_config"`
+	Gestures       map[dispatcher.Command]gesture.Gesture `json:"gestures"`
+	HotkeyPolicies map[dispatcher.Command]HotkeyPolicy    `json:"hotkey_policies"`
+	Guards         GuardConfig                            `json:"guards"`
+}
+
+// Validate reports a profile without a name, or with a policy or guard that the config would refuse
+func (p Profile) Validate() error {
+	if p.Name == "" {
+		return errors.New("profile has no name")
+	}
+	for _, policy := range p.HotkeyPolicies {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	return p.Guards.Validate()
+}
+
+// clone copies the profile's maps so the config and the stored profile never share them
+func (p Profile) clone() Profile {
+	out := p
+	out.KeyConfig = make(map[dispatcher.Command]Bindings, len(p.KeyConfig))
+	for command, bindings := range p.KeyConfig {
+		out.KeyConfig[command] = slices.Clone(bindings)
+	}
+	out.Gestures = maps.Clone(p.Gestures)
+	out.HotkeyPolicies = maps.Clone(p.HotkeyPolicies)
+	out.Guards.DebounceMS = maps.Clone(p.Guards.DebounceMS)
+	return out
+}
+
+// profileLocked returns the settings in use as a profile called name.  It must be called under lock.
+func (s *Service) profileLocked(name string) Profile {
+	return Profile{
+		Name:           name,
+		KeyConfig:      s.KeyConfig,
+		Gestures:       s.Gestures,
+		HotkeyPolicies: s.HotkeyPolicies,
+		Guards:         s.Guards,
+	}.clone()
+}
+
+// applyLocked puts the profile's settings in use.  It must be called under lock.
+func (s *Service) applyLocked(p Profile) {
+	p = p.clone()
+	s.KeyConfig = p.KeyConfig
+	s.Gestures = p.Gestures
+	s.HotkeyPolicies = p.HotkeyPolicies
+	s.Guards = p.Guards
+}
+
+// CurrentProfile returns the settings in use as a profile called name, to store as a new profile
+func (s *Service) CurrentProfile(name string) Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.profileLocked(name)
+}
+
+// ActiveProfileSettings returns the active profile with any changes made since it was activated, false when the
+// config's own settings are in use
+func (s *Service) ActiveProfileSettings() (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		return Profile{}, false
+	}
+	return s.profileLocked(s.ActiveProfile), true
+}
+
+// ActivateProfile puts the profile's settings in use in place of the config's own, which are kept to be saved and
+// restored by DeactivateProfile.
+func (s *Service) ActivateProfile(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		base := s.profileLocked("")
+		s.base = &base
+	}
+	s.applyLocked(p)
+	s.ActiveProfile = p.Name
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "activated profile %s", p.Name)
+	return nil
+}
+
+// DeactivateProfile puts the config's own settings back in use, it does nothing if no profile is active
+func (s *Service) DeactivateProfile() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		return
+	}
+	s.applyLocked(*s.base)
+	s.base = nil
+	logger.Infof(logModule, "deactivated profile %s", s.ActiveProfile)
+	s.ActiveProfile = ""
+	s.sendUIBridgeUpdate()
+}
+
+// SetProfileNames records the names of the stored profiles for the frontend to offer
+func (s *Service) SetProfileNames(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ProfileNames = slices.Sorted(slices.Values(names))
+	s.sendUIBridgeUpdate()
+}
+
+// Base returns a copy of the config with its own settings in place of the active profile's, which is what is saved
+// to the config file.  The profile's settings are saved with the profile.
+func (s *Service) Base() *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := &Service{
+		Version:             SchemaVersion,
+		SpeedRunAPIBase:     s.SpeedRunAPIBase,
+		KeyConfig:           s.KeyConfig,
+		Gestures:            s.Gestures,
+		GlobalHotkeysActive: s.GlobalHotkeysActive,
+		HotkeyPolicies:      s.HotkeyPolicies,
+		Guards:              s.Guards,
+		TextOutput:          s.TextOutput,
+		PinnedSplitFiles:    s.PinnedSplitFiles,
+		Race:                s.Race,
+		Control:             s.Control,
+	}
+	if s.base != nil {
+		out.applyLocked(*s.base)
+	}
+	return out
+}
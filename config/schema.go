@@ -0,0 +1,369 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"text/template"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/gesture"
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+// SchemaVersion is the version of the config file format this build writes.  Version 0 is a config saved before the
+// format was versioned.
+const SchemaVersion = 1
+
+// migrations[v] upgrades the settings of a version v config file to version v+1.  They work on the raw JSON so a
+// setting that changed shape can still be read in its old one.
+var migrations = []func(settings map[string]json.RawMessage) error{
+	migrateSingleBindings,
+}
+
+// LoadReport describes what Decode had to change to load a config file
+type LoadReport struct {
+	// Version is the schema version the file was saved with
+	Version int
+	// Problems are the settings that couldn't be read or were invalid, each was dropped or reset to its default
+	Problems []error
+}
+
+// Changed reports whether the loaded config differs from the file, so the file is worth keeping before the config is
+// saved over it
+func (r LoadReport) Changed() bool {
+	return r.Version != SchemaVersion || len(r.Problems) > 0
+}
+
+// Decode reads a config file saved with any schema version.
+//
+// The file is migrated to SchemaVersion, settings it doesn't have get their defaults, and a setting that can't be read
+// or is invalid is dropped or reset to its default and reported in the LoadReport, so one bad setting never costs the
+// rest of the file.  The error is only for a file that isn't a JSON object, or that a migration couldn't upgrade.
+func Decode(data []byte) (*Service, LoadReport, error) {
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, LoadReport{}, fmt.Errorf("config is not a JSON object: %w", err)
+	}
+
+	d := &decoder{settings: settings}
+	version, err := d.migrate("config")
+	if err != nil {
+		return nil, LoadReport{}, err
+	}
+
+	s := &Service{}
+	s.setDefaults()
+	decodeSetting(d, "speed_run_API_base", &s.SpeedRunAPIBase)
+	d.decodeProfileSettings(s)
+	decodeSetting(d, "global_hotkeys_active", &s.GlobalHotkeysActive)
+	decodeSetting(d, "text_output", &s.TextOutput)
+	decodeSetting(d, "pinned_split_files", &s.PinnedSplitFiles)
+	decodeSetting(d, "race", &s.Race)
+	decodeSetting(d, "control", &s.Control)
+	for _, name := range slices.Sorted(maps.Keys(d.settings)) {
+		d.problemf("unknown setting %q ignored", name)
+	}
+
+	d.validate(s)
+	for _, problem := range d.problems {
+		s.LoadProblems = append(s.LoadProblems, problem.Error())
+	}
+	return s, LoadReport{Version: version, Problems: d.problems}, nil
+}
+
+// DecodeProfiles reads the stored config profiles by name, saved with any schema version.
+//
+// Each profile's settings are migrated, read and validated like Decode does for the config, so a bad setting is
+// dropped and a profile that can't be read at all is left out.  Both are reported in the LoadReport, prefixed with
+// the profile's name.  The error is only for data that isn't a JSON object, or that a migration couldn't upgrade.
+func DecodeProfiles(data []byte) (map[string]Profile, LoadReport, error) {
+	profiles := map[string]Profile{}
+	report := LoadReport{Version: SchemaVersion}
+	if len(data) == 0 {
+		return profiles, report, nil
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, LoadReport{}, fmt.Errorf("profiles are not a JSON object: %w", err)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		profile, problems, err := decodeProfile(name, entries[name])
+		if err != nil {
+			return nil, LoadReport{}, err
+		}
+		for _, problem := range problems {
+			report.Problems = append(report.Problems, fmt.Errorf("profile %q: %w", name, problem))
+		}
+		if profile != nil {
+			profiles[name] = *profile
+		}
+	}
+	return profiles, report, nil
+}
+
+// decodeProfile reads one stored profile, it returns nil if the profile isn't an object
+func decodeProfile(name string, data json.RawMessage) (*Profile, []error, error) {
+	if name == "" {
+		return nil, []error{errors.New("profile has no name, it was left out")}, nil
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil || settings == nil {
+		return nil, []error{errors.New("profile is not a JSON object, it was left out")}, nil
+	}
+
+	d := &decoder{settings: settings}
+	if _, err := d.migrate("profile"); err != nil {
+		return nil, nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	// profiles are stored by name, the name inside is only kept for people reading the file
+	d.take("name")
+
+	s := &Service{}
+	d.decodeProfileSettings(s)
+	for _, setting := range slices.Sorted(maps.Keys(d.settings)) {
+		d.problemf("unknown setting %q ignored", setting)
+	}
+	d.validate(s)
+
+	profile := Profile{
+		Name:           name,
+		KeyConfig:      s.KeyConfig,
+		Gestures:       s.Gestures,
+		HotkeyPolicies: s.HotkeyPolicies,
+		Guards:         s.Guards,
+	}
+	return &profile, d.problems, nil
+}
+
+// decoder reads a config file's settings one at a time, collecting problems instead of stopping at the first
+type decoder struct {
+	settings map[string]json.RawMessage
+	problems []error
+}
+
+func (d *decoder) problemf(format string, args ...any) {
+	d.problems = append(d.problems, fmt.Errorf(format, args...))
+}
+
+// migrate upgrades the settings to SchemaVersion from the version they were saved with, which it returns.  Settings
+// saved without a version are version 0.  what names the settings in errors.
+func (d *decoder) migrate(what string) (int, error) {
+	version := 0
+	decodeSetting(d, "version", &version)
+	if version > SchemaVersion {
+		d.problemf("%s was saved by a newer version of OpenSplit (schema %d), settings this version doesn't "+
+			"know are ignored", what, version)
+	}
+	for v := max(version, 0); v < SchemaVersion; v++ {
+		if err := migrations[v](d.settings); err != nil {
+			return 0, fmt.Errorf("failed to migrate %s from schema %d: %w", what, v, err)
+		}
+	}
+	return version, nil
+}
+
+// decodeProfileSettings reads the settings a Profile can change into s
+func (d *decoder) decodeProfileSettings(s *Service) {
+	if keyConfig, ok := decodeCommands[Bindings](d, "key_config"); ok {
+		s.KeyConfig = keyConfig
+	}
+	if gestures, ok := d.decodeGestures(); ok {
+		s.Gestures = gestures
+	}
+	if policies, ok := decodeCommands[HotkeyPolicy](d, "hotkey_policies"); ok {
+		s.HotkeyPolicies = policies
+	}
+	decodeSetting(d, "guards", &s.Guards)
+}
+
+// decodeGestures reads the gestures setting.  A zero hold_ms or window_ms can't be told from a missing one once
+// decoded, when the gesture would get its default, so gestures that set one are dropped here.
+func (d *decoder) decodeGestures() (map[dispatcher.Command]gesture.Gesture, bool) {
+	entries, ok := decodeCommands[json.RawMessage](d, "gestures")
+	if !ok {
+		return nil, false
+	}
+
+	gestures := make(map[dispatcher.Command]gesture.Gesture, len(entries))
+	for _, command := range slices.Sorted(maps.Keys(entries)) {
+		var g gesture.Gesture
+		if err := json.Unmarshal(entries[command], &g); err != nil {
+			d.problemf("gestures: %v: %s", command, err)
+			continue
+		}
+		var durations struct {
+			HoldMS   *int `json:"hold_ms"`
+			WindowMS *int `json:"window_ms"`
+		}
+		_ = json.Unmarshal(entries[command], &durations)
+		if zero := func(ms *int) bool { return ms != nil && *ms == 0 }; zero(durations.HoldMS) || zero(durations.WindowMS) {
+			d.problemf("gestures: %v: %s gesture with a zero duration dropped", command, g.Kind)
+			continue
+		}
+		gestures[command] = g
+	}
+	return gestures, true
+}
+
+// take removes the named setting so whatever is left at the end is unknown
+func (d *decoder) take(name string) (json.RawMessage, bool) {
+	raw, ok := d.settings[name]
+	delete(d.settings, name)
+	return raw, ok && !bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// decodeSetting sets *out to the named setting, leaving its default if the setting is missing or can't be read
+func decodeSetting[T any](d *decoder, name string, out *T) bool {
+	raw, ok := d.take(name)
+	if !ok {
+		return false
+	}
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		d.problemf("%s: %s", name, err)
+		return false
+	}
+	*out = value
+	return true
+}
+
+// decodeCommands reads the named setting keyed by command, by number or by name.  Commands that don't exist and
+// entries that can't be read are dropped.  It reports false if the setting is missing or isn't an object.
+func decodeCommands[T any](d *decoder, name string) (map[dispatcher.Command]T, bool) {
+	var entries map[string]json.RawMessage
+	if !decodeSetting(d, name, &entries) {
+		return nil, false
+	}
+
+	out := make(map[dispatcher.Command]T, len(entries))
+	for _, key := range slices.Sorted(maps.Keys(entries)) {
+		command, err := parseCommandKey(key)
+		if err != nil {
+			d.problemf("%s: %s", name, err)
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(entries[key], &value); err != nil {
+			d.problemf("%s: %v: %s", name, command, err)
+			continue
+		}
+		out[command] = value
+	}
+	return out, true
+}
+
+// parseCommandKey reads a command saved as a map key, which is its number, or its name in a hand edited config
+func parseCommandKey(key string) (dispatcher.Command, error) {
+	if n, err := strconv.Atoi(key); err == nil {
+		if n < 0 || n > 255 || !dispatcher.Command(n).Valid() {
+			return 0, fmt.Errorf("unknown command %s", key)
+		}
+		return dispatcher.Command(n), nil
+	}
+	return dispatcher.ParseCommand(key)
+}
+
+// validate drops or resets the settings that were read but can't be used
+func (d *decoder) validate(s *Service) {
+	for _, command := range slices.Sorted(maps.Keys(s.Gestures)) {
+		if err := s.Gestures[command].Validate(); err != nil {
+			d.problemf("gestures: %v: %s", command, err)
+			delete(s.Gestures, command)
+		}
+	}
+
+	for _, command := range slices.Sorted(maps.Keys(s.HotkeyPolicies)) {
+		if err := s.HotkeyPolicies[command].Validate(); err != nil {
+			d.problemf("hotkey_policies: %v: %s", command, err)
+			delete(s.HotkeyPolicies, command)
+		}
+	}
+
+	for _, command := range slices.Sorted(maps.Keys(s.Guards.DebounceMS)) {
+		ms := s.Guards.DebounceMS[command]
+		if !command.Valid() || ms < 0 {
+			d.problemf("guards: debounce of %dms for %v ignored", ms, command)
+			delete(s.Guards.DebounceMS, command)
+		}
+	}
+	if s.Guards.ResetConfirmAfterMS < 0 {
+		d.problemf("guards: negative reset confirmation threshold %dms ignored", s.Guards.ResetConfirmAfterMS)
+		s.Guards.ResetConfirmAfterMS = 0
+	}
+
+	if s.TextOutput.WriteIntervalMS < 0 {
+		d.problemf("text_output: negative write interval %dms, using %s", s.TextOutput.WriteIntervalMS,
+			DefaultTextOutputWriteInterval)
+		s.TextOutput.WriteIntervalMS = int(DefaultTextOutputWriteInterval.Milliseconds())
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.TextOutput.Templates)) {
+		if _, err := template.New(name).Parse(s.TextOutput.Templates[name]); err != nil {
+			d.problemf("text_output: template for %s dropped: %s", name, err)
+			delete(s.TextOutput.Templates, name)
+		}
+	}
+
+	if err := validateAddress(s.Race.Address); err != nil {
+		d.problemf("race: address %q ignored: %s", s.Race.Address, err)
+		s.Race.Address = ""
+	}
+}
+
+// validateAddress reports an address that isn't host:port with a port from 1 to 65535, an empty address is the default
+func validateAddress(address string) error {
+	if address == "" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return errors.New("port must be a number from 1 to 65535")
+	}
+	return nil
+}
+
+// migrateSingleBindings turns key_config's single binding per command into a list of bindings.  Before version 1 an
+// unassigned command was saved with an empty binding, which becomes an empty list.
+func migrateSingleBindings(settings map[string]json.RawMessage) error {
+	raw, ok := settings["key_config"]
+	if !ok {
+		return nil
+	}
+	var commands map[string]json.RawMessage
+	if json.Unmarshal(raw, &commands) != nil {
+		// Decode reports the setting when it can't read it either
+		return nil
+	}
+
+	for command, binding := range commands {
+		var single keyinfo.KeyData
+		if !bytes.HasPrefix(bytes.TrimSpace(binding), []byte("{")) || json.Unmarshal(binding, &single) != nil {
+			continue
+		}
+		bindings := Bindings{}
+		if single.Bound() {
+			bindings = Bindings{single}
+		}
+		migrated, err := json.Marshal(bindings)
+		if err != nil {
+			return err
+		}
+		commands[command] = migrated
+	}
+
+	migrated, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+	settings["key_config"] = migrated
+	return nil
+}
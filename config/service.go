@@ -1,10 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"slices"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/gesture"
 	"github.com/zellydev-games/opensplit/keyinfo"
 	"github.com/zellydev-games/opensplit/logger"
 )
@@ -12,19 +17,94 @@ import (
 const logModule = "config"
 
 // Service holds configuration options so that Service.GetEnvironment can work for both backend and frontend.
+//
+// ActiveProfile names the Profile whose settings are in use, empty for the config's own, and ProfileNames lists the
+// stored profiles for the frontend to offer.  LoadProblems describes the settings Decode couldn't load from the config
+// file.  None of them are saved to the config file, see Base.
 type Service struct {
 	mu                   sync.Mutex
+	Version              int                                    `json:"version"`
 	SpeedRunAPIBase      string                                 `json:"speed_run_API_base"`
-	KeyConfig            map[dispatcher.Command]keyinfo.KeyData `json:"key_config"`
+	KeyConfig            map[dispatcher.Command]Bindings        `json:"key_config"`
+	Gestures             map[dispatcher.Command]gesture.Gesture `json:"gestures"`
 	GlobalHotkeysActive  bool                                   `json:"global_hotkeys_active"`
+	HotkeyPolicies       map[dispatcher.Command]HotkeyPolicy    `json:"hotkey_policies"`
+	Guards               GuardConfig                            `json:"guards"`
+	TextOutput           TextOutputConfig                       `json:"text_output"`
+	PinnedSplitFiles     []PinnedSplitFile                      `json:"pinned_split_files"`
+	Race                 RaceConfig                             `json:"race"`
+	Control              ControlConfig                          `json:"control"`
+	ActiveProfile        string                                 `json:"active_profile,omitempty"`
+	ProfileNames         []string                               `json:"profile_names,omitempty"`
+	LoadProblems         []string                               `json:"load_problems,omitempty"`
+	base                 *Profile
 	configUpdatedChannel chan<- *Service
 }
 
+// TextOutputConfig controls the text files written for streaming software "read from file" sources.
+//
+// Directory is where the files are written, an empty Directory uses the default text output folder.  Templates maps a
+// file name to a text/template that is rendered against textoutput.Fields; files without a template use the defaults.
+type TextOutputConfig struct {
+	Enabled         bool              `json:"enabled"`
+	Directory       string            `json:"directory"`
+	Templates       map[string]string `json:"templates"`
+	WriteIntervalMS int               `json:"write_interval_ms"`
+}
+
+// PinnedSplitFile is a split file the Running state can SWITCH to without going back to Welcome.
+//
+// ID is the split file's own ID so a pin can be referenced without knowing where the file is on disk.  Key is an
+// optional hotkey that switches to the file straight away.
+type PinnedSplitFile struct {
+	ID   string          `json:"id"`
+	Name string          `json:"name"`
+	Path string          `json:"path"`
+	Key  keyinfo.KeyData `json:"key"`
+}
+
+// RaceConfig is where RACE joins a race when it isn't given an address, and the name to race under.
+//
+// An empty Address uses the coordinator's default address and an empty Name uses the computer's host name.
+type RaceConfig struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+// ControlConfig secures the local HTTP control API, every request must carry Token as a bearer token
+type ControlConfig struct {
+	Token string `json:"token"`
+}
+
+// DefaultTextOutputWriteInterval is used when TextOutputConfig.WriteIntervalMS is not set
+const DefaultTextOutputWriteInterval = 250 * time.Millisecond
+
+// Validate reports a negative write interval or a template that doesn't parse
+func (t TextOutputConfig) Validate() error {
+	if t.WriteIntervalMS < 0 {
+		return fmt.Errorf("negative text output write interval %dms", t.WriteIntervalMS)
+	}
+	for name, source := range t.Templates {
+		if _, err := template.New(name).Parse(source); err != nil {
+			return fmt.Errorf("text output template for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteInterval returns the throttled write rate for text outputs as a time.Duration
+func (t TextOutputConfig) WriteInterval() time.Duration {
+	if t.WriteIntervalMS <= 0 {
+		return DefaultTextOutputWriteInterval
+	}
+	return time.Duration(t.WriteIntervalMS) * time.Millisecond
+}
+
 func NewService() (*Service, chan *Service) {
 	updateChannel := make(chan *Service)
 	return &Service{
 		SpeedRunAPIBase:      "",
-		KeyConfig:            map[dispatcher.Command]keyinfo.KeyData{},
+		KeyConfig:            map[dispatcher.Command]Bindings{},
 		configUpdatedChannel: updateChannel,
 	}, updateChannel
 }
@@ -42,29 +122,221 @@ func (s *Service) GetEnvironment() *Service {
 	}
 }
 
-// UpdateKeyBinding changes the ConfigPayload for the given command.
-func (s *Service) UpdateKeyBinding(command dispatcher.Command, data keyinfo.KeyData) {
+// GetTextOutputConfig returns a copy of the text output settings that is safe to use from other goroutines.
+func (s *Service) GetTextOutputConfig() TextOutputConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	templates := make(map[string]string, len(s.TextOutput.Templates))
+	for name, template := range s.TextOutput.Templates {
+		templates[name] = template
+	}
+	out := s.TextOutput
+	out.Templates = templates
+	return out
+}
+
+// SetTextOutput replaces the text output settings
+func (s *Service) SetTextOutput(textOutput TextOutputConfig) error {
+	if err := textOutput.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TextOutput = textOutput
+	if s.TextOutput.Templates == nil {
+		s.TextOutput.Templates = map[string]string{}
+	}
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "updated text output settings, enabled: %t", textOutput.Enabled)
+	return nil
+}
+
+// GetRaceConfig returns the race settings
+func (s *Service) GetRaceConfig() RaceConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Race
+}
+
+// AddKeyBinding adds a hotkey for the given command.
+//
+// Returns a *BindingConflict if the chord already sends a command or switches to a pinned split file.
+func (s *Service) AddKeyBinding(command dispatcher.Command, data keyinfo.KeyData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if boundTo, ok := s.findBindingLocked(data, -1); ok {
+		return &BindingConflict{Key: data, BoundTo: boundTo}
+	}
+	if s.KeyConfig == nil {
+		s.KeyConfig = map[dispatcher.Command]Bindings{}
+	}
+	s.KeyConfig[command] = append(s.KeyConfig[command], data)
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "added key binding %s for command %v", data, command)
+	return nil
+}
+
+// RemoveKeyBinding removes the command's hotkey at index.
+func (s *Service) RemoveKeyBinding(command dispatcher.Command, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bindings := s.KeyConfig[command]
+	if index < 0 || index >= len(bindings) {
+		return fmt.Errorf("command %v has no key binding at index %d", command, index)
+	}
+	removed := bindings[index]
+	s.KeyConfig[command] = slices.Delete(slices.Clone(bindings), index, index+1)
+	s.sendUIBridgeUpdate()
+	logger.Infof(logModule, "removed key binding %s for command %v", removed, command)
+	return nil
+}
+
+// TogglePinnedSplitFile pins the given split file, or unpins it if its path is already pinned.
+//
+// Returns true if the file is pinned afterward.
+func (s *Service) TogglePinnedSplitFile(pin PinnedSplitFile) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.KeyConfig[command] = data
+	defer s.sendUIBridgeUpdate()
+
+	for i, pinned := range s.PinnedSplitFiles {
+		if pinned.Path == pin.Path {
+			s.PinnedSplitFiles = append(s.PinnedSplitFiles[:i], s.PinnedSplitFiles[i+1:]...)
+			logger.Infof(logModule, "unpinned split file %s", pin.Path)
+			return false
+		}
+	}
+
+	s.PinnedSplitFiles = append(s.PinnedSplitFiles, pin)
+	logger.Infof(logModule, "pinned split file %s", pin.Path)
+	return true
+}
+
+// FindPinnedSplitFile returns the pinned split file whose ID or path is ref
+func (s *Service) FindPinnedSplitFile(ref string) (PinnedSplitFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pinned := range s.PinnedSplitFiles {
+		if pinned.ID == ref || pinned.Path == ref {
+			return pinned, true
+		}
+	}
+	return PinnedSplitFile{}, false
+}
+
+// UpdatePinnedKeyBinding changes the hotkey that switches to the pinned split file at index.
+//
+// Returns a *BindingConflict if the chord already sends a command or switches to another pinned split file.
+func (s *Service) UpdatePinnedKeyBinding(index int, data keyinfo.KeyData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index < 0 || index >= len(s.PinnedSplitFiles) {
+		return fmt.Errorf("no pinned split file at index %d", index)
+	}
+	if boundTo, ok := s.findBindingLocked(data, index); ok {
+		return &BindingConflict{Key: data, BoundTo: boundTo}
+	}
+	s.PinnedSplitFiles[index].Key = data
 	s.sendUIBridgeUpdate()
-	logger.Infof(logModule, "updated key binding for command %v to %s", command, data.LocaleName)
+	logger.Infof(logModule, "updated key binding for pinned split file %s to %s", s.PinnedSplitFiles[index].Name,
+		data.LocaleName)
+	return nil
 }
 
 // CreateDefaultConfig sets the service's options to reasonable defaults.
 //
 // Useful if the config file hasn't been created yet (first run)
 func (s *Service) CreateDefaultConfig() {
-	s.KeyConfig = map[dispatcher.Command]keyinfo.KeyData{}
-	s.KeyConfig[dispatcher.SPLIT] = keyinfo.KeyData{}
-	s.KeyConfig[dispatcher.UNDO] = keyinfo.KeyData{}
-	s.KeyConfig[dispatcher.SKIP] = keyinfo.KeyData{}
-	s.KeyConfig[dispatcher.PAUSE] = keyinfo.KeyData{}
-	s.KeyConfig[dispatcher.RESET] = keyinfo.KeyData{}
+	s.base = nil
+	s.ActiveProfile = ""
+	s.setDefaults()
 	s.sendUIBridgeUpdate()
 	logger.Infof(logModule, "created default config")
 }
 
+// setDefaults gives the settings a fresh config starts with, Decode starts from them too so a config file missing a
+// setting gets its default
+func (s *Service) setDefaults() {
+	s.Version = SchemaVersion
+	s.KeyConfig = map[dispatcher.Command]Bindings{}
+	s.KeyConfig[dispatcher.SPLIT] = Bindings{}
+	s.KeyConfig[dispatcher.UNDO] = Bindings{}
+	s.KeyConfig[dispatcher.SKIP] = Bindings{}
+	s.KeyConfig[dispatcher.PAUSE] = Bindings{}
+	s.KeyConfig[dispatcher.RESET] = Bindings{}
+	s.KeyConfig[dispatcher.SUCCESS] = Bindings{}
+	s.KeyConfig[dispatcher.FAIL] = Bindings{}
+	s.Guards = GuardConfig{
+		DebounceMS: map[dispatcher.Command]int{dispatcher.SPLIT: int(DefaultSplitDebounce.Milliseconds())},
+	}
+	s.TextOutput = TextOutputConfig{
+		Templates:       map[string]string{},
+		WriteIntervalMS: int(DefaultTextOutputWriteInterval.Milliseconds()),
+	}
+}
+
+// ReplaceSettings puts every saved setting of from in use, along with the problems found loading it.  An active
+// profile is dropped, it has to be activated again on top of the new settings.
+func (s *Service) ReplaceSettings(from *Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Version = from.Version
+	s.SpeedRunAPIBase = from.SpeedRunAPIBase
+	s.KeyConfig = from.KeyConfig
+	s.Gestures = from.Gestures
+	s.GlobalHotkeysActive = from.GlobalHotkeysActive
+	s.HotkeyPolicies = from.HotkeyPolicies
+	s.Guards = from.Guards
+	s.TextOutput = from.TextOutput
+	s.PinnedSplitFiles = from.PinnedSplitFiles
+	s.Race = from.Race
+	s.Control = from.Control
+	s.LoadProblems = from.LoadProblems
+	s.base = nil
+	s.ActiveProfile = ""
+	s.sendUIBridgeUpdate()
+}
+
+// ControlToken returns the token the control API requires, empty until one is set
+func (s *Service) ControlToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Control.Token
+}
+
+// SetControlToken sets the token the control API requires
+func (s *Service) SetControlToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Control.Token = token
+	s.sendUIBridgeUpdate()
+}
+
+// AddLoadProblems adds problems found loading settings kept apart from the config file, like its profiles
+func (s *Service) AddLoadProblems(problems []error) {
+	if len(problems) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, problem := range problems {
+		s.LoadProblems = append(s.LoadProblems, problem.Error())
+	}
+	s.sendUIBridgeUpdate()
+}
+
+// ClearLoadProblems forgets the problems found loading the config file, once the config has been saved over it
+func (s *Service) ClearLoadProblems() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.LoadProblems) == 0 {
+		return
+	}
+	s.LoadProblems = nil
+	s.sendUIBridgeUpdate()
+}
+
 func (s *Service) sendUIBridgeUpdate() {
 	select {
 	case s.configUpdatedChannel <- s:
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -58,11 +60,11 @@ func TestGetEnvironment(t *testing.T) {
 	})
 }
 
-func TestUpdateKeyBinding(t *testing.T) {
+func TestAddKeyBinding(t *testing.T) {
 	ch := make(chan *Service, 1)
 
 	s := &Service{
-		KeyConfig:            make(map[dispatcher.Command]keyinfo.KeyData),
+		KeyConfig:            make(map[dispatcher.Command]Bindings),
 		configUpdatedChannel: ch,
 	}
 
@@ -72,15 +74,17 @@ func TestUpdateKeyBinding(t *testing.T) {
 		LocaleName: "SPACE",
 	}
 
-	s.UpdateKeyBinding(cmd, data)
+	if err := s.AddKeyBinding(cmd, data); err != nil {
+		t.Fatalf("AddKeyBinding() returned error: %s", err)
+	}
 
 	// 1) Map updated
 	got, ok := s.KeyConfig[cmd]
 	if !ok {
 		t.Fatalf("expected KeyConfig to contain command %v", cmd)
 	}
-	if !reflect.DeepEqual(got, data) {
-		t.Fatalf("expected KeyConfig[%v] = %#v, got %#v", cmd, data, got)
+	if !reflect.DeepEqual(got, Bindings{data}) {
+		t.Fatalf("expected KeyConfig[%v] = %#v, got %#v", cmd, Bindings{data}, got)
 	}
 
 	// 2) UI update emitted (non-blocking send)
@@ -94,6 +98,327 @@ func TestUpdateKeyBinding(t *testing.T) {
 	}
 }
 
+func TestKeyBindingConflicts(t *testing.T) {
+	s, _ := NewService()
+	space := keyinfo.NewKeyData(32, "Space", nil, nil)
+	ctrlSpace := keyinfo.NewKeyData(32, "Space", []int{0xA2}, []string{"Left Control"})
+	_ = s.AddKeyBinding(dispatcher.SPLIT, space)
+	if err := s.AddKeyBinding(dispatcher.SPLIT, ctrlSpace); err != nil {
+		t.Fatalf("AddKeyBinding() of a second chord returned error: %s", err)
+	}
+
+	var conflict *BindingConflict
+	err := s.AddKeyBinding(dispatcher.RESET, space)
+	if !errors.As(err, &conflict) || conflict.BoundTo != "SPLIT" || err.Error() != "Space is already bound to SPLIT" {
+		t.Fatalf("AddKeyBinding() of a bound chord want a conflict with SPLIT, got %v", err)
+	}
+
+	s.PinnedSplitFiles = []PinnedSplitFile{{Name: "Any%"}, {Name: "100%"}}
+	if err = s.UpdatePinnedKeyBinding(0, ctrlSpace); !errors.As(err, &conflict) {
+		t.Fatalf("UpdatePinnedKeyBinding() of a bound chord want a conflict, got %v", err)
+	}
+	f1 := keyinfo.NewKeyData(112, "F1", nil, nil)
+	_ = s.UpdatePinnedKeyBinding(0, f1)
+	if err = s.UpdatePinnedKeyBinding(0, f1); err != nil {
+		t.Fatalf("UpdatePinnedKeyBinding() rebinding a pin's own chord returned error: %s", err)
+	}
+	err = s.UpdatePinnedKeyBinding(1, f1)
+	if err == nil || err.Error() != "F1 is already bound to pinned split file Any%" {
+		t.Fatalf("UpdatePinnedKeyBinding() want a conflict with the first pin, got %v", err)
+	}
+
+	if err = s.RemoveKeyBinding(dispatcher.SPLIT, 0); err != nil || len(s.KeyConfig[dispatcher.SPLIT]) != 1 {
+		t.Fatalf("RemoveKeyBinding() want one binding left, got %v (%v)", s.KeyConfig[dispatcher.SPLIT], err)
+	}
+	if err = s.RemoveKeyBinding(dispatcher.SPLIT, 1); err == nil {
+		t.Fatalf("RemoveKeyBinding() past the end want error, got nil")
+	}
+}
+
+func TestMatchCommand(t *testing.T) {
+	space := keyinfo.NewKeyData(32, "Space", nil, nil)
+	// a hand edited config can bind a chord twice, the lower command always wins
+	s, _, err := Decode([]byte(`{"key_config": {
+		"12": [{"key_code": 32, "locale_name": "Space"}],
+		"9": [{"key_code": 80, "locale_name": "P"}, {"key_code": 32, "locale_name": "Space"}],
+		"7": {"key_code": 0, "locale_name": ""}
+	}, "pinned_split_files": [{"name": "Any%", "key": {"key_code": 32, "locale_name": "Space"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.KeyConfig[dispatcher.RESET]) != 0 {
+		t.Fatalf("an unassigned legacy binding want no bindings, got %v", s.KeyConfig[dispatcher.RESET])
+	}
+	for range 20 {
+		if command, ok := s.MatchCommand(space); !ok || command != dispatcher.SPLIT {
+			t.Fatalf("MatchCommand() want SPLIT, got %v", command)
+		}
+	}
+	if _, ok := s.MatchCommand(keyinfo.NewKeyData(0, "", nil, nil)); ok {
+		t.Fatalf("MatchCommand() matched an unassigned binding")
+	}
+
+	conflicts := s.Conflicts()
+	if len(conflicts) != 2 || conflicts[0].Error() != "Space is already bound to SPLIT" ||
+		conflicts[1].Error() != "Space is already bound to SPLIT" {
+		t.Fatalf("Conflicts() want PAUSE and the pin reported against SPLIT, got %v", conflicts)
+	}
+}
+
+func TestGestures(t *testing.T) {
+	var s Service
+	if err := json.Unmarshal([]byte(`{"gestures": {
+		"7": {"kind": "hold", "hold_ms": 800},
+		"9": {"kind": "sequence"},
+		"12": {"kind": ""}
+	}}`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	gestures := s.CommandGestures()
+	if len(gestures) != 1 || gestures[dispatcher.RESET].HoldDuration() != 800*time.Millisecond {
+		t.Fatalf("CommandGestures() want only RESET's hold, got %v", gestures)
+	}
+	if errs := s.GestureErrors(); len(errs) != 1 || errs[0].Error() != "SPLIT: sequence gesture has no leader key" {
+		t.Fatalf("GestureErrors() want SPLIT's sequence reported, got %v", errs)
+	}
+}
+
+func TestHotkeyPolicy(t *testing.T) {
+	s := &Service{configUpdatedChannel: make(chan *Service, 4), GlobalHotkeysActive: true}
+	if p := s.HotkeyPolicy(dispatcher.SPLIT); p != PolicyGlobal {
+		t.Fatalf("HotkeyPolicy() want global hotkeys to follow GlobalHotkeysActive, got %q", p)
+	}
+
+	if err := s.SetHotkeyPolicies(map[dispatcher.Command]HotkeyPolicy{dispatcher.SPLIT: "sometimes"}); err == nil {
+		t.Fatalf("SetHotkeyPolicies() with an unknown policy want error, got nil")
+	}
+	err := s.SetHotkeyPolicies(map[dispatcher.Command]HotkeyPolicy{
+		dispatcher.RESET: PolicyFocused,
+		dispatcher.PAUSE: PolicyDisabled,
+		dispatcher.SPLIT: PolicyDefault,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.HotkeyPolicies) != 2 {
+		t.Fatalf("SetHotkeyPolicies() want default policies dropped, got %v", s.HotkeyPolicies)
+	}
+
+	s.GlobalHotkeysActive = false
+	want := map[dispatcher.Command]HotkeyPolicy{
+		dispatcher.SPLIT: PolicyFocused,
+		dispatcher.RESET: PolicyFocused,
+		dispatcher.PAUSE: PolicyDisabled,
+	}
+	for command, policy := range want {
+		if got := s.HotkeyPolicy(command); got != policy {
+			t.Errorf("HotkeyPolicy(%v) want %q, got %q", command, policy, got)
+		}
+	}
+}
+
+func TestGuards(t *testing.T) {
+	s := &Service{configUpdatedChannel: make(chan *Service, 4)}
+	if d := s.Debounce(dispatcher.SPLIT); d != 0 {
+		t.Fatalf("Debounce() want no debounce by default, got %s", d)
+	}
+
+	if err := s.SetGuards(GuardConfig{ResetConfirmAfterMS: -1}); err == nil {
+		t.Fatalf("SetGuards() with a negative threshold want error, got nil")
+	}
+	err := s.SetGuards(GuardConfig{
+		DebounceMS:          map[dispatcher.Command]int{dispatcher.SPLIT: 300, dispatcher.UNDO: 0},
+		ResetConfirmAfterMS: 60000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Guards.DebounceMS) != 1 || s.Debounce(dispatcher.SPLIT) != 300*time.Millisecond {
+		t.Fatalf("SetGuards() want only SPLIT debounced, got %v", s.Guards.DebounceMS)
+	}
+	if d := s.ResetConfirmAfter(); d != time.Minute {
+		t.Fatalf("ResetConfirmAfter() want %s, got %s", time.Minute, d)
+	}
+}
+
+func TestSetTextOutput(t *testing.T) {
+	s := &Service{configUpdatedChannel: make(chan *Service, 4)}
+	if err := s.SetTextOutput(TextOutputConfig{Templates: map[string]string{"bad.txt": "{{"}}); err == nil {
+		t.Fatalf("SetTextOutput() with a template that doesn't parse want error, got nil")
+	}
+	if err := s.SetTextOutput(TextOutputConfig{WriteIntervalMS: -1}); err == nil {
+		t.Fatalf("SetTextOutput() with a negative write interval want error, got nil")
+	}
+	if err := s.SetTextOutput(TextOutputConfig{Enabled: true, Directory: "/obs"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetTextOutputConfig(); !got.Enabled || got.Directory != "/obs" || got.Templates == nil {
+		t.Fatalf("SetTextOutput() want output enabled to /obs, got %#v", got)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	s := &Service{configUpdatedChannel: make(chan *Service, 4)}
+	space := keyinfo.NewKeyData(32, "Space", nil, nil)
+	button := keyinfo.NewButtonData(0, "Button 0")
+	_ = s.AddKeyBinding(dispatcher.SPLIT, space)
+
+	if err := s.ActivateProfile(Profile{}); err == nil {
+		t.Fatalf("ActivateProfile() without a name want error, got nil")
+	}
+	err := s.ActivateProfile(Profile{
+		Name:      "pad",
+		KeyConfig: map[dispatcher.Command]Bindings{dispatcher.SPLIT: {button}},
+		Guards:    GuardConfig{ResetConfirmAfterMS: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.MatchCommand(space); ok || s.ResetConfirmAfter() != time.Second {
+		t.Fatalf("ActivateProfile() want the profile's settings in use")
+	}
+
+	_ = s.AddKeyBinding(dispatcher.UNDO, space)
+	profile, active := s.ActiveProfileSettings()
+	if !active || profile.Name != "pad" || len(profile.KeyConfig[dispatcher.UNDO]) != 1 {
+		t.Fatalf("ActiveProfileSettings() want pad with the UNDO binding added, got %v (%t)", profile, active)
+	}
+	base := s.Base()
+	if base.ActiveProfile != "" || len(base.KeyConfig[dispatcher.UNDO]) != 0 || base.Guards.ResetConfirmAfterMS != 0 {
+		t.Fatalf("Base() want the config's own settings, got %v", base.KeyConfig)
+	}
+
+	s.DeactivateProfile()
+	if command, ok := s.MatchCommand(space); !ok || command != dispatcher.SPLIT || s.ActiveProfile != "" {
+		t.Fatalf("DeactivateProfile() want Space back on SPLIT, got %v (%t)", command, ok)
+	}
+	if _, active := s.ActiveProfileSettings(); active {
+		t.Fatalf("ActiveProfileSettings() want no active profile after DeactivateProfile()")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if _, _, err := Decode([]byte(`[]`)); err == nil {
+		t.Fatalf("Decode() of a file that isn't an object want error, got nil")
+	}
+
+	s, report, err := Decode([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Version != 0 || len(report.Problems) != 0 || s.Version != SchemaVersion {
+		t.Fatalf("Decode() of an empty unversioned file want it migrated without problems, got %v", report)
+	}
+	if _, ok := s.KeyConfig[dispatcher.SPLIT]; !ok || s.TextOutput.WriteInterval() != DefaultTextOutputWriteInterval {
+		t.Fatalf("Decode() want missing settings given their defaults, got %v", s.KeyConfig)
+	}
+
+	s, report, err = Decode([]byte(`{
+		"version": 1,
+		"key_config": {"9": [{"key_code": 32, "locale_name": "Space"}], "PAUSE": [], "200": [], "12": {"key_code": 1}},
+		"gestures": {
+			"RESET": {"kind": "hold", "hold_ms": 0},
+			"PAUSE": {"kind": "spin"},
+			"UNDO": {"kind": "double_tap", "window_ms": 300},
+			"WOBBLE": {"kind": "hold"}
+		},
+		"global_hotkeys_active": "yes",
+		"hotkey_policies": {"9": "sometimes", "7": "focused"},
+		"guards": {"debounce_ms": {"9": 100, "10": -5}, "reset_confirm_after_ms": -1},
+		"text_output": {"templates": {"good.txt": "{{.Time}}", "bad.txt": "{{"}},
+		"race": {"address": "127.0.0.1:99999", "name": "zelly"},
+		"control": {"token": "secret"},
+		"colour": "blue"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"key_config: PAUSE: json: cannot unmarshal object into Go value of type config.Bindings",
+		"key_config: unknown command 200",
+		`gestures: unknown command "WOBBLE"`,
+		"gestures: RESET: hold gesture with a zero duration dropped",
+		"global_hotkeys_active: json: cannot unmarshal string into Go value of type bool",
+		`unknown setting "colour" ignored`,
+		`gestures: PAUSE: unknown gesture kind "spin"`,
+		`hotkey_policies: SPLIT: unknown hotkey policy "sometimes"`,
+		"guards: debounce of -5ms for UNDO ignored",
+		"guards: negative reset confirmation threshold -1ms ignored",
+		"text_output: template for bad.txt dropped: template: bad.txt:1: unclosed action",
+		`race: address "127.0.0.1:99999" ignored: port must be a number from 1 to 65535`,
+	}
+	if !reflect.DeepEqual(s.LoadProblems, want) || !report.Changed() {
+		t.Fatalf("Decode() problems\nwant %q\ngot  %q", want, s.LoadProblems)
+	}
+	if len(s.KeyConfig[dispatcher.SPLIT]) != 1 || s.HotkeyPolicy(dispatcher.RESET) != PolicyFocused ||
+		s.Debounce(dispatcher.SPLIT) != 100*time.Millisecond || s.Race.Name != "zelly" ||
+		s.Control.Token != "secret" || s.Gestures[dispatcher.UNDO].WindowMS != 300 || len(s.Gestures) != 1 ||
+		s.TextOutput.Templates["good.txt"] == "" {
+		t.Fatalf("Decode() want the valid settings kept")
+	}
+
+	_, report, _ = Decode([]byte(`{"version": 99}`))
+	if len(report.Problems) != 1 || !report.Changed() {
+		t.Fatalf("Decode() of a newer schema want it reported, got %v", report.Problems)
+	}
+
+	saved, err := json.Marshal(s.Base())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, report, _ := Decode(saved); report.Changed() {
+		t.Fatalf("Decode() of a saved config want it unchanged, got %v", report.Problems)
+	}
+}
+
+func TestDecodeProfiles(t *testing.T) {
+	profiles, report, err := DecodeProfiles(nil)
+	if err != nil || len(profiles) != 0 || report.Changed() {
+		t.Fatalf("DecodeProfiles() of no data want no profiles, got %v %v (%v)", profiles, report.Problems, err)
+	}
+	if _, _, err = DecodeProfiles([]byte(`[]`)); err == nil {
+		t.Fatalf("DecodeProfiles() of data that isn't an object want error, got nil")
+	}
+
+	// pad is saved before bindings were lists, the rest of keyboard is version 1
+	profiles, report, err = DecodeProfiles([]byte(`{
+		"pad": {"name": "pad", "key_config": {"9": {"key_code": 32, "locale_name": "Space"}}},
+		"keyboard": {
+			"version": 1,
+			"name": "keyboard",
+			"key_config": {"SPLIT": [{"key_code": 13, "locale_name": "Enter"}]},
+			"gestures": {"RESET": {"kind": "hold", "hold_ms": 0}},
+			"hotkey_policies": {"9": "never"},
+			"colour": "blue"
+		},
+		"broken": 5
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`profile "broken": profile is not a JSON object, it was left out`,
+		`profile "keyboard": gestures: RESET: hold gesture with a zero duration dropped`,
+		`profile "keyboard": unknown setting "colour" ignored`,
+		`profile "keyboard": hotkey_policies: SPLIT: unknown hotkey policy "never"`,
+	}
+	var got []string
+	for _, problem := range report.Problems {
+		got = append(got, problem.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DecodeProfiles() problems\nwant %q\ngot  %q", want, got)
+	}
+	if len(profiles) != 2 || profiles["keyboard"].Name != "keyboard" || len(profiles["keyboard"].Gestures) != 0 {
+		t.Fatalf("DecodeProfiles() want pad and keyboard without its gesture, got %v", profiles)
+	}
+	if bindings := profiles["pad"].KeyConfig[dispatcher.SPLIT]; len(bindings) != 1 || bindings[0].KeyCode != 32 {
+		t.Fatalf("DecodeProfiles() want pad's single binding migrated to a list, got %v", bindings)
+	}
+}
+
 func TestCreateDefaultConfig(t *testing.T) {
 	ch := make(chan *Service, 1)
 	s := &Service{
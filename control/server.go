@@ -0,0 +1,212 @@
+// Package control serves a small HTTP API for driving OpenSplit from scripts and other local programs.
+//
+//	GET  /commands           names of every dispatcher.Command
+//	POST /commands/{command} dispatches the command, the request body (if any) is sent as the payload
+//	GET  /events/{name}      the last payload emitted for a UI event, e.g. session:update or timer:update
+//
+// Commands the current state doesn't accept are answered with 409 Conflict.  The commands that are currently valid
+// are listed in the validCommands field of the ui:model event.
+//
+// Every request must carry the config's control token as "Authorization: Bearer <token>".  Requests addressed to
+// anything but the local machine, or sent from a web page on another site, are refused so a browser can't be used to
+// drive OpenSplit.
+package control
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+const logModule = "control"
+
+// maxPayloadSize bounds request bodies, the largest payload in practice is a split file sent with SUBMIT
+const maxPayloadSize = 8 << 20
+
+// Dispatcher sends commands to the state machine, in production this is *dispatcher.Service
+type Dispatcher interface {
+	DispatchFrom(dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error)
+}
+
+// EventSource provides the most recent payload of each UI event, in production this is *platform.HeadlessRuntime
+type EventSource interface {
+	LastEvent(name string) (any, bool)
+}
+
+type Server struct {
+	dispatcher Dispatcher
+	events     EventSource
+	address    string
+	token      string
+	mu         sync.Mutex
+	listener   net.Listener
+}
+
+// NewServer creates a Server that will listen on address, e.g. 127.0.0.1:6768, and accept requests carrying token.
+// An empty token refuses every request.
+func NewServer(d Dispatcher, events EventSource, address string, token string) *Server {
+	return &Server{
+		dispatcher: d,
+		events:     events,
+		address:    address,
+		token:      token,
+	}
+}
+
+// NewToken returns a random token for config.ControlConfig
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /commands", s.listCommands)
+	mux.HandleFunc("POST /commands/{command}", s.dispatch)
+	mux.HandleFunc("GET /events/{name}", s.lastEvent)
+	return s.authorize(mux)
+}
+
+// authorize refuses requests that aren't for the local machine, that come from another site's web page, or that
+// don't carry the token
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalHost(r.Host) {
+			logger.Warnf(logModule, "refused request from %s for host %q", r.RemoteAddr, r.Host)
+			writeJSON(w, http.StatusForbidden, dispatcher.DispatchReply{Code: 1, Message: "host not allowed"})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || !isLocalHost(u.Host) {
+				logger.Warnf(logModule, "refused request from %s with origin %q", r.RemoteAddr, origin)
+				writeJSON(w, http.StatusForbidden, dispatcher.DispatchReply{Code: 1, Message: "origin not allowed"})
+				return
+			}
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, dispatcher.DispatchReply{Code: 1, Message: "missing or wrong token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalHost reports whether host, with or without a port, names the local machine
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Listen serves the API until Close is called
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	logger.Infof(logModule, "control API listening on %s", listener.Addr())
+	err = http.Serve(listener, s.Handler())
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) listCommands(w http.ResponseWriter, _ *http.Request) {
+	var names []string
+	for _, command := range dispatcher.Commands() {
+		names = append(names, command.String())
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request) {
+	command, err := dispatcher.ParseCommand(r.PathValue("command"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, dispatcher.DispatchReply{Code: 1, Message: err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dispatcher.DispatchReply{Code: 1, Message: err.Error()})
+		return
+	}
+
+	var payload *string
+	if len(body) > 0 {
+		p := string(body)
+		payload = &p
+	}
+
+	source := dispatcher.Source{Kind: dispatcher.SourceControl, Detail: r.RemoteAddr}
+	reply, err := s.dispatcher.DispatchFrom(source, command, payload)
+	// the command was understood but not taken, by the current state or by a safety guard
+	if err == nil && (reply.Code == dispatcher.CodeRejected || reply.Code == dispatcher.CodeGuarded) {
+		writeJSON(w, http.StatusConflict, reply)
+		return
+	}
+	if err != nil {
+		logger.Warnf(logModule, "command %s from %s failed: %s", command, r.RemoteAddr, err)
+		if reply.Code == 0 {
+			reply = dispatcher.DispatchReply{Code: 1, Message: err.Error()}
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, reply)
+		return
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
+
+func (s *Server) lastEvent(w http.ResponseWriter, r *http.Request) {
+	payload, ok := s.events.LastEvent(r.PathValue("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warnf(logModule, "failed to write response: %s", err)
+	}
+}
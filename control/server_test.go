@@ -0,0 +1,153 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+)
+
+type mockDispatcher struct {
+	source  dispatcher.Source
+	command dispatcher.Command
+	payload *string
+	code    int
+	err     error
+}
+
+func (m *mockDispatcher) DispatchFrom(source dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+	m.source = source
+	m.command = command
+	m.payload = payload
+	return dispatcher.DispatchReply{Code: m.code, Message: "ok"}, m.err
+}
+
+const testToken = "secret"
+
+// newRequest makes a request the server accepts, from the local machine with the token
+func newRequest(method string, target string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	r.Host = "127.0.0.1:6768"
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r
+}
+
+type mockEvents map[string]any
+
+func (m mockEvents) LastEvent(name string) (any, bool) {
+	payload, ok := m[name]
+	return payload, ok
+}
+
+func TestDispatch(t *testing.T) {
+	d := &mockDispatcher{}
+	s := NewServer(d, mockEvents{}, "", testToken)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/split", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /commands/split want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if d.command != dispatcher.SPLIT || d.payload != nil || d.source.Kind != dispatcher.SourceControl {
+		t.Fatalf("POST /commands/split dispatched %v %v %v", d.source, d.command, d.payload)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/FOCUS", strings.NewReader("true")))
+	if d.command != dispatcher.FOCUS || d.payload == nil || *d.payload != "true" {
+		t.Fatalf("POST /commands/FOCUS expected payload %q, got %v", "true", d.payload)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/wobble", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("POST /commands/wobble want status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	d.code = dispatcher.CodeRejected
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/split", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("rejected dispatch want status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	d.code = dispatcher.CodeGuarded
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/reset", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("guarded dispatch want status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	d.code = 0
+	d.err = errors.New("invalid command")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodPost, "/commands/edit", nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("failed dispatch want status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+	var reply dispatcher.DispatchReply
+	if err := json.NewDecoder(rec.Body).Decode(&reply); err != nil || reply.Code == 0 {
+		t.Fatalf("failed dispatch want error reply, got %v (%v)", reply, err)
+	}
+}
+
+func TestLastEvent(t *testing.T) {
+	s := NewServer(&mockDispatcher{}, mockEvents{"timer:update": 1234}, "", testToken)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodGet, "/events/timer:update", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "1234" {
+		t.Fatalf("GET /events/timer:update want 1234, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, newRequest(http.MethodGet, "/events/session:update", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET missing event want status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	d := &mockDispatcher{}
+	s := NewServer(d, mockEvents{}, "", testToken)
+
+	for name, tc := range map[string]struct {
+		modify func(r *http.Request)
+		status int
+	}{
+		"local":          {func(r *http.Request) {}, http.StatusOK},
+		"localhost":      {func(r *http.Request) { r.Host = "localhost:6768" }, http.StatusOK},
+		"ipv6 loopback":  {func(r *http.Request) { r.Host = "[::1]:6768" }, http.StatusOK},
+		"local origin":   {func(r *http.Request) { r.Header.Set("Origin", "http://localhost:34115") }, http.StatusOK},
+		"foreign host":   {func(r *http.Request) { r.Host = "evil.test:6768" }, http.StatusForbidden},
+		"foreign origin": {func(r *http.Request) { r.Header.Set("Origin", "https://evil.test") }, http.StatusForbidden},
+		"null origin":    {func(r *http.Request) { r.Header.Set("Origin", "null") }, http.StatusForbidden},
+		"no token":       {func(r *http.Request) { r.Header.Del("Authorization") }, http.StatusUnauthorized},
+		"wrong token":    {func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d.command = 0
+			r := newRequest(http.MethodPost, "/commands/split", nil)
+			tc.modify(r)
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, r)
+			if rec.Code != tc.status {
+				t.Fatalf("want status %d, got %d", tc.status, rec.Code)
+			}
+			if tc.status != http.StatusOK && d.command == dispatcher.SPLIT {
+				t.Fatal("refused request was dispatched")
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	NewServer(d, mockEvents{}, "", "").Handler().ServeHTTP(rec, newRequest(http.MethodGet, "/commands", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("server without a token want status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
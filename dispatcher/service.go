@@ -1,7 +1,12 @@
 package dispatcher
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"github.com/zellydev-games/opensplit/logger"
@@ -34,8 +39,108 @@ const (
 	TOGGLEGLOBAL
 	FOCUS
 	HELLO
+	PRACTICE
+	SUCCESS
+	FAIL
+	SWITCH
+	PIN
+	RACE
+	READY
+	GHOST
+	REPLAY
+	UNBIND
+	LOCK
+	PROFILE
 )
 
+var commandNames = map[Command]string{
+	QUIT:         "QUIT",
+	NEW:          "NEW",
+	LOAD:         "LOAD",
+	EDIT:         "EDIT",
+	CANCEL:       "CANCEL",
+	SUBMIT:       "SUBMIT",
+	CLOSE:        "CLOSE",
+	RESET:        "RESET",
+	SAVE:         "SAVE",
+	SPLIT:        "SPLIT",
+	UNDO:         "UNDO",
+	SKIP:         "SKIP",
+	PAUSE:        "PAUSE",
+	TOGGLEGLOBAL: "TOGGLEGLOBAL",
+	FOCUS:        "FOCUS",
+	HELLO:        "HELLO",
+	PRACTICE:     "PRACTICE",
+	SUCCESS:      "SUCCESS",
+	FAIL:         "FAIL",
+	SWITCH:       "SWITCH",
+	PIN:          "PIN",
+	RACE:         "RACE",
+	READY:        "READY",
+	GHOST:        "GHOST",
+	REPLAY:       "REPLAY",
+	UNBIND:       "UNBIND",
+	LOCK:         "LOCK",
+	PROFILE:      "PROFILE",
+}
+
+// Commands returns every Command in order
+func Commands() []Command {
+	commands := make([]Command, 0, len(commandNames))
+	for command := range commandNames {
+		commands = append(commands, command)
+	}
+	slices.Sort(commands)
+	return commands
+}
+
+// String returns the name of the Command as used in the constants above
+func (c Command) String() string {
+	if name, ok := commandNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Command(%d)", byte(c))
+}
+
+// Valid reports whether c is one of the constants above
+func (c Command) Valid() bool {
+	_, ok := commandNames[c]
+	return ok
+}
+
+// ParseCommand returns the Command with the given name, ignoring case
+func ParseCommand(name string) (Command, error) {
+	for command, commandName := range commandNames {
+		if strings.EqualFold(commandName, name) {
+			return command, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown command %q", name)
+}
+
+// SourceKind identifies what kind of caller dispatched a Command
+type SourceKind string
+
+const (
+	SourceUnknown      SourceKind = ""
+	SourceFrontend     SourceKind = "frontend"
+	SourceHotkey       SourceKind = "hotkey"
+	SourceAutosplitter SourceKind = "autosplitter"
+	SourceScript       SourceKind = "script"
+	SourceControl      SourceKind = "control"
+	SourceRace         SourceKind = "race"
+)
+
+// Source identifies the caller of a Command so splits can be attributed after the fact
+type Source struct {
+	Kind SourceKind `json:"kind"`
+	// Detail narrows down the caller, e.g. an autosplitter's remote address or a script's file name
+	Detail string `json:"detail"`
+	// At is when the input behind the command happened, e.g. a key press or an autosplitter's packet timestamp.  It is
+	// zero when the caller can't tell, and the receiver uses the time it handles the command instead.
+	At time.Time `json:"-"`
+}
+
 // DispatchReply is sent in response to Dispatch
 //
 // Code greater than zero indicates an error situation
@@ -44,8 +149,19 @@ type DispatchReply struct {
 	Message string `json:"message"`
 }
 
+// CodeRejected is the DispatchReply.Code for a Command the receiver doesn't accept in its current state
+const CodeRejected = 100
+
+// CodeGuarded is the DispatchReply.Code for a Command ignored by a safety guard, e.g. a split faster than its segment's
+// minimum time or a RESET waiting to be confirmed.  The message says which guard.
+const CodeGuarded = 101
+
+// ResetConfirmPayload is the RESET payload that confirms the reset straight away, for callers that asked the user
+// themselves or that reset on purpose
+const ResetConfirmPayload = "confirm"
+
 type DispatchReceiver interface {
-	ReceiveDispatch(Command, *string) (DispatchReply, error)
+	ReceiveDispatch(Source, Command, *string) (DispatchReply, error)
 }
 
 type Service struct {
@@ -63,22 +179,50 @@ func NewService(receiver DispatchReceiver, runtime RuntimeProvider, autoSplitter
 	}
 }
 
+// Dispatch sends a Command from the frontend to the receiver
 func (s *Service) Dispatch(command Command, payload *string) (DispatchReply, error) {
-	logger.Debugf(logModule, "dispatching command: %v", command)
+	return s.DispatchFrom(Source{Kind: SourceFrontend}, command, payload)
+}
+
+// DispatchFrom sends a Command to the receiver on behalf of the given Source
+func (s *Service) DispatchFrom(source Source, command Command, payload *string) (DispatchReply, error) {
+	logger.Debugf(logModule, "dispatching command: %v from %s", command, source.Kind)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.receiver.ReceiveDispatch(source, command, payload)
+}
+
+// DispatchContext is DispatchFrom for senders that can be stopped while waiting for the dispatcher.  The command is
+// dropped with ctx's error if ctx is done by the time the dispatcher is free.
+func (s *Service) DispatchContext(
+	ctx context.Context, source Source, command Command, payload *string,
+) (DispatchReply, error) {
+	logger.Debugf(logModule, "dispatching command: %v from %s", command, source.Kind)
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.receiver.ReceiveDispatch(command, payload)
+	if err := ctx.Err(); err != nil {
+		return DispatchReply{}, err
+	}
+	return s.receiver.ReceiveDispatch(source, command, payload)
 }
 
 func (s *Service) PickAutoSplitterFile() (string, error) {
 	return s.runtime.OpenFileDialog(runtime.OpenDialogOptions{
 		DefaultDirectory: s.autoSplitterDirectory,
-		Title:            "Select an Autosplitter lua file",
+		Title:            "Select an Autosplitter file",
 		Filters: []runtime.FileFilter{
 			{
 				DisplayName: "Autosplitter Files",
+				Pattern:     "*.lua;*.yaml;*.yml;*.json",
+			},
+			{
+				DisplayName: "Lua Autosplitters",
 				Pattern:     "*.lua",
 			},
+			{
+				DisplayName: "Memory Watcher Autosplitters",
+				Pattern:     "*.yaml;*.yml;*.json",
+			},
 		},
 	})
 }
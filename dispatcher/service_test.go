@@ -1,10 +1,21 @@
 package dispatcher
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
 
-type mockDispatchReceiver struct{}
+type mockDispatchReceiver struct {
+	source *Source
+}
 
-func (r mockDispatchReceiver) ReceiveDispatch(Command, *string) (DispatchReply, error) {
+func (r mockDispatchReceiver) ReceiveDispatch(source Source, _ Command, _ *string) (DispatchReply, error) {
+	if r.source != nil {
+		*r.source = source
+	}
 	return DispatchReply{
 		Code:    69,
 		Message: "Nice.",
@@ -12,10 +23,69 @@ func (r mockDispatchReceiver) ReceiveDispatch(Command, *string) (DispatchReply,
 }
 
 func TestDispatch(t *testing.T) {
-	dr := mockDispatchReceiver{}
+	var source Source
+	dr := mockDispatchReceiver{source: &source}
 	s := NewService(dr, nil, "")
 	reply, _ := s.Dispatch(SPLIT, nil)
 	if reply.Code != 69 || reply.Message != "Nice." {
 		t.Fatalf("Dispatch expected to return code 69 with message Nice. but got %v: %s", reply.Code, reply.Message)
 	}
+	if source.Kind != SourceFrontend {
+		t.Fatalf("Dispatch expected source %q, got %q", SourceFrontend, source.Kind)
+	}
+}
+
+func TestDispatchFrom(t *testing.T) {
+	var source Source
+	s := NewService(mockDispatchReceiver{source: &source}, nil, "")
+	want := Source{Kind: SourceAutosplitter, Detail: "127.0.0.1:50000"}
+	_, _ = s.DispatchFrom(want, SPLIT, nil)
+	if source != want {
+		t.Fatalf("DispatchFrom expected source %v, got %v", want, source)
+	}
+}
+
+func TestDispatchContext(t *testing.T) {
+	var source Source
+	s := NewService(mockDispatchReceiver{source: &source}, nil, "")
+	want := Source{Kind: SourceScript, Detail: "game.lua"}
+	if reply, err := s.DispatchContext(context.Background(), want, SPLIT, nil); err != nil || reply.Code != 69 {
+		t.Fatalf("DispatchContext expected code 69, got %d (%v)", reply.Code, err)
+	}
+	if source != want {
+		t.Fatalf("DispatchContext expected source %v, got %v", want, source)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	source = Source{}
+	if _, err := s.DispatchContext(ctx, want, SPLIT, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DispatchContext with a cancelled context expected context.Canceled, got %v", err)
+	}
+	if source != (Source{}) {
+		t.Fatal("DispatchContext with a cancelled context should not reach the receiver")
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for command, name := range commandNames {
+		got, err := ParseCommand(strings.ToLower(name))
+		if err != nil || got != command {
+			t.Fatalf("ParseCommand(%q) want %d, got %d (%v)", name, command, got, err)
+		}
+		if command.String() != name {
+			t.Fatalf("Command(%d).String() want %s, got %s", command, name, command.String())
+		}
+	}
+
+	if _, err := ParseCommand("WOBBLE"); err == nil {
+		t.Fatal("ParseCommand expected error for unknown command")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	commands := Commands()
+	if len(commands) != len(commandNames) || commands[0] != QUIT || !slices.IsSorted(commands) {
+		t.Fatalf("Commands() want %d sorted commands starting at QUIT, got %v", len(commandNames), commands)
+	}
 }
@@ -9,6 +9,73 @@ type Session struct {
 	CurrentSegmentIndex int          `json:"current_segment_index"`
 	SessionState        SessionState `json:"session_state"`
 	Dirty               bool         `json:"dirty"`
+	Practice            *Practice    `json:"practice"`
+	// GhostRunID is the run the ghost replays, empty for the PB
+	GhostRunID string `json:"ghost_run_id"`
+	// ReplayRunID is the run being replayed, empty when not replaying
+	ReplayRunID string `json:"replay_run_id"`
+	// GameStatus is what the autosplitter reads from the game, nil when no autosplitter is reporting
+	GameStatus *GameStatus `json:"game_status"`
+}
+
+// GameStatus is the autosplitter's load state and in game time, GameTime is in milliseconds and -1 when not reported
+type GameStatus struct {
+	Loading  bool  `json:"loading"`
+	GameTime int64 `json:"game_time"`
+}
+
+// Ghost is where the ghost run was at the time of the last timer update, Progress runs 0 to 1 through the segment
+type Ghost struct {
+	RunID        string  `json:"run_id"`
+	SegmentIndex int     `json:"segment_index"`
+	SegmentID    string  `json:"segment_id"`
+	Progress     float64 `json:"progress"`
+	Finished     bool    `json:"finished"`
+	Stopped      bool    `json:"stopped"`
+}
+
+// Practice is the leaf segment range being practiced, it is also the PRACTICE command payload
+type Practice struct {
+	StartIndex  int  `json:"start_index"`
+	EndIndex    int  `json:"end_index"`
+	UpdateGolds bool `json:"update_golds"`
+}
+
+// Unbind is the UNBIND payload that removes the hotkey at Index from Command's bindings
+type Unbind struct {
+	Command int `json:"command"`
+	Index   int `json:"index"`
+}
+
+// RaceJoin is the RACE payload that joins the coordinator at Address as Name
+type RaceJoin struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+// PracticeResult is a trick success or failure recorded while practicing, Timestamp is in unix milliseconds
+type PracticeResult struct {
+	SegmentID    string `json:"segment_id"`
+	Success      bool   `json:"success"`
+	Timestamp    int64  `json:"timestamp"`
+	Source       string `json:"source"`
+	SourceDetail string `json:"source_detail"`
+}
+
+// PracticeStat summarizes a segment's practice results, the rolling rate covers its most recent results only
+type PracticeStat struct {
+	Successes          int     `json:"successes"`
+	Failures           int     `json:"failures"`
+	RollingSuccessRate float64 `json:"rolling_success_rate"`
+	RollingAttempts    int     `json:"rolling_attempts"`
+}
+
+// PracticeRun is a Run recorded in practice mode over the segments StartSegmentID through EndSegmentID
+type PracticeRun struct {
+	Run
+	StartSegmentID string `json:"start_segment_id"`
+	EndSegmentID   string `json:"end_segment_id"`
+	UpdateGolds    bool   `json:"update_golds"`
 }
 
 type Run struct {
@@ -1,18 +1,22 @@
 package dto
 
 type Segment struct {
-	ID       string    `json:"id"`
-	Name     string    `json:"name"`
-	Gold     int64     `json:"gold"`
-	Average  int64     `json:"average"`
-	PB       int64     `json:"pb"`
-	Children []Segment `json:"children"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Gold    int64  `json:"gold"`
+	Average int64  `json:"average"`
+	PB      int64  `json:"pb"`
+	// MinDuration is the fastest the segment can be split in milliseconds, 0 for no minimum
+	MinDuration int64     `json:"min_duration,omitempty"`
+	Children    []Segment `json:"children"`
 }
 
 type Split struct {
 	SplitSegmentID    string `json:"split_segment_id"`
 	CurrentCumulative int64  `json:"current_cumulative"`
 	CurrentDuration   int64  `json:"current_duration"`
+	Source            string `json:"source"`
+	SourceDetail      string `json:"source_detail"`
 }
 
 // SplitFile represents the data and history of a game/category combo.
@@ -32,4 +36,23 @@ type SplitFile struct {
 	PB               *Run      `json:"pb"`
 	Offset           int64     `json:"offset"`
 	AutosplitterFile string    `json:"autosplitter_file"`
+	PracticeAttempts int       `json:"practice_attempts"`
+	// PracticeRuns are kept apart from Runs so they never count towards the PB or averages
+	PracticeRuns []PracticeRun    `json:"practice_runs"`
+	PracticeLog  []PracticeResult `json:"practice_log"`
+	// PracticeStats summarizes PracticeLog by segment ID.  It is derived from PracticeLog and ignored on load.
+	PracticeStats map[string]PracticeStat `json:"practice_stats"`
+	// SplitSources counts splits across all runs by what triggered them.  It is derived from Runs and ignored on load.
+	SplitSources map[string]int `json:"split_sources"`
+	// Marathon lists the split files a marathon plays back to back, Segments are rebuilt from them on load
+	Marathon []MarathonGame `json:"marathon,omitempty"`
+	// Profile names the config profile activated while the split file is loaded
+	Profile string `json:"profile,omitempty"`
+}
+
+// MarathonGame references one game's own split file from a marathon split file
+type MarathonGame struct {
+	Path        string `json:"path"`
+	SplitFileID string `json:"split_file_id"`
+	SegmentID   string `json:"segment_id"`
 }
@@ -0,0 +1,48 @@
+package fanout
+
+// Tee copies every value received on in to n new channels so that several consumers can share an update channel.
+//
+// The update channels in OpenSplit (timer, session, config) are single consumer, and the UI bridge is the first
+// consumer of all of them.  Tee lets additional sinks (e.g. text file outputs) see the same updates without stealing
+// them from the bridge.  Sends to the outputs never block the producer: when a consumer falls behind and its output
+// is full, the oldest value waiting in it is dropped to make room, so the consumer always ends up with the latest
+// update even if it misses ones in between.  Each output buffers at least one value.  All outputs are closed when in
+// closes.
+func Tee[T any](in <-chan T, n int, buffer int) []chan T {
+	outs := make([]chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T, max(buffer, 1))
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+
+		for v := range in {
+			for _, out := range outs {
+				sendLatest(out, v)
+			}
+		}
+	}()
+
+	return outs
+}
+
+// sendLatest sends v to out, dropping the oldest value waiting in out if it's full.  Tee is the only sender, so once a
+// value has been dropped there is room for v.
+func sendLatest[T any](out chan T, v T) {
+	for {
+		select {
+		case out <- v:
+			return
+		default:
+		}
+		select {
+		case <-out:
+		default:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package fanout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTee(t *testing.T) {
+	in := make(chan int)
+	outs := Tee(in, 2, 1)
+	if len(outs) != 2 {
+		t.Fatalf("Tee() outputs want %d, got %d", 2, len(outs))
+	}
+
+	in <- 42
+	for i, out := range outs {
+		select {
+		case v := <-out:
+			if v != 42 {
+				t.Fatalf("Tee() output %d want %d, got %d", i, 42, v)
+			}
+		case <-time.After(200 * time.Millisecond):
+			t.Fatalf("timed out waiting for output %d", i)
+		}
+	}
+}
+
+func TestTeeDoesNotBlockOnSlowConsumer(t *testing.T) {
+	in := make(chan int)
+	outs := Tee(in, 2, 1)
+
+	// Fill the first output's buffer and never read it again
+	in <- 1
+	<-outs[1]
+
+	done := make(chan struct{})
+	go func() {
+		in <- 2
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Tee() blocked on a full output")
+	}
+
+	select {
+	case v := <-outs[1]:
+		if v != 2 {
+			t.Fatalf("Tee() fast consumer want %d, got %d", 2, v)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timed out waiting for fast consumer")
+	}
+	// the slow consumer missed 1 but still gets the latest value
+	select {
+	case v := <-outs[0]:
+		if v != 2 {
+			t.Fatalf("Tee() slow consumer want the latest value %d, got %d", 2, v)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timed out waiting for slow consumer")
+	}
+}
+
+func TestTeeClosesOutputs(t *testing.T) {
+	in := make(chan int)
+	outs := Tee(in, 1, 0)
+	close(in)
+
+	select {
+	case _, ok := <-outs[0]:
+		if ok {
+			t.Fatal("Tee() output should be closed after input closes")
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timed out waiting for output to close")
+	}
+}
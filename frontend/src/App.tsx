@@ -26,6 +26,19 @@ export enum Command {
     PAUSE,
     TOGGLEGLOBAL,
     FOCUS,
+    HELLO,
+    PRACTICE,
+    SUCCESS,
+    FAIL,
+    SWITCH,
+    PIN,
+    RACE,
+    READY,
+    GHOST,
+    REPLAY,
+    UNBIND,
+    LOCK,
+    PROFILE,
 }
 
 export enum AppView {
@@ -36,16 +49,18 @@ export enum AppView {
     Settings = "settings",
 }
 
-export type AppViewModel =
+// validCommands names the commands the backend accepts in the current state
+export type AppViewModel = (
     | { view: AppView.Welcome }
     | { view: AppView.NewSplitFile; speedrunApiBaseUrl: string }
     | { view: AppView.EditSplitFile; splitFile: SplitFilePayload | null; speedrunApiBaseUrl: string }
     | { view: AppView.Running; session: SessionPayload; config: ConfigPayload }
-    | { view: AppView.Settings; config: ConfigPayload };
+    | { view: AppView.Settings; config: ConfigPayload }
+) & { validCommands?: string[] };
 
-type ViewRouterProps = { model: AppViewModel };
+type ViewRouterProps = { model: AppViewModel; hotkeysError: string };
 
-function ViewRouter({ model }: ViewRouterProps) {
+function ViewRouter({ model, hotkeysError }: ViewRouterProps) {
     switch (model.view) {
         case AppView.Welcome:
             return <Welcome />;
@@ -57,7 +72,14 @@ function ViewRouter({ model }: ViewRouterProps) {
             return <SplitEditor splitFilePayload={model.splitFile} speedRunAPIBase={model.speedrunApiBaseUrl} />;
 
         case AppView.Running:
-            return <Splitter sessionPayload={model.session} configPayload={model.config} />;
+            return (
+                <Splitter
+                    sessionPayload={model.session}
+                    configPayload={model.config}
+                    validCommands={model.validCommands ?? null}
+                    hotkeysError={hotkeysError}
+                />
+            );
 
         case AppView.Settings:
             return <Config configPayload={model.config} />;
@@ -66,13 +88,14 @@ function ViewRouter({ model }: ViewRouterProps) {
 
 export default function App() {
     const [viewModel, setViewModel] = React.useState<AppViewModel>({ view: AppView.Welcome });
+    const [hotkeysError, setHotkeysError] = React.useState<string>("");
     useDetectWindowChange();
-    useAppEventBindings(setViewModel);
+    useAppEventBindings(setViewModel, setHotkeysError);
     useWindowFocus();
 
     return (
         <div id="App" className="app">
-            <ViewRouter model={viewModel} />
+            <ViewRouter model={viewModel} hotkeysError={hotkeysError} />
         </div>
     );
 }
@@ -118,7 +141,10 @@ function useDetectWindowChange() {
     }, []);
 }
 
-function useAppEventBindings(setViewModel: React.Dispatch<React.SetStateAction<AppViewModel>>) {
+function useAppEventBindings(
+    setViewModel: React.Dispatch<React.SetStateAction<AppViewModel>>,
+    setHotkeysError: React.Dispatch<React.SetStateAction<string>>,
+) {
     useEffect(() => {
         const unsubViewModel = EventsOn("ui:model", (nextModel: AppViewModel) => {
             console.log("[UI MODEL]", nextModel.view, nextModel);
@@ -137,11 +163,17 @@ function useAppEventBindings(setViewModel: React.Dispatch<React.SetStateAction<A
             });
         });
 
+        // sent before the running view, so it's kept here rather than in the Splitter it's shown by
+        const unsubHotkeysError = EventsOn("hotkeys:error", (message: string) => {
+            setHotkeysError(message);
+        });
+
         return () => {
             unsubViewModel();
             unsubSession();
+            unsubHotkeysError();
         };
-    }, [setViewModel]);
+    }, [setViewModel, setHotkeysError]);
 }
 
 function useWindowFocus() {
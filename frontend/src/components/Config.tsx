@@ -3,7 +3,7 @@ import { useEffect, useState } from "react";
 import { Dispatch } from "../../wailsjs/go/dispatcher/Service";
 import { EventsOn, WindowSetSize } from "../../wailsjs/runtime";
 import { Command } from "../App";
-import { ConfigPayload, KeyInfo } from "../models/configPayload";
+import { ConfigPayload, Gesture, HotkeyPolicy, KeyInfo, TextOutputConfig } from "../models/configPayload";
 
 export type ConfigParams = {
     configPayload: ConfigPayload;
@@ -14,28 +14,46 @@ const RECORDING_ARMED = 10;
 export default function Config({ configPayload }: ConfigParams) {
     const [recording, setRecording] = useState(false);
     const [config, setConfig] = useState<ConfigPayload>(configPayload);
+    const [error, setError] = useState<string | null>(null);
+    const [newProfile, setNewProfile] = useState("");
 
     useEffect(() => {
         WindowSetSize(700, 800);
-        return EventsOn("config:update", (newConfigPayload: ConfigPayload) => {
+        const offUpdate = EventsOn("config:update", (newConfigPayload: ConfigPayload) => {
             console.log("received update from backend", newConfigPayload);
             setConfig(newConfigPayload);
             setRecording(false);
+            setError(null);
         });
+        // a recorded chord that's already bound, or conflicting bindings in the loaded config
+        const offError = EventsOn("config:error", (message: string) => {
+            setError(message);
+            setRecording(false);
+        });
+        return () => {
+            offUpdate();
+            offError();
+        };
     }, []);
 
-    const armHotkey = async (command: Command) => {
-        const reply = await Dispatch(command, null);
+    const armHotkey = async (command: Command, payload: string | null = null) => {
+        const reply = await Dispatch(command, payload);
         if (reply.code == RECORDING_ARMED) {
             console.log("backend confirms recording is armed");
             setRecording(true);
         }
     };
 
-    const getHotkeyName = (ki: KeyInfo): string => {
+    const getHotkeyName = (ki: KeyInfo | undefined): string => {
         console.log(ki);
+        if (!ki) {
+            return "No Hotkey Assigned";
+        }
         let ret =
             (ki.modifiers !== null && ki.modifiers.length > 0 && ki.modifier_locale_names.join(" + ") + " + ") || "";
+        if (ki.input && ki.locale_name) {
+            ret += "Controller ";
+        }
         ret += (ki.locale_name && ki.locale_name) || "";
         if (ret === "") {
             return "No Hotkey Assigned";
@@ -44,6 +62,63 @@ export default function Config({ configPayload }: ConfigParams) {
         }
     };
 
+    // mirrors gesture.Gesture.String and its defaults
+    const describeGesture = (g: Gesture | undefined): string => {
+        switch (g?.kind) {
+            case "hold":
+                return `hold ${g.hold_ms || 500}ms`;
+            case "double_tap":
+                return `double tap within ${g.window_ms || 400}ms`;
+            case "sequence":
+                return `after ${getHotkeyName(g.leader)} within ${g.window_ms || 1000}ms`;
+            default:
+                return "";
+        }
+    };
+
+    const unbind = (command: Command, index: number) => {
+        Dispatch(Command.UNBIND, JSON.stringify({ command: command, index: index }));
+    };
+
+    // saved along with the rest of the config on submit
+    const setPolicy = (command: Command, policy: HotkeyPolicy) => {
+        setConfig({ ...config, hotkey_policies: { ...config.hotkey_policies, [command]: policy } });
+    };
+
+    const displayPolicy = (command: Command) => (
+        <select
+            className="hotkeyPolicy"
+            disabled={recording}
+            value={config.hotkey_policies?.[command] || ""}
+            onChange={(e) => setPolicy(command, e.target.value as HotkeyPolicy)}
+        >
+            <option value="">Default</option>
+            <option value="global">Global</option>
+            <option value="focused">Focused Only</option>
+            <option value="disabled">Disabled</option>
+        </select>
+    );
+
+    const displayBindings = (command: Command) => {
+        const bindings = config.key_config[command] || [];
+        if (bindings.length === 0) {
+            return <p className="hotkeyValue">No Hotkey Assigned</p>;
+        }
+
+        return (
+            <div className="hotkeyValue">
+                {bindings.map((ki, index) => (
+                    <span className="hotkeyBinding" key={index}>
+                        {getHotkeyName(ki)}
+                        <button disabled={recording} onClick={() => unbind(command, index)}>
+                            ×
+                        </button>
+                    </span>
+                ))}
+            </div>
+        );
+    };
+
     const displayHotkeyRows = () => {
         const commands: [Command, string][] = [
             [Command.SPLIT, "Split"],
@@ -51,14 +126,207 @@ export default function Config({ configPayload }: ConfigParams) {
             [Command.SKIP, "Skip Split"],
             [Command.PAUSE, "Pause Run"],
             [Command.RESET, "Reset Run"],
+            [Command.SUCCESS, "Practice Trick Hit"],
+            [Command.FAIL, "Practice Trick Missed"],
+            [Command.LOCK, "Lock Hotkeys"],
         ];
 
         return commands.map((command: [Command, string]) => (
             <div className="row" key={command[0]}>
                 <div className="hotkeyContainer">
-                    <p className="hotkeyID">{command[1]}: </p>
-                    <p className="hotkeyValue">{getHotkeyName(config.key_config[command[0]])}</p>
+                    <p className="hotkeyID">
+                        {command[1]}:{" "}
+                        <span className="hotkeyGesture">{describeGesture(config.gestures?.[command[0]])}</span>
+                    </p>
+                    {displayBindings(command[0])}
+                    {displayPolicy(command[0])}
                     <button disabled={recording} onClick={() => armHotkey(command[0])}>
+                        {(recording && "Recording") || "Add Hotkey"}
+                    </button>
+                </div>
+            </div>
+        ));
+    };
+
+    // a cleared or invalid field turns the guard off
+    const setDebounce = (command: Command, value: string) => {
+        const ms = Math.max(0, Math.round(Number(value) || 0));
+        setConfig({
+            ...config,
+            guards: { ...config.guards, debounce_ms: { ...config.guards?.debounce_ms, [command]: ms } },
+        });
+    };
+
+    const setResetConfirmAfter = (value: string) => {
+        const seconds = Math.max(0, Number(value) || 0);
+        setConfig({ ...config, guards: { ...config.guards, reset_confirm_after_ms: Math.round(seconds * 1000) } });
+    };
+
+    const displayGuardRows = () => {
+        const commands: [Command, string][] = [
+            [Command.SPLIT, "Split"],
+            [Command.UNDO, "Undo Split"],
+            [Command.SKIP, "Skip Split"],
+            [Command.PAUSE, "Pause Run"],
+            [Command.RESET, "Reset Run"],
+        ];
+        const resetConfirmAfter = (config.guards?.reset_confirm_after_ms || 0) / 1000;
+
+        return (
+            <>
+                <div className="row">
+                    <div className="hotkeyContainer">
+                        <p className="hotkeyID">Confirm Reset After: </p>
+                        <p className="hotkeyValue">seconds into a run, 0 never asks</p>
+                        <input
+                            className="guardValue"
+                            type="number"
+                            min={0}
+                            value={resetConfirmAfter || ""}
+                            onChange={(e) => setResetConfirmAfter(e.target.value)}
+                        />
+                    </div>
+                </div>
+                {commands.map((command: [Command, string]) => (
+                    <div className="row" key={command[0]}>
+                        <div className="hotkeyContainer">
+                            <p className="hotkeyID">{command[1]}: </p>
+                            <p className="hotkeyValue">ignore repeats within milliseconds</p>
+                            <input
+                                className="guardValue"
+                                type="number"
+                                min={0}
+                                value={config.guards?.debounce_ms?.[command[0]] || ""}
+                                onChange={(e) => setDebounce(command[0], e.target.value)}
+                            />
+                        </div>
+                    </div>
+                ))}
+            </>
+        );
+    };
+
+    // saved along with the rest of the config on submit, templates are only edited in os-config.json
+    const setTextOutput = (changes: Partial<TextOutputConfig>) => {
+        setConfig({ ...config, text_output: { ...config.text_output, ...changes } });
+    };
+
+    const displayTextOutputRows = () => (
+        <>
+            <div className="row">
+                <div className="hotkeyContainer">
+                    <p className="hotkeyID">Write Files: </p>
+                    <p className="hotkeyValue">for streaming software "read from file" sources</p>
+                    <input
+                        type="checkbox"
+                        checked={config.text_output?.enabled || false}
+                        onChange={(e) => setTextOutput({ enabled: e.target.checked })}
+                    />
+                </div>
+            </div>
+            <div className="row">
+                <div className="hotkeyContainer">
+                    <p className="hotkeyID">Folder: </p>
+                    <input
+                        className="textOutputDirectory"
+                        placeholder="Text Output folder beside the config"
+                        value={config.text_output?.directory || ""}
+                        onChange={(e) => setTextOutput({ directory: e.target.value })}
+                    />
+                </div>
+            </div>
+            <div className="row">
+                <div className="hotkeyContainer">
+                    <p className="hotkeyID">Write Every: </p>
+                    <p className="hotkeyValue">milliseconds, file formats are set in os-config.json</p>
+                    <input
+                        className="guardValue"
+                        type="number"
+                        min={0}
+                        value={config.text_output?.write_interval_ms || ""}
+                        onChange={(e) =>
+                            setTextOutput({ write_interval_ms: Math.max(0, Math.round(Number(e.target.value) || 0)) })
+                        }
+                    />
+                </div>
+            </div>
+        </>
+    );
+
+    // hotkeys, policies and guards are edited for the active profile, PROFILE adds a name that isn't stored yet
+    const useProfile = async (name: string) => {
+        const reply = await Dispatch(Command.PROFILE, name);
+        if (reply.code != 0) {
+            setError(reply.message);
+            return;
+        }
+        setNewProfile("");
+    };
+
+    const displayProfileRow = () => (
+        <div className="row">
+            <div className="hotkeyContainer">
+                <p className="hotkeyID">Editing: </p>
+                <select
+                    className="profileSelect"
+                    disabled={recording}
+                    value={config.active_profile || ""}
+                    onChange={(e) => useProfile(e.target.value)}
+                >
+                    <option value="">Default Settings</option>
+                    {(config.profile_names || []).map((name) => (
+                        <option key={name} value={name}>
+                            {name}
+                        </option>
+                    ))}
+                </select>
+                <input
+                    className="profileName"
+                    placeholder="New profile name"
+                    value={newProfile}
+                    onChange={(e) => setNewProfile(e.target.value)}
+                />
+                <button disabled={recording || newProfile.trim() === ""} onClick={() => useProfile(newProfile.trim())}>
+                    Add Profile
+                </button>
+            </div>
+        </div>
+    );
+
+    const displayLoadProblems = () => {
+        const problems = config.load_problems || [];
+        if (problems.length === 0) {
+            return null;
+        }
+
+        return (
+            <>
+                <h3>Config Problems</h3>
+                <p className="configError">
+                    These settings couldn't be loaded and were dropped or reset. The original file is kept as
+                    os-config.backup.json, saving replaces os-config.json with the settings below.
+                </p>
+                <ul className="loadProblems">
+                    {problems.map((problem, index) => (
+                        <li key={index}>{problem}</li>
+                    ))}
+                </ul>
+            </>
+        );
+    };
+
+    const displayPinnedRows = () => {
+        const pinned = config.pinned_split_files || [];
+        if (pinned.length === 0) {
+            return <p>Pin a split file from the splitter's menu to switch to it with a hotkey.</p>;
+        }
+
+        return pinned.map((p, index) => (
+            <div className="row" key={p.path}>
+                <div className="hotkeyContainer">
+                    <p className="hotkeyID">{p.name}: </p>
+                    <p className="hotkeyValue">{getHotkeyName(p.key)}</p>
+                    <button disabled={recording} onClick={() => armHotkey(Command.SWITCH, index.toString())}>
                         {(recording && "Recording") || "Record Hotkey"}
                     </button>
                 </div>
@@ -70,8 +338,18 @@ export default function Config({ configPayload }: ConfigParams) {
         <div className="container form-container">
             <h2>OpenSplit Configuration</h2>
             <div className="options">
+                {displayLoadProblems()}
+                <h3>Profile</h3>
+                {displayProfileRow()}
                 <h3>Hotkeys</h3>
+                {error && <p className="configError">{error}</p>}
                 {displayHotkeyRows()}
+                <h3>Safety Guards</h3>
+                {displayGuardRows()}
+                <h3>Text Outputs</h3>
+                {displayTextOutputRows()}
+                <h3>Pinned Split Files</h3>
+                {displayPinnedRows()}
             </div>
             <div className="actions">
                 <button onClick={() => Dispatch(Command.SUBMIT, JSON.stringify(config))}>Save</button>
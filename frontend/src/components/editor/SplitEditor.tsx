@@ -159,6 +159,7 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
     const [segments, setSegments] = useState<SegmentPayload[]>(splitFilePayload?.segments ?? []);
     const [offsetMS, setOffsetMS] = React.useState(0);
     const [autosplitterFile, setAutosplitterFile] = React.useState<string>(splitFilePayload?.autosplitter_file ?? "");
+    const [profile, setProfile] = React.useState<string>(splitFilePayload?.profile ?? "");
 
     // Speedrun search
     const [gameResults, setGameResults] = React.useState<Game[]>([]);
@@ -275,23 +276,23 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
             sob: splitFilePayload?.sob ?? 0,
             offset: offsetMS,
             autosplitter_file: autosplitterFile,
+            profile: profile.trim(),
+            practice_attempts: splitFilePayload?.practice_attempts ?? 0,
+            practice_runs: splitFilePayload?.practice_runs ?? [],
+            practice_log: splitFilePayload?.practice_log ?? [],
         });
 
         const payload = JSON.stringify(newSplitFilePayload);
         await Dispatch(Command.SUBMIT, payload);
     };
 
-    const handleTimeChange = (id: string, time: TimeParts, isBest: boolean) => {
+    const handleTimeChange = (id: string, time: TimeParts, field: "average" | "pb" | "min_duration") => {
         const ms = partsToMS(time);
 
         function updateRecursive(list: SegmentPayload[]): SegmentPayload[] {
             return list.map((seg) => {
                 if (seg.id === id) {
-                    return new SegmentPayload({
-                        ...seg,
-                        pb: isBest ? ms : seg.pb,
-                        average: isBest ? seg.average : ms,
-                    });
+                    return new SegmentPayload({ ...seg, [field]: ms });
                 }
 
                 if ((seg.children ?? []).length > 0) {
@@ -482,7 +483,7 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
                                 <TimeRow
                                     id={segment.id}
                                     time={segment.average ? msToParts(segment.average) : null}
-                                    onChangeCallback={(id, ts) => handleTimeChange(id, ts, false)}
+                                    onChangeCallback={(id, ts) => handleTimeChange(id, ts, "average")}
                                 />
                             )}
                         </td>
@@ -492,7 +493,17 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
                                 <TimeRow
                                     id={segment.id}
                                     time={segment.pb ? msToParts(segment.pb) : null}
-                                    onChangeCallback={(id, ts) => handleTimeChange(id, ts, true)}
+                                    onChangeCallback={(id, ts) => handleTimeChange(id, ts, "pb")}
+                                />
+                            )}
+                        </td>
+
+                        <td>
+                            {!hasChildren && (
+                                <TimeRow
+                                    id={segment.id}
+                                    time={segment.min_duration ? msToParts(segment.min_duration) : null}
+                                    onChangeCallback={(id, ts) => handleTimeChange(id, ts, "min_duration")}
                                 />
                             )}
                         </td>
@@ -587,6 +598,20 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
                     />
                 </div>
 
+                {splitFilePayload && Object.keys(splitFilePayload.split_sources ?? {}).length > 0 && (
+                    <div className="row">
+                        <label htmlFor="split_sources">Splits by Source</label>
+                        <input
+                            id="split_sources"
+                            name="split_sources"
+                            readOnly
+                            value={Object.entries(splitFilePayload.split_sources)
+                                .map(([source, count]) => `${source}: ${count}`)
+                                .join(", ")}
+                        />
+                    </div>
+                )}
+
                 <div className="row">
                     <label htmlFor="offset">Negative Start Offset (milliseconds)</label>
                     <input
@@ -603,6 +628,18 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
                     <FilePicker fileName={autosplitterFile} setFilename={setAutosplitterFile} />
                 </div>
 
+                <div className="row">
+                    <label htmlFor="profile">Config Profile (blank for the default settings)</label>
+                    <input
+                        onChange={(e) => setProfile(e.target.value)}
+                        id="profile"
+                        name="profile"
+                        type="text"
+                        autoComplete="off"
+                        value={profile}
+                    />
+                </div>
+
                 <div style={{ marginTop: 20, marginBottom: 20 }} className="row">
                     <div>
                         <button onClick={() => addSegment(null)} type="button">
@@ -618,13 +655,16 @@ export default function SplitEditor({ splitFilePayload, speedRunAPIBase }: Split
                                 <thead>
                                     <tr>
                                         <th style={{ width: "5%" }}>#</th>
-                                        <th style={{ width: "50%" }}>Segment Name</th>
+                                        <th style={{ width: "40%" }}>Segment Name</th>
                                         <th>
                                             Average Time <small>(HH:MM:SS.ccc)</small>
                                         </th>
                                         <th>
                                             Personal Best <small>(HH:MM:SS.ccc)</small>
                                         </th>
+                                        <th>
+                                            Minimum Time <small>(HH:MM:SS.ccc)</small>
+                                        </th>
                                         <th style={{ width: "5%" }}>Add Subsegment</th>
                                         <th style={{ width: "5%" }}></th>
                                     </tr>
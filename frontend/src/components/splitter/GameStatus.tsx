@@ -0,0 +1,22 @@
+import GameStatusPayload from "../../models/gameStatusPayload";
+import { displayFormattedTimeParts, formatDuration, msToParts } from "./Timer";
+
+type GameStatusParams = {
+    status: GameStatusPayload | null;
+};
+
+// GameStatus shows the autosplitter's load state and in game time, the timer itself is never paused for loads
+export default function GameStatus({ status }: GameStatusParams) {
+    if (!status) return null;
+
+    const parts: string[] = [];
+    if (status.game_time >= 0) {
+        parts.push("Game time " + displayFormattedTimeParts(formatDuration(msToParts(status.game_time)))[0]);
+    }
+    if (status.loading) {
+        parts.push("Loading");
+    }
+    if (parts.length === 0) return null;
+
+    return <p className="gameStatus">{parts.join(" · ")}</p>;
+}
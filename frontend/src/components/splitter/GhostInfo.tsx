@@ -0,0 +1,39 @@
+import { useEffect, useState } from "react";
+
+import { EventsOn } from "../../../wailsjs/runtime";
+import GhostPayload from "../../models/ghostPayload";
+import SegmentPayload from "../../models/segmentPayload";
+
+type GhostInfoParams = {
+    leafSegments: SegmentPayload[] | null;
+    label: string;
+};
+
+// GhostInfo shows where the ghost run was at the current time
+export default function GhostInfo({ leafSegments, label }: GhostInfoParams) {
+    const [ghost, setGhost] = useState<GhostPayload | null>(null);
+
+    useEffect(() => {
+        return EventsOn("ghost:update", (payload: GhostPayload | null) => {
+            setGhost(payload);
+        });
+    }, []);
+
+    if (!ghost) return null;
+
+    let position = "";
+    if (ghost.finished) {
+        position = "Finished";
+    } else if (ghost.stopped) {
+        position = "Reset";
+    } else {
+        const name = leafSegments?.find((s) => s.id === ghost.segment_id)?.name ?? "";
+        position = `${name} ${Math.floor(ghost.progress * 100)}%`;
+    }
+
+    return (
+        <p className="ghostInfo">
+            {label}: {position}
+        </p>
+    );
+}
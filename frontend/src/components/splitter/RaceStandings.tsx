@@ -0,0 +1,66 @@
+import { useEffect, useState } from "react";
+
+import RaceStandingsPayload, { RaceStandingPayload } from "../../models/raceStandingsPayload";
+import { displayFormattedTimeParts, formatDuration, msToParts } from "./Timer";
+
+type RaceStandingsParams = {
+    standings: RaceStandingsPayload;
+};
+
+const formatTime = (ms: number, showSign: boolean = false) => {
+    const t = displayFormattedTimeParts(formatDuration(msToParts(ms), showSign));
+    return t[0] + t[1];
+};
+
+// progress is where a racer is in the race, their time against yours is shown alongside it
+const progress = (racer: RaceStandingPayload, status: RaceStandingsPayload["status"]) => {
+    if (racer.forfeited) return "Forfeit";
+    if (racer.finished) return formatTime(racer.time);
+    if (racer.segment_index >= 0) return racer.segment_name + " " + formatTime(racer.time);
+    if (status === "open") return racer.ready ? "Ready" : "Not Ready";
+    return "";
+};
+
+export default function RaceStandings({ standings }: RaceStandingsParams) {
+    const [now, setNow] = useState(Date.now());
+
+    // tick only while counting down
+    useEffect(() => {
+        if (!standings.countdown_ends_at) return;
+        const interval = setInterval(() => setNow(Date.now()), 100);
+        return () => clearInterval(interval);
+    }, [standings.countdown_ends_at]);
+
+    if (!standings.status) {
+        if (standings.address) return <p className="raceStatus">Joining race at {standings.address}…</p>;
+        if (standings.error) return <p className="raceStatus">Race: {standings.error}</p>;
+        return null;
+    }
+
+    const countdown = standings.countdown_ends_at ? Math.max(0, standings.countdown_ends_at - now) : 0;
+
+    return (
+        <div className="raceStandings">
+            {countdown > 0 && <p className="raceCountdown">Starting in {Math.ceil(countdown / 1000)}</p>}
+            {standings.status === "finished" && <p className="raceCountdown">Race finished</p>}
+            <table>
+                <tbody>
+                    {standings.racers.map((racer, i) => (
+                        <tr key={racer.name} className={racer.you ? "selected" : ""}>
+                            <td>{i + 1}</td>
+                            <td>{racer.name}</td>
+                            <td>{progress(racer, standings.status)}</td>
+                            <td>
+                                {!racer.you && racer.delta !== null && (
+                                    <span className={racer.delta > 0 ? "timer-behind" : "timer-ahead"}>
+                                        {formatTime(racer.delta, true)}
+                                    </span>
+                                )}
+                            </td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+        </div>
+    );
+}
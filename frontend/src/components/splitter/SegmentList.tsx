@@ -167,7 +167,10 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
         let cumulative = 0;
         const results: Targets = { cumulative: {}, individual: {} };
 
-        sessionPayload.leaf_segments?.forEach((segment) => {
+        // practice attempts are timed from the first practiced segment
+        const firstIndex = sessionPayload.practice?.start_index ?? 0;
+        sessionPayload.leaf_segments?.forEach((segment, idx) => {
+            if (idx < firstIndex) return;
             if (segment.average !== 0) {
                 switch (comparison) {
                     case CompareAgainst.Average:
@@ -183,7 +186,7 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
         });
 
         return results;
-    }, [comparison, sessionPayload.leaf_segments]);
+    }, [comparison, sessionPayload.leaf_segments, sessionPayload.practice?.start_index]);
 
     const flatSegments = useMemo<FlatSegment[]>(() => {
         if (!sessionPayload.loaded_split_file) return [];
@@ -204,6 +207,18 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
         return leaves[leaves.length - 1].id;
     }, [sessionPayload.leaf_segments]);
 
+    // while practice is idle, highlight the segment the next attempt starts at
+    const activeIndex =
+        sessionPayload.practice && sessionPayload.current_run === null
+            ? sessionPayload.practice.start_index
+            : sessionPayload.current_segment_index;
+
+    const activeLeafId = sessionPayload.leaf_segments?.[activeIndex]?.id;
+    const practiceStat =
+        sessionPayload.practice && activeLeafId
+            ? sessionPayload.loaded_split_file?.practice_stats?.[activeLeafId]
+            : undefined;
+
     const { mainRows, finalRow } = useMemo(() => {
         const main: JSX.Element[] = [];
         let final: JSX.Element | null = null;
@@ -229,7 +244,7 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
                 continue;
             }
 
-            const isSelected = leafIndex === sessionPayload.current_segment_index;
+            const isSelected = leafIndex === activeIndex;
 
             const cTarget = targets.cumulative[segmentData.Segment.id];
             const iTarget = targets.individual[segmentData.Segment.id];
@@ -260,7 +275,7 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
     }, [
         sessionPayload.loaded_split_file,
         sessionPayload.leaf_segments,
-        sessionPayload.current_segment_index,
+        activeIndex,
         sessionPayload.current_run?.splits,
         flatSegments,
         leafIndexById,
@@ -277,6 +292,20 @@ export default function SegmentList({ sessionPayload, comparison }: SplitListPar
                 <h2 className="gameCategory">
                     <small>{sessionPayload.loaded_split_file?.game_category}</small>
                 </h2>
+                {sessionPayload.practice && (
+                    <p className="practiceInfo">
+                        Practice: {sessionPayload.leaf_segments?.[sessionPayload.practice.start_index]?.name} -{" "}
+                        {sessionPayload.leaf_segments?.[sessionPayload.practice.end_index]?.name}
+                        {practiceStat && (
+                            <>
+                                <br />
+                                Hit {practiceStat.successes}/{practiceStat.successes + practiceStat.failures},{" "}
+                                {Math.round(practiceStat.rolling_success_rate * 100)}% of the last{" "}
+                                {practiceStat.rolling_attempts}
+                            </>
+                        )}
+                    </p>
+                )}
             </div>
 
             <div className="splitBody">
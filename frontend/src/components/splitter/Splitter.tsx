@@ -1,12 +1,16 @@
 import React, { useEffect } from "react";
 
 import { Dispatch } from "../../../wailsjs/go/dispatcher/Service";
-import { WindowSetPosition, WindowSetSize } from "../../../wailsjs/runtime";
+import { EventsOn, WindowSetPosition, WindowSetSize } from "../../../wailsjs/runtime";
 import { Command } from "../../App";
 import { MenuItem, useContextMenu } from "../../hooks/useContextMenu";
 import { ConfigPayload } from "../../models/configPayload";
+import RaceStandingsPayload from "../../models/raceStandingsPayload";
 import SessionPayload from "../../models/sessionPayload";
 import { ContextMenu } from "../ContextMenu";
+import GameStatus from "./GameStatus";
+import GhostInfo from "./GhostInfo";
+import RaceStandings from "./RaceStandings";
 import SegmentList from "./SegmentList";
 import Timer from "./Timer";
 
@@ -20,19 +24,76 @@ export type Comparison = CompareAgainst.Best | CompareAgainst.Average;
 type SplitterParams = {
     sessionPayload: SessionPayload;
     configPayload: ConfigPayload;
+    validCommands: string[] | null;
+    hotkeysError: string;
 };
 
-export default function Splitter({ sessionPayload, configPayload }: SplitterParams) {
+export default function Splitter({ sessionPayload, configPayload, validCommands, hotkeysError }: SplitterParams) {
     const contextMenu = useContextMenu();
     const [contextMenuItems, setContextMenuItems] = React.useState<MenuItem[]>([]);
     const [comparison, setComparison] = React.useState<Comparison>(CompareAgainst.Average);
     const [globalHotkeys, setGlobalHotkeys] = React.useState<boolean>(configPayload.global_hotkeys_active);
+    const [hotkeysLocked, setHotkeysLocked] = React.useState<boolean>(false);
+    const [activeProfile, setActiveProfile] = React.useState<string>(configPayload.active_profile || "");
+    const [guardMessage, setGuardMessage] = React.useState<string | null>(null);
+    const [raceStandings, setRaceStandings] = React.useState<RaceStandingsPayload>(new RaceStandingsPayload());
+
+    useEffect(() => {
+        return EventsOn("race:update", (standings: RaceStandingsPayload) => {
+            setRaceStandings(standings);
+        });
+    }, []);
+
+    // the lock can also be toggled by its hotkey
+    useEffect(() => {
+        return EventsOn("hotkeys:lock", (locked: boolean) => {
+            setHotkeysLocked(locked);
+        });
+    }, []);
+
+    // loading a split file or PROFILE can change the profile in use
+    useEffect(() => {
+        return EventsOn("config:update", (config: ConfigPayload) => {
+            setActiveProfile(config.active_profile || "");
+        });
+    }, []);
+
+    // why a hotkey was ignored by a safety guard, shown for a few seconds
+    useEffect(() => {
+        let clear: ReturnType<typeof setTimeout> | undefined;
+        const off = EventsOn("session:guard", (reason: string) => {
+            setGuardMessage(reason);
+            clearTimeout(clear);
+            clear = setTimeout(() => setGuardMessage(null), 3000);
+        });
+        return () => {
+            off();
+            clearTimeout(clear);
+        };
+    }, []);
 
     useEffect(() => {
         (async () => {
             setContextMenuItems(await buildContextMenu());
         })();
-    }, [globalHotkeys]);
+    }, [
+        globalHotkeys,
+        hotkeysLocked,
+        activeProfile,
+        sessionPayload.practice !== null,
+        sessionPayload.loaded_split_file?.id,
+        configPayload.pinned_split_files,
+        configPayload.profile_names,
+        validCommands,
+        sessionPayload.ghost_run_id,
+        sessionPayload.replay_run_id,
+        sessionPayload.loaded_split_file?.runs?.length,
+        raceStandings.status,
+        raceStandings.racers.find((r) => r.you)?.ready,
+    ]);
+
+    // only offer commands the backend accepts in its current state
+    const isValid = (command: Command) => validCommands === null || validCommands.includes(Command[command]);
 
     useEffect(() => {
         (async () => {
@@ -60,12 +121,28 @@ export default function Splitter({ sessionPayload, configPayload }: SplitterPara
             },
         });
 
-        contextMenuItems.push({
-            label: "Edit Split File",
-            onClick: async () => {
-                await Dispatch(Command.EDIT, null);
-            },
-        });
+        if (isValid(Command.LOCK)) {
+            contextMenuItems.push({
+                label: (hotkeysLocked ? "✓ " : "") + "Lock Hotkeys",
+                onClick: async () => {
+                    Dispatch(Command.LOCK, null).then((r) => {
+                        if (r.code == 0) {
+                            setHotkeysLocked(r.message === "true");
+                        }
+                    });
+                },
+            });
+        }
+
+        // a marathon's segments come from its games' split files
+        if (isValid(Command.EDIT) && !sessionPayload.loaded_split_file?.marathon?.length) {
+            contextMenuItems.push({
+                label: "Edit Split File",
+                onClick: async () => {
+                    await Dispatch(Command.EDIT, null);
+                },
+            });
+        }
 
         contextMenuItems.push({
             label: "Save",
@@ -76,6 +153,139 @@ export default function Splitter({ sessionPayload, configPayload }: SplitterPara
 
         contextMenuItems.push({ type: "separator" });
 
+        if (sessionPayload.practice) {
+            contextMenuItems.push({
+                label: "Stop Practice",
+                onClick: async () => {
+                    await Dispatch(Command.PRACTICE, null);
+                },
+            });
+        } else if (isValid(Command.PRACTICE)) {
+            contextMenuItems.push({
+                label: "Practice",
+                onClick: async () => {
+                    await Dispatch(Command.PRACTICE, null);
+                },
+            });
+
+            contextMenuItems.push({
+                label: "Practice (Update Golds)",
+                onClick: async () => {
+                    await Dispatch(
+                        Command.PRACTICE,
+                        JSON.stringify({ start_index: 0, end_index: -1, update_golds: true }),
+                    );
+                },
+            });
+        }
+
+        contextMenuItems.push({ type: "separator" });
+
+        const loadedID = sessionPayload.loaded_split_file?.id;
+        const pinned = configPayload.pinned_split_files || [];
+        if (isValid(Command.PIN)) {
+            contextMenuItems.push({
+                label: (pinned.some((p) => p.id === loadedID) ? "✓ " : "") + "Pin Split File",
+                onClick: async () => {
+                    await Dispatch(Command.PIN, null);
+                },
+            });
+        }
+
+        if (isValid(Command.SWITCH)) {
+            pinned
+                .filter((p) => p.id !== loadedID)
+                .forEach((p) => {
+                    contextMenuItems.push({
+                        label: "Switch to " + p.name,
+                        onClick: async () => {
+                            await Dispatch(Command.SWITCH, p.id);
+                        },
+                    });
+                });
+        }
+
+        const profiles = configPayload.profile_names || [];
+        if (isValid(Command.PROFILE) && profiles.length > 0) {
+            contextMenuItems.push({ type: "separator" });
+            ["", ...profiles].forEach((name) => {
+                contextMenuItems.push({
+                    label: (activeProfile === name ? "✓ " : "") + "Profile: " + (name || "Default Settings"),
+                    onClick: async () => {
+                        await Dispatch(Command.PROFILE, name);
+                    },
+                });
+            });
+        }
+
+        contextMenuItems.push({ type: "separator" });
+
+        // the ghost and replay default to the PB, the last run is offered alongside it
+        const runs = sessionPayload.loaded_split_file?.runs || [];
+        const lastRun = runs.length > 0 ? runs[runs.length - 1] : null;
+        if (isValid(Command.GHOST) && runs.length > 0) {
+            contextMenuItems.push({
+                label: (sessionPayload.ghost_run_id === "" ? "✓ " : "") + "Ghost: PB",
+                onClick: async () => {
+                    await Dispatch(Command.GHOST, null);
+                },
+            });
+            if (lastRun) {
+                contextMenuItems.push({
+                    label: (sessionPayload.ghost_run_id === lastRun.id ? "✓ " : "") + "Ghost: Last Run",
+                    onClick: async () => {
+                        await Dispatch(Command.GHOST, lastRun.id);
+                    },
+                });
+            }
+        }
+
+        if (sessionPayload.replay_run_id) {
+            contextMenuItems.push({
+                label: "Stop Replay",
+                onClick: async () => {
+                    await Dispatch(Command.CANCEL, null);
+                },
+            });
+        } else if (isValid(Command.REPLAY) && lastRun) {
+            contextMenuItems.push({
+                label: "Replay PB",
+                onClick: async () => {
+                    await Dispatch(Command.REPLAY, null);
+                },
+            });
+            contextMenuItems.push({
+                label: "Replay Last Run",
+                onClick: async () => {
+                    await Dispatch(Command.REPLAY, lastRun.id);
+                },
+            });
+        }
+
+        contextMenuItems.push({ type: "separator" });
+
+        if (isValid(Command.RACE)) {
+            contextMenuItems.push({
+                label: raceStandings.status ? "Leave Race" : "Join Race",
+                onClick: async () => {
+                    await Dispatch(Command.RACE, null);
+                },
+            });
+        }
+
+        // readying up after a race finishes opens the next one
+        const ready = raceStandings.status === "open" && raceStandings.racers.some((r) => r.you && r.ready);
+        if (isValid(Command.READY) && raceStandings.status && raceStandings.status !== "running") {
+            contextMenuItems.push({
+                label: ready ? "Not Ready" : "Ready",
+                onClick: async () => {
+                    await Dispatch(Command.READY, ready ? "false" : null);
+                },
+            });
+        }
+
+        contextMenuItems.push({ type: "separator" });
+
         contextMenuItems.push({
             label: "Compare Against Average",
             onClick: () => {
@@ -111,6 +321,14 @@ export default function Splitter({ sessionPayload, configPayload }: SplitterPara
         <div {...contextMenu.bind} className="splitter">
             <ContextMenu state={contextMenu.state} close={contextMenu.close} items={contextMenuItems} />
             <SegmentList sessionPayload={sessionPayload} comparison={comparison} />
+            <GhostInfo
+                leafSegments={sessionPayload.leaf_segments}
+                label={sessionPayload.replay_run_id ? "Replaying, ghost" : "Ghost"}
+            />
+            <GameStatus status={sessionPayload.game_status} />
+            <RaceStandings standings={raceStandings} />
+            {hotkeysError && <div className="guardNotice">{hotkeysError}</div>}
+            {guardMessage && <div className="guardNotice">{guardMessage}</div>}
             <Timer offset={(sessionPayload.loaded_split_file?.offset || 0) * -1} />
         </div>
     );
@@ -5,10 +5,64 @@ export type KeyInfo = {
     locale_name: string;
     modifiers: string[];
     modifier_locale_names: string[];
+    // input is unset for keyboard keys
+    input?: "button" | "axis";
+    axis_direction?: number;
+};
+
+// Gesture is how a command's hotkeys have to be pressed, an empty kind sends the command on a press
+export type Gesture = {
+    kind: "" | "hold" | "double_tap" | "sequence";
+    hold_ms?: number;
+    window_ms?: number;
+    leader: KeyInfo;
+};
+
+// HotkeyPolicy is when a command's hotkeys are listened to, an empty policy follows global_hotkeys_active
+export type HotkeyPolicy = "" | "global" | "focused" | "disabled";
+
+// GuardConfig keeps stray inputs from ruining a run, durations are in milliseconds and 0 turns a guard off
+export type GuardConfig = {
+    debounce_ms: Partial<Record<Command, number>> | null;
+    reset_confirm_after_ms: number;
+};
+
+// TextOutputConfig controls the text files written for streaming software, templates are keyed by file name
+export type TextOutputConfig = {
+    enabled: boolean;
+    directory: string;
+    templates: Record<string, string> | null;
+    write_interval_ms: number;
+};
+
+export type PinnedSplitFile = {
+    id: string;
+    name: string;
+    path: string;
+    key: KeyInfo;
+};
+
+export type RaceConfig = {
+    address: string;
+    name: string;
 };
 
 export type ConfigPayload = {
+    // the config file's schema version
+    version: number;
     speed_run_API_base: string;
-    key_config: Record<Command, KeyInfo>;
+    // any of a command's hotkeys sends it
+    key_config: Record<Command, KeyInfo[]>;
+    gestures: Partial<Record<Command, Gesture>> | null;
     global_hotkeys_active: boolean;
+    hotkey_policies: Partial<Record<Command, HotkeyPolicy>> | null;
+    guards: GuardConfig;
+    text_output: TextOutputConfig;
+    pinned_split_files: PinnedSplitFile[] | null;
+    race: RaceConfig;
+    // the profile whose bindings, gestures, policies and guards are in use and edited, unset for the config's own
+    active_profile?: string;
+    profile_names?: string[];
+    // settings in the config file that couldn't be loaded and were dropped or reset, cleared once the config is saved
+    load_problems?: string[];
 };
@@ -0,0 +1,4 @@
+export default class GameStatusPayload {
+    loading: boolean = false;
+    game_time: number = -1;
+}
@@ -0,0 +1,8 @@
+export default class GhostPayload {
+    run_id: string = "";
+    segment_index: number = 0;
+    segment_id: string = "";
+    progress: number = 0;
+    finished: boolean = false;
+    stopped: boolean = false;
+}
@@ -0,0 +1,5 @@
+export default class MarathonGamePayload {
+    path: string = "";
+    split_file_id: string = "";
+    segment_id: string = "";
+}
@@ -0,0 +1,5 @@
+export default class PracticePayload {
+    start_index: number = 0;
+    end_index: number = -1;
+    update_golds: boolean = false;
+}
@@ -0,0 +1,7 @@
+export default class PracticeResultPayload {
+    segment_id: string = "";
+    success: boolean = false;
+    timestamp: number = 0;
+    source: string = "";
+    source_detail: string = "";
+}
@@ -0,0 +1,7 @@
+import RunPayload from "./runPayload";
+
+export default class PracticeRunPayload extends RunPayload {
+    start_segment_id: string = "";
+    end_segment_id: string = "";
+    update_golds: boolean = false;
+}
@@ -0,0 +1,6 @@
+export default class PracticeStatPayload {
+    successes: number = 0;
+    failures: number = 0;
+    rolling_success_rate: number = 0;
+    rolling_attempts: number = 0;
+}
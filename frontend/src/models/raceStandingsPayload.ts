@@ -0,0 +1,20 @@
+export class RaceStandingPayload {
+    name: string = "";
+    you: boolean = false;
+    ready: boolean = false;
+    finished: boolean = false;
+    forfeited: boolean = false;
+    segment_index: number = -1;
+    segment_name: string = "";
+    time: number = 0;
+    delta: number | null = null;
+}
+
+export default class RaceStandingsPayload {
+    address: string = "";
+    you: string = "";
+    status: "" | "open" | "running" | "finished" = "";
+    countdown_ends_at: number = 0;
+    racers: RaceStandingPayload[] = [];
+    error: string = "";
+}
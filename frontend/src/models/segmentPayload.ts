@@ -4,6 +4,8 @@ export default class SegmentPayload {
     gold: number = 0;
     average: number = 0;
     pb: number = 0;
+    // splits faster than this many milliseconds are ignored, 0 for no minimum
+    min_duration: number = 0;
     children: SegmentPayload[] = [];
 
     constructor(init?: Partial<SegmentPayload>) {
@@ -12,6 +14,7 @@ export default class SegmentPayload {
         this.gold = init?.gold ?? 0;
         this.average = init?.average ?? 0;
         this.pb = init?.pb ?? 0;
+        this.min_duration = init?.min_duration ?? 0;
         this.children = (init?.children ?? []).map((c) => new SegmentPayload(c));
     }
 }
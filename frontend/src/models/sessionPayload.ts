@@ -1,3 +1,5 @@
+import GameStatusPayload from "./gameStatusPayload";
+import PracticePayload from "./practicePayload";
 import RunPayload from "./runPayload";
 import SegmentPayload from "./segmentPayload";
 import SplitFilePayload from "./splitFilePayload";
@@ -8,4 +10,8 @@ export default class SessionPayload {
     current_run: RunPayload | null = null;
     current_segment_index: number = -1;
     dirty: boolean = false;
+    practice: PracticePayload | null = null;
+    ghost_run_id: string = "";
+    replay_run_id: string = "";
+    game_status: GameStatusPayload | null = null;
 }
@@ -1,3 +1,7 @@
+import MarathonGamePayload from "./marathonGamePayload";
+import PracticeResultPayload from "./practiceResultPayload";
+import PracticeRunPayload from "./practiceRunPayload";
+import PracticeStatPayload from "./practiceStatPayload";
 import RunPayload from "./runPayload";
 import SegmentPayload from "./segmentPayload";
 
@@ -17,6 +21,14 @@ export default class SplitFilePayload {
     pb: RunPayload | null = null;
     offset: number = 0;
     autosplitter_file: string = "";
+    split_sources: Record<string, number> = {};
+    practice_attempts: number = 0;
+    practice_runs: PracticeRunPayload[] = [];
+    practice_log: PracticeResultPayload[] = [];
+    practice_stats: Record<string, PracticeStatPayload> = {};
+    marathon?: MarathonGamePayload[];
+    // the config profile activated while the split file is loaded
+    profile?: string;
 
     constructor(init?: Partial<SplitFilePayload>) {
         if (init) {
@@ -2,4 +2,6 @@ export default class SplitPayload {
     split_segment_id: string = "";
     current_cumulative: number = 0;
     current_duration: number = 0;
+    source: string = "";
+    source_detail: string = "";
 }
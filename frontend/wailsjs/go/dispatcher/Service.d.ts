@@ -4,4 +4,6 @@ import {dispatcher} from '../models';
 
 export function Dispatch(arg1:dispatcher.Command,arg2:any):Promise<dispatcher.DispatchReply>;
 
+export function DispatchFrom(arg1:dispatcher.Source,arg2:dispatcher.Command,arg3:any):Promise<dispatcher.DispatchReply>;
+
 export function PickAutoSplitterFile():Promise<string>;
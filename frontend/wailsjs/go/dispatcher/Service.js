@@ -6,6 +6,10 @@ export function Dispatch(arg1, arg2) {
   return window['go']['dispatcher']['Service']['Dispatch'](arg1, arg2);
 }
 
+export function DispatchFrom(arg1, arg2, arg3) {
+  return window['go']['dispatcher']['Service']['DispatchFrom'](arg1, arg2, arg3);
+}
+
 export function PickAutoSplitterFile() {
   return window['go']['dispatcher']['Service']['PickAutoSplitterFile']();
 }
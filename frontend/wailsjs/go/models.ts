@@ -14,6 +14,20 @@ export namespace dispatcher {
 	        this.message = source["message"];
 	    }
 	}
+	export class Source {
+	    kind: string;
+	    detail: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Source(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.detail = source["detail"];
+	    }
+	}
 
 }
 
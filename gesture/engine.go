@@ -0,0 +1,207 @@
+package gesture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/keyinfo"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// Bindings is what the Engine needs from the hotkey config
+type Bindings interface {
+	// MatchCommand returns the command a chord is bound to
+	MatchCommand(key keyinfo.KeyData) (dispatcher.Command, bool)
+	// CommandGestures returns the gesture of every command that has one besides Press
+	CommandGestures() map[dispatcher.Command]Gesture
+}
+
+// physicalKey identifies a key regardless of the modifiers held, so a hold ends when its key is let go even if a
+// modifier was let go first
+type physicalKey struct {
+	input         keyinfo.Input
+	code          int
+	axisDirection int
+}
+
+func physical(key keyinfo.KeyData) physicalKey {
+	return physicalKey{input: key.Input, code: key.KeyCode, axisDirection: key.AxisDirection}
+}
+
+// hold is a key being held for a Hold gesture, id tells a stale timer from the current one
+type hold struct {
+	id    uint64
+	timer *time.Timer
+}
+
+// Engine turns hotkey presses and releases into commands according to each command's Gesture.
+//
+// Handle may be called from a provider's goroutine while hold timers fire on their own, so send may be called from
+// either; it is never called with the Engine locked.  send is given when the gesture completed, taken from the
+// KeyData's At, and zero when the provider didn't stamp the input.
+type Engine struct {
+	mu       sync.Mutex
+	bindings Bindings
+	send     func(dispatcher.Command, time.Time)
+	clock    func() time.Time
+
+	holds    map[physicalKey]hold
+	down     map[physicalKey]bool
+	nextHold uint64
+	lastTap  map[dispatcher.Command]time.Time
+	leader   keyinfo.KeyData
+	leaderAt time.Time
+}
+
+// NewEngine creates an Engine that calls send with each command whose gesture completes
+func NewEngine(bindings Bindings, send func(dispatcher.Command, time.Time)) *Engine {
+	return &Engine{
+		bindings: bindings,
+		send:     send,
+		clock:    time.Now,
+		holds:    map[physicalKey]hold{},
+		down:     map[physicalKey]bool{},
+		lastTap:  map[dispatcher.Command]time.Time{},
+	}
+}
+
+// SetClock replaces the clock used to time double taps and sequences, for tests
+func (e *Engine) SetClock(clock func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clock = clock
+}
+
+// Handle applies a press or release and reports whether key is bound to a command, so the caller can try other
+// bindings for keys that aren't.
+func (e *Engine) Handle(key keyinfo.KeyData) bool {
+	if key.Released {
+		e.release(key)
+		_, bound := e.bindings.MatchCommand(key)
+		return bound
+	}
+
+	gestures := e.bindings.CommandGestures()
+	command, bound := e.bindings.MatchCommand(key)
+
+	e.mu.Lock()
+	now := e.clock()
+	pk := physical(key)
+	// the OS repeats the press of a key held down, a press of a key that is already down is one of those
+	repeat := e.down[pk]
+	e.down[pk] = true
+	if e.followsLeaderLocked(command, bound, gestures, now) {
+		e.mu.Unlock()
+		logger.Debugf(logModule, "sequence completed for %v", command)
+		e.send(command, key.At)
+		return true
+	}
+	e.armLeaderLocked(key, gestures, now)
+	if !bound {
+		e.mu.Unlock()
+		return false
+	}
+
+	gesture := gestures[command]
+	switch gesture.Kind {
+	case Hold:
+		if _, held := e.holds[pk]; !held {
+			e.nextHold++
+			id := e.nextHold
+			// the hold completed a hold duration after the press, however late the timer fires
+			var at time.Time
+			if !key.At.IsZero() {
+				at = key.At.Add(gesture.HoldDuration())
+			}
+			e.holds[pk] = hold{id: id, timer: time.AfterFunc(gesture.HoldDuration(), func() {
+				e.fireHold(pk, id, command, at)
+			})}
+		}
+		e.mu.Unlock()
+	case DoubleTap:
+		// a key held down isn't tapped again until it is let go
+		if repeat {
+			e.mu.Unlock()
+			return true
+		}
+		last, tapped := e.lastTap[command]
+		if tapped && now.Sub(last) <= gesture.Window() {
+			delete(e.lastTap, command)
+			e.mu.Unlock()
+			e.send(command, key.At)
+			return true
+		}
+		e.lastTap[command] = now
+		e.mu.Unlock()
+	case Sequence:
+		// only sent when pressed after its leader
+		e.mu.Unlock()
+	default:
+		e.mu.Unlock()
+		e.send(command, key.At)
+	}
+	return true
+}
+
+// Reset cancels holds in progress and forgets taps and leaders, it is called when hotkeys are unhooked so a key let
+// go while unhooked doesn't leave a hold running.
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for pk, h := range e.holds {
+		h.timer.Stop()
+		delete(e.holds, pk)
+	}
+	clear(e.down)
+	clear(e.lastTap)
+	e.leader = keyinfo.KeyData{}
+}
+
+// release marks a key as let go and cancels its hold if the hold hadn't completed
+func (e *Engine) release(key keyinfo.KeyData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	pk := physical(key)
+	delete(e.down, pk)
+	if h, held := e.holds[pk]; held {
+		h.timer.Stop()
+		delete(e.holds, pk)
+	}
+}
+
+// fireHold sends command if id is still the hold of pk, i.e. the key wasn't let go in the meantime
+func (e *Engine) fireHold(pk physicalKey, id uint64, command dispatcher.Command, at time.Time) {
+	e.mu.Lock()
+	if h, held := e.holds[pk]; !held || h.id != id {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.holds, pk)
+	e.mu.Unlock()
+	logger.Debugf(logModule, "hold completed for %v", command)
+	e.send(command, at)
+}
+
+// followsLeaderLocked reports whether the press of command completes its Sequence.  Any press after a leader uses it
+// up.  It must be called under lock.
+func (e *Engine) followsLeaderLocked(command dispatcher.Command, bound bool, gestures map[dispatcher.Command]Gesture,
+	now time.Time) bool {
+	leader, at := e.leader, e.leaderAt
+	e.leader = keyinfo.KeyData{}
+	if !bound || !leader.Bound() {
+		return false
+	}
+	gesture, ok := gestures[command]
+	return ok && gesture.Kind == Sequence && gesture.Leader.Matches(leader) && now.Sub(at) <= gesture.Window()
+}
+
+// armLeaderLocked remembers key if it is the leader of a Sequence.  It must be called under lock.
+func (e *Engine) armLeaderLocked(key keyinfo.KeyData, gestures map[dispatcher.Command]Gesture, now time.Time) {
+	for _, gesture := range gestures {
+		if gesture.Kind == Sequence && gesture.Leader.Matches(key) {
+			e.leader, e.leaderAt = key, now
+			return
+		}
+	}
+}
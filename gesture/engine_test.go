@@ -0,0 +1,194 @@
+package gesture
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+var (
+	space  = keyinfo.NewKeyData(32, "Space", nil, nil)
+	r      = keyinfo.NewKeyData(82, "R", nil, nil)
+	ctrlR  = keyinfo.NewKeyData(82, "R", []int{0xA2}, []string{"Left Control"})
+	p      = keyinfo.NewKeyData(80, "P", nil, nil)
+	leader = keyinfo.NewKeyData(76, "L", nil, nil)
+)
+
+type fakeBindings struct {
+	keys     map[dispatcher.Command]keyinfo.KeyData
+	gestures map[dispatcher.Command]Gesture
+}
+
+func (f fakeBindings) MatchCommand(key keyinfo.KeyData) (dispatcher.Command, bool) {
+	for command, bound := range f.keys {
+		if bound.Matches(key) {
+			return command, true
+		}
+	}
+	return 0, false
+}
+
+func (f fakeBindings) CommandGestures() map[dispatcher.Command]Gesture {
+	return f.gestures
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	sent []dispatcher.Command
+	at   []time.Time
+}
+
+func (r *recorder) send(command dispatcher.Command, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, command)
+	r.at = append(r.at, at)
+}
+
+func (r *recorder) times() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time{}, r.at...)
+}
+
+func (r *recorder) commands() []dispatcher.Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]dispatcher.Command{}, r.sent...)
+}
+
+func newTestEngine(keys map[dispatcher.Command]keyinfo.KeyData, gestures map[dispatcher.Command]Gesture) (*Engine,
+	*recorder, *time.Time) {
+	rec := &recorder{}
+	e := NewEngine(fakeBindings{keys: keys, gestures: gestures}, rec.send)
+	now := time.Unix(1700000000, 0)
+	e.SetClock(func() time.Time { return now })
+	return e, rec, &now
+}
+
+func TestPress(t *testing.T) {
+	e, rec, _ := newTestEngine(map[dispatcher.Command]keyinfo.KeyData{dispatcher.SPLIT: space}, nil)
+	if !e.Handle(space) || !e.Handle(space.Release()) {
+		t.Fatalf("Handle() want Space reported as bound")
+	}
+	if e.Handle(r) {
+		t.Fatalf("Handle() reported an unbound key as bound")
+	}
+	if sent := rec.commands(); len(sent) != 1 || sent[0] != dispatcher.SPLIT {
+		t.Fatalf("want SPLIT sent once on the press, got %v", sent)
+	}
+
+	pressed := time.Unix(1700000000, 0)
+	e.Handle(space.Stamped(pressed))
+	if at := rec.times(); len(at) != 2 || !at[1].Equal(pressed) {
+		t.Fatalf("want SPLIT sent with the press's time, got %v", at)
+	}
+}
+
+func TestHold(t *testing.T) {
+	e, rec, _ := newTestEngine(map[dispatcher.Command]keyinfo.KeyData{dispatcher.RESET: ctrlR},
+		map[dispatcher.Command]Gesture{dispatcher.RESET: {Kind: Hold, HoldMS: 30}})
+
+	// let go too soon, with Control let go first so the release has no modifiers
+	e.Handle(ctrlR)
+	e.Handle(r.Release())
+	time.Sleep(60 * time.Millisecond)
+	if sent := rec.commands(); len(sent) != 0 {
+		t.Fatalf("a hold let go early sent %v", sent)
+	}
+
+	pressed := time.Unix(1700000000, 0)
+	e.Handle(ctrlR.Stamped(pressed))
+	deadline := time.Now().Add(time.Second)
+	for len(rec.commands()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	e.Handle(r.Release())
+	if sent := rec.commands(); len(sent) != 1 || sent[0] != dispatcher.RESET {
+		t.Fatalf("want RESET sent once after the hold, got %v", sent)
+	}
+	if at := rec.times(); !at[0].Equal(pressed.Add(30 * time.Millisecond)) {
+		t.Fatalf("want RESET sent with the time the hold completed, got %v", at[0])
+	}
+
+	e.Handle(ctrlR)
+	e.Reset()
+	time.Sleep(60 * time.Millisecond)
+	if sent := rec.commands(); len(sent) != 1 {
+		t.Fatalf("a hold in progress when the engine was reset sent %v", sent[1:])
+	}
+}
+
+func TestDoubleTap(t *testing.T) {
+	e, rec, now := newTestEngine(map[dispatcher.Command]keyinfo.KeyData{dispatcher.PAUSE: p},
+		map[dispatcher.Command]Gesture{dispatcher.PAUSE: {Kind: DoubleTap}})
+
+	tap := func() {
+		e.Handle(p)
+		e.Handle(p.Release())
+	}
+
+	tap()
+	*now = now.Add(DefaultDoubleTapWindow + time.Millisecond)
+	tap()
+	if sent := rec.commands(); len(sent) != 0 {
+		t.Fatalf("taps further apart than the window sent %v", sent)
+	}
+
+	// the OS repeating the press of a held key isn't a second tap
+	*now = now.Add(DefaultDoubleTapWindow + time.Millisecond)
+	e.Handle(p)
+	*now = now.Add(50 * time.Millisecond)
+	e.Handle(p)
+	e.Handle(p.Release())
+	if sent := rec.commands(); len(sent) != 0 {
+		t.Fatalf("an auto-repeated press sent %v", sent)
+	}
+
+	*now = now.Add(100 * time.Millisecond)
+	tap()
+	// a third tap starts over rather than pairing with the second
+	*now = now.Add(100 * time.Millisecond)
+	tap()
+	if sent := rec.commands(); len(sent) != 1 || sent[0] != dispatcher.PAUSE {
+		t.Fatalf("want PAUSE sent once for a double tap, got %v", sent)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	e, rec, now := newTestEngine(map[dispatcher.Command]keyinfo.KeyData{dispatcher.RESET: r, dispatcher.SPLIT: space},
+		map[dispatcher.Command]Gesture{dispatcher.RESET: {Kind: Sequence, Leader: leader, WindowMS: 500}})
+
+	e.Handle(r)
+	e.Handle(leader)
+	e.Handle(space)
+	e.Handle(r)
+	if sent := rec.commands(); len(sent) != 1 || sent[0] != dispatcher.SPLIT {
+		t.Fatalf("R alone or after another key want nothing sent, got %v", sent)
+	}
+
+	e.Handle(leader)
+	*now = now.Add(600 * time.Millisecond)
+	e.Handle(r)
+	e.Handle(leader)
+	*now = now.Add(100 * time.Millisecond)
+	e.Handle(r)
+	if sent := rec.commands(); len(sent) != 2 || sent[1] != dispatcher.RESET {
+		t.Fatalf("want RESET sent once for L then R within the window, got %v", sent)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Gesture{Kind: Sequence}).Validate(); err == nil {
+		t.Fatalf("Validate() of a sequence without a leader want error, got nil")
+	}
+	if err := (Gesture{Kind: "triple_tap"}).Validate(); err == nil {
+		t.Fatalf("Validate() of an unknown kind want error, got nil")
+	}
+	if s := (Gesture{Kind: Hold}).String(); s != "hold 500ms" {
+		t.Fatalf("String() want hold 500ms, got %q", s)
+	}
+}
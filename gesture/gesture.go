@@ -0,0 +1,97 @@
+// Package gesture decides when hotkey presses send their command.
+//
+// Most commands are sent the moment their hotkey is pressed, but a command can instead require its hotkey to be held,
+// double tapped, or pressed right after a leader key, so that commands like RESET are hard to send by accident.  The
+// Engine sits between a HotkeyProvider's callback and the state machine: it is handed every press and release and
+// calls back with the commands whose gesture completed.
+package gesture
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+const logModule = "gesture"
+
+// Kind is how a command's hotkey has to be pressed
+type Kind string
+
+const (
+	// Press sends the command as soon as the hotkey is pressed, it is the zero value
+	Press Kind = ""
+	// Hold sends the command once the hotkey has been held down for Gesture.HoldMS
+	Hold Kind = "hold"
+	// DoubleTap sends the command when the hotkey is pressed twice within Gesture.WindowMS
+	DoubleTap Kind = "double_tap"
+	// Sequence sends the command when the hotkey is pressed within Gesture.WindowMS of Gesture.Leader
+	Sequence Kind = "sequence"
+)
+
+// Defaults used when a Gesture leaves its timing unset
+const (
+	DefaultHold            = 500 * time.Millisecond
+	DefaultDoubleTapWindow = 400 * time.Millisecond
+	DefaultSequenceWindow  = time.Second
+)
+
+// Gesture is how the hotkeys bound to a command have to be pressed to send it
+type Gesture struct {
+	Kind     Kind `json:"kind"`
+	HoldMS   int  `json:"hold_ms,omitempty"`
+	WindowMS int  `json:"window_ms,omitempty"`
+	// Leader is the key pressed before the command's hotkey for a Sequence.  Any command it is bound to is still sent
+	// when it is pressed.
+	Leader keyinfo.KeyData `json:"leader"`
+}
+
+// HoldDuration is how long a Hold gesture's hotkey must be held
+func (g Gesture) HoldDuration() time.Duration {
+	if g.HoldMS <= 0 {
+		return DefaultHold
+	}
+	return time.Duration(g.HoldMS) * time.Millisecond
+}
+
+// Window is how soon the second press of a DoubleTap or Sequence must follow the first
+func (g Gesture) Window() time.Duration {
+	if g.WindowMS > 0 {
+		return time.Duration(g.WindowMS) * time.Millisecond
+	}
+	if g.Kind == Sequence {
+		return DefaultSequenceWindow
+	}
+	return DefaultDoubleTapWindow
+}
+
+// Validate reports a gesture that can never complete
+func (g Gesture) Validate() error {
+	switch g.Kind {
+	case Press, Hold, DoubleTap:
+	case Sequence:
+		if !g.Leader.Bound() {
+			return fmt.Errorf("sequence gesture has no leader key")
+		}
+	default:
+		return fmt.Errorf("unknown gesture kind %q", g.Kind)
+	}
+	if g.HoldMS < 0 || g.WindowMS < 0 {
+		return fmt.Errorf("%s gesture has a negative duration", g.Kind)
+	}
+	return nil
+}
+
+// String describes the gesture for logs and the config view, e.g. "hold 800ms"
+func (g Gesture) String() string {
+	switch g.Kind {
+	case Hold:
+		return fmt.Sprintf("hold %dms", g.HoldDuration().Milliseconds())
+	case DoubleTap:
+		return fmt.Sprintf("double tap within %dms", g.Window().Milliseconds())
+	case Sequence:
+		return fmt.Sprintf("after %s within %dms", g.Leader, g.Window().Milliseconds())
+	default:
+		return "press"
+	}
+}
@@ -5,7 +5,10 @@ go 1.25
 require (
 	github.com/google/uuid v1.6.0
 	github.com/wailsapp/wails/v2 v2.10.2
+	github.com/yuin/gopher-lua v1.1.2
 	golang.org/x/sys v0.40.0
+	golang.org/x/term v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
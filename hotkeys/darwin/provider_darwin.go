@@ -9,11 +9,13 @@ void hk_start(void);
 void hk_stop(void);
 int  hk_wait_next(unsigned short* out_keycode,
                   char*           out_name,
-                  unsigned long   out_name_cap);
+                  unsigned long   out_name_cap,
+                  int*            out_released);
 */
 import "C"
 import (
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/zellydev-games/opensplit/keyinfo"
@@ -41,11 +43,14 @@ func (m *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
 	go func() {
 		for {
 			var kc C.ushort
+			var released C.int
 			buf := make([]byte, 32)
-			ok := C.hk_wait_next(&kc, (*C.char)(unsafe.Pointer(&buf[0])), C.ulong(len(buf))) != 0
+			ok := C.hk_wait_next(&kc, (*C.char)(unsafe.Pointer(&buf[0])), C.ulong(len(buf)), &released) != 0
 			if !ok {
 				return
 			}
+			// the tap only queues events, so this is within a scheduling delay of the keypress
+			at := time.Now()
 			name := C.GoString((*C.char)(unsafe.Pointer(&buf[0])))
 			m.mu.Lock()
 			cb := m.callback
@@ -54,6 +59,8 @@ func (m *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
 				cb(keyinfo.KeyData{
 					KeyCode:    int(kc),
 					LocaleName: name,
+					Released:   released != 0,
+					At:         at,
 				})
 			}
 		}
@@ -7,7 +7,7 @@
 
 void hk_start(void);
 void hk_stop(void);
-int  hk_wait_next(uint16_t* out_keycode, char* out_name, size_t out_name_cap);
+int  hk_wait_next(uint16_t* out_keycode, char* out_name, size_t out_name_cap, int* out_released);
 
 static CFMachPortRef      gEventTap     = NULL;  // The tap itself
 static CFRunLoopSourceRef gRunLoopSrc   = NULL;  // RunLoop source wrapping the tap
@@ -18,9 +18,18 @@ static pthread_mutex_t gMu = PTHREAD_MUTEX_INITIALIZER;
 static pthread_cond_t  gCv = PTHREAD_COND_INITIALIZER;
 
 static bool         gRunning = false;   // tap/thread alive
-static bool         gHasMsg  = false;   // mailbox has an unread event
-static uint16_t     gKeycode = 0;       // mailbox fields
-static char         gName[32];
+
+// Mailbox of unread events.  It is a small queue rather than a single slot so a quick tap's release can't overwrite
+// its press before Go reads it; when it's full the oldest event is dropped.
+#define HK_QUEUE_CAP 16
+typedef struct {
+    uint16_t keycode;
+    bool     released;
+    char     name[32];
+} hk_event;
+static hk_event gQueue[HK_QUEUE_CAP];
+static int      gHead  = 0;  // index of the oldest unread event
+static int      gCount = 0;  // number of unread events
 
 static void CGEventKeyDisplayName(CGEventRef event, char *out, size_t cap) {
     if (!out || cap == 0 || !event) return;
@@ -97,13 +106,13 @@ static void CGEventKeyDisplayName(CGEventRef event, char *out, size_t cap) {
     snprintf(out, cap, "Keycode:%u", (unsigned)kc);
 }
 
-// Event tap callback: write the *latest* event into the mailbox and signal.
+// Event tap callback: queue key presses and releases in the mailbox and signal.
 static CGEventRef tapCallback(CGEventTapProxy proxy, CGEventType type, CGEventRef event, void *refcon) {
     if (type == kCGEventTapDisabledByTimeout || type == kCGEventTapDisabledByUserInput) {
         if (gEventTap) CGEventTapEnable(gEventTap, true);
         return event;
     }
-    if (type != kCGEventKeyDown) return event;
+    if (type != kCGEventKeyDown && type != kCGEventKeyUp) return event;
 
     int64_t isRepeat = CGEventGetIntegerValueField(event, kCGKeyboardEventAutorepeat);
     if (isRepeat) return event;
@@ -112,12 +121,18 @@ static CGEventRef tapCallback(CGEventTapProxy proxy, CGEventType type, CGEventRe
     CGEventKeyDisplayName(event, name, sizeof(name));
     uint16_t kc = (uint16_t)CGEventGetIntegerValueField(event, kCGKeyboardEventKeycode);
 
-    // Store into mailbox (overwrites any previous unread event).
     pthread_mutex_lock(&gMu);
-    gKeycode = kc;
-    strncpy(gName, name, sizeof(gName)-1);
-    gName[sizeof(gName)-1] = '\0';
-    gHasMsg = true;
+    if (gCount == HK_QUEUE_CAP) {
+        // drop the oldest unread event
+        gHead = (gHead + 1) % HK_QUEUE_CAP;
+        gCount--;
+    }
+    hk_event *slot = &gQueue[(gHead + gCount) % HK_QUEUE_CAP];
+    slot->keycode = kc;
+    slot->released = (type == kCGEventKeyUp);
+    strncpy(slot->name, name, sizeof(slot->name)-1);
+    slot->name[sizeof(slot->name)-1] = '\0';
+    gCount++;
     pthread_cond_signal(&gCv); // wake one waiter
     pthread_mutex_unlock(&gMu);
 
@@ -129,7 +144,7 @@ static void* tapThread(void* _) {
     gRunning = true;
     pthread_mutex_unlock(&gMu);
 
-    CGEventMask mask = (CGEventMaskBit(kCGEventKeyDown));
+    CGEventMask mask = (CGEventMaskBit(kCGEventKeyDown) | CGEventMaskBit(kCGEventKeyUp));
     gEventTap = CGEventTapCreate(kCGSessionEventTap, kCGHeadInsertEventTap,
                                  kCGEventTapOptionListenOnly, mask, tapCallback, NULL);
     if (!gEventTap) {
@@ -170,18 +185,22 @@ void hk_stop(void) {
 }
 
 // Blocks until we either (a) have a mailbox message, or (b) the tap stops.
-int hk_wait_next(uint16_t* out_keycode, char* out_name, size_t out_cap) {
+int hk_wait_next(uint16_t* out_keycode, char* out_name, size_t out_cap, int* out_released) {
     pthread_mutex_lock(&gMu);
     for (;;) {
-        if (gHasMsg) {
-            if (out_keycode) *out_keycode = gKeycode;
+        if (gCount > 0) {
+            hk_event *next = &gQueue[gHead];
+            if (out_keycode) *out_keycode = next->keycode;
+            if (out_released) *out_released = next->released ? 1 : 0;
             if (out_name && out_cap) {
-                size_t n = strnlen(gName, sizeof(gName));
+                size_t n = strnlen(next->name, sizeof(next->name));
                 if (n >= out_cap) n = out_cap - 1;
-                memcpy(out_name, gName, n);
+                memcpy(out_name, next->name, n);
                 out_name[n] = '\0';
             }
-            gHasMsg = false; // consume
+            // consume
+            gHead = (gHead + 1) % HK_QUEUE_CAP;
+            gCount--;
             pthread_mutex_unlock(&gMu);
             return 1;
         }
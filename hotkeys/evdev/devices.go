@@ -0,0 +1,135 @@
+package evdev
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DevicesFile lists the input devices the kernel knows about along with their event handlers
+const DevicesFile = "/proc/bus/input/devices"
+
+// DevicesEnv selects devices by name or path instead of using every keyboard and controller, e.g.
+//
+//	OPENSPLIT_EVDEV_DEVICES="/dev/input/event3,Foot Pedal"
+//
+// A name matches any device whose name contains it, ignoring case.
+const DevicesEnv = "OPENSPLIT_EVDEV_DEVICES"
+
+// Device is an input device listed in DevicesFile
+type Device struct {
+	Name string
+	// Path is the device's /dev/input/event* file
+	Path     string
+	Keyboard bool
+	// JoystickPath is the device's /dev/input/js* file when the kernel's joystick handler claimed it, which it does for
+	// gamepads, joysticks and most pedals that present as one
+	JoystickPath string
+}
+
+// source is a file a selected device is read from
+type source struct {
+	path     string
+	joystick bool
+}
+
+// sources returns the files to read the device from.  Controller inputs come from the joystick file because it
+// numbers buttons from zero and scales every axis to the same range, keys come from the event file.
+func (d Device) sources() []source {
+	var sources []source
+	if d.JoystickPath != "" {
+		sources = append(sources, source{path: d.JoystickPath, joystick: true})
+	}
+	if d.Keyboard || d.JoystickPath == "" {
+		sources = append(sources, source{path: d.Path})
+	}
+	return sources
+}
+
+// ParseDevices reads the devices with an event handler from the DevicesFile format.
+//
+// A device is a keyboard when the kernel attached its kbd handler and it reports key events, which leaves out mice
+// and power buttons that only send a key or two without the handler.  Controllers are the devices with a js handler.
+func ParseDevices(r io.Reader) ([]Device, error) {
+	var devices []Device
+	var device Device
+	var kbd, keys bool
+
+	flush := func() {
+		if device.Path != "" {
+			device.Keyboard = kbd && keys
+			devices = append(devices, device)
+		}
+		device, kbd, keys = Device{}, false, false
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		kind, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch kind {
+		case "N":
+			device.Name = strings.Trim(strings.TrimPrefix(value, "Name="), `"`)
+		case "H":
+			for _, handler := range strings.Fields(strings.TrimPrefix(value, "Handlers=")) {
+				if handler == "kbd" {
+					kbd = true
+				}
+				if strings.HasPrefix(handler, "event") {
+					device.Path = filepath.Join("/dev/input", handler)
+				}
+				if strings.HasPrefix(handler, "js") {
+					device.JoystickPath = filepath.Join("/dev/input", handler)
+				}
+			}
+		case "B":
+			name, bits, _ := strings.Cut(value, "=")
+			if name == "EV" {
+				ev, err := strconv.ParseUint(bits, 16, 64)
+				keys = err == nil && ev&(1<<evKey) != 0
+			}
+		}
+	}
+	flush()
+	return devices, scanner.Err()
+}
+
+// SelectDevices returns the devices to read hotkeys from.
+//
+// An empty selection is every keyboard and controller.  Otherwise it is a comma separated list of event or joystick
+// paths and name fragments, and matching devices are used even if they don't look like either, so pedals and macro
+// pads can be picked.
+func SelectDevices(devices []Device, selection string) []Device {
+	var filters []string
+	for _, filter := range strings.Split(selection, ",") {
+		if filter = strings.TrimSpace(filter); filter != "" {
+			filters = append(filters, strings.ToLower(filter))
+		}
+	}
+
+	var selected []Device
+	for _, device := range devices {
+		if len(filters) == 0 {
+			if device.Keyboard || device.JoystickPath != "" {
+				selected = append(selected, device)
+			}
+			continue
+		}
+		for _, filter := range filters {
+			if device.Path == filter || device.JoystickPath == filter || strings.Contains(strings.ToLower(device.Name), filter) {
+				selected = append(selected, device)
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,292 @@
+package evdev
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+const devicesFile = `I: Bus=0011 Vendor=0001 Product=0001 Version=ab41
+N: Name="AT Translated Set 2 keyboard"
+P: Phys=isa0060/serio0/input0
+H: Handlers=sysrq kbd leds event3
+B: EV=120013
+
+I: Bus=0003 Vendor=046d Product=c077 Version=0111
+N: Name="Logitech USB Optical Mouse"
+H: Handlers=mouse0 event5
+B: EV=17
+
+I: Bus=0003 Vendor=0b33 Product=0030 Version=0110
+N: Name="Foot Pedal"
+H: Handlers=event7
+B: EV=1f
+
+I: Bus=0003 Vendor=045e Product=028e Version=0114
+N: Name="Microsoft X-Box 360 pad"
+H: Handlers=event9 js0
+B: EV=20000b
+`
+
+func key(code uint16, value int32) Event {
+	return Event{Time: time.Unix(1700000000, 250000000), Type: evKey, Code: code, Value: value}
+}
+
+func recording(t *testing.T, events ...Event) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, events...); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	want := key(30, keyPressed)
+	decoder := NewDecoder(bytes.NewReader(recording(t, want)))
+	got, err := decoder.Decode()
+	if err != nil || got != want {
+		t.Fatalf("Decode() want %+v, got %+v (%v)", want, got, err)
+	}
+	if _, err = decoder.Decode(); err != io.EOF {
+		t.Fatalf("Decode() at the end want io.EOF, got %v", err)
+	}
+
+	// a 32 bit kernel's timeval is two 4 byte longs
+	record := []byte{1, 0, 0, 0, 2, 0, 0, 0, evKey, 0, 57, 0, 1, 0, 0, 0}
+	if got, err = newDecoder(bytes.NewReader(record), 4).Decode(); err != nil || got.Code != 57 || got.Value != 1 {
+		t.Fatalf("Decode() of a 32 bit record want Space pressed, got %+v (%v)", got, err)
+	}
+}
+
+func TestParseDevices(t *testing.T) {
+	devices, err := ParseDevices(strings.NewReader(devicesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 4 || !devices[0].Keyboard || devices[1].Keyboard || devices[2].Keyboard || devices[3].Keyboard {
+		t.Fatalf("ParseDevices() want only the first of 4 devices to be a keyboard, got %+v", devices)
+	}
+	if devices[3].JoystickPath != "/dev/input/js0" || devices[0].JoystickPath != "" {
+		t.Fatalf("ParseDevices() want only the pad to have a joystick file, got %+v", devices)
+	}
+	if devices[0].Path != "/dev/input/event3" || devices[0].Name != "AT Translated Set 2 keyboard" {
+		t.Fatalf("ParseDevices() got %+v", devices[0])
+	}
+
+	if selected := SelectDevices(devices, ""); len(selected) != 2 || selected[0].Path != "/dev/input/event3" ||
+		selected[1].JoystickPath != "/dev/input/js0" {
+		t.Fatalf("SelectDevices() want the keyboard and the pad, got %+v", selected)
+	}
+	if sources := devices[3].sources(); len(sources) != 1 || !sources[0].joystick {
+		t.Fatalf("sources() of a pad want only its joystick file, got %+v", sources)
+	}
+	if selected := SelectDevices(devices, "foot pedal, /dev/input/event3"); len(selected) != 2 {
+		t.Fatalf("SelectDevices() want the pedal and the keyboard, got %+v", selected)
+	}
+}
+
+func TestKeyboardState(t *testing.T) {
+	k := newKeyboardState()
+	handle := func(path string, event Event) (keyinfo.KeyData, bool) {
+		return k.handle(deviceEvent{path: path, event: event})
+	}
+
+	handle("a", key(keyLeftCtrl, keyPressed))
+	handle("b", key(keyLeftShift, keyPressed))
+	handle("a", key(keyLeftCtrl, keyRepeated))
+	data, ok := handle("b", key(31, keyPressed))
+	if !ok || data.KeyCode != 31 || data.LocaleName != "S" {
+		t.Fatalf("handle() want S pressed, got %+v", data)
+	}
+	if len(data.Modifiers) != 2 || data.ModifierLocaleNames[0] != "Left Control" {
+		t.Fatalf("handle() want Left Control and Left Shift held across devices, got %v", data.ModifierLocaleNames)
+	}
+
+	if _, ok = handle("b", key(31, keyRepeated)); ok {
+		t.Fatalf("handle() reported a key repeat as a press")
+	}
+	if data, ok = handle("b", key(31, keyReleased)); !ok || !data.Released || len(data.Modifiers) != 2 {
+		t.Fatalf("handle() want S released with the modifiers still held, got %+v", data)
+	}
+	if _, ok = handle("a", key(keyLeftCtrl, keyReleased)); ok {
+		t.Fatalf("handle() reported a modifier release")
+	}
+	handle("a", key(keyLeftCtrl, keyPressed))
+	if _, ok = handle("b", key(0x120, keyPressed)); ok {
+		t.Fatalf("handle() reported a joystick button as a key")
+	}
+
+	handle("a", Event{Type: evSyn, Code: synDropped})
+	handle("b", key(keyLeftShift, keyReleased))
+	if data, _ = handle("b", key(31, keyPressed)); len(data.Modifiers) != 0 {
+		t.Fatalf("handle() after a dropped event and a release want no modifiers, got %v", data.ModifierLocaleNames)
+	}
+}
+
+func TestJoystickDecode(t *testing.T) {
+	want := []JoystickEvent{
+		{Time: 1500 * time.Millisecond, Type: jsButton, Number: 3, Value: 1},
+		{Time: 1510 * time.Millisecond, Type: jsAxis, Number: 1, Value: -32767},
+	}
+	var buf bytes.Buffer
+	if err := EncodeJoystick(&buf, want...); err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewJoystickDecoder(&buf)
+	for _, w := range want {
+		if got, err := decoder.Decode(); err != nil || got != w {
+			t.Fatalf("Decode() want %+v, got %+v (%v)", w, got, err)
+		}
+	}
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Fatalf("Decode() at the end want io.EOF, got %v", err)
+	}
+}
+
+func TestGamepadState(t *testing.T) {
+	g := newGamepadState()
+	axis := func(value int16) (keyinfo.KeyData, bool) {
+		return g.handle("pad", JoystickEvent{Type: jsAxis, Number: 1, Value: value})
+	}
+
+	if _, ok := g.handle("pad", JoystickEvent{Type: jsButton | jsInit, Number: 0, Value: 1}); ok {
+		t.Fatalf("handle() reported a button held when the device opened as a press")
+	}
+	data, ok := g.handle("pad", JoystickEvent{Type: jsButton, Number: 2, Value: 1})
+	if !ok || data.Input != keyinfo.InputButton || data.KeyCode != 2 || data.LocaleName != "Button 2" {
+		t.Fatalf("handle() want button 2 pressed, got %+v", data)
+	}
+	if data, ok = g.handle("pad", JoystickEvent{Type: jsButton, Number: 2, Value: 0}); !ok || !data.Released {
+		t.Fatalf("handle() want button 2 released, got %+v", data)
+	}
+
+	if _, ok = axis(axisReleased); ok {
+		t.Fatalf("handle() reported an axis inside its threshold")
+	}
+	data, ok = axis(-30000)
+	if !ok || data.Input != keyinfo.InputAxis || data.AxisDirection != -1 || data.LocaleName != "Axis 1-" {
+		t.Fatalf("handle() want axis 1 pushed negative, got %+v", data)
+	}
+	// between the thresholds the axis stays pushed, so it only fires again after coming back
+	if _, ok = axis(-12000); ok {
+		t.Fatalf("handle() fired again for an axis that wasn't released")
+	}
+	if _, ok = axis(-30000); ok {
+		t.Fatalf("handle() fired again for an axis that wasn't released")
+	}
+	if data, ok = axis(0); !ok || !data.Released || data.AxisDirection != -1 {
+		t.Fatalf("handle() want axis 1 released from negative, got %+v", data)
+	}
+	if data, ok = axis(32767); !ok || data.AxisDirection != 1 {
+		t.Fatalf("handle() want axis 1 pushed positive after release, got %+v", data)
+	}
+}
+
+// fakeDevices serves recorded streams for device paths, and can add devices to simulate hot plugging
+type fakeDevices struct {
+	mu      sync.Mutex
+	devices []Device
+	streams map[string]io.ReadCloser
+}
+
+func (f *fakeDevices) list() ([]Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Device{}, f.devices...), nil
+}
+
+func (f *fakeDevices) open(path string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streams[path], nil
+}
+
+func (f *fakeDevices) plug(device Device, stream io.ReadCloser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices = append(f.devices, device)
+	if device.JoystickPath != "" {
+		f.streams[device.JoystickPath] = stream
+	} else {
+		f.streams[device.Path] = stream
+	}
+}
+
+func TestManager(t *testing.T) {
+	devices := &fakeDevices{streams: map[string]io.ReadCloser{}}
+	devices.plug(Device{Name: "Keyboard", Path: "/dev/input/event0", Keyboard: true},
+		io.NopCloser(bytes.NewReader(recording(t, key(57, keyPressed), key(57, keyReleased)))))
+
+	pressed := make(chan keyinfo.KeyData, 4)
+	m := NewManager(devices.list, devices.open, "", 10*time.Millisecond)
+	if err := m.StartHook(func(data keyinfo.KeyData) { pressed <- data }); err != nil {
+		t.Fatalf("StartHook() returned error: %s", err)
+	}
+	defer func() {
+		_ = m.Unhook()
+	}()
+
+	next := func() keyinfo.KeyData {
+		t.Helper()
+		select {
+		case data := <-pressed:
+			return data
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a key press")
+		}
+		return keyinfo.KeyData{}
+	}
+	if data := next(); data.LocaleName != "Space" || data.Released {
+		t.Fatalf("want Space from the first keyboard, got %+v", data)
+	}
+	if data := next(); data.LocaleName != "Space" || !data.Released {
+		t.Fatalf("want Space released on the first keyboard, got %+v", data)
+	}
+
+	// plugged in after the hook started
+	devices.plug(Device{Name: "Second Keyboard", Path: "/dev/input/event1", Keyboard: true},
+		io.NopCloser(bytes.NewReader(recording(t, key(59, keyPressed)))))
+	if data := next(); data.LocaleName != "F1" {
+		t.Fatalf("want F1 from the hot plugged keyboard, got %+v", data)
+	}
+
+	var pad bytes.Buffer
+	_ = EncodeJoystick(&pad, JoystickEvent{Type: jsButton | jsInit, Number: 0},
+		JoystickEvent{Type: jsButton, Number: 5, Value: 1})
+	devices.plug(Device{Name: "Pad", Path: "/dev/input/event2", JoystickPath: "/dev/input/js0"}, io.NopCloser(&pad))
+	if data := next(); data.Input != keyinfo.InputButton || data.KeyCode != 5 {
+		t.Fatalf("want button 5 from the pad, got %+v", data)
+	}
+}
+
+func TestEventTime(t *testing.T) {
+	read := time.Now()
+	pressed := read.Add(-5 * time.Millisecond).Round(0)
+	if at := (deviceEvent{read: read, event: Event{Time: pressed}}).at(); !at.Equal(pressed) || at.Round(0) == at {
+		t.Fatalf("at() want the kernel timestamp on the monotonic clock, got %v", at)
+	}
+	stale := deviceEvent{read: read, event: Event{Time: read.Add(-time.Hour)}}
+	future := deviceEvent{read: read, event: Event{Time: read.Add(time.Second)}}
+	joystick := deviceEvent{read: read, joystick: true, event: Event{Time: pressed}}
+	for _, e := range []deviceEvent{stale, future, joystick} {
+		if at := e.at(); at != read {
+			t.Errorf("at() of %+v want the read time, got %v", e, at)
+		}
+	}
+}
+
+func TestManagerPermissionDenied(t *testing.T) {
+	list := func() ([]Device, error) { return []Device{{Path: "/dev/input/event0", Keyboard: true}}, nil }
+	open := func(string) (io.ReadCloser, error) { return nil, &fs.PathError{Op: "open", Err: fs.ErrPermission} }
+	m := NewManager(list, open, "", time.Second)
+	if err := m.StartHook(func(keyinfo.KeyData) {}); err == nil {
+		t.Fatalf("StartHook() with no readable devices want error, got nil")
+	}
+}
@@ -0,0 +1,107 @@
+// Package evdev is a HotkeyProvider that reads keyboards and controllers straight from the Linux input subsystem.
+//
+// It works wherever /dev/input/event* is readable, which covers Wayland sessions and the console where the x11 package
+// can't see global key presses.  It is plain Go with no cgo: keyboards are found through /proc/bus/input/devices and
+// their input_event records are decoded from the device files, so everything but the device files themselves can be
+// tested with recorded byte streams.  Gamepads and joysticks are read from /dev/input/js* in the same way.  Reading the
+// device files usually requires membership of the input group.
+package evdev
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+	"time"
+)
+
+const logModule = "hotkeys"
+
+// Event types and codes from linux/input-event-codes.h
+const (
+	evSyn = 0x00
+	evKey = 0x01
+
+	synDropped = 3
+)
+
+// Key event values
+const (
+	keyReleased = 0
+	keyPressed  = 1
+	keyRepeated = 2
+)
+
+// Event is one struct input_event read from a device
+type Event struct {
+	Time  time.Time
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// eventSize is the size of struct input_event, whose timeval is two C longs
+var eventSize = 2*strconv.IntSize/8 + 8
+
+// Decoder reads input_event records from a device file or a recording of one
+type Decoder struct {
+	r   io.Reader
+	buf []byte
+	// longSize is the size of a C long on the machine that wrote the stream
+	longSize int
+}
+
+// NewDecoder decodes events written by this machine's kernel
+func NewDecoder(r io.Reader) *Decoder {
+	return newDecoder(r, strconv.IntSize/8)
+}
+
+func newDecoder(r io.Reader, longSize int) *Decoder {
+	return &Decoder{r: r, buf: make([]byte, 2*longSize+8), longSize: longSize}
+}
+
+// Decode reads the next event, it returns io.EOF at the end of a recording and io.ErrUnexpectedEOF for a torn record
+func (d *Decoder) Decode() (Event, error) {
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		return Event{}, err
+	}
+
+	var sec, usec int64
+	if d.longSize == 8 {
+		sec = int64(binary.NativeEndian.Uint64(d.buf[0:]))
+		usec = int64(binary.NativeEndian.Uint64(d.buf[8:]))
+	} else {
+		sec = int64(int32(binary.NativeEndian.Uint32(d.buf[0:])))
+		usec = int64(int32(binary.NativeEndian.Uint32(d.buf[4:])))
+	}
+	rest := d.buf[2*d.longSize:]
+	return Event{
+		Time:  time.Unix(sec, usec*int64(time.Microsecond)),
+		Type:  binary.NativeEndian.Uint16(rest[0:]),
+		Code:  binary.NativeEndian.Uint16(rest[2:]),
+		Value: int32(binary.NativeEndian.Uint32(rest[4:])),
+	}, nil
+}
+
+// Encode writes events in this machine's input_event layout, it is used to build recordings
+func Encode(w io.Writer, events ...Event) error {
+	longSize := strconv.IntSize / 8
+	buf := make([]byte, eventSize)
+	for _, event := range events {
+		sec, usec := event.Time.Unix(), int64(event.Time.Nanosecond()/1000)
+		if longSize == 8 {
+			binary.NativeEndian.PutUint64(buf[0:], uint64(sec))
+			binary.NativeEndian.PutUint64(buf[8:], uint64(usec))
+		} else {
+			binary.NativeEndian.PutUint32(buf[0:], uint32(sec))
+			binary.NativeEndian.PutUint32(buf[4:], uint32(usec))
+		}
+		rest := buf[2*longSize:]
+		binary.NativeEndian.PutUint16(rest[0:], event.Type)
+		binary.NativeEndian.PutUint16(rest[2:], event.Code)
+		binary.NativeEndian.PutUint32(rest[4:], uint32(event.Value))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package evdev
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+// js_event types from linux/joystick.h
+const (
+	jsButton = 0x01
+	jsAxis   = 0x02
+	// jsInit marks the synthetic events describing the device's state when it is opened
+	jsInit = 0x80
+)
+
+// jsEventSize is the size of struct js_event: a u32 millisecond timestamp, s16 value, u8 type and u8 number
+const jsEventSize = 8
+
+// Axis thresholds on the joystick interface's -32767..32767 scale.  An axis fires when pushed past axisPressed and
+// re-arms once it comes back inside axisReleased, so a stick wobbling around the threshold doesn't fire repeatedly.
+const (
+	axisPressed  = 16384
+	axisReleased = 8192
+)
+
+// JoystickEvent is one struct js_event read from a /dev/input/js* file
+type JoystickEvent struct {
+	// Time is the event's timestamp in milliseconds, its epoch is unspecified
+	Time   time.Duration
+	Type   uint8
+	Number uint8
+	Value  int16
+}
+
+// JoystickDecoder reads js_event records from a joystick device file or a recording of one
+type JoystickDecoder struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewJoystickDecoder decodes events written by this machine's kernel
+func NewJoystickDecoder(r io.Reader) *JoystickDecoder {
+	return &JoystickDecoder{r: r, buf: make([]byte, jsEventSize)}
+}
+
+// Decode reads the next event, it returns io.EOF at the end of a recording and io.ErrUnexpectedEOF for a torn record
+func (d *JoystickDecoder) Decode() (JoystickEvent, error) {
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		return JoystickEvent{}, err
+	}
+	return JoystickEvent{
+		Time:   time.Duration(binary.NativeEndian.Uint32(d.buf[0:])) * time.Millisecond,
+		Value:  int16(binary.NativeEndian.Uint16(d.buf[4:])),
+		Type:   d.buf[6],
+		Number: d.buf[7],
+	}, nil
+}
+
+// EncodeJoystick writes events in the js_event layout, it is used to build recordings
+func EncodeJoystick(w io.Writer, events ...JoystickEvent) error {
+	buf := make([]byte, jsEventSize)
+	for _, event := range events {
+		binary.NativeEndian.PutUint32(buf[0:], uint32(event.Time/time.Millisecond))
+		binary.NativeEndian.PutUint16(buf[4:], uint16(event.Value))
+		buf[6], buf[7] = event.Type, event.Number
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gamepadState is the direction each axis of each controller is pushed in
+type gamepadState struct {
+	axes map[string]map[uint8]int
+}
+
+func newGamepadState() *gamepadState {
+	return &gamepadState{axes: map[string]map[uint8]int{}}
+}
+
+// handle applies an event from the controller at path and returns the KeyData for a button press or release, or an
+// axis being pushed or coming back inside its threshold.
+//
+// Controllers are told apart only by path, so the same button on two controllers is the same binding.
+func (g *gamepadState) handle(path string, event JoystickEvent) (keyinfo.KeyData, bool) {
+	init := event.Type&jsInit != 0
+	switch event.Type &^ jsInit {
+	case jsButton:
+		// the initial state isn't a press, or a button held while the hook starts would fire
+		if init {
+			break
+		}
+		data := keyinfo.NewButtonData(int(event.Number), fmt.Sprintf("Button %d", event.Number))
+		if event.Value == 0 {
+			data = data.Release()
+		}
+		return data, true
+	case jsAxis:
+		if g.axes[path] == nil {
+			g.axes[path] = map[uint8]int{}
+		}
+		previous := g.axes[path][event.Number]
+		direction := previous
+		switch {
+		case event.Value >= axisPressed:
+			direction = 1
+		case event.Value <= -axisPressed:
+			direction = -1
+		case event.Value < axisReleased && event.Value > -axisReleased:
+			direction = 0
+		}
+		g.axes[path][event.Number] = direction
+		if init || direction == previous {
+			break
+		}
+		if direction == 0 {
+			return keyinfo.NewAxisData(int(event.Number), previous, axisName(event.Number, previous)).Release(), true
+		}
+		// an axis flicked straight across releases one direction and pushes the other, only the push is reported
+		return keyinfo.NewAxisData(int(event.Number), direction, axisName(event.Number, direction)), true
+	}
+	return keyinfo.KeyData{}, false
+}
+
+// forget drops a controller that went away
+func (g *gamepadState) forget(path string) {
+	delete(g.axes, path)
+}
+
+func axisName(axis uint8, direction int) string {
+	if direction < 0 {
+		return fmt.Sprintf("Axis %d-", axis)
+	}
+	return fmt.Sprintf("Axis %d+", axis)
+}
@@ -0,0 +1,63 @@
+package evdev
+
+import "fmt"
+
+// Modifier key codes from linux/input-event-codes.h
+const (
+	keyLeftCtrl   = 29
+	keyLeftShift  = 42
+	keyRightShift = 54
+	keyLeftAlt    = 56
+	keyRightCtrl  = 97
+	keyRightAlt   = 100
+	keyLeftMeta   = 125
+	keyRightMeta  = 126
+)
+
+var modifierNames = map[uint16]string{
+	keyLeftCtrl:   "Left Control",
+	keyRightCtrl:  "Right Control",
+	keyLeftShift:  "Left Shift",
+	keyRightShift: "Right Shift",
+	keyLeftAlt:    "Left Alt",
+	keyRightAlt:   "Right Alt",
+	keyLeftMeta:   "Left Super",
+	keyRightMeta:  "Right Super",
+}
+
+func isModifier(code uint16) bool {
+	_, ok := modifierNames[code]
+	return ok
+}
+
+// keyNames are the names of keys a hotkey is likely to be bound to.  Codes are physical keys, so the names follow the
+// US layout the kernel's codes are named after.
+var keyNames = map[uint16]string{
+	1: "Escape", 14: "Backspace", 15: "Tab", 28: "Enter", 57: "Space", 58: "Caps Lock",
+	12: "-", 13: "=", 26: "[", 27: "]", 39: ";", 40: "'", 41: "`", 43: "\\", 51: ",", 52: ".", 53: "/",
+	2: "1", 3: "2", 4: "3", 5: "4", 6: "5", 7: "6", 8: "7", 9: "8", 10: "9", 11: "0",
+	16: "Q", 17: "W", 18: "E", 19: "R", 20: "T", 21: "Y", 22: "U", 23: "I", 24: "O", 25: "P",
+	30: "A", 31: "S", 32: "D", 33: "F", 34: "G", 35: "H", 36: "J", 37: "K", 38: "L",
+	44: "Z", 45: "X", 46: "C", 47: "V", 48: "B", 49: "N", 50: "M",
+	59: "F1", 60: "F2", 61: "F3", 62: "F4", 63: "F5", 64: "F6", 65: "F7", 66: "F8", 67: "F9", 68: "F10",
+	87: "F11", 88: "F12", 183: "F13", 184: "F14", 185: "F15", 186: "F16", 187: "F17", 188: "F18", 189: "F19",
+	190: "F20", 191: "F21", 192: "F22", 193: "F23", 194: "F24",
+	69: "Num Lock", 70: "Scroll Lock", 99: "Print Screen", 119: "Pause",
+	71: "Num 7", 72: "Num 8", 73: "Num 9", 74: "Num -", 75: "Num 4", 76: "Num 5", 77: "Num 6", 78: "Num +",
+	79: "Num 1", 80: "Num 2", 81: "Num 3", 82: "Num 0", 83: "Num .", 96: "Num Enter", 98: "Num /", 55: "Num *",
+	102: "Home", 103: "Up", 104: "Page Up", 105: "Left", 106: "Right", 107: "End", 108: "Down", 109: "Page Down",
+	110: "Insert", 111: "Delete", 127: "Menu",
+	113: "Mute", 114: "Volume Down", 115: "Volume Up", 163: "Next Track", 164: "Play/Pause", 165: "Previous Track",
+	166: "Stop",
+}
+
+// keyName returns a readable name for a key code
+func keyName(code uint16) string {
+	if name, ok := keyNames[code]; ok {
+		return name
+	}
+	if name, ok := modifierNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key %d", code)
+}
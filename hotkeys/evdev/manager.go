@@ -0,0 +1,350 @@
+package evdev
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/keyinfo"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// DefaultScanInterval is how often DevicesFile is checked for keyboards and controllers being plugged in
+const DefaultScanInterval = 2 * time.Second
+
+// maxEventAge is how far a kernel timestamp may trail the event being read before it is distrusted, e.g. after the
+// wall clock was changed
+const maxEventAge = time.Second
+
+// Manager implements the HotkeyProvider interface with evdev.
+//
+// Each selected device is read on its own goroutine and its events are funnelled to a single goroutine that tracks
+// held modifiers and axis positions and calls the callback, so callbacks never run concurrently.  Modifiers held on any
+// device apply to keys pressed on any other, so a modifier on a keyboard can chord with a pedal, but never to
+// controller inputs.  Devices are rescanned every scanInterval so devices plugged in mid-session are picked up, and
+// devices that go away are dropped.
+type Manager struct {
+	mu       sync.Mutex
+	callback func(data keyinfo.KeyData)
+	hooked   bool
+	stop     chan struct{}
+	events   chan deviceEvent
+	open     map[string]io.Closer
+	failed   map[string]bool
+
+	listDevices  func() ([]Device, error)
+	openDevice   func(path string) (io.ReadCloser, error)
+	selection    string
+	scanInterval time.Duration
+}
+
+// deviceEvent is an event read from the device at path, or the device going away when closed is set.  Events read
+// from a joystick file are in joystickEvent.  read is when the event was read.
+type deviceEvent struct {
+	path          string
+	read          time.Time
+	event         Event
+	joystick      bool
+	joystickEvent JoystickEvent
+	closed        bool
+}
+
+// SetupHotkeys returns a Manager reading the keyboards and controllers in DevicesFile, or those picked with DevicesEnv
+func SetupHotkeys() *Manager {
+	return NewManager(readDevicesFile, func(path string) (io.ReadCloser, error) { return os.Open(path) },
+		os.Getenv(DevicesEnv), DefaultScanInterval)
+}
+
+// NewManager creates a Manager that finds devices with listDevices and reads them with openDevice
+func NewManager(listDevices func() ([]Device, error), openDevice func(path string) (io.ReadCloser, error),
+	selection string, scanInterval time.Duration) *Manager {
+	return &Manager{
+		listDevices:  listDevices,
+		openDevice:   openDevice,
+		selection:    selection,
+		scanInterval: scanInterval,
+	}
+}
+
+func readDevicesFile() ([]Device, error) {
+	f, err := os.Open(DevicesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ParseDevices(f)
+}
+
+// StartHook opens the selected devices and calls callback for every key or button pressed or released and axis pushed
+// or let go on them.
+//
+// It fails when devices were found but none could be opened, which is almost always a missing input group membership.
+// Calling it while hooked only replaces the callback.
+func (m *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.callback = callback
+	if m.hooked {
+		logger.Debug(logModule, "evdev hotkeys previously started, updating callback")
+		return nil
+	}
+
+	m.stop = make(chan struct{})
+	m.events = make(chan deviceEvent, 64)
+	m.open = map[string]io.Closer{}
+	m.failed = map[string]bool{}
+	if err := m.scanLocked(); err != nil {
+		close(m.stop)
+		return err
+	}
+
+	m.hooked = true
+	go m.handleEvents(m.stop, m.events)
+	go m.watchDevices(m.stop)
+	logger.Infof(logModule, "evdev hotkey provider started with %d devices", len(m.open))
+	return nil
+}
+
+// Unhook stops reading every device
+func (m *Manager) Unhook() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.hooked {
+		return nil
+	}
+	m.hooked = false
+	m.callback = nil
+	close(m.stop)
+	for path, device := range m.open {
+		_ = device.Close()
+		delete(m.open, path)
+	}
+	logger.Debug(logModule, "evdev hotkey provider unhooked")
+	return nil
+}
+
+// scanLocked opens selected devices that aren't open yet, it must be called under lock
+func (m *Manager) scanLocked() error {
+	devices, err := m.listDevices()
+	if err != nil {
+		return fmt.Errorf("failed to list input devices: %w", err)
+	}
+
+	selected := SelectDevices(devices, m.selection)
+	var denied error
+	var paths []string
+	for _, device := range selected {
+		for _, src := range device.sources() {
+			paths = append(paths, src.path)
+			if _, ok := m.open[src.path]; ok || m.failed[src.path] {
+				continue
+			}
+			rc, err := m.openDevice(src.path)
+			if err != nil {
+				// log each device once rather than on every scan
+				m.failed[src.path] = true
+				logger.Warnf(logModule, "failed to open %s (%s): %s", src.path, device.Name, err)
+				if errors.Is(err, fs.ErrPermission) {
+					denied = err
+				}
+				continue
+			}
+			m.open[src.path] = rc
+			logger.Infof(logModule, "reading hotkeys from %s (%s)", src.path, device.Name)
+			go m.readDevice(src, rc, m.stop, m.events)
+		}
+	}
+
+	// forget failures for devices that were unplugged so they are retried if they come back
+	for path := range m.failed {
+		if !slices.Contains(paths, path) {
+			delete(m.failed, path)
+		}
+	}
+
+	if len(m.open) == 0 && denied != nil {
+		return fmt.Errorf("no input devices could be opened, is the user in the input group? %w", denied)
+	}
+	if len(selected) == 0 {
+		logger.Warn(logModule, "no keyboards or controllers found for evdev hotkeys")
+	}
+	return nil
+}
+
+// watchDevices rescans for devices until stop is closed
+func (m *Manager) watchDevices(stop chan struct{}) {
+	ticker := time.NewTicker(m.scanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			if m.hooked {
+				if err := m.scanLocked(); err != nil {
+					logger.Warn(logModule, err.Error())
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// readDevice decodes a device's events until it is closed or goes away
+func (m *Manager) readDevice(src source, rc io.ReadCloser, stop chan struct{}, events chan deviceEvent) {
+	var decode func() (deviceEvent, error)
+	if src.joystick {
+		decoder := NewJoystickDecoder(rc)
+		decode = func() (deviceEvent, error) {
+			event, err := decoder.Decode()
+			return deviceEvent{joystick: true, joystickEvent: event}, err
+		}
+	} else {
+		decoder := NewDecoder(rc)
+		decode = func() (deviceEvent, error) {
+			event, err := decoder.Decode()
+			return deviceEvent{event: event}, err
+		}
+	}
+
+	for {
+		e, err := decode()
+		e.path, e.read = src.path, time.Now()
+		if err != nil {
+			m.mu.Lock()
+			if m.open[src.path] == rc {
+				delete(m.open, src.path)
+				logger.Infof(logModule, "stopped reading %s: %s", src.path, err)
+			}
+			m.mu.Unlock()
+			_ = rc.Close()
+			e = deviceEvent{path: src.path, joystick: src.joystick, closed: true}
+		}
+
+		select {
+		case events <- e:
+		case <-stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleEvents tracks modifiers and axes and calls the callback for presses and releases until stop is closed
+func (m *Manager) handleEvents(stop chan struct{}, events chan deviceEvent) {
+	keyboards := newKeyboardState()
+	gamepads := newGamepadState()
+	for {
+		select {
+		case <-stop:
+			return
+		case e := <-events:
+			var data keyinfo.KeyData
+			var ok bool
+			switch {
+			case e.joystick && e.closed:
+				gamepads.forget(e.path)
+			case e.joystick:
+				data, ok = gamepads.handle(e.path, e.joystickEvent)
+			default:
+				data, ok = keyboards.handle(e)
+			}
+			if !ok {
+				continue
+			}
+			data.At = e.at()
+			m.mu.Lock()
+			callback := m.callback
+			m.mu.Unlock()
+			if callback != nil {
+				callback(data)
+			}
+		}
+	}
+}
+
+// at is when the event happened.  Keyboard events carry the kernel's wall clock timestamp, which is moved onto the
+// monotonic clock by how long before the read it was.  Joystick timestamps have no known epoch, so they are timed
+// from the read.
+func (e deviceEvent) at() time.Time {
+	if e.joystick {
+		return e.read
+	}
+	age := e.read.Sub(e.event.Time)
+	if age < 0 || age > maxEventAge {
+		return e.read
+	}
+	return e.read.Add(-age)
+}
+
+// keyboardState is the modifiers held down on each device
+type keyboardState struct {
+	held map[string]map[uint16]bool
+}
+
+func newKeyboardState() *keyboardState {
+	return &keyboardState{held: map[string]map[uint16]bool{}}
+}
+
+// handle applies an event and returns the KeyData for a key press or release
+func (k *keyboardState) handle(e deviceEvent) (keyinfo.KeyData, bool) {
+	event := e.event
+	switch {
+	case e.closed:
+		delete(k.held, e.path)
+	case event.Type == evSyn && event.Code == synDropped:
+		// the kernel dropped events, so any release may have been missed
+		delete(k.held, e.path)
+	case event.Type != evKey || isButton(event.Code):
+	case isModifier(event.Code):
+		if k.held[e.path] == nil {
+			k.held[e.path] = map[uint16]bool{}
+		}
+		if event.Value == keyReleased {
+			delete(k.held[e.path], event.Code)
+		} else {
+			k.held[e.path][event.Code] = true
+		}
+	case event.Value == keyPressed:
+		modifiers, names := k.modifiers()
+		return keyinfo.NewKeyData(int(event.Code), keyName(event.Code), modifiers, names), true
+	case event.Value == keyReleased:
+		modifiers, names := k.modifiers()
+		return keyinfo.NewKeyData(int(event.Code), keyName(event.Code), modifiers, names).Release(), true
+	}
+	return keyinfo.KeyData{}, false
+}
+
+// modifiers returns the modifiers held on any device, ordered by code
+func (k *keyboardState) modifiers() ([]int, []string) {
+	var codes []int
+	for _, held := range k.held {
+		for code := range held {
+			if !slices.Contains(codes, int(code)) {
+				codes = append(codes, int(code))
+			}
+		}
+	}
+	slices.Sort(codes)
+	names := make([]string, 0, len(codes))
+	for _, code := range codes {
+		names = append(names, modifierNames[uint16(code)])
+	}
+	return codes, names
+}
+
+// isButton reports whether code is a mouse, joystick or gamepad button rather than a key.  Controller buttons are read
+// from the joystick file instead, where they are numbered from zero.
+func isButton(code uint16) bool {
+	return (code >= 0x100 && code < 0x160) || (code >= 0x2c0 && code < 0x2e8)
+}
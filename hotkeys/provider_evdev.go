@@ -0,0 +1,11 @@
+//go:build linux && !x11
+
+package hotkeys
+
+import "github.com/zellydev-games/opensplit/hotkeys/evdev"
+
+// SetupHotkeys reads keyboards through evdev when the x11 provider isn't built in, so Wayland sessions and the console
+// get global hotkeys without cgo.
+func SetupHotkeys() *evdev.Manager {
+	return evdev.SetupHotkeys()
+}
@@ -3,10 +3,10 @@
 package hotkeys
 
 import (
-	"fmt"
 	"runtime"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/zellydev-games/opensplit/keyinfo"
@@ -161,6 +161,8 @@ func (w *WindowsManager) Unhook() error {
 
 // handleKeyDown is called by the OS after StartHook installs it. The callback receives nCode, lparam, and wparam as
 // defined by the Win32 API: https://learn.microsoft.com/en-us/windows/win32/winmsg/lowlevelkeyboardproc
+//
+// Key releases are reported with KeyData.Released set so hold gestures can tell how long a key was down.
 func (w *WindowsManager) handleKeyDown(nCode uintptr, identifier uintptr, kbHookStruct uintptr) uintptr {
 	// If nCode is less than zero we're obligated to pass the message along
 	if int32(nCode) < 0 {
@@ -169,6 +171,9 @@ func (w *WindowsManager) handleKeyDown(nCode uintptr, identifier uintptr, kbHook
 	}
 
 	if isKeyEvent(identifier) {
+		// the hook runs as the key is pressed, before the event is queued anywhere
+		at := time.Now()
+
 		// Process modifiers first
 		hookInfo := *(*kbDLLHook)(unsafe.Pointer(kbHookStruct)) //nolint:all
 		vk := hookInfo.vkCode
@@ -196,47 +201,47 @@ func (w *WindowsManager) handleKeyDown(nCode uintptr, identifier uintptr, kbHook
 		}
 		modifierState.mu.Unlock()
 
-		if identifier == wmKeyDown || identifier == wmSysKeyDown {
-			if !isModifierKey(hookInfo.vkCode) {
-				nameLen, _, err := getKeyName.Call(
-					lparam,
-					uintptr(unsafe.Pointer(p)),
-					uintptr(len(buf)),
-				)
-				if nameLen == 0 {
-					logger.Error(logModule, err.Error())
-				}
+		released := identifier == wmKeyUp || identifier == wmSysKeyUp
+		if !isModifierKey(hookInfo.vkCode) {
+			nameLen, _, err := getKeyName.Call(
+				lparam,
+				uintptr(unsafe.Pointer(p)),
+				uintptr(len(buf)),
+			)
+			if nameLen == 0 {
+				logger.Error(logModule, err.Error())
+			}
 
-				localeString := windows.UTF16ToString(buf)
+			localeString := windows.UTF16ToString(buf)
 
-				modifierState.mu.Lock()
-				modifiers := make([]int, 0, len(modifierState.m))
-				for code, state := range modifierState.m {
-					if state {
-						modifiers = append(modifiers, int(code))
-					}
+			modifierState.mu.Lock()
+			modifiers := make([]int, 0, len(modifierState.m))
+			for code, state := range modifierState.m {
+				if state {
+					modifiers = append(modifiers, int(code))
 				}
-				modifierLocaleNames := make([]string, 0, len(modifiers))
-				for _, vkInt := range modifiers {
-					if name := w.modCodeToString(vkInt); name != "" {
-						modifierLocaleNames = append(modifierLocaleNames, name)
-					}
+			}
+			modifierLocaleNames := make([]string, 0, len(modifiers))
+			for _, vkInt := range modifiers {
+				if name := w.modCodeToString(vkInt); name != "" {
+					modifierLocaleNames = append(modifierLocaleNames, name)
 				}
-				modifierState.mu.Unlock()
-
-				fmt.Println(localeString)
-				fmt.Println(modifierState.m)
-
-				if w.keyPressedCallback != nil {
-					w.keyPressedCallback(
-						keyinfo.NewKeyData(
-							int(hookInfo.vkCode),
-							localeString,
-							modifiers,
-							modifierLocaleNames,
-						),
-					)
+			}
+			modifierState.mu.Unlock()
+
+			if w.keyPressedCallback != nil {
+				data := keyinfo.NewKeyData(
+					int(hookInfo.vkCode),
+					localeString,
+					modifiers,
+					modifierLocaleNames,
+				).Stamped(at)
+				if released {
+					data = data.Release()
 				}
+				w.keyPressedCallback(data)
+			}
+			if !released {
 				resetModifiers()
 			}
 		}
@@ -0,0 +1,90 @@
+package x11
+
+import (
+	"slices"
+	"time"
+
+	"github.com/zellydev-games/opensplit/keyinfo"
+)
+
+// Event types filled in by xi2_next
+const (
+	eventPress   = 1
+	eventRelease = 2
+)
+
+// dedupeWindow is how soon an identical event is treated as a copy.  XInput2 reports raw events from both the master
+// and slave devices, and some VMs deliver each twice.
+const dedupeWindow = 20 * time.Millisecond
+
+// modifierNames are the keysyms treated as modifiers and the names shown for them, matching the other providers
+var modifierNames = map[string]string{
+	"Control_L":        "Left Control",
+	"Control_R":        "Right Control",
+	"Shift_L":          "Left Shift",
+	"Shift_R":          "Right Shift",
+	"Alt_L":            "Left Alt",
+	"Alt_R":            "Right Alt",
+	"ISO_Level3_Shift": "Right Alt",
+	"Super_L":          "Left Super",
+	"Super_R":          "Right Super",
+}
+
+// rawEvent is a key event as read from the X server, name is the keycode's unshifted keysym
+type rawEvent struct {
+	kind    int
+	keycode int
+	name    string
+}
+
+// keyState tracks the modifiers held down and turns raw key events into KeyData.
+//
+// XInput2 raw events carry no modifier state, so it is rebuilt from the modifier keys' own presses and releases.
+type keyState struct {
+	held   map[int]string
+	last   rawEvent
+	lastAt time.Time
+}
+
+func newKeyState() *keyState {
+	return &keyState{held: map[int]string{}}
+}
+
+// handle applies an event received at the given time and returns the KeyData for a key press or release, stamped with
+// that time.  Modifiers are not reported on their own and copies of the previous event are dropped.
+func (k *keyState) handle(e rawEvent, at time.Time) (keyinfo.KeyData, bool) {
+	if e == k.last && at.Sub(k.lastAt) < dedupeWindow {
+		return keyinfo.KeyData{}, false
+	}
+	k.last, k.lastAt = e, at
+
+	if name, ok := modifierNames[e.name]; ok {
+		if e.kind == eventRelease {
+			delete(k.held, e.keycode)
+		} else {
+			k.held[e.keycode] = name
+		}
+		return keyinfo.KeyData{}, false
+	}
+
+	modifiers, names := k.modifiers()
+	data := keyinfo.NewKeyData(e.keycode, e.name, modifiers, names).Stamped(at)
+	if e.kind == eventRelease {
+		data = data.Release()
+	}
+	return data, true
+}
+
+// modifiers returns the modifiers held, ordered by keycode
+func (k *keyState) modifiers() ([]int, []string) {
+	codes := make([]int, 0, len(k.held))
+	for code := range k.held {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	names := make([]string, 0, len(codes))
+	for _, code := range codes {
+		names = append(names, k.held[code])
+	}
+	return codes, names
+}
@@ -0,0 +1,44 @@
+package x11
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyState(t *testing.T) {
+	k := newKeyState()
+	now := time.Unix(1700000000, 0)
+	handle := func(kind int, keycode int, name string) (bool, bool, []string) {
+		now = now.Add(50 * time.Millisecond)
+		data, ok := k.handle(rawEvent{kind: kind, keycode: keycode, name: name}, now)
+		return ok, data.Released, data.ModifierLocaleNames
+	}
+
+	if ok, _, _ := handle(eventPress, 50, "Shift_L"); ok {
+		t.Fatalf("handle() reported a modifier on its own")
+	}
+	handle(eventPress, 37, "Control_L")
+	ok, released, names := handle(eventPress, 39, "s")
+	if !ok || released || len(names) != 2 || names[0] != "Left Control" || names[1] != "Left Shift" {
+		t.Fatalf("handle() want s pressed with Left Control and Left Shift, got %v %v %v", ok, released, names)
+	}
+	if ok, released, _ = handle(eventRelease, 39, "s"); !ok || !released {
+		t.Fatalf("handle() want s released")
+	}
+
+	handle(eventRelease, 50, "Shift_L")
+	handle(eventRelease, 37, "Control_L")
+	if _, _, names = handle(eventPress, 39, "s"); len(names) != 0 {
+		t.Fatalf("handle() after releasing the modifiers want none held, got %v", names)
+	}
+
+	// the same event from the slave device right after the master's
+	if _, ok = k.handle(rawEvent{kind: eventPress, keycode: 39, name: "s"}, now.Add(time.Millisecond)); ok {
+		t.Fatalf("handle() reported a duplicate event")
+	}
+
+	at := now.Add(time.Second)
+	if data, _ := k.handle(rawEvent{kind: eventPress, keycode: 40, name: "d"}, at); !data.At.Equal(at) {
+		t.Fatalf("handle() want the press stamped with %v, got %v", at, data.At)
+	}
+}
@@ -19,11 +19,14 @@ import (
 
 const logModule = "hotkeys"
 
+// Manager implements the HotkeyProvider interface with XInput2 raw key events.
+//
+// Raw events carry no modifier state, so the Manager tracks the modifier keys itself and reports them as held with the
+// next key, and reports key releases as well as presses.
 type Manager struct {
-	callback   func(data keyinfo.KeyData)
-	mu         sync.Mutex
-	started    bool
-	lastUpdate time.Time
+	callback func(data keyinfo.KeyData)
+	mu       sync.Mutex
+	started  bool
 }
 
 // SetupHotkeys implements the HotKeyProvider interface to deliver a manager to the caller
@@ -31,9 +34,9 @@ func SetupHotkeys() *Manager {
 	return new(Manager)
 }
 
-// StartHook starts the low level hotkey listener and calls the provided callback when a keypress event is detected
+// StartHook starts the low level hotkey listener and calls the provided callback when a key is pressed or released
 //
-// The underlying low level listener needs to deliver a keycode and the name of the key
+// The underlying low level listener needs to deliver a keycode, the name of the key and whether it was released
 func (x *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
 	logger.Info(logModule, "starting x11 hotkey producer hook")
 	x.mu.Lock()
@@ -62,6 +65,7 @@ func (x *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
 		}
 		logger.Debug(logModule, "x11 display opened and raw event selector installed")
 
+		keys := newKeyState()
 		var ev C.xi2_event
 		for {
 			// Blocking call into C; wakes on key events.
@@ -73,16 +77,18 @@ func (x *Manager) StartHook(callback func(data keyinfo.KeyData)) error {
 				return
 			}
 
+			// modifiers are tracked even while unhooked so a modifier held across StartHook isn't lost
+			data, ok := keys.handle(rawEvent{
+				kind:    int(ev._type),
+				keycode: int(ev.keycode),
+				name:    C.GoString(&ev.name[0]),
+			}, time.Now())
+
 			x.mu.Lock()
 			cb := x.callback
 			x.mu.Unlock()
-			// Dedupe on a timer to ignore events from master/non-master devices (common in VMs)
-			if cb != nil && time.Now().Sub(x.lastUpdate) > time.Millisecond*20 {
-				cb(keyinfo.KeyData{
-					KeyCode:    int(ev.keycode),
-					LocaleName: C.GoString(&ev.name[0]),
-				})
-				x.lastUpdate = time.Now()
+			if ok && cb != nil {
+				cb(data)
 			}
 		}
 	}()
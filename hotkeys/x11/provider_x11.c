@@ -1,3 +1,5 @@
+//go:build linux && x11
+
 #include "provider_x11.h"
 #include <stdio.h>
 #include <string.h>
@@ -25,6 +27,7 @@ static int select_raw(Display *dpy, Window root) {
     em.mask_len = mlen;
     em.mask = g_mask;
     XISetMask(g_mask, XI_RawKeyPress);
+    XISetMask(g_mask, XI_RawKeyRelease);
 
     if (XISelectEvents(dpy, root, &em, 1) != Success) return 3;
     XFlush(dpy);
@@ -70,6 +73,11 @@ int xi2_next(xi2_event *out) {
                 XFreeEventData(g_dpy, cookie); continue;
             }
 
+            // held keys auto-repeat presses, only the first one is a press
+            if (raw->flags & XIKeyRepeat) {
+                XFreeEventData(g_dpy, cookie); continue;
+            }
+
             // simple group/level: group from XKB state; level 0/1 by Shift snapshot
             XkbStateRec st;
             if (XkbGetState(g_dpy, XkbUseCoreKbd, &st) != Success) st.group = 0;
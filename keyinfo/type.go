@@ -1,11 +1,39 @@
 package keyinfo
 
+import (
+	"strings"
+	"time"
+)
+
+// Input is the kind of control a KeyData was read from
+type Input string
+
+const (
+	// InputKey is a keyboard key, it is the zero value so bindings saved before controllers were supported load as keys
+	InputKey Input = ""
+	// InputButton is a gamepad or joystick button
+	InputButton Input = "button"
+	// InputAxis is a gamepad or joystick axis pushed past its threshold in AxisDirection
+	InputAxis Input = "axis"
+)
+
 // KeyData is the Go-friendly struct to capture key code and key name data from the OS
+//
+// For controller inputs KeyCode is the button or axis number and there are never modifiers.
 type KeyData struct {
 	KeyCode             int      `json:"key_code"`
 	LocaleName          string   `json:"locale_name"`
 	Modifiers           []int    `json:"modifiers"`
 	ModifierLocaleNames []string `json:"modifier_locale_names"`
+	Input               Input    `json:"input,omitempty"`
+	// AxisDirection is -1 or 1 for an InputAxis and 0 otherwise
+	AxisDirection int `json:"axis_direction,omitempty"`
+	// Released is set when a provider reports the input being let go rather than pressed.  It describes an event, not
+	// a binding, so it is never saved.
+	Released bool `json:"-"`
+	// At is when the input happened, as close to the hardware as the provider can tell.  Splits are timed from it so
+	// the time spent getting the event to the session isn't added to the segment.  It is zero when unknown.
+	At time.Time `json:"-"`
 }
 
 func NewKeyData(kCode int, localeName string, modifiers []int, modifierLocalNames []string) KeyData {
@@ -24,3 +52,74 @@ func NewKeyData(kCode int, localeName string, modifiers []int, modifierLocalName
 		ModifierLocaleNames: modifierLocalNames,
 	}
 }
+
+// NewButtonData is a press of a controller button
+func NewButtonData(button int, localeName string) KeyData {
+	data := NewKeyData(button, localeName, nil, nil)
+	data.Input = InputButton
+	return data
+}
+
+// NewAxisData is a controller axis pushed past its threshold, direction is -1 or 1
+func NewAxisData(axis int, direction int, localeName string) KeyData {
+	data := NewKeyData(axis, localeName, nil, nil)
+	data.Input = InputAxis
+	data.AxisDirection = direction
+	return data
+}
+
+// Release is the same input being let go
+func (k KeyData) Release() KeyData {
+	k.Released = true
+	return k
+}
+
+// Stamped is the same input with At set
+func (k KeyData) Stamped(at time.Time) KeyData {
+	k.At = at
+	return k
+}
+
+// IsController reports whether the input came from a gamepad or joystick rather than a keyboard
+func (k KeyData) IsController() bool {
+	return k.Input == InputButton || k.Input == InputAxis
+}
+
+// Bound reports whether the KeyData is an input rather than the empty placeholder of an unassigned hotkey
+func (k KeyData) Bound() bool {
+	return k.KeyCode != 0 || k.LocaleName != "" || k.Input != InputKey
+}
+
+// Matches reports whether other is the same chord: the same input with exactly the same modifiers held.  Controller
+// inputs match on their kind and axis direction too, so button 3 is never mistaken for key 3.
+func (k KeyData) Matches(other KeyData) bool {
+	if k.Input != other.Input || k.AxisDirection != other.AxisDirection {
+		return false
+	}
+	if k.KeyCode != other.KeyCode || len(k.Modifiers) != len(other.Modifiers) {
+		return false
+	}
+
+	// Build lookup of pressed modifiers
+	sent := make(map[int]struct{}, len(other.Modifiers))
+	for _, m := range other.Modifiers {
+		sent[m] = struct{}{}
+	}
+
+	// Ensure every required modifier exists
+	for _, required := range k.Modifiers {
+		if _, ok := sent[required]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String is the chord as shown to the user, e.g. "Left Control + S"
+func (k KeyData) String() string {
+	name := k.LocaleName
+	if k.IsController() {
+		name = "Controller " + name
+	}
+	return strings.Join(append(append([]string{}, k.ModifierLocaleNames...), name), " + ")
+}
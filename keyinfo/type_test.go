@@ -0,0 +1,34 @@
+package keyinfo
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	ctrlS := NewKeyData(31, "S", []int{29}, []string{"Left Control"})
+	if !ctrlS.Matches(NewKeyData(31, "S", []int{29}, nil)) {
+		t.Fatalf("Matches() want Ctrl+S to match itself")
+	}
+	if ctrlS.Matches(NewKeyData(31, "S", nil, nil)) {
+		t.Fatalf("Matches() matched S without the binding's modifier")
+	}
+
+	button := NewButtonData(31, "Button 31")
+	if NewKeyData(31, "S", nil, nil).Matches(button) || !button.Matches(button) {
+		t.Fatalf("Matches() want button 31 to match only itself, not key 31")
+	}
+	if NewAxisData(1, 1, "Axis 1+").Matches(NewAxisData(1, -1, "Axis 1-")) {
+		t.Fatalf("Matches() matched an axis pushed the other way")
+	}
+}
+
+func TestString(t *testing.T) {
+	chord := NewKeyData(31, "S", []int{29, 42}, []string{"Left Control", "Left Shift"})
+	if s := chord.String(); s != "Left Control + Left Shift + S" {
+		t.Fatalf("String() want Left Control + Left Shift + S, got %q", s)
+	}
+	if s := NewButtonData(3, "Button 3").String(); s != "Controller Button 3" {
+		t.Fatalf("String() want Controller Button 3, got %q", s)
+	}
+	if (KeyData{}).Bound() || !NewButtonData(0, "Button 0").Bound() {
+		t.Fatalf("Bound() want false only for the empty placeholder")
+	}
+}
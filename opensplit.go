@@ -22,12 +22,15 @@ import (
 	"github.com/zellydev-games/opensplit/bridge"
 	"github.com/zellydev-games/opensplit/config"
 	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/fanout"
 	"github.com/zellydev-games/opensplit/hotkeys"
 	"github.com/zellydev-games/opensplit/logger"
 	"github.com/zellydev-games/opensplit/platform"
+	"github.com/zellydev-games/opensplit/race"
 	"github.com/zellydev-games/opensplit/repo"
 	"github.com/zellydev-games/opensplit/session"
 	"github.com/zellydev-games/opensplit/statemachine"
+	"github.com/zellydev-games/opensplit/textoutput"
 	"github.com/zellydev-games/opensplit/timer"
 
 	"github.com/wailsapp/wails/v2"
@@ -49,7 +52,7 @@ func main() {
 	runtimeProvider := platform.NewWailsRuntime()
 	fileProvider := platform.NewFileRuntime()
 
-	_, logDir, _, _, autoSplittersDir := setupPaths(fileProvider)
+	appDir, logDir, _, _, autoSplittersDir := setupPaths(fileProvider)
 	setupLogging(logDir)
 	logger.Info(logModule, "logging initialized, starting opensplit")
 
@@ -62,15 +65,27 @@ func main() {
 	sessionService, sessionUpdateChannel := session.NewService(timerService)
 	machine := statemachine.InitMachine(runtimeProvider, repoService, sessionService, configService)
 
+	// Share the timer and session update channels between the UI bridges, the text output sink and the race client
+	timerUpdateChannels := fanout.Tee(timerUpdateChannel, 3, 1)
+	sessionUpdateChannels := fanout.Tee(sessionUpdateChannel, 3, 128)
+
 	// Build UI bridges with model update channels
-	timerUIBridge := bridge.NewTimer(timerUpdateChannel, runtimeProvider)
-	sessionUIBridge := bridge.NewSession(sessionUpdateChannel, runtimeProvider)
+	timerUIBridge := bridge.NewTimer(timerUpdateChannels[0], runtimeProvider)
+	sessionUIBridge := bridge.NewSession(sessionUpdateChannels[0], runtimeProvider)
 	configUIBridge := bridge.NewConfig(configUpdateChannel, runtimeProvider)
+	ghostUIBridge := bridge.NewGhost(timerUpdateChannels[2], sessionService, runtimeProvider)
+	textOutputSink := textoutput.NewSink(timerUpdateChannels[1], sessionUpdateChannels[1],
+		configService, fileProvider, filepath.Join(appDir, "Text Output"))
 
 	// Build dispatcher that can receive commands from frontend or backend and dispatch them to the state machine
 	commandDispatcher := dispatcher.NewService(machine, runtimeProvider, autoSplittersDir)
 	remoteControl := autosplitter.NewSocket(commandDispatcher, 6767)
+	startPacketRecording(remoteControl)
 	go remoteControl.Listen()
+	machine.AttachAutosplitterRuntime(autosplitter.NewRuntime(commandDispatcher, sessionService, autoSplittersDir))
+	raceClient := race.NewClient(commandDispatcher, runtimeProvider)
+	raceClient.WatchSession(sessionUpdateChannels[2])
+	machine.AttachRaceClient(raceClient)
 
 	var hotkeyProvider statemachine.HotkeyProvider
 
@@ -101,6 +116,8 @@ func main() {
 			sessionUIBridge.StartUIPump()
 			timerUIBridge.StartUIPump()
 			configUIBridge.StartUIPump()
+			ghostUIBridge.StartUIPump()
+			textOutputSink.Start(ctx)
 
 			startInterruptListener(ctx, hotkeyProvider)
 			runtime.WindowSetAlwaysOnTop(ctx, true)
@@ -204,6 +221,24 @@ func setupPaths(fileProvider repo.FileProvider) (string, string, string, string,
 	return appDir, logDir, skinDir, splitFileDir, autosplittersDir
 }
 
+// startPacketRecording records autosplitter packets to the file named by OPENSPLIT_AUTOSPLITTER_RECORDING, if set.
+//
+// Recordings can be replayed with cmd/osreplay to debug autosplitter integrations.
+func startPacketRecording(socket *autosplitter.Socket) {
+	recordingPath := os.Getenv("OPENSPLIT_AUTOSPLITTER_RECORDING")
+	if recordingPath == "" {
+		return
+	}
+
+	f, err := os.OpenFile(recordingPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Errorf(logModule, "failed to open autosplitter recording: %s", err)
+		return
+	}
+	socket.Record(autosplitter.NewRecorder(f))
+	logger.Infof(logModule, "recording autosplitter packets to %s", recordingPath)
+}
+
 func startInterruptListener(ctx context.Context, hotkeyProvider statemachine.HotkeyProvider) {
 	go func() {
 		ch := make(chan os.Signal, 1)
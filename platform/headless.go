@@ -0,0 +1,136 @@
+package platform
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+const logModule = "platform"
+
+// HeadlessOptions decides how a HeadlessRuntime answers the dialogs the state machine would show a user
+type HeadlessOptions struct {
+	// SplitFile is returned from open and save dialogs for *.osf files
+	SplitFile string
+	// AutosplitterFile is returned from any other open dialog
+	AutosplitterFile string
+	// Answers maps a MessageDialog title to the button that should be "clicked"
+	Answers map[string]string
+	// DefaultAnswer is used for dialogs without an entry in Answers, if empty the dialog's DefaultButton is used
+	DefaultAnswer string
+}
+
+// HeadlessRuntime stands in for WailsRuntime when OpenSplit runs without a window.
+//
+// Dialogs are answered from HeadlessOptions, and the last payload of every emitted event is kept so it can be served
+// to other clients (see LastEvent).
+type HeadlessRuntime struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	options  HeadlessOptions
+	events   map[string]any
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+func NewHeadlessRuntime(options HeadlessOptions) *HeadlessRuntime {
+	return &HeadlessRuntime{
+		options: options,
+		events:  map[string]any{},
+		quit:    make(chan struct{}),
+	}
+}
+
+func (h *HeadlessRuntime) Startup(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ctx = ctx
+}
+
+func (h *HeadlessRuntime) OpenFileDialog(options runtime.OpenDialogOptions) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if isSplitFileDialog(options.Filters) {
+		return h.options.SplitFile, nil
+	}
+	return h.options.AutosplitterFile, nil
+}
+
+func (h *HeadlessRuntime) SaveFileDialog(runtime.SaveDialogOptions) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.options.SplitFile, nil
+}
+
+func (h *HeadlessRuntime) MessageDialog(options runtime.MessageDialogOptions) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	answer, ok := h.options.Answers[options.Title]
+	if !ok {
+		answer = h.options.DefaultAnswer
+	}
+	if answer == "" {
+		answer = options.DefaultButton
+	}
+	logger.Infof(logModule, "answered dialog %q with %q", options.Title, answer)
+	return answer, nil
+}
+
+// SetSplitFile changes the file returned from split file dialogs, so the next LOAD opens a different file
+func (h *HeadlessRuntime) SetSplitFile(path string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.options.SplitFile = path
+}
+
+func (h *HeadlessRuntime) EventsEmit(name string, payload ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(payload) == 1 {
+		h.events[name] = payload[0]
+	} else {
+		h.events[name] = payload
+	}
+}
+
+// LastEvent returns the most recent payload emitted with the given event name
+func (h *HeadlessRuntime) LastEvent(name string) (any, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	payload, ok := h.events[name]
+	return payload, ok
+}
+
+// EventsOn never calls back, there is no frontend to emit events
+func (h *HeadlessRuntime) EventsOn(string, func(...any)) func() {
+	return func() {}
+}
+
+func (h *HeadlessRuntime) WindowGetSize() (int, int) {
+	return 0, 0
+}
+
+func (h *HeadlessRuntime) WindowGetPosition() (int, int) {
+	return 0, 0
+}
+
+func (h *HeadlessRuntime) Quit() {
+	h.quitOnce.Do(func() {
+		close(h.quit)
+	})
+}
+
+// Done is closed once the state machine has asked to quit
+func (h *HeadlessRuntime) Done() <-chan struct{} {
+	return h.quit
+}
+
+func isSplitFileDialog(filters []runtime.FileFilter) bool {
+	return slices.ContainsFunc(filters, func(f runtime.FileFilter) bool {
+		return f.Pattern == "*.osf"
+	})
+}
@@ -0,0 +1,43 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+func TestHeadlessRuntimeDialogs(t *testing.T) {
+	h := NewHeadlessRuntime(HeadlessOptions{
+		SplitFile:        "run.osf",
+		AutosplitterFile: "game.lua",
+		Answers:          map[string]string{"Save New Run Data?": "No"},
+	})
+
+	osf := runtime.OpenDialogOptions{Filters: []runtime.FileFilter{{Pattern: "*.osf"}}}
+	if got, _ := h.OpenFileDialog(osf); got != "run.osf" {
+		t.Fatalf("OpenFileDialog(*.osf) want %s, got %s", "run.osf", got)
+	}
+	if got, _ := h.OpenFileDialog(runtime.OpenDialogOptions{}); got != "game.lua" {
+		t.Fatalf("OpenFileDialog() want %s, got %s", "game.lua", got)
+	}
+
+	if got, _ := h.MessageDialog(runtime.MessageDialogOptions{Title: "Save New Run Data?", DefaultButton: "Yes"}); got != "No" {
+		t.Fatalf("MessageDialog() configured answer want %s, got %s", "No", got)
+	}
+	if got, _ := h.MessageDialog(runtime.MessageDialogOptions{Title: "Other", DefaultButton: "Yes"}); got != "Yes" {
+		t.Fatalf("MessageDialog() default button want %s, got %s", "Yes", got)
+	}
+
+	h.EventsEmit("timer:update", int64(42))
+	if got, ok := h.LastEvent("timer:update"); !ok || got != int64(42) {
+		t.Fatalf("LastEvent() want %d, got %v", 42, got)
+	}
+
+	h.Quit()
+	h.Quit()
+	select {
+	case <-h.Done():
+	default:
+		t.Fatal("Done() not closed after Quit()")
+	}
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package procmem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Attach finds a running process by name and opens its memory for reading.
+//
+// The name is matched against /proc/<pid>/comm and the base name of /proc/<pid>/exe, so both "game" and the
+// truncated 15 character comm value work.  Reading another process's memory requires ptrace permission
+// (same user and kernel.yama.ptrace_scope permitting it, or CAP_SYS_PTRACE).
+func Attach(name string) (*Process, error) {
+	pid, err := findPID(name)
+	if err != nil {
+		return nil, err
+	}
+
+	mapsFile, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := ParseMaps(mapsFile)
+	_ = mapsFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewProcess(pid, name, mem, mappings)
+	p.closer = mem
+	p.alive = func() bool {
+		err := syscall.Kill(pid, 0)
+		return err == nil || errors.Is(err, syscall.EPERM)
+	}
+	return p, nil
+}
+
+func findPID(name string) (int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+
+		if comm, err := os.ReadFile(filepath.Join("/proc", e.Name(), "comm")); err == nil {
+			if strings.TrimSpace(string(comm)) == name {
+				return pid, nil
+			}
+		}
+
+		if exe, err := os.Readlink(filepath.Join("/proc", e.Name(), "exe")); err == nil {
+			if filepath.Base(exe) == name {
+				return pid, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrProcessNotFound, name)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package procmem
+
+// Attach is only implemented on Linux, where process memory is available through /proc/<pid>/mem
+func Attach(string) (*Process, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,223 @@
+package procmem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned by Attach on platforms where reading another process's memory isn't implemented.
+var ErrUnsupported = errors.New("process memory reading is not supported on this platform")
+
+// ErrProcessNotFound is returned by Attach when no running process matches the requested name.
+var ErrProcessNotFound = errors.New("process not found")
+
+// Mapping is a single region from a process memory map (e.g. a line of /proc/<pid>/maps)
+type Mapping struct {
+	Start  uint64
+	End    uint64
+	Offset uint64
+	Perms  string
+	Path   string
+}
+
+// Process is an attached game process whose memory can be read by autosplitters.
+//
+// Memory is read through an io.ReaderAt where the offset is the virtual address in the target process, which is
+// exactly how /proc/<pid>/mem behaves on Linux, and is easy to fake in tests.
+type Process struct {
+	pid      int
+	name     string
+	mem      io.ReaderAt
+	closer   io.Closer
+	mappings []Mapping
+	alive    func() bool
+}
+
+// NewProcess builds a Process from its parts.  Attach should be used to find real processes.
+func NewProcess(pid int, name string, mem io.ReaderAt, mappings []Mapping) *Process {
+	return &Process{
+		pid:      pid,
+		name:     name,
+		mem:      mem,
+		mappings: mappings,
+		alive:    func() bool { return true },
+	}
+}
+
+// PID returns the process identifier of the attached process
+func (p *Process) PID() int { return p.pid }
+
+// Name returns the name the process was attached with
+func (p *Process) Name() string { return p.name }
+
+// Alive reports whether the attached process is still running
+func (p *Process) Alive() bool { return p.alive() }
+
+// Close releases the handle to the process memory
+func (p *Process) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// ModuleBase returns the lowest mapped address of the module (executable or shared library) with the given file name
+func (p *Process) ModuleBase(module string) (uint64, bool) {
+	var base uint64
+	found := false
+	for _, m := range p.mappings {
+		if m.Path == "" || filepath.Base(m.Path) != module {
+			continue
+		}
+		if !found || m.Start < base {
+			base = m.Start
+			found = true
+		}
+	}
+	return base, found
+}
+
+// Read copies size bytes from the target process starting at addr
+func (p *Process) Read(addr uint64, size int) ([]byte, error) {
+	if addr > math.MaxInt64 {
+		return nil, fmt.Errorf("address 0x%x out of range", addr)
+	}
+	buf := make([]byte, size)
+	n, err := p.mem.ReadAt(buf, int64(addr))
+	if n == size {
+		return buf, nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("read %d bytes at 0x%x: %w", size, addr, err)
+}
+
+func (p *Process) ReadU8(addr uint64) (uint8, error) {
+	b, err := p.Read(addr, 1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (p *Process) ReadU16(addr uint64) (uint16, error) {
+	b, err := p.Read(addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (p *Process) ReadU32(addr uint64) (uint32, error) {
+	b, err := p.Read(addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (p *Process) ReadU64(addr uint64) (uint64, error) {
+	b, err := p.Read(addr, 8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (p *Process) ReadF32(addr uint64) (float32, error) {
+	v, err := p.ReadU32(addr)
+	return math.Float32frombits(v), err
+}
+
+func (p *Process) ReadF64(addr uint64) (float64, error) {
+	v, err := p.ReadU64(addr)
+	return math.Float64frombits(v), err
+}
+
+// ReadString reads up to maxLength bytes at addr and returns them up to the first NUL byte
+func (p *Process) ReadString(addr uint64, maxLength int) (string, error) {
+	b, err := p.Read(addr, maxLength)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b), nil
+}
+
+// ReadPointer reads a pointer of pointerSize bytes (4 or 8) at addr
+func (p *Process) ReadPointer(addr uint64, pointerSize int) (uint64, error) {
+	if pointerSize == 4 {
+		v, err := p.ReadU32(addr)
+		return uint64(v), err
+	}
+	return p.ReadU64(addr)
+}
+
+// ResolvePointerPath follows a pointer path the way most autosplitters describe them.
+//
+// Every offset but the last is added to the current address and dereferenced, the last offset is added to the final
+// pointer without dereferencing, so the returned address is where the value itself lives.
+func (p *Process) ResolvePointerPath(base uint64, offsets []int64, pointerSize int) (uint64, error) {
+	addr := base
+	for i, offset := range offsets {
+		addr = uint64(int64(addr) + offset)
+		if i == len(offsets)-1 {
+			break
+		}
+		next, err := p.ReadPointer(addr, pointerSize)
+		if err != nil {
+			return 0, err
+		}
+		if next == 0 {
+			return 0, fmt.Errorf("null pointer at offset index %d", i)
+		}
+		addr = next
+	}
+	return addr, nil
+}
+
+// ParseMaps parses the contents of a /proc/<pid>/maps file
+func ParseMaps(r io.Reader) ([]Mapping, error) {
+	var mappings []Mapping
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		bounds := strings.SplitN(fields[0], "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid address range %q", fields[0])
+		}
+		start, err := strconv.ParseUint(bounds[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		end, err := strconv.ParseUint(bounds[1], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := strconv.ParseUint(fields[2], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		m := Mapping{Start: start, End: end, Offset: offset, Perms: fields[1]}
+		if len(fields) >= 6 {
+			m.Path = strings.Join(fields[5:], " ")
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, scanner.Err()
+}
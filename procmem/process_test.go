@@ -0,0 +1,97 @@
+package procmem
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fakeMemory is a flat block of memory starting at base, addressed like /proc/<pid>/mem
+type fakeMemory struct {
+	base uint64
+	data []byte
+}
+
+func (f *fakeMemory) ReadAt(p []byte, off int64) (int, error) {
+	start := uint64(off)
+	if start < f.base || start >= f.base+uint64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[start-f.base:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+const mapsFixture = `55d0c0a00000-55d0c0a21000 r--p 00000000 103:02 1234   /opt/games/game.x86_64
+55d0c0a21000-55d0c0b00000 r-xp 00021000 103:02 1234   /opt/games/game.x86_64
+7f1e2c000000-7f1e2c021000 rw-p 00000000 00:00 0
+7f1e2d400000-7f1e2d428000 r--p 00000000 103:02 5678   /usr/lib/libc.so.6
+7ffd8e1f0000-7ffd8e211000 rw-p 00000000 00:00 0      [stack]
+`
+
+func TestParseMaps(t *testing.T) {
+	mappings, err := ParseMaps(strings.NewReader(mapsFixture))
+	if err != nil {
+		t.Fatalf("ParseMaps() returned error: %s", err)
+	}
+	if len(mappings) != 5 {
+		t.Fatalf("ParseMaps() mappings want %d, got %d", 5, len(mappings))
+	}
+	if mappings[1].Start != 0x55d0c0a21000 || mappings[1].Offset != 0x21000 || mappings[1].Perms != "r-xp" {
+		t.Fatalf("ParseMaps() unexpected mapping %#v", mappings[1])
+	}
+	if mappings[2].Path != "" {
+		t.Fatalf("ParseMaps() anonymous mapping path want empty, got %q", mappings[2].Path)
+	}
+
+	p := NewProcess(1, "game", nil, mappings)
+	base, ok := p.ModuleBase("game.x86_64")
+	if !ok || base != 0x55d0c0a00000 {
+		t.Fatalf("ModuleBase() want 0x55d0c0a00000, got 0x%x (%v)", base, ok)
+	}
+	if _, ok = p.ModuleBase("missing.so"); ok {
+		t.Fatal("ModuleBase() found a module that isn't mapped")
+	}
+}
+
+func TestReads(t *testing.T) {
+	mem := &fakeMemory{base: 0x1000, data: make([]byte, 0x100)}
+	binary.LittleEndian.PutUint32(mem.data[0x00:], 0xDEADBEEF)
+	binary.LittleEndian.PutUint64(mem.data[0x08:], math.Float64bits(1.5))
+	copy(mem.data[0x10:], "Level 1\x00garbage")
+	// pointer chain: 0x1020 -> 0x1040, 0x1040+0x8 -> 0x1080, value at 0x1080+0x4
+	binary.LittleEndian.PutUint64(mem.data[0x20:], 0x1040)
+	binary.LittleEndian.PutUint64(mem.data[0x48:], 0x1080)
+	binary.LittleEndian.PutUint32(mem.data[0x84:], 7)
+
+	p := NewProcess(1, "game", mem, nil)
+
+	if v, err := p.ReadU32(0x1000); err != nil || v != 0xDEADBEEF {
+		t.Fatalf("ReadU32() want 0xDEADBEEF, got 0x%x (%v)", v, err)
+	}
+	if v, err := p.ReadF64(0x1008); err != nil || v != 1.5 {
+		t.Fatalf("ReadF64() want 1.5, got %f (%v)", v, err)
+	}
+	if v, err := p.ReadString(0x1010, 32); err != nil || v != "Level 1" {
+		t.Fatalf("ReadString() want %q, got %q (%v)", "Level 1", v, err)
+	}
+
+	addr, err := p.ResolvePointerPath(0x1000, []int64{0x20, 0x8, 0x4}, 8)
+	if err != nil || addr != 0x1084 {
+		t.Fatalf("ResolvePointerPath() want 0x1084, got 0x%x (%v)", addr, err)
+	}
+	if v, _ := p.ReadU32(addr); v != 7 {
+		t.Fatalf("value at resolved pointer path want 7, got %d", v)
+	}
+
+	if _, err = p.ReadU32(0x5000); err == nil {
+		t.Fatal("ReadU32() outside mapped memory should fail")
+	}
+	if _, err = p.ResolvePointerPath(0x1000, []int64{0x30, 0x0}, 8); err == nil {
+		t.Fatal("ResolvePointerPath() through a null pointer should fail")
+	}
+}
@@ -0,0 +1,489 @@
+package race
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+// dialTimeout bounds connecting to a coordinator and waiting for it to accept the join
+const dialTimeout = 5 * time.Second
+
+// StandingsEventName is the UI event the Client emits Standings on
+const StandingsEventName = "race:update"
+
+// Dispatcher sends commands to the state machine, in production this is *dispatcher.Service
+type Dispatcher interface {
+	DispatchFrom(dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error)
+}
+
+// EventEmitter sends events to the frontend, in production this is the Wails or headless runtime
+type EventEmitter interface {
+	EventsEmit(string, ...any)
+}
+
+// Standings is the race as shown next to the splits, ordered by who is ahead
+type Standings struct {
+	// Address is the coordinator joined or being joined, empty when not in a race
+	Address string `json:"address"`
+	You     string `json:"you"`
+	// Status is empty when not in a race
+	Status Status `json:"status"`
+	// CountdownEndsAt is when the run starts in unix milliseconds, zero outside of a countdown
+	CountdownEndsAt int64      `json:"countdown_ends_at"`
+	Racers          []Standing `json:"racers"`
+	Error           string     `json:"error"`
+}
+
+// Standing is one racer's place in the race.
+//
+// Delta compares the racer's latest split to yours for the same segment, positive when they were slower.  It is nil
+// when you haven't reached that segment yet.
+type Standing struct {
+	Name         string `json:"name"`
+	You          bool   `json:"you"`
+	Ready        bool   `json:"ready"`
+	Finished     bool   `json:"finished"`
+	Forfeited    bool   `json:"forfeited"`
+	SegmentIndex int    `json:"segment_index"`
+	SegmentName  string `json:"segment_name"`
+	Time         int64  `json:"time"`
+	Delta        *int64 `json:"delta"`
+}
+
+// Client joins a race on a Coordinator for one OpenSplit instance.
+//
+// It starts the run through the Dispatcher when the countdown ends, streams the run's splits to the coordinator as
+// the session reports them, and forfeits if the run is reset.  Standings are emitted to the frontend on every change.
+type Client struct {
+	dispatcher Dispatcher
+	emitter    EventEmitter
+	mu         sync.Mutex
+	conn       net.Conn
+	encoder    *json.Encoder
+	address    string
+	name       string
+	state      State
+	lastError  string
+	// joining is set while Join connects, joinAttempt changes on every Leave so a Join can tell it was abandoned
+	joining     bool
+	joinAttempt int
+	joinResult  chan error
+	joinSentAt  time.Time
+	// clockOffset is how far the coordinator's clock is ahead of ours, estimated when joining
+	clockOffset time.Duration
+
+	countdown       *time.Timer
+	countdownEndsAt time.Time
+	racing          bool
+	started         bool
+	runID           uuid.UUID
+	sent            map[int]bool
+	finished        bool
+	sessionState    session.State
+}
+
+// NewClient creates a Client that isn't in a race
+func NewClient(d Dispatcher, emitter EventEmitter) *Client {
+	return &Client{
+		dispatcher: d,
+		emitter:    emitter,
+	}
+}
+
+// Join connects to the coordinator at address and joins its race as name.
+//
+// Connecting and waiting for the coordinator to accept happen without holding the client, so Leave can abandon a
+// join in progress.  A failed join is reported in the standings' Error as well as returned.
+func (c *Client) Join(address string, name string) error {
+	c.mu.Lock()
+	if c.conn != nil || c.joining {
+		c.mu.Unlock()
+		return errors.New("already in a race, leave it first")
+	}
+	if name == "" {
+		c.mu.Unlock()
+		return errors.New("a racer name is required")
+	}
+	c.joining = true
+	c.joinAttempt++
+	attempt := c.joinAttempt
+	c.address = address
+	c.name = name
+	c.lastError = ""
+	c.emitLocked()
+	c.mu.Unlock()
+
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+
+	c.mu.Lock()
+	if c.joinAttempt != attempt {
+		c.mu.Unlock()
+		if conn != nil {
+			_ = conn.Close()
+		}
+		return errors.New("left the race before joining it")
+	}
+	c.joining = false
+	if err != nil {
+		c.lastError = err.Error()
+		c.emitLocked()
+		c.mu.Unlock()
+		return err
+	}
+	result := make(chan error, 1)
+	c.conn = conn
+	c.encoder = json.NewEncoder(conn)
+	c.state = State{}
+	c.joinResult = result
+	c.clockOffset = 0
+	c.joinSentAt = time.Now()
+	err = c.sendLocked(Message{Type: MessageJoin, Racer: name})
+	c.mu.Unlock()
+	if err != nil {
+		c.failJoin(attempt, err)
+		return err
+	}
+	go c.readLoop(conn)
+
+	select {
+	case err = <-result:
+	case <-time.After(dialTimeout):
+		err = errors.New("the coordinator didn't answer")
+	}
+	if err != nil {
+		c.failJoin(attempt, err)
+		return err
+	}
+	logger.Infof(logModule, "joined race at %s as %q", address, name)
+	return nil
+}
+
+// failJoin leaves the race a Join couldn't complete, keeping why in the standings unless the user left it first
+func (c *Client) failJoin(attempt int, err error) {
+	c.mu.Lock()
+	if c.joinAttempt == attempt {
+		c.lastError = err.Error()
+	}
+	c.mu.Unlock()
+	c.Leave()
+}
+
+// Leave disconnects from the race, forfeiting if a run is in progress.  A Join still connecting is abandoned.
+func (c *Client) Leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joinAttempt++
+	if c.joining {
+		c.joining = false
+		c.emitLocked()
+		logger.Info(logModule, "abandoned joining race")
+		return
+	}
+	if c.conn == nil {
+		return
+	}
+	if c.racing && !c.finished {
+		_ = c.sendLocked(Message{Type: MessageForfeit})
+	}
+	_ = c.conn.Close()
+	c.disconnectLocked()
+	logger.Info(logModule, "left race")
+}
+
+// InRace reports whether the client is connected to a coordinator, or connecting to one
+func (c *Client) InRace() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil || c.joining
+}
+
+// Ready tells the coordinator whether this racer is ready to start
+func (c *Client) Ready(ready bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errors.New("not in a race")
+	}
+	return c.sendLocked(Message{Type: MessageReady, Ready: ready})
+}
+
+// WatchSession streams the runs in session updates to the race until updates is closed
+func (c *Client) WatchSession(updates <-chan *session.Service) {
+	go func() {
+		for s := range updates {
+			c.sessionUpdated(s)
+		}
+	}()
+}
+
+// Standings returns the race as it should be shown to the user
+func (c *Client) Standings() Standings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.standingsLocked()
+}
+
+func (c *Client) readLoop(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			logger.Warnf(logModule, "invalid message from coordinator: %s", err)
+			continue
+		}
+		c.receive(message)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		logger.Warn(logModule, "lost connection to race coordinator")
+		c.disconnectLocked()
+	}
+}
+
+func (c *Client) receive(message Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.emitLocked()
+
+	switch message.Type {
+	case MessageState:
+		if message.Race == nil {
+			return
+		}
+		c.state = *message.Race
+		if c.joinResult != nil && slices.ContainsFunc(c.state.Racers, func(r Racer) bool { return r.Name == c.name }) {
+			c.estimateClockOffsetLocked(message.SentAt)
+			c.joinResult <- nil
+			c.joinResult = nil
+		}
+		if c.state.Status != StatusRunning {
+			c.countdownEndsAt = time.Time{}
+		}
+	case MessageCountdown:
+		// every racer starts at the same moment however long the countdown took to reach them
+		startAt := time.UnixMilli(message.StartAt).Add(-c.clockOffset)
+		c.racing = true
+		c.started = false
+		c.finished = false
+		c.runID = uuid.Nil
+		c.sent = map[int]bool{}
+		c.countdownEndsAt = startAt
+		c.countdown = time.AfterFunc(max(time.Until(startAt), 0), c.start)
+		logger.Infof(logModule, "race starts in %s", time.Until(startAt).Round(time.Millisecond))
+	case MessageError:
+		logger.Warnf(logModule, "race coordinator: %s", message.Error)
+		c.lastError = message.Error
+		if c.joinResult != nil {
+			c.joinResult <- errors.New(message.Error)
+			c.joinResult = nil
+		}
+	}
+}
+
+// estimateClockOffsetLocked estimates the coordinator's clock offset from the sent_at of the state that accepted the
+// join, taking it to be sent halfway between sending the join and receiving the answer
+func (c *Client) estimateClockOffsetLocked(sentAt int64) {
+	if sentAt == 0 || c.joinSentAt.IsZero() {
+		return
+	}
+	roundTrip := time.Since(c.joinSentAt)
+	c.clockOffset = time.UnixMilli(sentAt).Sub(c.joinSentAt.Add(roundTrip / 2))
+	logger.Debugf(logModule, "coordinator clock is %s ahead, round trip %s", c.clockOffset, roundTrip)
+}
+
+// start begins the run when the countdown ends
+func (c *Client) start() {
+	c.mu.Lock()
+	if !c.racing {
+		c.mu.Unlock()
+		return
+	}
+	source := dispatcher.Source{Kind: dispatcher.SourceRace, Detail: c.address}
+	stale := c.sessionState != session.Idle
+	c.countdownEndsAt = time.Time{}
+	c.mu.Unlock()
+
+	// a run left over from before the race would be split instead of started, it is reset without waiting for the
+	// reset confirmation guard
+	if stale {
+		confirm := dispatcher.ResetConfirmPayload
+		_, _ = c.dispatcher.DispatchFrom(source, dispatcher.RESET, &confirm)
+	}
+	_, _ = c.dispatcher.DispatchFrom(source, dispatcher.SPLIT, nil)
+
+	c.mu.Lock()
+	c.started = true
+	c.emitLocked()
+	c.mu.Unlock()
+	logger.Info(logModule, "race started")
+}
+
+// sessionUpdated sends the splits of the race run that the coordinator hasn't seen yet
+func (c *Client) sessionUpdated(s *session.Service) {
+	state := s.State()
+	run, ok := s.Run()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionState = state
+	if c.conn == nil || !c.racing || !c.started || c.finished {
+		return
+	}
+
+	if c.runID == uuid.Nil && ok && state != session.Idle {
+		c.runID = run.ID
+	}
+	if !ok || run.ID != c.runID {
+		logger.Info(logModule, "race run was reset, forfeiting")
+		_ = c.sendLocked(Message{Type: MessageForfeit})
+		c.racing = false
+		return
+	}
+
+	for i, segment := range run.LeafSegments {
+		split, ok := run.Splits[segment.ID]
+		if !ok || c.sent[i] {
+			continue
+		}
+		c.sent[i] = true
+		_ = c.sendLocked(Message{Type: MessageSplit, Split: &Split{
+			SegmentIndex: i,
+			SegmentName:  segment.Name,
+			Time:         split.CurrentCumulative.Milliseconds(),
+		}})
+	}
+
+	if run.Completed {
+		c.finished = true
+		_ = c.sendLocked(Message{Type: MessageFinish, Time: run.TotalTime.Milliseconds()})
+	}
+}
+
+func (c *Client) sendLocked(message Message) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	err := c.encoder.Encode(message)
+	if err != nil {
+		logger.Errorf(logModule, "failed to send %s to race coordinator: %s", message.Type, err)
+	}
+	return err
+}
+
+// disconnectLocked forgets the race after the connection is closed
+func (c *Client) disconnectLocked() {
+	if c.countdown != nil {
+		c.countdown.Stop()
+		c.countdown = nil
+	}
+	if c.joinResult != nil {
+		c.joinResult <- errors.New("disconnected from the race coordinator")
+		c.joinResult = nil
+	}
+	c.conn = nil
+	c.encoder = nil
+	c.state = State{}
+	c.racing = false
+	c.countdownEndsAt = time.Time{}
+	c.emitLocked()
+}
+
+func (c *Client) emitLocked() {
+	if c.emitter != nil {
+		c.emitter.EventsEmit(StandingsEventName, c.standingsLocked())
+	}
+}
+
+func (c *Client) standingsLocked() Standings {
+	standings := Standings{
+		Address: c.address,
+		You:     c.name,
+		Status:  c.state.Status,
+		Racers:  []Standing{},
+		Error:   c.lastError,
+	}
+	if c.conn == nil {
+		// the address is kept while joining so the frontend can say where it's connecting to
+		if c.joining {
+			return Standings{Address: c.address, You: c.name, Racers: []Standing{}}
+		}
+		return Standings{Racers: []Standing{}, Error: c.lastError}
+	}
+	if !c.countdownEndsAt.IsZero() {
+		standings.CountdownEndsAt = c.countdownEndsAt.UnixMilli()
+	}
+
+	var you Racer
+	for _, racer := range c.state.Racers {
+		if racer.Name == c.name {
+			you = racer
+		}
+	}
+
+	for _, racer := range c.state.Racers {
+		standing := Standing{
+			Name:         racer.Name,
+			You:          racer.Name == c.name,
+			Ready:        racer.Ready,
+			Finished:     racer.Finished,
+			Forfeited:    racer.Forfeited,
+			SegmentIndex: -1,
+		}
+		if split, ok := racer.lastSplit(); ok {
+			standing.SegmentIndex = split.SegmentIndex
+			standing.SegmentName = split.SegmentName
+			standing.Time = split.Time
+			if yours, ok := you.splitAt(split.SegmentIndex); ok {
+				delta := split.Time - yours.Time
+				standing.Delta = &delta
+			}
+		}
+		if racer.Finished {
+			standing.Time = racer.FinalTime
+			if you.Finished {
+				delta := racer.FinalTime - you.FinalTime
+				standing.Delta = &delta
+			}
+		}
+		standings.Racers = append(standings.Racers, standing)
+	}
+
+	slices.SortStableFunc(standings.Racers, compareStandings)
+	return standings
+}
+
+// compareStandings orders finishers by time, then racers by how far they've got, then forfeits
+func compareStandings(a, b Standing) int {
+	rank := func(s Standing) int {
+		switch {
+		case s.Finished:
+			return 0
+		case s.Forfeited:
+			return 2
+		}
+		return 1
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	if !a.Finished && a.SegmentIndex != b.SegmentIndex {
+		return b.SegmentIndex - a.SegmentIndex
+	}
+	switch {
+	case a.Time < b.Time:
+		return -1
+	case a.Time > b.Time:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,291 @@
+package race
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// writeTimeout stops one stalled racer from holding up messages to everyone else
+const writeTimeout = 5 * time.Second
+
+// MinRacers is how many racers must be ready before a race starts
+const MinRacers = 2
+
+// Coordinator hosts races between Clients.
+//
+// It keeps one race at a time.  Racers join while the race is open, the countdown starts when at least MinRacers have
+// joined and all of them are ready, and the race finishes once everyone has finished or forfeited.
+type Coordinator struct {
+	countdown time.Duration
+	mu        sync.Mutex
+	listener  net.Listener
+	status    Status
+	racers    []*racerConn
+}
+
+// racerConn is a racer and the connection the coordinator talks to it on
+type racerConn struct {
+	Racer
+	conn    net.Conn
+	encoder *json.Encoder
+	joined  bool
+}
+
+// NewCoordinator creates a Coordinator that counts down for countdown before starting each race
+func NewCoordinator(countdown time.Duration) *Coordinator {
+	return &Coordinator{
+		countdown: countdown,
+		status:    StatusOpen,
+	}
+}
+
+// Listen serves races on address until Close is called
+func (c *Coordinator) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return c.Serve(listener)
+}
+
+// Serve accepts racers on listener until Close is called
+func (c *Coordinator) Serve(listener net.Listener) error {
+	c.mu.Lock()
+	c.listener = listener
+	c.mu.Unlock()
+
+	logger.Infof(logModule, "race coordinator listening on %s", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go c.handle(conn)
+	}
+}
+
+// Addr is the address the coordinator is listening on, nil before Listen or Serve
+func (c *Coordinator) Addr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listener == nil {
+		return nil
+	}
+	return c.listener.Addr()
+}
+
+// Close stops accepting racers and disconnects everyone
+func (c *Coordinator) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, racer := range c.racers {
+		_ = racer.conn.Close()
+	}
+	if c.listener == nil {
+		return nil
+	}
+	return c.listener.Close()
+}
+
+// State returns a copy of the race
+func (c *Coordinator) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateLocked()
+}
+
+func (c *Coordinator) handle(conn net.Conn) {
+	racer := &racerConn{conn: conn, encoder: json.NewEncoder(conn)}
+	c.mu.Lock()
+	c.racers = append(c.racers, racer)
+	c.mu.Unlock()
+	logger.Infof(logModule, "racer connected from %s", conn.RemoteAddr())
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			c.sendError(racer, fmt.Sprintf("invalid message: %s", err))
+			continue
+		}
+
+		c.mu.Lock()
+		err := c.receiveLocked(racer, message)
+		if err != nil {
+			c.sendLocked(racer, Message{Type: MessageError, Error: err.Error()})
+		} else {
+			c.broadcastLocked(Message{Type: MessageState})
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.racers {
+		if c.racers[i] == racer {
+			c.racers = append(c.racers[:i], c.racers[i+1:]...)
+			break
+		}
+	}
+	_ = conn.Close()
+	logger.Infof(logModule, "racer %q disconnected", racer.Name)
+	if c.status == StatusOpen {
+		c.startIfReadyLocked()
+	}
+	c.checkFinishedLocked()
+	c.broadcastLocked(Message{Type: MessageState})
+}
+
+// receiveLocked applies a racer's message to the race, it must be called under lock
+func (c *Coordinator) receiveLocked(racer *racerConn, message Message) error {
+	if message.Type != MessageJoin && !racer.joined {
+		return errors.New("join the race first")
+	}
+
+	switch message.Type {
+	case MessageJoin:
+		if racer.joined {
+			return errors.New("already joined")
+		}
+		if message.Racer == "" {
+			return errors.New("a racer name is required")
+		}
+		if c.status == StatusRunning {
+			return errors.New("the race has already started")
+		}
+		for _, other := range c.racers {
+			if other.joined && other.Name == message.Racer {
+				return fmt.Errorf("the name %q is taken", message.Racer)
+			}
+		}
+		racer.Name = message.Racer
+		racer.joined = true
+		logger.Infof(logModule, "racer %q joined", racer.Name)
+	case MessageReady:
+		if c.status == StatusRunning {
+			return errors.New("the race has already started")
+		}
+		if c.status == StatusFinished {
+			c.openLocked()
+		}
+		racer.Ready = message.Ready
+		c.startIfReadyLocked()
+	case MessageSplit:
+		if c.status != StatusRunning || racer.done() || message.Split == nil {
+			return errors.New("not racing")
+		}
+		racer.Splits = append(racer.Splits, *message.Split)
+	case MessageFinish:
+		if c.status != StatusRunning || racer.done() {
+			return errors.New("not racing")
+		}
+		racer.Finished = true
+		racer.FinalTime = message.Time
+		logger.Infof(logModule, "racer %q finished in %dms", racer.Name, message.Time)
+		c.checkFinishedLocked()
+	case MessageForfeit:
+		if c.status != StatusRunning || racer.done() {
+			return errors.New("not racing")
+		}
+		racer.Forfeited = true
+		logger.Infof(logModule, "racer %q forfeited", racer.Name)
+		c.checkFinishedLocked()
+	default:
+		return fmt.Errorf("unknown message type %q", message.Type)
+	}
+	return nil
+}
+
+// openLocked clears the last race's results so a new one can be readied
+func (c *Coordinator) openLocked() {
+	c.status = StatusOpen
+	for _, racer := range c.racers {
+		racer.Racer = Racer{Name: racer.Name}
+	}
+	logger.Info(logModule, "new race opened")
+}
+
+// startIfReadyLocked sends the countdown once enough racers have joined and all of them are ready
+func (c *Coordinator) startIfReadyLocked() {
+	joined := 0
+	for _, racer := range c.racers {
+		if !racer.joined {
+			continue
+		}
+		if !racer.Ready {
+			return
+		}
+		joined++
+	}
+	if joined < MinRacers {
+		return
+	}
+
+	c.status = StatusRunning
+	logger.Infof(logModule, "race starting with %d racers in %s", joined, c.countdown)
+	c.broadcastLocked(Message{Type: MessageCountdown, StartAt: time.Now().Add(c.countdown).UnixMilli()})
+}
+
+// checkFinishedLocked finishes a running race once every racer is done
+func (c *Coordinator) checkFinishedLocked() {
+	if c.status != StatusRunning {
+		return
+	}
+	for _, racer := range c.racers {
+		if racer.joined && !racer.done() {
+			return
+		}
+	}
+	c.status = StatusFinished
+	logger.Info(logModule, "race finished")
+}
+
+func (c *Coordinator) stateLocked() State {
+	state := State{Status: c.status, Racers: []Racer{}}
+	for _, racer := range c.racers {
+		if !racer.joined {
+			continue
+		}
+		r := racer.Racer
+		r.Splits = append([]Split{}, racer.Splits...)
+		state.Racers = append(state.Racers, r)
+	}
+	return state
+}
+
+// broadcastLocked sends message to every joined racer, state messages get the current race filled in
+func (c *Coordinator) broadcastLocked(message Message) {
+	if message.Type == MessageState {
+		state := c.stateLocked()
+		message.Race = &state
+	}
+	for _, racer := range c.racers {
+		if racer.joined {
+			c.sendLocked(racer, message)
+		}
+	}
+}
+
+func (c *Coordinator) sendLocked(racer *racerConn, message Message) {
+	message.SentAt = time.Now().UnixMilli()
+	_ = racer.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := racer.encoder.Encode(message); err != nil {
+		logger.Warnf(logModule, "failed to send %s to %q: %s", message.Type, racer.Name, err)
+	}
+}
+
+func (c *Coordinator) sendError(racer *racerConn, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendLocked(racer, Message{Type: MessageError, Error: text})
+}
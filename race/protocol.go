@@ -0,0 +1,111 @@
+// Package race lets OpenSplit instances race each other through a small coordinator.
+//
+// Racers connect to the Coordinator over TCP and exchange newline delimited JSON Messages:
+//
+//	racer -> coordinator  join {racer}, ready {ready}, split {split}, finish {time}, forfeit
+//	coordinator -> racer  state {race}, countdown {start_at}, error {error}
+//
+// Every coordinator message carries sent_at, the coordinator's clock when it was sent.  A Client estimates how far
+// its clock is from the coordinator's when it joins, from the sent_at of the state that accepts the join.
+//
+// Once every racer is ready the coordinator sends a countdown with the start time on its own clock, each Client
+// starts its run at that time on its clock and streams its splits back.  Every change is answered with a state
+// message holding the whole race, so a racer that misses a message catches up on the next one.  The coordinator is
+// run with cmd/opensplit-race.
+package race
+
+import "time"
+
+const logModule = "race"
+
+// DefaultAddress is where cmd/opensplit-race listens unless told otherwise
+const DefaultAddress = "127.0.0.1:6769"
+
+// DefaultCountdown is the time between the last racer readying up and the start
+const DefaultCountdown = 10 * time.Second
+
+// MessageType identifies a Message
+type MessageType string
+
+const (
+	MessageJoin      MessageType = "join"
+	MessageReady     MessageType = "ready"
+	MessageSplit     MessageType = "split"
+	MessageFinish    MessageType = "finish"
+	MessageForfeit   MessageType = "forfeit"
+	MessageState     MessageType = "state"
+	MessageCountdown MessageType = "countdown"
+	MessageError     MessageType = "error"
+)
+
+// Status is the phase a race is in
+type Status string
+
+const (
+	// StatusOpen races accept new racers and wait for everyone to ready up
+	StatusOpen Status = "open"
+	// StatusRunning races have started counting down or are being run
+	StatusRunning Status = "running"
+	// StatusFinished races have every racer finished or forfeited, readying up again opens a new race
+	StatusFinished Status = "finished"
+)
+
+// Message is one line of the race protocol, only the fields for its Type are set.  StartAt and SentAt are unix
+// milliseconds on the coordinator's clock.
+type Message struct {
+	Type    MessageType `json:"type"`
+	Racer   string      `json:"racer,omitempty"`
+	Ready   bool        `json:"ready,omitempty"`
+	Split   *Split      `json:"split,omitempty"`
+	Time    int64       `json:"time,omitempty"`
+	StartAt int64       `json:"start_at,omitempty"`
+	Race    *State      `json:"race,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	SentAt  int64       `json:"sent_at,omitempty"`
+}
+
+// Split is a racer finishing a segment, Time is the cumulative run time in milliseconds
+type Split struct {
+	SegmentIndex int    `json:"segment_index"`
+	SegmentName  string `json:"segment_name"`
+	Time         int64  `json:"time"`
+}
+
+// Racer is one racer's progress through the race
+type Racer struct {
+	Name      string  `json:"name"`
+	Ready     bool    `json:"ready"`
+	Splits    []Split `json:"splits"`
+	Finished  bool    `json:"finished"`
+	Forfeited bool    `json:"forfeited"`
+	FinalTime int64   `json:"final_time"`
+}
+
+// State is the whole race as the coordinator sees it, racers are in the order they joined
+type State struct {
+	Status Status  `json:"status"`
+	Racers []Racer `json:"racers"`
+}
+
+// done reports whether the racer has stopped racing
+func (r Racer) done() bool {
+	return r.Finished || r.Forfeited
+}
+
+// lastSplit returns the racer's furthest split
+func (r Racer) lastSplit() (Split, bool) {
+	if len(r.Splits) == 0 {
+		return Split{}, false
+	}
+	return r.Splits[len(r.Splits)-1], true
+}
+
+// splitAt returns the racer's split for segmentIndex
+func (r Racer) splitAt(segmentIndex int) (Split, bool) {
+	for _, split := range r.Splits {
+		if split.SegmentIndex == segmentIndex {
+			return split, true
+		}
+	}
+	return Split{}, false
+}
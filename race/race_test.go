@@ -0,0 +1,258 @@
+package race
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+type fakeTimer struct {
+	mu  sync.Mutex
+	now time.Duration
+}
+
+func (t *fakeTimer) Startup(context.Context)    {}
+func (t *fakeTimer) IsRunning() bool            { return true }
+func (t *fakeTimer) Run()                       {}
+func (t *fakeTimer) Start()                     {}
+func (t *fakeTimer) Pause()                     {}
+func (t *fakeTimer) Reset()                     {}
+func (t *fakeTimer) SubtractTime(time.Duration) {}
+func (t *fakeTimer) StartAt(time.Time)          {}
+func (t *fakeTimer) GetCurrentTime() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now
+}
+func (t *fakeTimer) TimeAt(time.Time) time.Duration {
+	return t.GetCurrentTime()
+}
+func (t *fakeTimer) set(now time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// sessionDispatcher applies the commands a Client dispatches to a session, like the state machine would
+type sessionDispatcher struct {
+	session *session.Service
+}
+
+func (d sessionDispatcher) DispatchFrom(_ dispatcher.Source, command dispatcher.Command, _ *string) (dispatcher.DispatchReply, error) {
+	switch command {
+	case dispatcher.SPLIT:
+		d.session.Split(session.SplitSource{Kind: string(dispatcher.SourceRace)})
+	case dispatcher.RESET:
+		d.session.Reset()
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+type racer struct {
+	client  *Client
+	session *session.Service
+	timer   *fakeTimer
+}
+
+func newRacer(t *testing.T) racer {
+	t.Helper()
+	timer := &fakeTimer{}
+	s, updates := session.NewService(timer)
+	s.SetLoadedSplitFile(session.SplitFile{ID: uuid.New(), Segments: []session.Segment{
+		{ID: uuid.New(), Name: "Level 1"},
+		{ID: uuid.New(), Name: "Level 2"},
+	}})
+
+	// every split happens a second after the last
+	now := time.Now()
+	s.SetClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+
+	client := NewClient(sessionDispatcher{s}, nil)
+	client.WatchSession(updates)
+	t.Cleanup(client.Leave)
+	return racer{client: client, session: s, timer: timer}
+}
+
+func (r racer) split(at time.Duration) {
+	r.timer.set(at)
+	r.session.Split(session.SplitSource{})
+}
+
+func startCoordinator(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coordinator := NewCoordinator(50 * time.Millisecond)
+	go func() { _ = coordinator.Serve(listener) }()
+	t.Cleanup(func() { _ = coordinator.Close() })
+	return listener.Addr().String()
+}
+
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func standing(r racer, name string) Standing {
+	for _, s := range r.client.Standings().Racers {
+		if s.Name == name {
+			return s
+		}
+	}
+	return Standing{}
+}
+
+func TestJoin(t *testing.T) {
+	address := startCoordinator(t)
+	a, b := newRacer(t), newRacer(t)
+	if err := a.client.Join(address, "a"); err != nil {
+		t.Fatalf("Join() returned error: %s", err)
+	}
+	if err := b.client.Join(address, "a"); err == nil {
+		t.Fatalf("Join() with a taken name want error, got nil")
+	}
+	if b.client.InRace() {
+		t.Fatalf("InRace() after a failed join want false")
+	}
+	if b.client.Standings().Error == "" {
+		t.Fatalf("Standings() after a failed join want the error")
+	}
+	if err := b.client.Join(address, "b"); err != nil {
+		t.Fatalf("Join() returned error: %s", err)
+	}
+	eventually(t, "both racers in a's standings", func() bool { return len(a.client.Standings().Racers) == 2 })
+}
+
+func TestLeaveWhileJoining(t *testing.T) {
+	// a coordinator that accepts the connection but never answers the join
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	r := newRacer(t)
+	joined := make(chan error, 1)
+	go func() { joined <- r.client.Join(listener.Addr().String(), "a") }()
+	eventually(t, "the join to start", r.client.InRace)
+
+	r.client.Leave()
+	select {
+	case err = <-joined:
+		if err == nil {
+			t.Fatal("Join() abandoned by Leave() want error, got nil")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Join() didn't return after Leave()")
+	}
+	if r.client.InRace() || r.client.Standings().Error != "" {
+		t.Fatalf("leaving a join want no race and no error, got %+v", r.client.Standings())
+	}
+}
+
+func TestRace(t *testing.T) {
+	address := startCoordinator(t)
+	a, b := newRacer(t), newRacer(t)
+	_ = a.client.Join(address, "a")
+	_ = b.client.Join(address, "b")
+
+	_ = a.client.Ready(true)
+	if a.session.State() != session.Idle {
+		t.Fatalf("race started with one racer ready")
+	}
+	_ = b.client.Ready(true)
+	eventually(t, "the countdown to start both runs", func() bool {
+		return a.session.State() == session.Running && b.session.State() == session.Running
+	})
+
+	a.split(time.Second)
+	eventually(t, "a's split to reach b", func() bool { return standing(b, "a").SegmentIndex == 0 })
+	if s := standing(b, "a"); s.Time != 1000 || s.Delta != nil {
+		t.Fatalf("a's standing before b splits want 1000ms with no delta, got %+v", s)
+	}
+
+	b.split(3 * time.Second)
+	eventually(t, "b's split to reach the coordinator", func() bool { return standing(b, "b").SegmentIndex == 0 })
+	if s := standing(b, "a"); s.Delta == nil || *s.Delta != -2000 {
+		t.Fatalf("a's delta against b want -2000ms, got %+v", s)
+	}
+
+	a.split(2 * time.Second)
+	eventually(t, "a to finish", func() bool { return standing(b, "a").Finished })
+	if standings := b.client.Standings(); standings.Racers[0].Name != "a" || standings.Status != StatusRunning {
+		t.Fatalf("standings want a first in a running race, got %+v", standings)
+	}
+
+	b.session.Reset()
+	eventually(t, "b to forfeit and the race to finish", func() bool {
+		return standing(a, "b").Forfeited && a.client.Standings().Status == StatusFinished
+	})
+}
+
+type recordingDispatcher struct {
+	mu       sync.Mutex
+	commands []dispatcher.Command
+	payloads []*string
+}
+
+func (d *recordingDispatcher) DispatchFrom(
+	_ dispatcher.Source, command dispatcher.Command, payload *string,
+) (dispatcher.DispatchReply, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = append(d.commands, command)
+	d.payloads = append(d.payloads, payload)
+	return dispatcher.DispatchReply{}, nil
+}
+
+func TestCountdownStartsOnCoordinatorClock(t *testing.T) {
+	d := &recordingDispatcher{}
+	c := NewClient(d, nil)
+	c.clockOffset = time.Hour
+	c.sessionState = session.Running
+
+	start := time.Now()
+	c.receive(Message{Type: MessageCountdown, StartAt: start.Add(time.Hour + 100*time.Millisecond).UnixMilli()})
+	c.mu.Lock()
+	ends := c.countdownEndsAt.UnixMilli()
+	c.mu.Unlock()
+	if ends < start.Add(50*time.Millisecond).UnixMilli() ||
+		ends > start.Add(150*time.Millisecond).UnixMilli() {
+		t.Fatalf("countdown want to end 100ms from now on our clock, got %dms", ends-start.UnixMilli())
+	}
+
+	eventually(t, "the run to start", func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return len(d.commands) == 2
+	})
+	if d.commands[0] != dispatcher.RESET || d.payloads[0] == nil || *d.payloads[0] != dispatcher.ResetConfirmPayload {
+		t.Fatalf("leftover run want a confirmed RESET, got %v", d.commands)
+	}
+	if d.commands[1] != dispatcher.SPLIT {
+		t.Fatalf("race start want SPLIT after the RESET, got %v", d.commands)
+	}
+}
@@ -10,8 +10,28 @@ func ConfigToFrontEnd(configService *config.Service) ([]byte, error) {
 	return json.Marshal(configService)
 }
 
-func FrontEndToConfig(configServiceBytes []byte) (*config.Service, error) {
-	var configService config.Service
-	err := json.Unmarshal(configServiceBytes, &configService)
-	return &configService, err
+// FrontEndToConfig reads a config file of any schema version, see config.Decode
+func FrontEndToConfig(configServiceBytes []byte) (*config.Service, config.LoadReport, error) {
+	return config.Decode(configServiceBytes)
+}
+
+// versionedProfile is a config profile as stored, with the schema version its settings were saved with
+type versionedProfile struct {
+	Version int `json:"version"`
+	config.Profile
+}
+
+// ProfilesToFrontEnd marshals the config profiles by name
+func ProfilesToFrontEnd(profiles map[string]config.Profile) ([]byte, error) {
+	stored := make(map[string]versionedProfile, len(profiles))
+	for name, profile := range profiles {
+		stored[name] = versionedProfile{Version: config.SchemaVersion, Profile: profile}
+	}
+	return json.Marshal(stored)
+}
+
+// FrontEndToProfiles reads config profiles by name saved with any schema version, see config.DecodeProfiles.  No
+// data is no profiles.
+func FrontEndToProfiles(profilesBytes []byte) (map[string]config.Profile, config.LoadReport, error) {
+	return config.DecodeProfiles(profilesBytes)
 }
@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"github.com/google/uuid"
 	"github.com/zellydev-games/opensplit/dto"
 	"github.com/zellydev-games/opensplit/session"
 )
@@ -19,6 +20,32 @@ func DomainToDTO(svc *session.Service) *dto.Session {
 		dtoRun = &r
 	}
 
+	var dtoPractice *dto.Practice
+	if practice, ok := svc.Practice(); ok {
+		dtoPractice = &dto.Practice{
+			StartIndex:  practice.StartIndex,
+			EndIndex:    practice.EndIndex,
+			UpdateGolds: practice.UpdateGolds,
+		}
+	}
+
+	ghostRunID := ""
+	if id := svc.GhostRunID(); id != uuid.Nil {
+		ghostRunID = id.String()
+	}
+	replayRunID := ""
+	if id, ok := svc.Replay(); ok {
+		replayRunID = id.String()
+	}
+
+	var dtoGameStatus *dto.GameStatus
+	if status := svc.GameStatus(); status != (session.GameStatus{}) {
+		dtoGameStatus = &dto.GameStatus{Loading: status.Loading, GameTime: -1}
+		if status.HasGameTime {
+			dtoGameStatus.GameTime = status.GameTime.Milliseconds()
+		}
+	}
+
 	return &dto.Session{
 		LoadedSplitFile:     dtoSplitFile,
 		LeafSegments:        domainSegmentsToDTO(sf.DeepCopyLeafSegments()),
@@ -26,5 +53,25 @@ func DomainToDTO(svc *session.Service) *dto.Session {
 		CurrentSegmentIndex: svc.Index(),
 		SessionState:        dto.SessionState(svc.State()),
 		Dirty:               svc.Dirty(),
+		Practice:            dtoPractice,
+		GhostRunID:          ghostRunID,
+		ReplayRunID:         replayRunID,
+		GameStatus:          dtoGameStatus,
+	}
+}
+
+// DomainGhostToDTO converts a ghost position for the ghost:update event
+func DomainGhostToDTO(ghost session.Ghost) dto.Ghost {
+	segmentID := ""
+	if ghost.SegmentID != uuid.Nil {
+		segmentID = ghost.SegmentID.String()
+	}
+	return dto.Ghost{
+		RunID:        ghost.RunID.String(),
+		SegmentIndex: ghost.SegmentIndex,
+		SegmentID:    segmentID,
+		Progress:     ghost.Progress,
+		Finished:     ghost.Finished,
+		Stopped:      ghost.Stopped,
 	}
 }
@@ -36,6 +36,13 @@ func DomainSplitFileToDTO(sf session.SplitFile) dto.SplitFile {
 		Attempts:         sf.Attempts,
 		Offset:           sf.Offset.Milliseconds(),
 		AutosplitterFile: sf.AutosplitterFile,
+		SplitSources:     sf.SplitSourceCounts(),
+		PracticeAttempts: sf.PracticeAttempts,
+		PracticeRuns:     domainPracticeRunsToDTO(sf.PracticeRuns, sf.ID, sf.Version),
+		PracticeLog:      domainPracticeLogToDTO(sf.PracticeLog),
+		PracticeStats:    domainPracticeStatsToDTO(sf.PracticeStats()),
+		Marathon:         domainMarathonToDTO(sf.Marathon),
+		Profile:          sf.Profile,
 	}
 }
 
@@ -78,6 +85,16 @@ func DTOSplitFileToDomain(payload dto.SplitFile) (session.SplitFile, error) {
 	newSplitFile.PB = PB
 	newSplitFile.Offset = time.Duration(payload.Offset) * time.Millisecond
 	newSplitFile.AutosplitterFile = payload.AutosplitterFile
+	newSplitFile.PracticeAttempts = payload.PracticeAttempts
+	newSplitFile.PracticeRuns = dtoPracticeRunsToDomain(payload.PracticeRuns)
+	newSplitFile.PracticeLog = dtoPracticeLogToDomain(payload.PracticeLog)
+	newSplitFile.Profile = payload.Profile
+	marathon, err := dtoMarathonToDomain(payload.Marathon)
+	if err != nil {
+		logger.Error(logModule, "DTOSplitFileToDomain failed to parse marathon games from payload")
+		return newSplitFile, err
+	}
+	newSplitFile.Marathon = marathon
 	return newSplitFile, nil
 }
 
@@ -126,12 +143,13 @@ func domainSegmentsToDTO(segments []session.Segment) []dto.Segment {
 
 func domainSegmentToDTO(s session.Segment) dto.Segment {
 	dtoSeg := dto.Segment{
-		ID:       s.ID.String(),
-		Name:     s.Name,
-		Gold:     s.Gold.Milliseconds(),
-		Average:  s.Average.Milliseconds(),
-		PB:       s.PB.Milliseconds(),
-		Children: []dto.Segment{},
+		ID:          s.ID.String(),
+		Name:        s.Name,
+		Gold:        s.Gold.Milliseconds(),
+		Average:     s.Average.Milliseconds(),
+		PB:          s.PB.Milliseconds(),
+		MinDuration: s.MinDuration.Milliseconds(),
+		Children:    []dto.Segment{},
 	}
 
 	for _, c := range s.Children {
@@ -151,11 +169,12 @@ func dtoSegmentsToDomain(segments []dto.Segment) []session.Segment {
 
 func dtoSegmentToDomain(dtoSeg dto.Segment) session.Segment {
 	seg := session.Segment{
-		ID:      uuid.MustParse(dtoSeg.ID),
-		Name:    dtoSeg.Name,
-		Gold:    time.Duration(dtoSeg.Gold) * time.Millisecond,
-		Average: time.Duration(dtoSeg.Average) * time.Millisecond,
-		PB:      time.Duration(dtoSeg.PB) * time.Millisecond,
+		ID:          uuid.MustParse(dtoSeg.ID),
+		Name:        dtoSeg.Name,
+		Gold:        time.Duration(dtoSeg.Gold) * time.Millisecond,
+		Average:     time.Duration(dtoSeg.Average) * time.Millisecond,
+		PB:          time.Duration(dtoSeg.PB) * time.Millisecond,
+		MinDuration: time.Duration(dtoSeg.MinDuration) * time.Millisecond,
 	}
 
 	// recursively convert children
@@ -181,12 +200,12 @@ func domainRunToDTO(run session.Run, splitFileID uuid.UUID, splitFileVersion int
 		TotalTime:        run.TotalTime.Milliseconds(),
 		Splits:           domainSplitsToDTO(run.Splits),
 		LeafSegments:     nil,
-		Completed:        false,
+		Completed:        run.Completed,
 	}
 }
 
 func dtoRunsToDomain(runs []dto.Run) []session.Run {
-	out := make([]session.Run, len(runs))
+	out := make([]session.Run, 0, len(runs))
 	for _, r := range runs {
 		r, err := dtoRunToDomain(r)
 		if err != nil {
@@ -214,6 +233,138 @@ func dtoRunToDomain(run dto.Run) (session.Run, error) {
 	}, nil
 }
 
+func domainPracticeRunsToDTO(runs []session.PracticeRun, splitFileID uuid.UUID, splitFileVersion int) []dto.PracticeRun {
+	out := make([]dto.PracticeRun, len(runs))
+	for i, r := range runs {
+		out[i] = dto.PracticeRun{
+			Run:            domainRunToDTO(r.Run, splitFileID, splitFileVersion),
+			StartSegmentID: r.StartSegmentID.String(),
+			EndSegmentID:   r.EndSegmentID.String(),
+			UpdateGolds:    r.UpdateGolds,
+		}
+	}
+	return out
+}
+
+func dtoPracticeRunsToDomain(runs []dto.PracticeRun) []session.PracticeRun {
+	out := make([]session.PracticeRun, 0, len(runs))
+	for _, r := range runs {
+		run, err := dtoRunToDomain(r.Run)
+		if err != nil {
+			logger.Errorf(logModule, "failed to get practice run from DTO splitfile: %s\n", err.Error())
+			continue
+		}
+		startID, err := uuid.Parse(r.StartSegmentID)
+		if err != nil {
+			logger.Errorf(logModule, "failed to parse practice run start segment: %s\n", err.Error())
+			continue
+		}
+		endID, err := uuid.Parse(r.EndSegmentID)
+		if err != nil {
+			logger.Errorf(logModule, "failed to parse practice run end segment: %s\n", err.Error())
+			continue
+		}
+		out = append(out, session.PracticeRun{
+			Run:            run,
+			StartSegmentID: startID,
+			EndSegmentID:   endID,
+			UpdateGolds:    r.UpdateGolds,
+		})
+	}
+	return out
+}
+
+func domainPracticeLogToDTO(log []session.PracticeResult) []dto.PracticeResult {
+	out := make([]dto.PracticeResult, len(log))
+	for i, result := range log {
+		out[i] = dto.PracticeResult{
+			SegmentID:    result.SegmentID.String(),
+			Success:      result.Success,
+			Timestamp:    result.Time.UnixMilli(),
+			Source:       result.Source.Kind,
+			SourceDetail: result.Source.Detail,
+		}
+	}
+	return out
+}
+
+func dtoPracticeLogToDomain(log []dto.PracticeResult) []session.PracticeResult {
+	out := make([]session.PracticeResult, 0, len(log))
+	for _, result := range log {
+		uid, err := uuid.Parse(result.SegmentID)
+		if err != nil {
+			logger.Errorf(logModule, "failed to parse practice result segment: %s\n", err.Error())
+			continue
+		}
+		out = append(out, session.PracticeResult{
+			SegmentID: uid,
+			Success:   result.Success,
+			Time:      time.UnixMilli(result.Timestamp),
+			Source: session.SplitSource{
+				Kind:   result.Source,
+				Detail: result.SourceDetail,
+			},
+		})
+	}
+	return out
+}
+
+func domainPracticeStatsToDTO(stats map[uuid.UUID]session.PracticeStat) map[string]dto.PracticeStat {
+	out := map[string]dto.PracticeStat{}
+	for segmentID, stat := range stats {
+		out[segmentID.String()] = dto.PracticeStat{
+			Successes:          stat.Successes,
+			Failures:           stat.Failures,
+			RollingSuccessRate: stat.RollingSuccessRate,
+			RollingAttempts:    stat.RollingAttempts,
+		}
+	}
+	return out
+}
+
+func domainMarathonToDTO(games []session.MarathonGame) []dto.MarathonGame {
+	if len(games) == 0 {
+		return nil
+	}
+	out := make([]dto.MarathonGame, len(games))
+	for i, game := range games {
+		out[i] = dto.MarathonGame{
+			Path:        game.Path,
+			SplitFileID: game.SplitFileID.String(),
+			SegmentID:   game.SegmentID.String(),
+		}
+	}
+	return out
+}
+
+// dtoMarathonToDomain parses marathon games, an empty ID is left as uuid.Nil for session.SplitFile.AttachMarathonGames
+// to fill in
+func dtoMarathonToDomain(games []dto.MarathonGame) ([]session.MarathonGame, error) {
+	if len(games) == 0 {
+		return nil, nil
+	}
+	out := make([]session.MarathonGame, len(games))
+	for i, game := range games {
+		splitFileID, err := parseOptionalUUID(game.SplitFileID)
+		if err != nil {
+			return nil, err
+		}
+		segmentID, err := parseOptionalUUID(game.SegmentID)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = session.MarathonGame{Path: game.Path, SplitFileID: splitFileID, SegmentID: segmentID}
+	}
+	return out, nil
+}
+
+func parseOptionalUUID(id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(id)
+}
+
 func domainSplitsToDTO(splits map[uuid.UUID]session.Split) map[string]dto.Split {
 	out := map[string]dto.Split{}
 	for segmentID, split := range splits {
@@ -221,6 +372,8 @@ func domainSplitsToDTO(splits map[uuid.UUID]session.Split) map[string]dto.Split
 			SplitSegmentID:    split.SplitSegmentID.String(),
 			CurrentCumulative: split.CurrentCumulative.Milliseconds(),
 			CurrentDuration:   split.CurrentDuration.Milliseconds(),
+			Source:            split.Source.Kind,
+			SourceDetail:      split.Source.Detail,
 		}
 	}
 	return out
@@ -238,6 +391,10 @@ func dtoSplitsToDomain(splits map[string]dto.Split) map[uuid.UUID]session.Split
 			SplitSegmentID:    uid,
 			CurrentCumulative: time.Duration(split.CurrentCumulative) * time.Millisecond,
 			CurrentDuration:   time.Duration(split.CurrentDuration) * time.Millisecond,
+			Source: session.SplitSource{
+				Kind:   split.Source,
+				Detail: split.SourceDetail,
+			},
 		}
 	}
 	return out
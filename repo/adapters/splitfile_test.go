@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/zellydev-games/opensplit/dto"
+)
+
+const segmentID = "c9bc9698-0f39-488d-80c6-06308f12b03e"
+
+func TestSplitFileRunsRoundTrip(t *testing.T) {
+	completed := dto.Run{
+		ID:        "fc498ab0-91c6-4d32-a2bf-d8ee51056531",
+		TotalTime: 1000,
+		Completed: true,
+		Splits:    map[string]dto.Split{segmentID: {SplitSegmentID: segmentID, CurrentCumulative: 1000}},
+	}
+	partial := dto.Run{ID: "05151851-9132-498e-b70a-344ee03c9384", Splits: map[string]dto.Split{}}
+	payload := dto.SplitFile{
+		ID:       "9a268f11-1c89-49af-ae00-a9e2246ec82d",
+		Attempts: 3,
+		Segments: []dto.Segment{{ID: segmentID, Name: "Level 1"}},
+		// a run that can't be parsed is skipped rather than loaded as a zero-value run
+		Runs: []dto.Run{completed, {ID: "not-a-run"}, partial},
+	}
+
+	sf, err := DTOSplitFileToDomain(payload)
+	if err != nil {
+		t.Fatalf("DTOSplitFileToDomain() returned error: %s", err)
+	}
+	if len(sf.Runs) != 2 || sf.Runs[0].ID.String() != completed.ID || sf.Runs[1].ID.String() != partial.ID {
+		t.Fatalf("DTOSplitFileToDomain() want the 2 valid runs, got %d: %v", len(sf.Runs), sf.Runs)
+	}
+	if !sf.Runs[0].Completed || sf.Runs[1].Completed {
+		t.Fatalf("DTOSplitFileToDomain() lost the runs' Completed flags: %v", sf.Runs)
+	}
+
+	saved := DomainSplitFileToDTO(sf)
+	if len(saved.Runs) != 2 || !saved.Runs[0].Completed || saved.Runs[1].Completed {
+		t.Fatalf("DomainSplitFileToDTO() want the runs' Completed flags kept, got %v", saved.Runs)
+	}
+}
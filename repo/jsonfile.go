@@ -170,6 +170,39 @@ func (j *JsonFile) LoadSplitFile() ([]byte, error) {
 	return data, nil
 }
 
+// ReadSplitFile reads a JSON (*.osf) file from path without a dialog, e.g. a pinned split file.
+//
+// The cached file name is left alone so saves keep going to the current file until SetLoadedFileName is called.
+func (j *JsonFile) ReadSplitFile(filename string) ([]byte, error) {
+	data, err := j.fileProvider.ReadFile(filename)
+	if err != nil {
+		logger.Errorf(logModule, "failed to read split file: %s", err.Error())
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteSplitFile writes a split file payload to filename without a dialog, leaving the cached file name alone
+func (j *JsonFile) WriteSplitFile(filename string, payload []byte) error {
+	err := j.fileProvider.WriteFile(filename, payload, 0644)
+	if err != nil {
+		logger.Errorf(logModule, "failed to write split file: %s", err.Error())
+	}
+	return err
+}
+
+// LoadedFileName returns the path of the file SaveSplitFile writes to
+func (j *JsonFile) LoadedFileName() string {
+	return j.fileName
+}
+
+// SetLoadedFileName changes the path SaveSplitFile writes to
+func (j *JsonFile) SetLoadedFileName(filename string) {
+	logger.Debugf(logModule, "using split file %s", filename)
+	j.fileName = filename
+	j.lastUsedDirectory = filepath.Dir(filename)
+}
+
 func (j *JsonFile) SaveConfig(configServicePayload []byte) error {
 	defaultDirectoryBase, err := j.fileProvider.UserHomeDir()
 	if err != nil {
@@ -217,6 +250,69 @@ func (j *JsonFile) LoadConfig() ([]byte, error) {
 	return data, err
 }
 
+// BackupConfig writes a copy of the config file's original contents to os-config.backup.json
+func (j *JsonFile) BackupConfig(configServicePayload []byte) error {
+	configDirectory, err := j.configDirectory()
+	if err != nil {
+		return err
+	}
+
+	err = j.fileProvider.WriteFile(path.Join(configDirectory, "os-config.backup.json"), configServicePayload, 0644)
+	if err != nil {
+		logger.Errorf(logModule, "failed to back up OpenSplit config: %s", err.Error())
+	}
+	return err
+}
+
+// SaveProfiles writes every config profile to os-profiles.json beside the config
+func (j *JsonFile) SaveProfiles(profilesPayload []byte) error {
+	configDirectory, err := j.configDirectory()
+	if err != nil {
+		return err
+	}
+
+	err = j.fileProvider.WriteFile(path.Join(configDirectory, "os-profiles.json"), profilesPayload, 0644)
+	if err != nil {
+		logger.Errorf(logModule, "failed to save config profiles: %s", err.Error())
+	}
+	return err
+}
+
+// LoadProfiles reads os-profiles.json, returning no data and no error if no profile has been saved yet
+func (j *JsonFile) LoadProfiles() ([]byte, error) {
+	configDirectory, err := j.configDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := j.fileProvider.ReadFile(path.Join(configDirectory, "os-profiles.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		logger.Errorf(logModule, "failed to load config profiles: %s", err.Error())
+		return nil, err
+	}
+	return data, nil
+}
+
+// configDirectory returns the OpenSplit user data folder the config is kept in, creating it if needs be
+func (j *JsonFile) configDirectory() (string, error) {
+	defaultDirectoryBase, err := j.fileProvider.UserHomeDir()
+	if err != nil {
+		logger.Errorf(logModule, "failed to get user home directory: %s", err.Error())
+		return "", err
+	}
+
+	configDirectory := path.Join(defaultDirectoryBase, "OpenSplit")
+	err = j.fileProvider.MkdirAll(configDirectory, 0755)
+	if err != nil {
+		logger.Errorf(logModule, "failed to create OpenSplit user data folder: %s", err.Error())
+		return "", err
+	}
+	return configDirectory, nil
+}
+
 func (j *JsonFile) getDefaultDirectory() (string, error) {
 	var defaultDirectory string
 	if j.lastUsedDirectory != "" {
@@ -10,11 +10,13 @@ import (
 
 type MockFileProvider struct {
 	WriteFileCalled int
+	LastWritten     string
 	MkdirAllCalled  int
 }
 
 func (f *MockFileProvider) WriteFile(filename string, data []byte, perm os.FileMode) error {
 	f.WriteFileCalled++
+	f.LastWritten = filename
 	return nil
 }
 
@@ -98,3 +100,30 @@ func TestLoad(t *testing.T) {
 		t.Errorf("LoadSplitFile didn't return expected payload")
 	}
 }
+
+func TestReadSplitFile(t *testing.T) {
+	m := &MockRuntimeProvider{}
+	f := &MockFileProvider{}
+	j := NewJsonFile(m, f)
+	if _, err := j.LoadSplitFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.ReadSplitFile("/splits/other.osf"); err != nil {
+		t.Fatal(err)
+	}
+	if m.LoadCalled != 1 {
+		t.Errorf("ReadSplitFile() opened OpenFileDialog")
+	}
+	if j.LoadedFileName() != "testfile.osf" {
+		t.Errorf("ReadSplitFile() changed the loaded file name to %s", j.LoadedFileName())
+	}
+
+	j.SetLoadedFileName("/splits/other.osf")
+	if err := j.SaveSplitFile([]byte(""), "other.osf"); err != nil {
+		t.Fatal(err)
+	}
+	if f.LastWritten != "/splits/other.osf" || m.SaveCalled != 0 {
+		t.Errorf("SaveSplitFile() after SetLoadedFileName wrote %s (dialogs: %d)", f.LastWritten, m.SaveCalled)
+	}
+}
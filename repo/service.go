@@ -2,6 +2,10 @@ package repo
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"sync"
 
 	"github.com/zellydev-games/opensplit/config"
@@ -16,15 +20,25 @@ const logModule = "repo"
 // ErrConfigMissing signals to the caller that the config file is not there (first run, or user moved it), so generate a default
 var ErrConfigMissing = errors.New("config missing")
 
+// ErrProfileMissing signals that no config profile has the requested name
+var ErrProfileMissing = errors.New("config profile missing")
+
 // Repository defines a contract for a repo provider to operate against
 type Repository interface {
 	LoadSplitFile() ([]byte, error)
+	ReadSplitFile(string) ([]byte, error)
+	WriteSplitFile(string, []byte) error
+	LoadedFileName() string
+	SetLoadedFileName(string)
 	GetLoadedSplitFile() ([]byte, error)
 	SaveSplitFile([]byte, string) error
 	SaveAs([]byte, string) error
 	ClearCachedFileName()
 	SaveConfig([]byte) error
 	LoadConfig() ([]byte, error)
+	BackupConfig([]byte) error
+	SaveProfiles([]byte) error
+	LoadProfiles() ([]byte, error)
 }
 
 type Service struct {
@@ -49,7 +63,109 @@ func (s *Service) LoadSplitFile() (session.SplitFile, error) {
 	s.splitFileLock.RUnlock()
 	splitFileDTO, _ := adapters.JSONSplitFileToDTO(string(splitFile))
 	logger.Infof(logModule, "loaded split file: %s-%s", splitFileDTO.GameName, splitFileDTO.GameCategory)
-	return adapters.DTOSplitFileToDomain(splitFileDTO)
+	sf, err := adapters.DTOSplitFileToDomain(splitFileDTO)
+	if err != nil {
+		return sf, err
+	}
+	return sf, s.loadMarathonGames(&sf, s.LoadedFileName())
+}
+
+// ReadSplitFile reads the split file at path without asking the user.
+//
+// Unlike LoadSplitFile it doesn't change which file saves go to, call SetLoadedFileName once the file is in use.
+func (s *Service) ReadSplitFile(path string) (session.SplitFile, error) {
+	logger.Debugf(logModule, "reading split file %s", path)
+	s.splitFileLock.RLock()
+	splitFile, err := s.repository.ReadSplitFile(path)
+	s.splitFileLock.RUnlock()
+	if err != nil {
+		return session.SplitFile{}, err
+	}
+	splitFileDTO, err := adapters.JSONSplitFileToDTO(string(splitFile))
+	if err != nil {
+		return session.SplitFile{}, err
+	}
+	logger.Infof(logModule, "read split file: %s-%s", splitFileDTO.GameName, splitFileDTO.GameCategory)
+	sf, err := adapters.DTOSplitFileToDomain(splitFileDTO)
+	if err != nil {
+		return sf, err
+	}
+	return sf, s.loadMarathonGames(&sf, path)
+}
+
+// loadMarathonGames reads the games of a marathon split file stored at path and attaches them, other split files are
+// left alone
+func (s *Service) loadMarathonGames(sf *session.SplitFile, path string) error {
+	if !sf.IsMarathon() {
+		return nil
+	}
+
+	games := make([]session.SplitFile, 0, len(sf.Marathon))
+	for _, game := range sf.Marathon {
+		s.splitFileLock.RLock()
+		payload, err := s.repository.ReadSplitFile(marathonGamePath(path, game.Path))
+		s.splitFileLock.RUnlock()
+		if err != nil {
+			return fmt.Errorf("failed to read marathon game %s: %w", game.Path, err)
+		}
+		gameDTO, err := adapters.JSONSplitFileToDTO(string(payload))
+		if err != nil {
+			return fmt.Errorf("failed to parse marathon game %s: %w", game.Path, err)
+		}
+		gameSplitFile, err := adapters.DTOSplitFileToDomain(gameDTO)
+		if err != nil {
+			return fmt.Errorf("failed to parse marathon game %s: %w", game.Path, err)
+		}
+		games = append(games, gameSplitFile)
+	}
+	return sf.AttachMarathonGames(games)
+}
+
+// SaveMarathonGames writes the games a marathon run changed back to their own split files
+func (s *Service) SaveMarathonGames(sf session.SplitFile) error {
+	path := s.LoadedFileName()
+	for _, game := range sf.Marathon {
+		if !game.Dirty || game.SplitFile == nil {
+			continue
+		}
+		payload, err := adapters.SplitFileToFrontEnd(adapters.DomainSplitFileToDTO(*game.SplitFile))
+		if err != nil {
+			return err
+		}
+
+		s.splitFileLock.Lock()
+		err = s.repository.WriteSplitFile(marathonGamePath(path, game.Path), payload)
+		s.splitFileLock.Unlock()
+		if err != nil {
+			logger.Errorf(logModule, "repo failed to save marathon game %s: %s", game.Path, err)
+			return err
+		}
+		logger.Infof(logModule, "repository saved marathon game: %s", game.Path)
+	}
+	return nil
+}
+
+// marathonGamePath resolves a game's path relative to the marathon split file at marathonPath
+func marathonGamePath(marathonPath string, gamePath string) string {
+	if filepath.IsAbs(gamePath) || marathonPath == "" {
+		return gamePath
+	}
+	return filepath.Join(filepath.Dir(marathonPath), gamePath)
+}
+
+// LoadedFileName is the path of the split file saves go to, empty if it hasn't been saved or loaded yet
+func (s *Service) LoadedFileName() string {
+	s.splitFileLock.RLock()
+	defer s.splitFileLock.RUnlock()
+	return s.repository.LoadedFileName()
+}
+
+// SetLoadedFileName makes path the split file saves go to
+func (s *Service) SetLoadedFileName(path string) {
+	s.splitFileLock.Lock()
+	s.repository.SetLoadedFileName(path)
+	s.splitFileLock.Unlock()
+	logger.Infof(logModule, "repository now using split file %s", path)
 }
 
 // SaveSplitFileWindowDimensions loads the active filename in the repository service,
@@ -114,8 +230,16 @@ func (s *Service) Close() {
 	logger.Infof(logModule, "repository cleared splitfile")
 }
 
+// SaveConfig saves the config's own settings to the config file, and the settings of the active profile to the
+// profile so changes made while it's active stay with it
 func (s *Service) SaveConfig(configService *config.Service) error {
-	payload, err := adapters.ConfigToFrontEnd(configService)
+	if profile, active := configService.ActiveProfileSettings(); active {
+		if err := s.SaveProfile(configService, profile); err != nil {
+			return err
+		}
+	}
+
+	payload, err := adapters.ConfigToFrontEnd(configService.Base())
 	if err != nil {
 		return err
 	}
@@ -130,6 +254,8 @@ func (s *Service) SaveConfig(configService *config.Service) error {
 		return err
 	}
 
+	// whatever couldn't be loaded is gone from the file now
+	configService.ClearLoadProblems()
 	logger.Infof(logModule, "repository saved config")
 	return nil
 }
@@ -145,16 +271,101 @@ func (s *Service) LoadConfig(c *config.Service) error {
 	}
 	s.configLock.RUnlock()
 
-	newConfig, err := adapters.FrontEndToConfig(b)
+	newConfig, report, err := adapters.FrontEndToConfig(b)
 	if err != nil {
+		logger.Errorf(logModule, "repo failed to read config: %s", err)
 		return err
 	}
+	if report.Changed() {
+		// the migrated or repaired config only replaces the file when it's next saved, keep the file as it was
+		s.configLock.Lock()
+		err = s.repository.BackupConfig(b)
+		s.configLock.Unlock()
+		if err != nil {
+			logger.Errorf(logModule, "repo failed to back up config: %s", err)
+		}
+	}
+	if report.Version < config.SchemaVersion {
+		logger.Infof(logModule, "migrated config from schema %d to %d", report.Version, config.SchemaVersion)
+	}
+	for _, problem := range report.Problems {
+		logger.Warnf(logModule, "config problem: %s", problem)
+	}
+
+	// the loaded settings are the config's own, a profile has to be activated again on top of them
+	c.ReplaceSettings(newConfig)
+	logger.Info(logModule, "repo loaded config")
+
+	profiles, profileReport, err := s.loadProfiles()
+	if err != nil {
+		// the config is still usable without its profiles
+		logger.Errorf(logModule, "repo failed to load config profiles: %s", err)
+		return nil
+	}
+	for _, problem := range profileReport.Problems {
+		logger.Warnf(logModule, "config profile problem: %s", problem)
+	}
+	c.AddLoadProblems(profileReport.Problems)
+	c.SetProfileNames(slices.Collect(maps.Keys(profiles)))
+	return nil
+}
+
+// LoadProfiles returns every stored config profile by name, leaving out the settings and profiles that couldn't be
+// read
+func (s *Service) LoadProfiles() (map[string]config.Profile, error) {
+	profiles, _, err := s.loadProfiles()
+	return profiles, err
+}
+
+func (s *Service) loadProfiles() (map[string]config.Profile, config.LoadReport, error) {
+	s.configLock.RLock()
+	b, err := s.repository.LoadProfiles()
+	s.configLock.RUnlock()
+	if err != nil {
+		return nil, config.LoadReport{}, err
+	}
+	return adapters.FrontEndToProfiles(b)
+}
 
+// LoadProfile returns the stored config profile called name
+func (s *Service) LoadProfile(name string) (config.Profile, error) {
+	profiles, err := s.LoadProfiles()
+	if err != nil {
+		return config.Profile{}, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return config.Profile{}, fmt.Errorf("%w: %s", ErrProfileMissing, name)
+	}
+	profile.Name = name
+	return profile, nil
+}
+
+// SaveProfile stores profile, replacing any profile with the same name, and updates the profile names c offers
+func (s *Service) SaveProfile(c *config.Service, profile config.Profile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	profiles, err := s.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	profiles[profile.Name] = profile
+	payload, err := adapters.ProfilesToFrontEnd(profiles)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf(logModule, "repository saving config profile %s", profile.Name)
 	s.configLock.Lock()
-	c.SpeedRunAPIBase = newConfig.SpeedRunAPIBase
-	c.KeyConfig = newConfig.KeyConfig
-	c.GlobalHotkeysActive = newConfig.GlobalHotkeysActive
+	err = s.repository.SaveProfiles(payload)
 	s.configLock.Unlock()
-	logger.Info(logModule, "repo loaded config")
+	if err != nil {
+		logger.Errorf(logModule, "repo failed to save config profile %s: %s", profile.Name, err)
+		return err
+	}
+
+	c.SetProfileNames(slices.Collect(maps.Keys(profiles)))
+	logger.Infof(logModule, "repository saved config profile %s", profile.Name)
 	return nil
 }
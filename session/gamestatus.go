@@ -0,0 +1,31 @@
+package session
+
+import "time"
+
+// GameStatus is what the autosplitter last read from the game while a run was in progress.
+//
+// OpenSplit has no game time clock yet, so the status is only shown alongside the real time timer and never pauses
+// or changes it.  The zero value means no autosplitter is reporting.
+type GameStatus struct {
+	Loading     bool
+	GameTime    time.Duration
+	HasGameTime bool
+}
+
+// SetGameStatus records the status reported by the autosplitter.
+//
+// Scripts report on every tick, so a session update is only sent when loading starts or stops, or the game time
+// reaches a new second.
+func (s *Service) SetGameStatus(status GameStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.gameStatus
+	s.gameStatus = status
+	if previous.Loading != status.Loading || previous.HasGameTime != status.HasGameTime ||
+		previous.GameTime.Truncate(time.Second) != status.GameTime.Truncate(time.Second) {
+		s.sendUpdate()
+	}
+}
+
+// GameStatus returns the status last reported by the autosplitter
+func (s *Service) GameStatus() GameStatus { s.mu.Lock(); defer s.mu.Unlock(); return s.gameStatus }
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// Ghost is where a past run was at some elapsed time, so the current run can be raced against it.
+//
+// SegmentIndex indexes the ghost run's LeafSegments and Progress is how far through that segment the ghost was, from
+// 0 to 1, interpolated between the splits either side.  Segments the ghost skipped are crossed in one go, as the run
+// recorded no time between them.  Finished is set once a completed run's TotalTime has passed, Stopped once elapsed
+// passes the last split of a run that was reset.
+type Ghost struct {
+	RunID        uuid.UUID
+	SegmentIndex int
+	SegmentID    uuid.UUID
+	Progress     float64
+	Finished     bool
+	Stopped      bool
+}
+
+// GhostAt returns where the run was after elapsed
+func (r Run) GhostAt(elapsed time.Duration) Ghost {
+	ghost := Ghost{RunID: r.ID}
+	prev := time.Duration(0)
+	spanStart := 0
+	for i, segment := range r.LeafSegments {
+		split, ok := r.Splits[segment.ID]
+		if !ok {
+			continue
+		}
+		if elapsed < split.CurrentCumulative {
+			ghost.SegmentIndex = spanStart
+			ghost.SegmentID = r.LeafSegments[spanStart].ID
+			if span := split.CurrentCumulative - prev; span > 0 && elapsed > prev {
+				ghost.Progress = float64(elapsed-prev) / float64(span)
+			}
+			return ghost
+		}
+		prev = split.CurrentCumulative
+		spanStart = i + 1
+	}
+
+	// elapsed is past the last split
+	ghost.SegmentIndex = len(r.LeafSegments)
+	if r.Completed {
+		ghost.Finished = true
+		return ghost
+	}
+	ghost.Stopped = true
+	return ghost
+}
+
+// SetGhost picks the past run the current run is raced against, uuid.Nil races the PB
+func (s *Service) SetGhost(runID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+
+	if s.loadedSplitFile == nil {
+		return fmt.Errorf("no split file loaded")
+	}
+	if _, ok := s.findRun(runID); !ok && runID != uuid.Nil {
+		return fmt.Errorf("no run %s in split file", runID)
+	}
+	s.ghostRunID = runID
+	logger.Infof(logModule, "ghost set to run %s", runID)
+	return nil
+}
+
+// GhostRunID is the run chosen with SetGhost, uuid.Nil when racing the PB
+func (s *Service) GhostRunID() uuid.UUID { s.mu.Lock(); defer s.mu.Unlock(); return s.ghostRunID }
+
+// Ghost returns where the ghost run was after elapsed, false when there is no run to race
+func (s *Service) Ghost(elapsed time.Duration) (Ghost, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.findRun(s.ghostRunID)
+	if !ok {
+		return Ghost{}, false
+	}
+	return run.GhostAt(elapsed), true
+}
+
+// findRun returns the split file's run with runID, or the PB for uuid.Nil.  It must be called under lock.
+func (s *Service) findRun(runID uuid.UUID) (*Run, bool) {
+	if s.loadedSplitFile == nil {
+		return nil, false
+	}
+	if runID == uuid.Nil {
+		return s.loadedSplitFile.PB, s.loadedSplitFile.PB != nil
+	}
+	for i := range s.loadedSplitFile.Runs {
+		if s.loadedSplitFile.Runs[i].ID == runID {
+			return &s.loadedSplitFile.Runs[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// elapsedTimer is a MockTimer whose current time is set by the test
+type elapsedTimer struct {
+	MockTimer
+	elapsed time.Duration
+}
+
+func (t *elapsedTimer) GetCurrentTime() time.Duration { return t.elapsed }
+
+func getGhostRun() Run {
+	uid3 := uuid.New()
+	leaves := []Segment{{ID: uid, Name: "Level 1"}, {ID: uid2, Name: "Level 2"}, {ID: uid3, Name: "Level 3"}}
+	return Run{ID: uuid.New(), LeafSegments: leaves, TotalTime: 6 * time.Second, Completed: true, Splits: map[uuid.UUID]Split{
+		uid:  {SplitSegmentID: uid, CurrentCumulative: 2 * time.Second, CurrentDuration: 2 * time.Second},
+		uid3: {SplitSegmentID: uid3, CurrentCumulative: 6 * time.Second, CurrentDuration: 4 * time.Second},
+	}}
+}
+
+func TestGhostAt(t *testing.T) {
+	run := getGhostRun()
+	tests := []struct {
+		elapsed  time.Duration
+		index    int
+		progress float64
+	}{
+		{0, 0, 0},
+		{time.Second, 0, 0.5},
+		// Level 2 was skipped, so the ghost crosses Level 2 and 3 between the 2s and 6s splits
+		{3 * time.Second, 1, 0.25},
+		{5 * time.Second, 1, 0.75},
+	}
+	for _, test := range tests {
+		ghost := run.GhostAt(test.elapsed)
+		if ghost.SegmentIndex != test.index || ghost.Progress != test.progress || ghost.Finished {
+			t.Fatalf("GhostAt(%s) want segment %d at %.2f, got %+v", test.elapsed, test.index, test.progress, ghost)
+		}
+	}
+
+	if ghost := run.GhostAt(7 * time.Second); !ghost.Finished || ghost.SegmentIndex != 3 {
+		t.Fatalf("GhostAt() past the end of a completed run want finished, got %+v", ghost)
+	}
+	run.Completed = false
+	if ghost := run.GhostAt(7 * time.Second); !ghost.Stopped || ghost.Finished {
+		t.Fatalf("GhostAt() past the end of a reset run want stopped, got %+v", ghost)
+	}
+}
+
+func TestSetGhost(t *testing.T) {
+	s, _ := NewService(&MockTimer{})
+	if _, ok := s.Ghost(time.Second); ok {
+		t.Fatalf("Ghost() with no split file want false")
+	}
+
+	run := getGhostRun()
+	other := getGhostRun()
+	other.TotalTime = 10 * time.Second
+	s.SetLoadedSplitFile(SplitFile{Runs: []Run{run, other}, PB: &run})
+	if ghost, ok := s.Ghost(time.Second); !ok || ghost.RunID != run.ID {
+		t.Fatalf("Ghost() want the PB, got %+v", ghost)
+	}
+	if err := s.SetGhost(uuid.New()); err == nil {
+		t.Fatalf("SetGhost() with an unknown run want error, got nil")
+	}
+	if err := s.SetGhost(other.ID); err != nil {
+		t.Fatalf("SetGhost() returned error: %s", err)
+	}
+	if ghost, _ := s.Ghost(time.Second); ghost.RunID != other.ID {
+		t.Fatalf("Ghost() want run %s, got %s", other.ID, ghost.RunID)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	timer := &elapsedTimer{}
+	s, _ := NewService(timer)
+	run := getGhostRun()
+	s.SetLoadedSplitFile(SplitFile{Segments: run.LeafSegments, Runs: []Run{run}, PB: &run, Attempts: 1})
+
+	if err := s.StartReplay(uuid.Nil); err != nil {
+		t.Fatalf("StartReplay() returned error: %s", err)
+	}
+	if err := s.StartPractice(Practice{}); err == nil {
+		t.Fatalf("StartPractice() during a replay want error, got nil")
+	}
+
+	timer.elapsed = time.Second
+	if !s.AdvanceReplay() || s.Index() != 0 {
+		t.Fatalf("AdvanceReplay() before the first split want segment 0, got %d", s.Index())
+	}
+
+	timer.elapsed = 3 * time.Second
+	s.AdvanceReplay()
+	if current, _ := s.Run(); s.Index() != 2 || len(current.Splits) != 1 {
+		t.Fatalf("AdvanceReplay() past the skipped segment want segment 2 with 1 split, got %d with %v",
+			s.Index(), current.Splits)
+	}
+
+	timer.elapsed = 6 * time.Second
+	if s.AdvanceReplay() {
+		t.Fatalf("AdvanceReplay() at the end of the run want false")
+	}
+	if current, _ := s.Run(); s.State() != Finished || !current.Completed || current.TotalTime != run.TotalTime {
+		t.Fatalf("replay want a finished copy of the run, got %+v", current)
+	}
+
+	s.StopReplay()
+	sf, _ := s.SplitFile()
+	if s.State() != Idle || sf.Attempts != 1 || len(sf.Runs) != 1 || s.Dirty() {
+		t.Fatalf("replay changed the split file: %d attempts, %d runs", sf.Attempts, len(sf.Runs))
+	}
+}
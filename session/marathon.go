@@ -0,0 +1,179 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// MarathonGame is one of the split files a marathon split file plays back to back.
+//
+// Path is where the game's own split file lives, relative paths are relative to the marathon split file.  SegmentID is
+// the marathon segment that groups the game's segments.  SplitFile is filled in by the repo when the marathon is loaded
+// and Dirty is set when a marathon run writes a portion back to it; neither is persisted in the marathon.
+type MarathonGame struct {
+	Path        string
+	SplitFileID uuid.UUID
+	SegmentID   uuid.UUID
+	SplitFile   *SplitFile
+	Dirty       bool
+}
+
+// IsMarathon reports whether the split file is made of other split files
+func (s *SplitFile) IsMarathon() bool {
+	return s != nil && len(s.Marathon) > 0
+}
+
+// AttachMarathonGames gives each MarathonGame its loaded split file, in order, and rebuilds the marathon's segments.
+//
+// Every game becomes a parent segment holding a copy of the game's segments, segment IDs are kept so splits in a
+// marathon run map straight back to the game's own split file.
+func (s *SplitFile) AttachMarathonGames(games []SplitFile) error {
+	if len(games) != len(s.Marathon) {
+		return fmt.Errorf("marathon has %d games, got %d split files", len(s.Marathon), len(games))
+	}
+
+	segments := make([]Segment, 0, len(games))
+	for i := range games {
+		game := &s.Marathon[i]
+		if game.SplitFileID != uuid.Nil && game.SplitFileID != games[i].ID {
+			return fmt.Errorf("%s is not the split file the marathon was made with", game.Path)
+		}
+		if games[i].IsMarathon() {
+			return fmt.Errorf("%s is a marathon, marathons can't be nested", game.Path)
+		}
+		if game.SegmentID == uuid.Nil {
+			game.SegmentID = uuid.New()
+		}
+
+		sf := games[i]
+		game.SplitFileID = sf.ID
+		game.SplitFile = &sf
+
+		name := sf.GameName
+		if sf.GameCategory != "" {
+			name += " - " + sf.GameCategory
+		}
+		segments = append(segments, Segment{
+			ID:       game.SegmentID,
+			Name:     name,
+			Children: deepCopySegments(sf.Segments),
+		})
+	}
+
+	s.Segments = segments
+	s.BuildStats()
+	logger.Infof(logModule, "attached %d games to marathon %s", len(games), s.GameName)
+	return nil
+}
+
+// recordMarathonPortions writes each game's part of a marathon run into the game's own split file.
+//
+// Games the run never split in are left alone.  A portion keeps the marathon run's ID so it can be found again by
+// removeMarathonPortions, and its times are re-based to start at zero when the game started.
+func (s *SplitFile) recordMarathonPortions(run Run) {
+	for i := range s.Marathon {
+		game := &s.Marathon[i]
+		if game.SplitFile == nil {
+			logger.Warnf(logModule, "marathon game %s isn't loaded, its portion of the run is lost", game.Path)
+			continue
+		}
+
+		portion, ok := marathonPortion(run, game.SplitFile)
+		if !ok {
+			continue
+		}
+		game.SplitFile.Attempts++
+		game.SplitFile.Runs = append(game.SplitFile.Runs, portion)
+		game.SplitFile.BuildStats()
+		game.Dirty = true
+		logger.Infof(logModule, "marathon run written to %s (completed: %t)", game.SplitFile.GameName, portion.Completed)
+	}
+}
+
+// removeMarathonPortions undoes recordMarathonPortions for the run with runID
+func (s *SplitFile) removeMarathonPortions(runID uuid.UUID) {
+	for i := range s.Marathon {
+		game := &s.Marathon[i]
+		if game.SplitFile == nil {
+			continue
+		}
+		runs := game.SplitFile.Runs
+		if len(runs) == 0 || runs[len(runs)-1].ID != runID {
+			continue
+		}
+		game.SplitFile.Runs = runs[:len(runs)-1]
+		game.SplitFile.Attempts--
+		game.SplitFile.BuildStats()
+		game.Dirty = true
+	}
+}
+
+// marathonPortion cuts the splits for game's segments out of a marathon run
+func marathonPortion(run Run, game *SplitFile) (Run, bool) {
+	leaves := game.DeepCopyLeafSegments()
+	if len(leaves) == 0 {
+		return Run{}, false
+	}
+
+	// the game started at the last split before its first segment
+	start := -1
+	for i, leaf := range run.LeafSegments {
+		if leaf.ID == leaves[0].ID {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return Run{}, false
+	}
+	offset := time.Duration(0)
+	for i := start - 1; i >= 0; i-- {
+		if split, ok := run.Splits[run.LeafSegments[i].ID]; ok {
+			offset = split.CurrentCumulative
+			break
+		}
+	}
+
+	var total time.Duration
+	splits := map[uuid.UUID]Split{}
+	for _, leaf := range leaves {
+		split, ok := run.Splits[leaf.ID]
+		if !ok {
+			continue
+		}
+		split.CurrentCumulative -= offset
+		splits[leaf.ID] = split
+		total = split.CurrentCumulative
+	}
+	if len(splits) == 0 {
+		return Run{}, false
+	}
+
+	_, completed := splits[leaves[len(leaves)-1].ID]
+	return Run{
+		ID:               run.ID,
+		TotalTime:        total,
+		Splits:           splits,
+		LeafSegments:     leaves,
+		Completed:        completed,
+		SplitFileVersion: game.Version,
+	}, true
+}
+
+func deepCopyMarathon(games []MarathonGame) []MarathonGame {
+	if games == nil {
+		return nil
+	}
+	out := make([]MarathonGame, len(games))
+	for i, game := range games {
+		out[i] = game
+		if game.SplitFile != nil {
+			sf := deepCopySplitFile(game.SplitFile)
+			out[i].SplitFile = &sf
+		}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func getMarathon(t *testing.T) SplitFile {
+	t.Helper()
+	first := SplitFile{ID: uuid.New(), GameName: "First", Segments: []Segment{{ID: uid, Name: "Level 1"}}}
+	second := SplitFile{ID: uuid.New(), GameName: "Second", GameCategory: "Any%", Segments: []Segment{
+		{ID: uid2, Name: "Level 1"},
+		{ID: uuid.New(), Name: "Level 2"},
+	}}
+
+	marathon := SplitFile{ID: uuid.New(), GameName: "Series", Marathon: []MarathonGame{
+		{Path: "first.osf"},
+		{Path: "second.osf", SplitFileID: second.ID},
+	}}
+	if err := marathon.AttachMarathonGames([]SplitFile{first, second}); err != nil {
+		t.Fatalf("AttachMarathonGames() returned error: %s", err)
+	}
+	return marathon
+}
+
+func TestAttachMarathonGames(t *testing.T) {
+	marathon := getMarathon(t)
+	if len(marathon.Segments) != 2 || marathon.Segments[1].Name != "Second - Any%" {
+		t.Fatalf("AttachMarathonGames() want a segment per game, got %v", marathon.Segments)
+	}
+	if leaves := marathon.DeepCopyLeafSegments(); len(leaves) != 3 || leaves[1].ID != uid2 {
+		t.Fatalf("AttachMarathonGames() want the games' 3 leaf segments in order, got %v", leaves)
+	}
+	if marathon.Marathon[0].SegmentID == uuid.Nil || marathon.Marathon[0].SplitFileID == uuid.Nil {
+		t.Fatalf("AttachMarathonGames() didn't fill in IDs: %v", marathon.Marathon[0])
+	}
+
+	wrong := getMarathon(t)
+	wrong.Marathon[1].SplitFileID = uuid.New()
+	games := []SplitFile{*marathon.Marathon[0].SplitFile, *marathon.Marathon[1].SplitFile}
+	if err := wrong.AttachMarathonGames(games); err == nil {
+		t.Fatalf("AttachMarathonGames() with a different split file want error, got nil")
+	}
+}
+
+func TestMarathonPortions(t *testing.T) {
+	marathon := getMarathon(t)
+	leaves := marathon.DeepCopyLeafSegments()
+	run := Run{ID: uuid.New(), LeafSegments: leaves, TotalTime: 6 * time.Second, Splits: map[uuid.UUID]Split{
+		leaves[0].ID: {SplitSegmentID: leaves[0].ID, CurrentCumulative: time.Second, CurrentDuration: time.Second},
+		leaves[1].ID: {SplitSegmentID: leaves[1].ID, CurrentCumulative: 3 * time.Second, CurrentDuration: 2 * time.Second},
+		leaves[2].ID: {SplitSegmentID: leaves[2].ID, CurrentCumulative: 6 * time.Second, CurrentDuration: 3 * time.Second},
+	}}
+
+	marathon.recordMarathonPortions(run)
+	second := marathon.Marathon[1]
+	if !second.Dirty || second.SplitFile.Attempts != 1 || len(second.SplitFile.Runs) != 1 {
+		t.Fatalf("recordMarathonPortions() want a run written to the second game, got %v", second.SplitFile.Runs)
+	}
+	portion := second.SplitFile.Runs[0]
+	if !portion.Completed || portion.TotalTime != 5*time.Second || portion.Splits[uid2].CurrentCumulative != 2*time.Second {
+		t.Fatalf("portion want completed in 5s with Level 1 at 2s, got %v", portion)
+	}
+	if second.SplitFile.Segments[0].Gold != 2*time.Second {
+		t.Fatalf("portion want a 2s gold in the game's split file, got %d", second.SplitFile.Segments[0].Gold)
+	}
+
+	marathon.removeMarathonPortions(run.ID)
+	for _, game := range marathon.Marathon {
+		if len(game.SplitFile.Runs) != 0 || game.SplitFile.Attempts != 0 {
+			t.Fatalf("removeMarathonPortions() left %d runs in %s", len(game.SplitFile.Runs), game.Path)
+		}
+	}
+}
+
+func TestMarathonPortionsPartial(t *testing.T) {
+	marathon := getMarathon(t)
+	leaves := marathon.DeepCopyLeafSegments()
+	run := Run{ID: uuid.New(), LeafSegments: leaves, Splits: map[uuid.UUID]Split{
+		leaves[0].ID: {SplitSegmentID: leaves[0].ID, CurrentCumulative: time.Second, CurrentDuration: time.Second},
+	}}
+
+	marathon.recordMarathonPortions(run)
+	if first := marathon.Marathon[0].SplitFile; len(first.Runs) != 1 || !first.Runs[0].Completed {
+		t.Fatalf("first game want a completed run, got %v", first.Runs)
+	}
+	if marathon.Marathon[1].Dirty || len(marathon.Marathon[1].SplitFile.Runs) != 0 {
+		t.Fatalf("a game the run never reached got a run written to it")
+	}
+}
@@ -0,0 +1,242 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// Practice describes the leaf segments StartIndex through EndIndex (inclusive) being practiced.
+//
+// A negative EndIndex means the last leaf segment.  When UpdateGolds is set, segments finished in practice count
+// towards golds and the sum of best.  Practice never changes the PB, averages or SplitFile.Attempts.
+type Practice struct {
+	StartIndex  int
+	EndIndex    int
+	UpdateGolds bool
+}
+
+// PracticeRun is a Run recorded in practice mode.  It is kept in SplitFile.PracticeRuns, apart from full runs.
+type PracticeRun struct {
+	Run
+	StartSegmentID uuid.UUID
+	EndSegmentID   uuid.UUID
+	UpdateGolds    bool
+}
+
+// StartPractice puts the session in practice mode.
+//
+// The next Split starts timing at p.StartIndex instead of the first segment, and the run finishes after p.EndIndex.
+// Practice can't be started while a run is in progress.
+func (s *Service) StartPractice(p Practice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+
+	if s.loadedSplitFile == nil {
+		return fmt.Errorf("no split file loaded")
+	}
+	if s.sessionState != Idle {
+		return fmt.Errorf("can't start practice with a run in progress")
+	}
+	if p.EndIndex < 0 {
+		p.EndIndex = len(s.leafSegments) - 1
+	}
+	if p.StartIndex < 0 || p.StartIndex > p.EndIndex || p.EndIndex >= len(s.leafSegments) {
+		return fmt.Errorf("invalid practice range %d-%d for %d segments", p.StartIndex, p.EndIndex, len(s.leafSegments))
+	}
+
+	s.practice = &p
+	logger.Infof(logModule, "practice started on segments %d-%d (update golds: %t)", p.StartIndex, p.EndIndex, p.UpdateGolds)
+	return nil
+}
+
+// StopPractice leaves practice mode, dropping any practice attempt in progress
+func (s *Service) StopPractice() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+
+	if s.practice == nil {
+		return
+	}
+	if s.sessionState != Idle {
+		s.resetLocked()
+	}
+	s.practice = nil
+	logger.Info(logModule, "practice stopped")
+}
+
+// Practice returns the practice range if the session is in practice mode
+func (s *Service) Practice() (Practice, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.practice == nil {
+		return Practice{}, false
+	}
+	return *s.practice, true
+}
+
+// firstIndex is the leaf segment index a run starts at, must be called under lock
+func (s *Service) firstIndex() int {
+	if s.practice != nil {
+		return s.practice.StartIndex
+	}
+	return 0
+}
+
+// lastIndex is the leaf segment index a run finishes after, must be called under lock
+func (s *Service) lastIndex() int {
+	if s.practice != nil {
+		return s.practice.EndIndex
+	}
+	return len(s.leafSegments) - 1
+}
+
+// movePracticeStart moves the practice start segment by delta while no attempt is in progress.
+//
+// This lets SKIP and UNDO pick the starting segment with hotkeys.  The end segment is pushed along when the start
+// passes it.
+func (s *Service) movePracticeStart(delta int) {
+	start := s.practice.StartIndex + delta
+	if start < 0 || start >= len(s.leafSegments) {
+		return
+	}
+	s.practice.StartIndex = start
+	if s.practice.EndIndex < start {
+		s.practice.EndIndex = start
+	}
+	logger.Infof(logModule, "practice start moved to %s", s.leafSegments[start].Name)
+}
+
+func (s *Service) startPracticeRun(at time.Time) SplitResult {
+	if s.loadedSplitFile == nil || s.practice.EndIndex >= len(s.leafSegments) {
+		logger.Debug(logModule, "Split() called in practice without a matching split file, NO-OP")
+		return SplitNoop
+	}
+
+	// practice attempts time the range on its own, so the split file's offset doesn't apply
+	s.timer.StartAt(at)
+	s.loadedSplitFile.PracticeAttempts++
+	s.sessionState = Running
+	s.currentSegmentIndex = s.practice.StartIndex
+	s.currentRun = &Run{
+		ID:               uuid.New(),
+		Splits:           map[uuid.UUID]Split{},
+		LeafSegments:     s.loadedSplitFile.DeepCopyLeafSegments(),
+		SplitFileVersion: s.loadedSplitFile.Version,
+	}
+
+	s.dirty = true
+	logger.Infof(logModule, "practice attempt started at %s (practice attempt: %d)",
+		s.leafSegments[s.practice.StartIndex].Name, s.loadedSplitFile.PracticeAttempts)
+	return SplitStarted
+}
+
+// persistPracticeRun is PersistRunToSession for practice attempts
+func (s *Service) persistPracticeRun() {
+	s.loadedSplitFile.PracticeRuns = append(s.loadedSplitFile.PracticeRuns, PracticeRun{
+		Run:            *s.currentRun,
+		StartSegmentID: s.leafSegments[s.practice.StartIndex].ID,
+		EndSegmentID:   s.leafSegments[s.practice.EndIndex].ID,
+		UpdateGolds:    s.practice.UpdateGolds,
+	})
+	s.loadedSplitFile.BuildStats()
+	logger.Info(logModule, "practice run persisted to session, new stats built")
+}
+
+// PracticeRollingWindow is how many of a segment's most recent practice results make up its rolling success rate
+const PracticeRollingWindow = 20
+
+// PracticeResult records whether a trick in a segment was hit while practicing
+type PracticeResult struct {
+	SegmentID uuid.UUID
+	Success   bool
+	Time      time.Time
+	Source    SplitSource
+}
+
+// PracticeStat summarizes the practice results of one segment
+type PracticeStat struct {
+	Successes          int
+	Failures           int
+	RollingSuccessRate float64
+	RollingAttempts    int
+}
+
+// RecordPracticeResult adds a success or failure to the practice log and returns the segment it was recorded for.
+//
+// Results are recorded for the segment being timed, the segment just finished once an attempt is over, or the
+// starting segment while no attempt is in progress.
+func (s *Service) RecordPracticeResult(success bool, source SplitSource) (Segment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+
+	if s.loadedSplitFile == nil || s.practice == nil {
+		return Segment{}, fmt.Errorf("practice results can only be recorded in practice")
+	}
+
+	index := s.practice.StartIndex
+	switch s.sessionState {
+	case Running, Paused:
+		index = s.currentSegmentIndex
+	case Finished:
+		index = s.currentSegmentIndex - 1
+	}
+	if index < 0 || index >= len(s.leafSegments) {
+		return Segment{}, fmt.Errorf("no segment to record a practice result for")
+	}
+
+	segment := s.leafSegments[index]
+	s.loadedSplitFile.PracticeLog = append(s.loadedSplitFile.PracticeLog, PracticeResult{
+		SegmentID: segment.ID,
+		Success:   success,
+		Time:      s.eventTime(source.At),
+		Source:    source,
+	})
+	s.dirty = true
+	logger.Infof(logModule, "practice result for %s: success %t", segment.Name, success)
+	return *segment, nil
+}
+
+// PracticeStats totals the practice log by segment ID
+func (s *SplitFile) PracticeStats() map[uuid.UUID]PracticeStat {
+	stats := map[uuid.UUID]PracticeStat{}
+	if s == nil {
+		return stats
+	}
+
+	recent := map[uuid.UUID][]bool{}
+	for _, result := range s.PracticeLog {
+		stat := stats[result.SegmentID]
+		if result.Success {
+			stat.Successes++
+		} else {
+			stat.Failures++
+		}
+		stats[result.SegmentID] = stat
+
+		window := append(recent[result.SegmentID], result.Success)
+		if len(window) > PracticeRollingWindow {
+			window = window[1:]
+		}
+		recent[result.SegmentID] = window
+	}
+
+	for segmentID, window := range recent {
+		successes := 0
+		for _, success := range window {
+			if success {
+				successes++
+			}
+		}
+		stat := stats[segmentID]
+		stat.RollingAttempts = len(window)
+		stat.RollingSuccessRate = float64(successes) / float64(len(window))
+		stats[segmentID] = stat
+	}
+	return stats
+}
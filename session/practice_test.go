@@ -0,0 +1,175 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func getPracticeService() (*Service, *MockTimer) {
+	s, mt, m, _ := getService()
+	sf, _ := m.Load()
+	s.SetLoadedSplitFile(sf)
+
+	// every split happens a second after the last
+	now := time.Now()
+	s.SetClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+	return s, mt
+}
+
+func TestStartPractice(t *testing.T) {
+	s, _ := getPracticeService()
+
+	if err := s.StartPractice(Practice{StartIndex: 1, EndIndex: 0}); err == nil {
+		t.Fatalf("StartPractice() with start after end want error, got nil")
+	}
+	if err := s.StartPractice(Practice{StartIndex: 0, EndIndex: 2}); err == nil {
+		t.Fatalf("StartPractice() with end out of range want error, got nil")
+	}
+
+	if err := s.StartPractice(Practice{StartIndex: 1, EndIndex: -1}); err != nil {
+		t.Fatalf("StartPractice() returned error: %s", err)
+	}
+	p, ok := s.Practice()
+	if !ok || p.StartIndex != 1 || p.EndIndex != 1 {
+		t.Fatalf("Practice() want 1-1, got %v %v", p, ok)
+	}
+
+	s.StopPractice()
+	s.Split(SplitSource{})
+	if err := s.StartPractice(Practice{}); err == nil {
+		t.Fatalf("StartPractice() mid run want error, got nil")
+	}
+}
+
+func TestPracticeRun(t *testing.T) {
+	s, mt := getPracticeService()
+	if err := s.StartPractice(Practice{StartIndex: 1, EndIndex: -1}); err != nil {
+		t.Fatalf("StartPractice() returned error: %s", err)
+	}
+
+	if s.Split(SplitSource{}) != SplitStarted {
+		t.Fatalf("Split() in practice didn't start a run")
+	}
+	if s.currentSegmentIndex != 1 {
+		t.Fatalf("Split() in practice currentSegmentIndex want %d, got %d", 1, s.currentSegmentIndex)
+	}
+	if mt.SubtractTimeCalled != 0 {
+		t.Fatalf("Split() in practice applied the split file offset")
+	}
+
+	s.Undo()
+	if s.currentSegmentIndex != 1 {
+		t.Fatalf("Undo() on the practice start segment currentSegmentIndex want %d, got %d", 1, s.currentSegmentIndex)
+	}
+
+	if s.Split(SplitSource{}) != SplitFinished {
+		t.Fatalf("Split() on the practice end segment didn't finish")
+	}
+
+	sf, _ := s.SplitFile()
+	if sf.Attempts != 0 || len(sf.Runs) != 0 || sf.PB != nil {
+		t.Fatalf("practice changed full run history: attempts %d, runs %d, PB %v", sf.Attempts, len(sf.Runs), sf.PB)
+	}
+	if sf.PracticeAttempts != 1 || len(sf.PracticeRuns) != 1 {
+		t.Fatalf("practice attempts want %d/%d, got %d/%d", 1, 1, sf.PracticeAttempts, len(sf.PracticeRuns))
+	}
+	run := sf.PracticeRuns[0]
+	if run.StartSegmentID != uid2 || run.EndSegmentID != uid2 || !run.Completed {
+		t.Fatalf("practice run want completed %s-%s, got %v", uid2, uid2, run)
+	}
+
+	s.Undo()
+	if sf, _ = s.SplitFile(); len(sf.PracticeRuns) != 0 {
+		t.Fatalf("Undo() of a finished practice run left %d practice runs", len(sf.PracticeRuns))
+	}
+}
+
+func TestPracticeGolds(t *testing.T) {
+	s, _ := getPracticeService()
+	_ = s.StartPractice(Practice{StartIndex: 1, EndIndex: 1})
+	s.Split(SplitSource{})
+	s.Split(SplitSource{})
+
+	sf, _ := s.SplitFile()
+	if gold := sf.Segments[1].Gold; gold != -1 {
+		t.Fatalf("practice without UpdateGolds set gold to %d", gold)
+	}
+
+	s.Split(SplitSource{})
+	s.StopPractice()
+	_ = s.StartPractice(Practice{StartIndex: 1, EndIndex: 1, UpdateGolds: true})
+	s.Split(SplitSource{})
+	s.Split(SplitSource{})
+
+	sf, _ = s.SplitFile()
+	if gold := sf.Segments[1].Gold; gold <= 0 {
+		t.Fatalf("practice with UpdateGolds want a gold, got %d", gold)
+	}
+	if sf.Segments[1].Average != -1 || sf.PB != nil {
+		t.Fatalf("practice changed average or PB: %d %v", sf.Segments[1].Average, sf.PB)
+	}
+}
+
+func TestPracticeMoveStart(t *testing.T) {
+	s, _ := getPracticeService()
+	_ = s.StartPractice(Practice{StartIndex: 0, EndIndex: 0})
+
+	s.Skip()
+	if p, _ := s.Practice(); p.StartIndex != 1 || p.EndIndex != 1 {
+		t.Fatalf("Skip() while practice is idle want range 1-1, got %d-%d", p.StartIndex, p.EndIndex)
+	}
+	s.Skip()
+	if p, _ := s.Practice(); p.StartIndex != 1 {
+		t.Fatalf("Skip() past the last segment moved start to %d", p.StartIndex)
+	}
+	s.Undo()
+	if p, _ := s.Practice(); p.StartIndex != 0 || p.EndIndex != 1 {
+		t.Fatalf("Undo() while practice is idle want range 0-1, got %d-%d", p.StartIndex, p.EndIndex)
+	}
+}
+
+func TestRecordPracticeResult(t *testing.T) {
+	s, _ := getPracticeService()
+	if _, err := s.RecordPracticeResult(true, SplitSource{}); err == nil {
+		t.Fatalf("RecordPracticeResult() outside practice want error, got nil")
+	}
+
+	_ = s.StartPractice(Practice{StartIndex: 1, EndIndex: 1})
+	segment, err := s.RecordPracticeResult(true, SplitSource{Kind: "hotkey"})
+	if err != nil || segment.ID != uid2 {
+		t.Fatalf("RecordPracticeResult() while idle want segment %s, got %s (%v)", uid2, segment.ID, err)
+	}
+
+	s.Split(SplitSource{})
+	s.Split(SplitSource{})
+	segment, _ = s.RecordPracticeResult(false, SplitSource{})
+	if segment.ID != uid2 {
+		t.Fatalf("RecordPracticeResult() after a finished attempt want segment %s, got %s", uid2, segment.ID)
+	}
+
+	sf, _ := s.SplitFile()
+	if len(sf.PracticeLog) != 2 || sf.PracticeLog[0].Source.Kind != "hotkey" || sf.PracticeLog[0].Time.IsZero() {
+		t.Fatalf("RecordPracticeResult() practice log want 2 timestamped results, got %v", sf.PracticeLog)
+	}
+}
+
+func TestPracticeStats(t *testing.T) {
+	sf := SplitFile{}
+	for i := 0; i < PracticeRollingWindow+10; i++ {
+		// the first 10 results fail and drop out of the rolling window
+		sf.PracticeLog = append(sf.PracticeLog, PracticeResult{SegmentID: uid, Success: i >= 10 && i%2 == 0})
+	}
+
+	stat := sf.PracticeStats()[uid]
+	if stat.Successes != PracticeRollingWindow/2 || stat.Failures != PracticeRollingWindow/2+10 {
+		t.Fatalf("PracticeStats() want %d/%d, got %d/%d",
+			PracticeRollingWindow/2, PracticeRollingWindow/2+10, stat.Successes, stat.Failures)
+	}
+	if stat.RollingAttempts != PracticeRollingWindow || stat.RollingSuccessRate != 0.5 {
+		t.Fatalf("PracticeStats() rolling want %d at 0.5, got %d at %f", PracticeRollingWindow, stat.RollingAttempts,
+			stat.RollingSuccessRate)
+	}
+}
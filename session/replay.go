@@ -0,0 +1,124 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// StartReplay plays a past run back on the session's timer, uuid.Nil replays the PB.
+//
+// The timer is restarted from zero and AdvanceReplay adds the past run's splits as the timer passes them, so the
+// replay reaches the frontend through the usual timer and session updates.  Nothing is recorded: the replay isn't an
+// attempt and never changes the split file.  Replays can't be started while a run is in progress.
+func (s *Service) StartReplay(runID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+
+	if s.loadedSplitFile == nil {
+		return fmt.Errorf("no split file loaded")
+	}
+	if s.sessionState != Idle || s.practice != nil {
+		return fmt.Errorf("can't replay with a run in progress")
+	}
+	run, ok := s.findRun(runID)
+	if !ok {
+		return fmt.Errorf("no run %s to replay", runID)
+	}
+
+	replay := deepCopyRun(*run)
+	s.replay = &replay
+	s.currentRun = &Run{
+		ID:               replay.ID,
+		Splits:           map[uuid.UUID]Split{},
+		LeafSegments:     deepCopySegments(replay.LeafSegments),
+		SplitFileVersion: replay.SplitFileVersion,
+	}
+	s.currentSegmentIndex = 0
+	s.sessionState = Running
+	s.timer.Reset()
+	s.timer.Start()
+	logger.Infof(logModule, "replaying run %s", replay.ID)
+	return nil
+}
+
+// AdvanceReplay adds the splits the timer has passed to the replay, it returns false once the replay has ended
+func (s *Service) AdvanceReplay() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.replay == nil {
+		return false
+	}
+	if s.sessionState != Running {
+		return s.sessionState == Paused
+	}
+
+	elapsed := s.timer.GetCurrentTime()
+	changed := false
+	for s.currentSegmentIndex < len(s.replay.LeafSegments) {
+		segmentID := s.replay.LeafSegments[s.currentSegmentIndex].ID
+		split, ok := s.replay.Splits[segmentID]
+		if ok {
+			if split.CurrentCumulative > elapsed {
+				break
+			}
+			s.currentRun.Splits[segmentID] = split
+			s.currentRun.TotalTime = split.CurrentCumulative
+		} else if !s.replayHasSplitAfterLocked(s.currentSegmentIndex) {
+			break
+		}
+		s.currentSegmentIndex++
+		changed = true
+	}
+
+	ended := s.currentSegmentIndex >= len(s.replay.LeafSegments) ||
+		!s.replayHasSplitAfterLocked(s.currentSegmentIndex)
+	if ended {
+		s.timer.Pause()
+		s.currentRun.Completed = s.replay.Completed
+		if s.replay.Completed {
+			s.currentRun.TotalTime = s.replay.TotalTime
+		}
+		s.sessionState = Finished
+		changed = true
+		logger.Infof(logModule, "replay of run %s ended", s.replay.ID)
+	}
+	if changed {
+		s.sendUpdate()
+	}
+	return !ended
+}
+
+// StopReplay ends the replay and resets the session
+func (s *Service) StopReplay() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.sendUpdate()
+	if s.replay == nil {
+		return
+	}
+	s.resetLocked()
+	logger.Info(logModule, "replay stopped")
+}
+
+// Replay returns the ID of the run being replayed
+func (s *Service) Replay() (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.replay == nil {
+		return uuid.Nil, false
+	}
+	return s.replay.ID, true
+}
+
+// replayHasSplitAfterLocked reports whether the replayed run split at index or later, it must be called under lock
+func (s *Service) replayHasSplitAfterLocked(index int) bool {
+	for _, segment := range s.replay.LeafSegments[min(index, len(s.replay.LeafSegments)):] {
+		if _, ok := s.replay.Splits[segment.ID]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -10,7 +10,9 @@ import (
 )
 
 const logModule = "session"
-const splitDebounce = 120 * time.Millisecond
+
+// maxEventAge is how old an input's timestamp may be before it is taken to come from a clock that disagrees with ours
+const maxEventAge = 5 * time.Second
 
 type SplitResult int
 
@@ -20,6 +22,8 @@ const (
 	SplitAdvanced
 	SplitFinished
 	SplitReset
+	// SplitTooShort is a split ignored because its segment took less than the segment's MinDuration
+	SplitTooShort
 )
 
 type State byte
@@ -41,6 +45,10 @@ type Timer interface {
 	Reset()
 	GetCurrentTime() time.Duration
 	SubtractTime(duration time.Duration)
+	// StartAt starts the timer as though Start had been called at the instant at
+	StartAt(at time.Time)
+	// TimeAt returns the time the timer read, or will read, at the instant at
+	TimeAt(at time.Time) time.Duration
 }
 
 // Split represents an advancement of a run through the DeepCopyLeafSegments.
@@ -50,16 +58,29 @@ type Split struct {
 	SplitSegmentID    uuid.UUID
 	CurrentCumulative time.Duration
 	CurrentDuration   time.Duration
+	Source            SplitSource
+}
+
+// SplitSource records what triggered a Split, e.g. a hotkey, the UI or an autosplitter
+type SplitSource struct {
+	Kind   string
+	Detail string
+	// At is when the input behind the split happened.  The split is timed from it rather than from when the session got
+	// to it, and it is zero for sources that can't tell.  It isn't saved.
+	At time.Time
 }
 
 // Segment represents a portion of a game that you want to time (e.g. "Level 1")
 type Segment struct {
-	ID       uuid.UUID
-	Name     string
-	Gold     time.Duration
-	Average  time.Duration
-	PB       time.Duration
-	Children []Segment
+	ID      uuid.UUID
+	Name    string
+	Gold    time.Duration
+	Average time.Duration
+	PB      time.Duration
+	// MinDuration ignores splits that would time the segment faster than it can be played, e.g. an accidental double
+	// press.  Zero allows any split.
+	MinDuration time.Duration
+	Children    []Segment
 }
 
 // Run is a snapshot of a SplitFile along with additional data to track a run
@@ -85,9 +106,13 @@ type Service struct {
 	currentRun           *Run
 	currentSegmentIndex  int
 	sessionState         State
-	lastSplitTime        time.Time
 	dirty                bool
 	sessionUpdateChannel chan *Service
+	now                  func() time.Time
+	practice             *Practice
+	ghostRunID           uuid.UUID
+	replay               *Run
+	gameStatus           GameStatus
 }
 
 // NewService creates a new Service from the passed in components.
@@ -100,6 +125,7 @@ func NewService(timer Timer) (*Service, chan *Service) {
 		timer:                timer,
 		currentSegmentIndex:  -1,
 		sessionUpdateChannel: make(chan *Service, 128),
+		now:                  time.Now,
 	}
 
 	return service, service.sessionUpdateChannel
@@ -118,6 +144,15 @@ func (s *Service) UpdateWindowDimensions(x, y, w, h int) {
 	logger.Debugf(logModule, "session received new window dimensions: x:%d y:%d w:%d h:%d", x, y, w, h)
 }
 
+// SetClock replaces the wall clock that input timestamps are checked against.
+//
+// This lets replays and tests feed commands faster than real time while keeping split times deterministic.
+func (s *Service) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
 func (s *Service) SetLoadedSplitFile(sf SplitFile) {
 	logger.Debugf(logModule, "setting loaded splitfile to %s", sf.GameName)
 	s.mu.Lock()
@@ -131,25 +166,30 @@ func (s *Service) SetLoadedSplitFile(sf SplitFile) {
 	s.currentSegmentIndex = -1
 	s.sessionState = Idle
 	s.dirty = false
+	s.practice = nil
+	s.ghostRunID = uuid.Nil
+	s.replay = nil
 	logger.Infof(logModule, "%s loaded in session (segments total/leaf %d/%d)",
 		sf.GameName, len(sf.Segments), len(s.leafSegments))
 }
 
 // Split starts, advances, finishes, or resets a run depending on the state
-func (s *Service) Split() SplitResult {
+//
+// source is recorded on the Split created when the run advances.
+func (s *Service) Split(source SplitSource) SplitResult {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	defer s.sendUpdate()
 
-	if !s.debounced() {
-		return SplitNoop
-	}
-
+	at := s.eventTime(source.At)
 	switch s.sessionState {
 	case Idle:
-		return s.startNewRun()
+		if s.practice != nil {
+			return s.startPracticeRun(at)
+		}
+		return s.startNewRun(at)
 	case Running:
-		return s.advanceRun()
+		return s.advanceRun(source, at)
 	case Finished:
 		s.resetLocked()
 		return SplitReset
@@ -165,7 +205,12 @@ func (s *Service) Undo() {
 	defer s.mu.Unlock()
 	defer s.sendUpdate()
 
-	if s.currentRun == nil || s.currentSegmentIndex <= 0 || s.sessionState == Idle {
+	if s.practice != nil && s.sessionState == Idle {
+		s.movePracticeStart(-1)
+		return
+	}
+
+	if s.currentRun == nil || s.currentSegmentIndex <= s.firstIndex() || s.sessionState == Idle {
 		return
 	}
 
@@ -203,10 +248,17 @@ func (s *Service) Undo() {
 		s.currentRun.Completed = false
 
 		// remove this run from finished runs
-		if len(s.loadedSplitFile.Runs) > 0 {
+		if s.practice != nil {
+			practiceRuns := s.loadedSplitFile.PracticeRuns
+			if len(practiceRuns) > 0 && practiceRuns[len(practiceRuns)-1].ID == s.currentRun.ID {
+				s.loadedSplitFile.PracticeRuns = practiceRuns[:len(practiceRuns)-1]
+				s.loadedSplitFile.BuildStats()
+			}
+		} else if len(s.loadedSplitFile.Runs) > 0 {
 			lastCompletedRun := s.loadedSplitFile.Runs[len(s.loadedSplitFile.Runs)-1]
 			if lastCompletedRun.ID == s.currentRun.ID {
 				s.loadedSplitFile.Runs = s.loadedSplitFile.Runs[:len(s.loadedSplitFile.Runs)-1]
+				s.loadedSplitFile.removeMarathonPortions(s.currentRun.ID)
 			}
 		}
 
@@ -221,8 +273,13 @@ func (s *Service) Skip() {
 	defer s.mu.Unlock()
 	defer s.sendUpdate()
 
+	if s.practice != nil && s.sessionState == Idle {
+		s.movePracticeStart(1)
+		return
+	}
+
 	if s.currentRun == nil ||
-		s.currentSegmentIndex >= len(s.leafSegments)-1 ||
+		s.currentSegmentIndex >= s.lastIndex() ||
 		s.sessionState == Idle ||
 		s.sessionState == Finished {
 		return
@@ -267,6 +324,7 @@ func (s *Service) Reset() {
 func (s *Service) CloseRun() {
 	s.mu.Lock()
 	s.currentRun = nil
+	s.practice = nil
 	s.mu.Unlock()
 	logger.Info(logModule, "run closed, resetting session")
 	s.resetLocked()
@@ -291,6 +349,11 @@ func (s *Service) ClearDirty() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.dirty = false
+	if s.loadedSplitFile != nil {
+		for i := range s.loadedSplitFile.Marathon {
+			s.loadedSplitFile.Marathon[i].Dirty = false
+		}
+	}
 	logger.Debug(logModule, "dirty flag cleared")
 }
 
@@ -300,6 +363,23 @@ func (s *Service) State() State { s.mu.Lock(); defer s.mu.Unlock(); return s.ses
 // Index returns the current segment index of the session
 func (s *Service) Index() int { s.mu.Lock(); defer s.mu.Unlock(); return s.currentSegmentIndex }
 
+// Elapsed returns the run time on the timer now
+func (s *Service) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timer.TimeAt(s.eventTime(time.Time{}))
+}
+
+// CurrentSegment returns the leaf segment the run is on, false when there isn't one
+func (s *Service) CurrentSegment() (Segment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.currentSegmentIndex < 0 || s.currentSegmentIndex >= len(s.leafSegments) {
+		return Segment{}, false
+	}
+	return deepCopySegments([]Segment{*s.leafSegments[s.currentSegmentIndex]})[0], true
+}
+
 // Run returns the currently loaded Run
 func (s *Service) Run() (Run, bool) {
 	s.mu.Lock()
@@ -318,32 +398,44 @@ func (s *Service) resetLocked() {
 	s.timer.Reset()
 
 	s.currentRun = nil
+	s.replay = nil
 	s.sessionState = Idle
 	s.currentSegmentIndex = -1
 	logger.Info(logModule, "session reset")
 }
 
 func (s *Service) PersistRunToSession() {
-	if s.currentRun != nil {
+	if s.currentRun != nil && s.practice != nil {
+		s.persistPracticeRun()
+	} else if s.currentRun != nil {
 		s.loadedSplitFile.Runs = append(s.loadedSplitFile.Runs, *s.currentRun)
 		s.loadedSplitFile.BuildStats()
+		s.loadedSplitFile.recordMarathonPortions(*s.currentRun)
 		logger.Info(logModule, "run persisted to session, new stats built")
 	} else {
 		logger.Warn(logModule, "persist requested on nil current run")
 	}
 }
 
-func (s *Service) debounced() bool {
-	if t := time.Now(); s.lastSplitTime.Add(splitDebounce).After(t) {
-		logger.Warn(logModule, "split debounced")
-		return false
-	} else {
-		s.lastSplitTime = t
-		return true
+// eventTime returns when the input behind a command happened, at when it is set and plausible and now otherwise.  It
+// must be called under lock.
+func (s *Service) eventTime(at time.Time) time.Time {
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+	t := now()
+	if at.IsZero() {
+		return t
 	}
+	if age := t.Sub(at); age < 0 || age > maxEventAge {
+		logger.Warnf(logModule, "input timestamp is %s off, timing it from now instead", age)
+		return t
+	}
+	return at
 }
 
-func (s *Service) startNewRun() SplitResult {
+func (s *Service) startNewRun(at time.Time) SplitResult {
 	// Start a new run
 	if s.loadedSplitFile == nil {
 		logger.Debug(logModule, "Split() called with no loaded dto.  NO-OP")
@@ -356,7 +448,7 @@ func (s *Service) startNewRun() SplitResult {
 	}
 
 	s.timer.SubtractTime(s.loadedSplitFile.Offset)
-	s.timer.Start()
+	s.timer.StartAt(at)
 	s.loadedSplitFile.Attempts++
 	s.sessionState = Running
 	s.currentSegmentIndex = 0
@@ -373,19 +465,14 @@ func (s *Service) startNewRun() SplitResult {
 	return SplitStarted
 }
 
-func (s *Service) advanceRun() SplitResult {
+// advanceRun records a split of the current segment at the run time the timer read at the instant at
+func (s *Service) advanceRun(source SplitSource, at time.Time) SplitResult {
 	if s.currentSegmentIndex < 0 || s.currentSegmentIndex >= len(s.leafSegments) {
 		logger.Warnf(logModule,
 			"Split() called in Running state, but current segment index is out of bounds: %d",
 			s.currentSegmentIndex)
 		return SplitNoop
 	}
-	now := s.timer.GetCurrentTime()
-
-	//if splitfile has a negative offset, don't let user split until it starts counting
-	if now < 1*time.Millisecond {
-		return SplitNoop
-	}
 
 	// find prev cumulative from the last non-nil split
 	prev := time.Duration(0)
@@ -397,20 +484,38 @@ func (s *Service) advanceRun() SplitResult {
 		}
 	}
 
+	now := s.timer.TimeAt(at)
+	if now < prev {
+		// the input happened before the previous split was recorded, or before the run was resumed
+		logger.Warnf(logModule, "split input predates the last split by %s, timing it from now instead", prev-now)
+		now = s.timer.TimeAt(s.eventTime(time.Time{}))
+	}
+
+	//if splitfile has a negative offset, don't let user split until it starts counting
+	if now < 1*time.Millisecond {
+		return SplitNoop
+	}
+
 	segTime := now - prev
 	segmentID := s.currentRun.LeafSegments[s.currentSegmentIndex].ID
 	segmentName := s.currentRun.LeafSegments[s.currentSegmentIndex].Name
+	if minimum := s.leafSegments[s.currentSegmentIndex].MinDuration; segTime < minimum {
+		logger.Warnf(logModule, "split of %s ignored, %d is under its minimum of %d", segmentName,
+			segTime.Milliseconds(), minimum.Milliseconds())
+		return SplitTooShort
+	}
 	s.currentRun.Splits[segmentID] = Split{
 		SplitSegmentID:    segmentID,
 		CurrentCumulative: now,
 		CurrentDuration:   segTime,
+		Source:            source,
 	}
 
 	s.dirty = true
 	s.currentSegmentIndex++
 	logger.Infof(logModule, "split %s at %d", segmentName, segTime.Milliseconds())
 
-	if s.currentSegmentIndex > len(s.leafSegments)-1 {
+	if s.currentSegmentIndex > s.lastIndex() {
 		logger.Info(logModule, "run complete")
 		s.timer.Pause()
 		s.sessionState = Finished
@@ -436,6 +541,11 @@ func (s *Service) sendUpdate() {
 func deepCopySplitFile(inFile *SplitFile) SplitFile {
 	segments := deepCopySegments(inFile.Segments)
 	runs := deepCopyRuns(inFile.Runs)
+	practiceRuns := make([]PracticeRun, len(inFile.PracticeRuns))
+	for i, run := range inFile.PracticeRuns {
+		practiceRuns[i] = run
+		practiceRuns[i].Run = deepCopyRun(run.Run)
+	}
 
 	var pbRun *Run
 	if inFile.PB != nil {
@@ -459,6 +569,11 @@ func deepCopySplitFile(inFile *SplitFile) SplitFile {
 		Runs:             runs,
 		PB:               pbRun,
 		AutosplitterFile: inFile.AutosplitterFile,
+		PracticeAttempts: inFile.PracticeAttempts,
+		PracticeRuns:     practiceRuns,
+		PracticeLog:      append([]PracticeResult(nil), inFile.PracticeLog...),
+		Marathon:         deepCopyMarathon(inFile.Marathon),
+		Profile:          inFile.Profile,
 	}
 }
 
@@ -491,6 +606,7 @@ func deepCopySplits(inSplits map[uuid.UUID]Split) map[uuid.UUID]Split {
 			SplitSegmentID:    split.SplitSegmentID,
 			CurrentCumulative: split.CurrentCumulative,
 			CurrentDuration:   split.CurrentDuration,
+			Source:            split.Source,
 		}
 	}
 	return splits
@@ -500,12 +616,13 @@ func deepCopySegments(list []Segment) []Segment {
 	out := make([]Segment, len(list))
 	for i, s := range list {
 		out[i] = Segment{
-			ID:       s.ID,
-			Name:     s.Name,
-			Gold:     s.Gold,
-			Average:  s.Average,
-			PB:       s.PB,
-			Children: deepCopySegments(s.Children),
+			ID:          s.ID,
+			Name:        s.Name,
+			Gold:        s.Gold,
+			Average:     s.Average,
+			PB:          s.PB,
+			MinDuration: s.MinDuration,
+			Children:    deepCopySegments(s.Children),
 		}
 	}
 	return out
@@ -23,6 +23,8 @@ type MockTimer struct {
 	GetCurrentTimeCalled          int
 	SubtractTimeCalled            int
 	Negative                      bool
+	StartedAt                     time.Time
+	ReadAt                        time.Time
 }
 
 func (t *MockTimer) IsRunning() bool {
@@ -43,6 +45,11 @@ func (t *MockTimer) Start() {
 	t.StartCalled++
 }
 
+func (t *MockTimer) StartAt(at time.Time) {
+	t.Start()
+	t.StartedAt = at
+}
+
 func (t *MockTimer) Pause() {
 	t.Running = false
 	t.PauseCalled++
@@ -67,6 +74,11 @@ func (t *MockTimer) GetCurrentTime() time.Duration {
 	return currentTime
 }
 
+func (t *MockTimer) TimeAt(at time.Time) time.Duration {
+	t.ReadAt = at
+	return t.GetCurrentTime()
+}
+
 func (t *MockTimer) SubtractTime(_ time.Duration) {
 	t.SubtractTimeCalled++
 }
@@ -143,7 +155,7 @@ func getService() (*Service, *MockTimer, *MockRepository, *SplitFile) {
 
 func TestSplit(t *testing.T) {
 	s, mt, m, _ := getService()
-	s.Split()
+	s.Split(SplitSource{})
 	if s.currentSegmentIndex != -1 {
 		t.Fatalf("Split() before load s.currentSegmentIndex want %d, got %d", -1, s.currentSegmentIndex)
 	}
@@ -151,8 +163,7 @@ func TestSplit(t *testing.T) {
 	sf, _ := m.Load()
 	s.SetLoadedSplitFile(sf)
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 
 	if s.currentSegmentIndex != 0 {
 		t.Fatalf("Split() s.currentSegmentIndex want %d, got %d", 0, s.currentSegmentIndex)
@@ -175,15 +186,13 @@ func TestSplit(t *testing.T) {
 	}
 
 	mt.SetNegativeTime(true)
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 	if s.currentSegmentIndex != 0 {
 		t.Fatalf("Split() currentsegmentindex when time is negative want %d, got %d", -1, s.currentSegmentIndex)
 	}
 	mt.SetNegativeTime(false)
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{Kind: "autosplitter", Detail: "127.0.0.1:50000"})
 	if s.currentSegmentIndex != 1 {
 		t.Fatalf("Split() s.currentSegmentIndex want %d, got %d", 1, s.currentSegmentIndex)
 	}
@@ -192,8 +201,11 @@ func TestSplit(t *testing.T) {
 		t.Fatalf("Split() 1st recorded split segment ID want %s, got %s", uid.String(), s.currentRun.Splits[uid].SplitSegmentID.String())
 	}
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	if source := s.currentRun.Splits[uid].Source; source.Kind != "autosplitter" || source.Detail != "127.0.0.1:50000" {
+		t.Fatalf("Split() 1st recorded split source want %s, got %v", "autosplitter 127.0.0.1:50000", source)
+	}
+
+	s.Split(SplitSource{})
 	if s.sessionState != Finished {
 		t.Fatalf("Split() s.sessionState want %v, got %v", Finished, s.sessionState)
 	}
@@ -209,8 +221,7 @@ func TestSplit(t *testing.T) {
 		t.Fatalf("Split() final split total time want %d, got %d (%d + %d)", totalTime, s.currentRun.TotalTime, totalTime1, totalTime2)
 	}
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 	if s.timer.IsRunning() {
 		t.Fatalf("reset Split() timer.IsRunning() want %v, got %v", false, s.timer.IsRunning())
 	}
@@ -219,8 +230,7 @@ func TestSplit(t *testing.T) {
 		t.Fatalf("Split() timer reset called want %d, got %d", 2, mt.ResetCalled)
 	}
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 	if s.currentSegmentIndex != 0 {
 		t.Fatalf("Split() s.currentSegmentIndex want %d, got %d", 0, s.currentSegmentIndex)
 	}
@@ -242,11 +252,52 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+func TestSplitEventTime(t *testing.T) {
+	s, mt, m, _ := getService()
+	sf, _ := m.Load()
+	s.SetLoadedSplitFile(sf)
+
+	pressed := time.Now().Add(-30 * time.Millisecond)
+	s.Split(SplitSource{At: pressed})
+	if !mt.StartedAt.Equal(pressed) {
+		t.Fatalf("Split() want the run started at the input's time %v, got %v", pressed, mt.StartedAt)
+	}
+
+	pressed = time.Now().Add(-30 * time.Millisecond)
+	s.Split(SplitSource{At: pressed})
+	if !mt.ReadAt.Equal(pressed) {
+		t.Fatalf("Split() want the timer read at the input's time %v, got %v", pressed, mt.ReadAt)
+	}
+
+	s.Reset()
+	stale := time.Now().Add(-time.Hour)
+	s.Split(SplitSource{At: stale})
+	if mt.StartedAt.Equal(stale) || time.Since(mt.StartedAt) > time.Second {
+		t.Fatalf("Split() with a stale timestamp want the run started now, got %v", mt.StartedAt)
+	}
+}
+
+func TestSplitMinDuration(t *testing.T) {
+	s, _, m, _ := getService()
+	sf, _ := m.Load()
+	// MockTimer always reads 1:02:03.04
+	sf.Segments[0].MinDuration = 2 * time.Hour
+	s.SetLoadedSplitFile(sf)
+
+	s.Split(SplitSource{})
+	if result := s.Split(SplitSource{}); result != SplitTooShort {
+		t.Fatalf("Split() under the segment's minimum want %d, got %d", SplitTooShort, result)
+	}
+	if segment, _ := s.CurrentSegment(); s.Index() != 0 || segment.MinDuration != 2*time.Hour {
+		t.Fatalf("Split() under the segment's minimum want the run left on %s, got index %d", segment.Name, s.Index())
+	}
+}
+
 func TestUndo(t *testing.T) {
 	s, _, m, _ := getService()
 	sf, _ := m.Load()
 	s.SetLoadedSplitFile(sf)
-	s.Split()
+	s.Split(SplitSource{})
 
 	// Should do nothing on the first segment
 	s.Undo()
@@ -254,11 +305,9 @@ func TestUndo(t *testing.T) {
 		t.Fatalf("Undo() on first segment currentSegmentIndex want %d, got %d", 0, s.currentSegmentIndex)
 	}
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 
-	time.Sleep(splitDebounce + 1*time.Millisecond)
-	s.Split()
+	s.Split(SplitSource{})
 	s.Undo()
 	if s.currentSegmentIndex != 1 {
 		t.Fatalf("Undo() on third segment currentSegmentIndex want %d, got %d", 1, s.currentSegmentIndex)
@@ -273,7 +322,7 @@ func TestSkip(t *testing.T) {
 	s, _, m, _ := getService()
 	sf, _ := m.Load()
 	s.SetLoadedSplitFile(sf)
-	s.Split()
+	s.Split(SplitSource{})
 	s.Skip()
 	if s.currentSegmentIndex != 1 {
 		t.Fatalf("Skip() currentSegmentIndex want %d, got %d", 1, s.currentSegmentIndex)
@@ -293,7 +342,7 @@ func TestPause(t *testing.T) {
 		t.Fatalf("Pause() before run start sessionState want %v, got %v", Idle, s.sessionState)
 	}
 
-	s.Split()
+	s.Split(SplitSource{})
 	s.Pause()
 	if s.sessionState != Paused {
 		t.Fatalf("Pause() sessionState want %v, got %v", Paused, s.sessionState)
@@ -317,7 +366,7 @@ func TestReset(t *testing.T) {
 	s, mt, m, _ := getService()
 	sf, _ := m.Load()
 	s.SetLoadedSplitFile(sf)
-	s.Split()
+	s.Split(SplitSource{})
 	s.Reset()
 
 	if mt.ResetCalled != 1 {
@@ -341,7 +390,7 @@ func TestDirty(t *testing.T) {
 		t.Fatalf("Dirty() before split want %v, got %v", false, s.Dirty())
 	}
 
-	s.Split()
+	s.Split(SplitSource{})
 
 	if !s.Dirty() {
 		t.Fatalf("Dirty() after split want %v, got %v", true, s.Dirty())
@@ -355,7 +404,7 @@ func TestState(t *testing.T) {
 	if s.State() != Idle {
 		t.Fatalf("State() before split want %v, got %v", Idle, s.State())
 	}
-	s.Split()
+	s.Split(SplitSource{})
 
 	if s.State() != Running {
 		t.Fatalf("State() after split want %v, got %v", Running, s.State())
@@ -370,7 +419,7 @@ func TestIndex(t *testing.T) {
 		t.Fatalf("Index() after split want %v, got %v", -1, s.Index())
 	}
 
-	s.Split()
+	s.Split(SplitSource{})
 	if s.Index() != 0 {
 		t.Fatalf("Index() after split want %v, got %v", 0, s.Index())
 	}
@@ -384,7 +433,7 @@ func TestRun(t *testing.T) {
 	if valid {
 		t.Fatalf("Run() returned valid current run with currentRun == nil")
 	}
-	s.Split()
+	s.Split(SplitSource{})
 	r, valid = s.Run()
 	if !valid {
 		t.Fatalf("Run() returned nil with valid currentRun")
@@ -395,3 +444,35 @@ func TestRun(t *testing.T) {
 	}
 
 }
+
+func TestSetGameStatus(t *testing.T) {
+	s, updates := NewService(&MockTimer{})
+	updated := func() bool {
+		select {
+		case <-updates:
+			return true
+		default:
+			return false
+		}
+	}
+
+	s.SetGameStatus(GameStatus{Loading: true})
+	if !updated() || !s.GameStatus().Loading {
+		t.Fatal("SetGameStatus() want an update when loading starts")
+	}
+	s.SetGameStatus(GameStatus{Loading: true, GameTime: 500 * time.Millisecond, HasGameTime: true})
+	if !updated() {
+		t.Fatal("SetGameStatus() want an update when game time is first reported")
+	}
+	s.SetGameStatus(GameStatus{Loading: true, GameTime: 900 * time.Millisecond, HasGameTime: true})
+	if updated() {
+		t.Fatal("SetGameStatus() within the same second should not send an update")
+	}
+	if s.GameStatus().GameTime != 900*time.Millisecond {
+		t.Fatalf("GameStatus() want the latest game time, got %s", s.GameStatus().GameTime)
+	}
+	s.SetGameStatus(GameStatus{Loading: true, GameTime: 1100 * time.Millisecond, HasGameTime: true})
+	if !updated() {
+		t.Fatal("SetGameStatus() want an update when game time reaches a new second")
+	}
+}
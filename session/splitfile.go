@@ -24,6 +24,12 @@ type SplitFile struct {
 	PB               *Run
 	Offset           time.Duration
 	AutosplitterFile string
+	PracticeAttempts int
+	PracticeRuns     []PracticeRun
+	PracticeLog      []PracticeResult
+	Marathon         []MarathonGame
+	// Profile names the config profile to use while the split file is loaded, empty for the config's own settings
+	Profile string
 }
 
 func (s *SplitFile) DeepCopyLeafSegments() []Segment {
@@ -52,6 +58,7 @@ func (s *SplitFile) BuildStats() {
 	}
 
 	golds, sumMap, countMap := s.perSegmentAggregates(s.Runs)
+	s.mergePracticeGolds(golds)
 
 	// Reset SOB
 	var SOB time.Duration
@@ -101,6 +108,28 @@ func (s *SplitFile) BuildStats() {
 	logger.Infof("stats", "stats built: PB: %f SOB:%f", s.PB.TotalTime.Seconds(), s.SOB.Seconds())
 }
 
+// UnknownSplitSource is the SplitSourceCounts key for splits recorded before sources were tracked
+const UnknownSplitSource = "unknown"
+
+// SplitSourceCounts counts the splits in every run by SplitSource.Kind
+func (s *SplitFile) SplitSourceCounts() map[string]int {
+	counts := map[string]int{}
+	if s == nil {
+		return counts
+	}
+
+	for _, run := range s.Runs {
+		for _, split := range run.Splits {
+			kind := split.Source.Kind
+			if kind == "" {
+				kind = UnknownSplitSource
+			}
+			counts[kind]++
+		}
+	}
+	return counts
+}
+
 func (s *SplitFile) perSegmentAggregates(runs []Run) (golds map[uuid.UUID]time.Duration, sums map[uuid.UUID]time.Duration, counts map[uuid.UUID]int) {
 	golds = make(map[uuid.UUID]time.Duration)
 	sums = make(map[uuid.UUID]time.Duration)
@@ -120,6 +149,20 @@ func (s *SplitFile) perSegmentAggregates(runs []Run) (golds map[uuid.UUID]time.D
 	return golds, sums, counts
 }
 
+// mergePracticeGolds lowers golds with segments finished in practice runs that were allowed to update golds
+func (s *SplitFile) mergePracticeGolds(golds map[uuid.UUID]time.Duration) {
+	for _, run := range s.PracticeRuns {
+		if !run.UpdateGolds {
+			continue
+		}
+		for segmentID, sp := range run.Splits {
+			if cur, ok := golds[segmentID]; !ok || sp.CurrentDuration < cur {
+				golds[segmentID] = sp.CurrentDuration
+			}
+		}
+	}
+}
+
 func getPB(runs []Run) (*Run, time.Duration, error) {
 	if len(runs) == 0 {
 		return nil, 0, errors.New("no runs found")
@@ -104,3 +104,31 @@ func TestBuildStats(t *testing.T) {
 		t.Errorf("SOB want %s got %s", want, sf.SOB)
 	}
 }
+
+func TestSplitSourceCounts(t *testing.T) {
+	sf := getSplitFile()
+	sf.Runs = []Run{{
+		ID: rID,
+		Splits: map[uuid.UUID]Split{
+			uid:  {SplitSegmentID: uid, Source: SplitSource{Kind: "hotkey"}},
+			uid2: {SplitSegmentID: uid2, Source: SplitSource{Kind: "autosplitter", Detail: "127.0.0.1:50000"}},
+		},
+	}, {
+		ID: rID2,
+		Splits: map[uuid.UUID]Split{
+			uid:  {SplitSegmentID: uid, Source: SplitSource{Kind: "hotkey"}},
+			uid2: {SplitSegmentID: uid2},
+		},
+	}}
+
+	counts := sf.SplitSourceCounts()
+	want := map[string]int{"hotkey": 2, "autosplitter": 1, UnknownSplitSource: 1}
+	if len(counts) != len(want) {
+		t.Fatalf("SplitSourceCounts() want %v, got %v", want, counts)
+	}
+	for kind, n := range want {
+		if counts[kind] != n {
+			t.Errorf("SplitSourceCounts()[%s] want %d, got %d", kind, n, counts[kind])
+		}
+	}
+}
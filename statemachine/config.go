@@ -1,21 +1,29 @@
 package statemachine
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/config"
 	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/dto"
 	"github.com/zellydev-games/opensplit/keyinfo"
 	"github.com/zellydev-games/opensplit/logger"
 )
 
 const RecordingArmed = 10
 
+// configErrorEvent tells the Config view why a hotkey couldn't be recorded, or which bindings in the config conflict
+const configErrorEvent = "config:error"
+
 type Config struct {
 	mu             sync.Mutex
 	listeningFor   dispatcher.Command
+	pinnedIndex    int
 	recordingArmed bool
 	previousState  StateID
 }
@@ -27,10 +35,13 @@ func NewConfigState(previousState StateID) (*Config, error) {
 }
 
 func (c *Config) OnEnter() error {
-	bridge.EmitUIEvent(machine.runtimeProvider, bridge.AppViewModel{
+	machine.emitUIEvent(bridge.AppViewModel{
 		View:   bridge.AppViewSettings,
 		Config: machine.configService,
 	})
+	if conflicts := machine.configService.Conflicts(); len(conflicts) > 0 {
+		machine.runtimeProvider.EventsEmit(configErrorEvent, errors.Join(conflicts...).Error())
+	}
 	return nil
 }
 
@@ -38,7 +49,7 @@ func (c *Config) OnExit() error {
 	return nil
 }
 
-func (c *Config) Receive(command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+func (c *Config) Receive(_ dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	switch command {
@@ -51,10 +62,33 @@ func (c *Config) Receive(command dispatcher.Command, payload *string) (dispatche
 	case dispatcher.PAUSE:
 		fallthrough
 	case dispatcher.RESET:
+		fallthrough
+	case dispatcher.SUCCESS:
+		fallthrough
+	case dispatcher.FAIL:
+		fallthrough
+	case dispatcher.LOCK:
+		fallthrough
+	case dispatcher.SWITCH:
+		if machine.hotkeyProvider == nil {
+			return dispatcher.DispatchReply{Code: 6, Message: "no hotkey provider to record from"}, nil
+		}
+		if command == dispatcher.SWITCH {
+			// SWITCH records the hotkey of the pinned split file at the index in payload
+			index, err := pinnedIndex(payload)
+			if err != nil {
+				return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+			}
+			c.pinnedIndex = index
+		}
 		c.recordingArmed = true
 		c.listeningFor = command
 		logger.Infof(logModule, "recording armed for command: %d", c.listeningFor)
 		err := machine.hotkeyProvider.StartHook(func(data keyinfo.KeyData) {
+			// only presses are recorded
+			if data.Released {
+				return
+			}
 			c.handleHotkey(data)
 			c.recordingArmed = false
 			logger.Infof(logModule, "updated command %v with hotkey %s (%d)",
@@ -70,10 +104,47 @@ func (c *Config) Receive(command dispatcher.Command, payload *string) (dispatche
 			return dispatcher.DispatchReply{Code: 6}, err
 		}
 		return dispatcher.DispatchReply{Code: RecordingArmed}, nil
+	case dispatcher.UNBIND:
+		var unbind dto.Unbind
+		if payload == nil || json.Unmarshal([]byte(*payload), &unbind) != nil {
+			return dispatcher.DispatchReply{Code: 1, Message: "UNBIND requires a command and binding index"}, nil
+		}
+		err := machine.configService.RemoveKeyBinding(dispatcher.Command(unbind.Command), unbind.Index)
+		if err != nil {
+			return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+		}
+		return dispatcher.DispatchReply{}, nil
+	case dispatcher.PROFILE:
+		// settings are edited and saved for whichever profile is active, so Config can add one to edit
+		return switchProfile(payload, true)
 	case dispatcher.CANCEL:
 		machine.changeState(c.previousState)
 		return dispatcher.DispatchReply{}, nil
 	case dispatcher.SUBMIT:
+		// hotkeys are recorded as they're pressed, the payload carries the settings edited in the form
+		if payload != nil && *payload != "" {
+			var submitted struct {
+				HotkeyPolicies map[dispatcher.Command]config.HotkeyPolicy `json:"hotkey_policies"`
+				Guards         *config.GuardConfig                        `json:"guards"`
+				TextOutput     *config.TextOutputConfig                   `json:"text_output"`
+			}
+			if err := json.Unmarshal([]byte(*payload), &submitted); err != nil {
+				return dispatcher.DispatchReply{Code: 1, Message: fmt.Sprintf("invalid config payload: %s", err)}, nil
+			}
+			if err := machine.configService.SetHotkeyPolicies(submitted.HotkeyPolicies); err != nil {
+				return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+			}
+			if submitted.Guards != nil {
+				if err := machine.configService.SetGuards(*submitted.Guards); err != nil {
+					return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+				}
+			}
+			if submitted.TextOutput != nil {
+				if err := machine.configService.SetTextOutput(*submitted.TextOutput); err != nil {
+					return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+				}
+			}
+		}
 		err := machine.repoService.SaveConfig(machine.configService)
 		if err != nil {
 			message := fmt.Sprintf("error saving config to repo %s", err)
@@ -83,16 +154,37 @@ func (c *Config) Receive(command dispatcher.Command, payload *string) (dispatche
 		machine.changeState(c.previousState)
 		return dispatcher.DispatchReply{}, nil
 	default:
-		message := fmt.Sprintf("unknown command sent to config service: %v", command)
-		return dispatcher.DispatchReply{Code: 5, Message: message}, errors.New(message)
+		return rejectCommand(c, command), nil
 	}
 }
 
+// handleHotkey binds a recorded hotkey, a chord that is already bound is reported to the Config view instead
 func (c *Config) handleHotkey(data keyinfo.KeyData) {
 	if c.recordingArmed {
 		c.recordingArmed = false
-		machine.configService.UpdateKeyBinding(c.listeningFor, data)
+		var err error
+		if c.listeningFor == dispatcher.SWITCH {
+			err = machine.configService.UpdatePinnedKeyBinding(c.pinnedIndex, data)
+		} else {
+			err = machine.configService.AddKeyBinding(c.listeningFor, data)
+		}
+		if err != nil {
+			logger.Warn(logModule, err.Error())
+			machine.runtimeProvider.EventsEmit(configErrorEvent, err.Error())
+		}
+	}
+}
+
+// pinnedIndex parses the index of a pinned split file from a SWITCH payload
+func pinnedIndex(payload *string) (int, error) {
+	if payload == nil {
+		return 0, errors.New("SWITCH requires the index of a pinned split file")
+	}
+	index, err := strconv.Atoi(*payload)
+	if err != nil || index < 0 || index >= len(machine.configService.PinnedSplitFiles) {
+		return 0, fmt.Errorf("no pinned split file at index %q", *payload)
 	}
+	return index, nil
 }
 
 func (c *Config) String() string {
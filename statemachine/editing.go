@@ -25,7 +25,7 @@ func (e *Editing) OnEnter() error {
 
 	splitFileDTO := adapters.DomainSplitFileToDTO(sf)
 	machine.sessionService.Pause()
-	bridge.EmitUIEvent(machine.runtimeProvider, bridge.AppViewModel{
+	machine.emitUIEvent(bridge.AppViewModel{
 		View:               bridge.AppViewEditSplitFile,
 		SplitFile:          &splitFileDTO,
 		SpeedrunAPIBaseURL: machine.configService.SpeedRunAPIBase,
@@ -34,7 +34,7 @@ func (e *Editing) OnEnter() error {
 }
 
 func (e *Editing) OnExit() error { return nil }
-func (e *Editing) Receive(command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+func (e *Editing) Receive(_ dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
 	switch command {
 	case dispatcher.CANCEL:
 		machine.changeState(RUNNING)
@@ -58,12 +58,11 @@ func (e *Editing) Receive(command dispatcher.Command, payload *string) (dispatch
 		if err != nil {
 			return dispatcher.DispatchReply{Code: 5, Message: err.Error()}, err
 		}
-		machine.sessionService.SetLoadedSplitFile(sf)
+		useSplitFile(sf)
 		machine.changeState(RUNNING)
 		return dispatcher.DispatchReply{}, nil
 	default:
-		panic("unhandled default case")
-
+		return rejectCommand(e, command), nil
 	}
 	return dispatcher.DispatchReply{}, nil
 }
@@ -0,0 +1,124 @@
+package statemachine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+// guardEvent tells the frontend a command was ignored by a safety guard, with the reason, since hotkeys have nobody
+// to read the DispatchReply
+const guardEvent = "session:guard"
+
+// resetConfirmWindow is how soon a second RESET has to follow the first to confirm it
+const resetConfirmWindow = 3 * time.Second
+
+// commandGuard applies the config's safety guards to commands in the states that time runs
+type commandGuard struct {
+	mu           sync.Mutex
+	accepted     map[dispatcher.Command]time.Time
+	resetPending time.Time
+}
+
+// check returns why command, sent at, should be ignored, or "" to let it through.  confirmResets is set in the states
+// where a RESET can throw away a run.
+func (g *commandGuard) check(at time.Time, command dispatcher.Command, payload *string, confirmResets bool) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if debounce := machine.configService.Debounce(command); debounce > 0 {
+		if last, ok := g.accepted[command]; ok && at.Sub(last) >= 0 && at.Sub(last) < debounce {
+			return fmt.Sprintf("%s ignored, it was sent %dms after the last one and is debounced for %dms",
+				command, at.Sub(last).Milliseconds(), debounce.Milliseconds())
+		}
+	}
+	if command == dispatcher.RESET && confirmResets {
+		if reason := g.confirmResetLocked(at, payload); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// accept records that command, sent at, was carried out, which is when its debounce starts
+func (g *commandGuard) accept(at time.Time, command dispatcher.Command) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.accepted == nil {
+		g.accepted = map[dispatcher.Command]time.Time{}
+	}
+	g.accepted[command] = at
+}
+
+// confirmResetLocked returns why a RESET needs confirming, or "" when it doesn't.  A RESET needs confirming once the
+// run is past the configured threshold, either by sending it again within resetConfirmWindow or with the "confirm"
+// payload.  It must be called under lock.
+func (g *commandGuard) confirmResetLocked(at time.Time, payload *string) string {
+	threshold := machine.configService.ResetConfirmAfter()
+	if threshold == 0 {
+		return ""
+	}
+	if state := machine.sessionService.State(); state != session.Running && state != session.Paused {
+		return ""
+	}
+	elapsed := machine.sessionService.Elapsed()
+	if elapsed < threshold {
+		return ""
+	}
+
+	pending := g.resetPending
+	g.resetPending = time.Time{}
+	if payload != nil && *payload == dispatcher.ResetConfirmPayload {
+		return ""
+	}
+	if !pending.IsZero() && at.Sub(pending) >= 0 && at.Sub(pending) <= resetConfirmWindow {
+		return ""
+	}
+	g.resetPending = at
+	return fmt.Sprintf("RESET at %s needs confirming, send it again within %s", timer.FormatTimeToString(elapsed),
+		resetConfirmWindow)
+}
+
+// receiveGuarded runs the safety guards, then hands the commands they let through to receive.
+//
+// Commands are timed from source.At so a debounce measures the gap between inputs rather than between their handling.
+// A command only starts its debounce once receive carried it out, so one the state rejected doesn't hold back the
+// next.
+func receiveGuarded(source dispatcher.Source, command dispatcher.Command, payload *string, confirmResets bool,
+	receive func(dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error),
+) (dispatcher.DispatchReply, error) {
+	at := source.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if reason := machine.guard.check(at, command, payload, confirmResets); reason != "" {
+		return guardedReply(reason), nil
+	}
+
+	reply, err := receive(source, command, payload)
+	if err == nil && reply.Code == 0 {
+		machine.guard.accept(at, command)
+	}
+	return reply, err
+}
+
+// guardedReply tells the caller and the frontend why a command was ignored
+func guardedReply(reason string) dispatcher.DispatchReply {
+	logger.Info(logModule, reason)
+	machine.runtimeProvider.EventsEmit(guardEvent, reason)
+	return dispatcher.DispatchReply{Code: dispatcher.CodeGuarded, Message: reason}
+}
+
+// splitReply turns the result of a split into its reply, a split under its segment's minimum time is reported
+func splitReply(result session.SplitResult) dispatcher.DispatchReply {
+	if result != session.SplitTooShort {
+		return dispatcher.DispatchReply{}
+	}
+	segment, _ := machine.sessionService.CurrentSegment()
+	return guardedReply(fmt.Sprintf("split ignored, %s can't be shorter than %s", segment.Name,
+		timer.FormatTimeToString(segment.MinDuration)))
+}
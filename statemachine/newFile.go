@@ -25,14 +25,14 @@ func (n *NewFile) ID() StateID {
 }
 
 func (n *NewFile) OnEnter() error {
-	bridge.EmitUIEvent(machine.runtimeProvider, bridge.AppViewModel{
+	machine.emitUIEvent(bridge.AppViewModel{
 		View:               bridge.AppViewNewSplitFile,
 		SpeedrunAPIBaseURL: machine.configService.SpeedRunAPIBase,
 	})
 	return nil
 }
 func (n *NewFile) OnExit() error { return nil }
-func (n *NewFile) Receive(command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+func (n *NewFile) Receive(_ dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
 	switch command {
 	case dispatcher.CANCEL:
 		machine.changeState(WELCOME)
@@ -56,11 +56,11 @@ func (n *NewFile) Receive(command dispatcher.Command, payload *string) (dispatch
 		if err != nil {
 			return dispatcher.DispatchReply{Code: 5, Message: err.Error()}, err
 		}
-		machine.sessionService.SetLoadedSplitFile(sf)
+		useSplitFile(sf)
 		machine.changeState(RUNNING)
 		return dispatcher.DispatchReply{}, nil
 	default:
-		panic("unhandled default case")
+		return rejectCommand(n, command), nil
 	}
 	return dispatcher.DispatchReply{}, nil
 }
@@ -0,0 +1,162 @@
+package statemachine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+// Practice represents the state where the user is timing a range of segments on their own.
+//
+// Practice attempts are stored apart from full runs by the session, so the PB and Attempts are untouched.  While no
+// attempt is in progress SKIP and UNDO move the starting segment.  CANCEL, or PRACTICE without a payload, returns to
+// Running.
+type Practice struct{}
+
+func NewPracticeState() (*Practice, error) {
+	return &Practice{}, nil
+}
+
+func (p *Practice) OnEnter() error {
+	machine.saveOnWindowDimensionChanges = true
+	startRunInputs()
+	emitRunningView()
+	return nil
+}
+
+func (p *Practice) OnExit() error {
+	machine.saveOnWindowDimensionChanges = false
+	return stopRunInputs()
+}
+
+func (p *Practice) Receive(source dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+	// a practice attempt is short, so resetting one needs no confirmation
+	return receiveGuarded(source, command, payload, false, p.receive)
+}
+
+func (p *Practice) receive(source dispatcher.Source, command dispatcher.Command,
+	payload *string) (dispatcher.DispatchReply, error) {
+	switch command {
+	case dispatcher.CLOSE:
+		logger.Debug(logModule, "Practice received CLOSE command")
+		err := machine.promptDirtySave()
+		if err != nil {
+			return dispatcher.DispatchReply{}, err
+		}
+		machine.sessionService.CloseRun()
+		machine.repoService.Close()
+		machine.changeState(WELCOME, nil)
+	case dispatcher.SAVE:
+		logger.Debug(logModule, "Practice received SAVE command")
+		err := machine.saveSplitFile()
+		if err != nil {
+			msg := fmt.Sprintf("failed to save split file to session: %s", err)
+			logger.Error(logModule, msg)
+			return dispatcher.DispatchReply{Code: 2, Message: msg}, err
+		}
+	case dispatcher.SPLIT:
+		logger.Debug(logModule, "Practice received SPLIT command")
+		return splitReply(machine.sessionService.Split(splitSource(source))), nil
+	case dispatcher.UNDO:
+		machine.sessionService.Undo()
+	case dispatcher.SKIP:
+		machine.sessionService.Skip()
+	case dispatcher.PAUSE:
+		machine.sessionService.Pause()
+	case dispatcher.RESET:
+		_ = machine.promptPartialRun()
+		machine.sessionService.Reset()
+	case dispatcher.PRACTICE:
+		logger.Debug(logModule, "Practice received PRACTICE command")
+		if payload == nil {
+			return stopPractice()
+		}
+		if _, ok := machine.sessionService.Run(); ok {
+			return dispatcher.DispatchReply{Code: 1, Message: "can't change practice segments mid attempt"}, nil
+		}
+		return startPractice(payload)
+	case dispatcher.CANCEL:
+		logger.Debug(logModule, "Practice received CANCEL command")
+		return stopPractice()
+	case dispatcher.SUCCESS, dispatcher.FAIL:
+		logger.Debugf(logModule, "Practice received %s command", command)
+		segment, err := machine.sessionService.RecordPracticeResult(command == dispatcher.SUCCESS,
+			splitSource(source))
+		if err != nil {
+			return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+		}
+		return practiceResultReply(segment), nil
+	case dispatcher.SWITCH:
+		logger.Debug(logModule, "Practice received SWITCH command")
+		return switchSplitFile(payload)
+	case dispatcher.PIN:
+		logger.Debug(logModule, "Practice received PIN command")
+		return pinSplitFile()
+	case dispatcher.LOCK:
+		return toggleLock(payload), nil
+	case dispatcher.PROFILE:
+		logger.Debug(logModule, "Practice received PROFILE command")
+		return switchProfile(payload, false)
+	default:
+		return rejectCommand(p, command), nil
+	}
+
+	return dispatcher.DispatchReply{}, nil
+}
+
+func (p *Practice) String() string {
+	return "Practice"
+}
+
+func (p *Practice) ID() StateID {
+	return PRACTICE
+}
+
+// practiceResultReply describes segment's practice stats after a result was recorded,
+// e.g. "Level 1: 7/10 hit, 70% of the last 10"
+func practiceResultReply(segment session.Segment) dispatcher.DispatchReply {
+	sf, _ := machine.sessionService.SplitFile()
+	stat := sf.PracticeStats()[segment.ID]
+	return dispatcher.DispatchReply{Message: fmt.Sprintf("%s: %d/%d hit, %.0f%% of the last %d", segment.Name,
+		stat.Successes, stat.Successes+stat.Failures, stat.RollingSuccessRate*100, stat.RollingAttempts)}
+}
+
+// startPractice puts the session in practice mode for the range in payload and enters the Practice state.
+//
+// payload is a dto.Practice in JSON, a nil payload practices every segment.
+func startPractice(payload *string) (dispatcher.DispatchReply, error) {
+	practice := dto.Practice{EndIndex: -1}
+	if payload != nil && *payload != "" {
+		if err := json.Unmarshal([]byte(*payload), &practice); err != nil {
+			msg := fmt.Sprintf("invalid practice payload: %s", err)
+			logger.Error(logModule, msg)
+			return dispatcher.DispatchReply{Code: 1, Message: msg}, nil
+		}
+	}
+
+	err := machine.sessionService.StartPractice(session.Practice{
+		StartIndex:  practice.StartIndex,
+		EndIndex:    practice.EndIndex,
+		UpdateGolds: practice.UpdateGolds,
+	})
+	if err != nil {
+		return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+	}
+
+	if machine.currentState.ID() != PRACTICE {
+		machine.changeState(PRACTICE)
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+// stopPractice offers to keep a partial practice attempt, then leaves practice mode and returns to Running
+func stopPractice() (dispatcher.DispatchReply, error) {
+	_ = machine.promptPartialRun()
+	machine.sessionService.StopPractice()
+	machine.changeState(RUNNING)
+	return dispatcher.DispatchReply{}, nil
+}
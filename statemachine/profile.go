@@ -0,0 +1,73 @@
+package statemachine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/repo"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+// useSplitFile makes sf the loaded split file and activates the config profile it names, or the config's own
+// settings if it names none
+func useSplitFile(sf session.SplitFile) {
+	machine.sessionService.SetLoadedSplitFile(sf)
+	if err := activateProfile(sf.Profile); err != nil {
+		// a missing profile shouldn't stop the split file from loading, the current settings are kept
+		logger.Warnf(logModule, "failed to activate profile %q for split file %s: %s", sf.Profile, sf.GameName, err)
+		machine.runtimeProvider.EventsEmit(configErrorEvent, err.Error())
+	}
+}
+
+// activateProfile puts the stored profile called name in use, an empty name puts the config's own settings back
+func activateProfile(name string) error {
+	if name == machine.configService.ActiveProfile {
+		return nil
+	}
+
+	if name == "" {
+		machine.configService.DeactivateProfile()
+	} else {
+		profile, err := machine.repoService.LoadProfile(name)
+		if err != nil {
+			return err
+		}
+		if err := machine.configService.ActivateProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	// a hold or sequence started with the old bindings shouldn't complete with the new ones
+	machine.gestures.Reset()
+	for _, conflict := range machine.configService.Conflicts() {
+		logger.Warnf(logModule, "hotkey conflict in profile %q: %s", name, conflict)
+	}
+	return nil
+}
+
+// switchProfile is PROFILE, payload names the profile to activate and an empty payload puts the config's own settings
+// back.  When create is set a name that isn't stored yet is created from the settings in use, which is how the Config
+// view adds profiles.
+func switchProfile(payload *string, create bool) (dispatcher.DispatchReply, error) {
+	name := ""
+	if payload != nil {
+		name = *payload
+	}
+
+	err := activateProfile(name)
+	if errors.Is(err, repo.ErrProfileMissing) && create {
+		profile := machine.configService.CurrentProfile(name)
+		if err = machine.repoService.SaveProfile(machine.configService, profile); err != nil {
+			message := fmt.Sprintf("error saving profile to repo %s", err)
+			return dispatcher.DispatchReply{Code: 4, Message: message}, errors.New(message)
+		}
+		logger.Infof(logModule, "created profile %s", name)
+		err = activateProfile(name)
+	}
+	if err != nil {
+		return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+	}
+	return dispatcher.DispatchReply{Message: name}, nil
+}
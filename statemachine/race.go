@@ -0,0 +1,89 @@
+package statemachine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/race"
+)
+
+// RaceClient takes part in races on a coordinator, in production this is *race.Client.
+//
+// Join blocks while connecting to the coordinator, so the state machine calls it without holding the dispatcher and
+// the client reports how the join went in its standings.  InRace is true from the start of a Join.
+type RaceClient interface {
+	Join(address string, name string) error
+	Leave()
+	InRace() bool
+	Ready(ready bool) error
+}
+
+// AttachRaceClient allows the Running state to join races
+func (s *Service) AttachRaceClient(client RaceClient) {
+	s.raceClient = client
+}
+
+// toggleRace joins the race payload describes, or leaves the current race when payload is nil.
+//
+// A nil payload outside of a race joins the race in the config, falling back to the default coordinator address and
+// the host name.  The join finishes in the background, its result reaches the frontend as race standings.
+func toggleRace(payload *string) (dispatcher.DispatchReply, error) {
+	if machine.raceClient == nil {
+		return dispatcher.DispatchReply{Code: 1, Message: "racing is not available"}, nil
+	}
+
+	if payload == nil && machine.raceClient.InRace() {
+		machine.raceClient.Leave()
+		return dispatcher.DispatchReply{Message: "left race"}, nil
+	}
+
+	cfg := machine.configService.GetRaceConfig()
+	join := dto.RaceJoin{Address: cfg.Address, Name: cfg.Name}
+	if payload != nil && *payload != "" {
+		if err := json.Unmarshal([]byte(*payload), &join); err != nil {
+			msg := fmt.Sprintf("invalid race payload: %s", err)
+			logger.Error(logModule, msg)
+			return dispatcher.DispatchReply{Code: 1, Message: msg}, nil
+		}
+	}
+	if join.Address == "" {
+		join.Address = race.DefaultAddress
+	}
+	if join.Name == "" {
+		join.Name, _ = os.Hostname()
+	}
+
+	client := machine.raceClient
+	go func() {
+		if err := client.Join(join.Address, join.Name); err != nil {
+			logger.Errorf(logModule, "failed to join race at %s: %s", join.Address, err)
+		}
+	}()
+	return dispatcher.DispatchReply{Message: "joining race at " + join.Address}, nil
+}
+
+// readyRace readies up for the race, or unreadies when payload is "false"
+func readyRace(payload *string) (dispatcher.DispatchReply, error) {
+	if machine.raceClient == nil || !machine.raceClient.InRace() {
+		return dispatcher.DispatchReply{Code: 1, Message: "not in a race"}, nil
+	}
+
+	ready := payload == nil || *payload != "false"
+	if err := machine.raceClient.Ready(ready); err != nil {
+		msg := fmt.Sprintf("failed to send ready to race: %s", err)
+		logger.Error(logModule, msg)
+		return dispatcher.DispatchReply{Code: 2, Message: msg}, nil
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+// leaveRace leaves the race, if any, when the split file it is being run with is closed
+func leaveRace() {
+	if machine.raceClient != nil && machine.raceClient.InRace() {
+		machine.raceClient.Leave()
+	}
+}
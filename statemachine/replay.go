@@ -0,0 +1,117 @@
+package statemachine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// replayInterval is how often the replay checks the timer for splits to add, it matches the stopwatch's tick
+const replayInterval = 20 * time.Millisecond
+
+// Replay represents the state where a past run is played back in real time through the running view.
+//
+// Hotkeys and the autosplitter are not hooked, so nothing can split the replay.  PAUSE pauses it, CANCEL or REPLAY
+// returns to Running.
+type Replay struct {
+	stop chan struct{}
+}
+
+func NewReplayState() (*Replay, error) {
+	return &Replay{}, nil
+}
+
+func (r *Replay) OnEnter() error {
+	r.stop = make(chan struct{})
+	sessionService := machine.sessionService
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(replayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				sessionService.AdvanceReplay()
+			}
+		}
+	}(r.stop)
+
+	emitRunningView()
+	return nil
+}
+
+func (r *Replay) OnExit() error {
+	close(r.stop)
+	return nil
+}
+
+func (r *Replay) Receive(_ dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+	switch command {
+	case dispatcher.PAUSE:
+		machine.sessionService.Pause()
+	case dispatcher.GHOST:
+		logger.Debug(logModule, "Replay received GHOST command")
+		return setGhost(payload)
+	case dispatcher.CANCEL, dispatcher.REPLAY:
+		logger.Debug(logModule, "Replay received CANCEL or REPLAY command")
+		machine.sessionService.StopReplay()
+		machine.changeState(RUNNING)
+	default:
+		return rejectCommand(r, command), nil
+	}
+
+	return dispatcher.DispatchReply{}, nil
+}
+
+func (r *Replay) String() string {
+	return "Replay"
+}
+
+func (r *Replay) ID() StateID {
+	return REPLAY
+}
+
+// startReplay plays back the run with the ID in payload, or the PB when payload is nil
+func startReplay(payload *string) (dispatcher.DispatchReply, error) {
+	runID, reply, ok := parseRunID(payload)
+	if !ok {
+		return reply, nil
+	}
+	if err := machine.sessionService.StartReplay(runID); err != nil {
+		logger.Error(logModule, err.Error())
+		return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+	}
+	machine.changeState(REPLAY)
+	return dispatcher.DispatchReply{}, nil
+}
+
+// setGhost races the ghost of the run with the ID in payload, or the PB when payload is nil
+func setGhost(payload *string) (dispatcher.DispatchReply, error) {
+	runID, reply, ok := parseRunID(payload)
+	if !ok {
+		return reply, nil
+	}
+	if err := machine.sessionService.SetGhost(runID); err != nil {
+		logger.Error(logModule, err.Error())
+		return dispatcher.DispatchReply{Code: 1, Message: err.Error()}, nil
+	}
+	return dispatcher.DispatchReply{}, nil
+}
+
+// parseRunID reads the run ID payload of GHOST and REPLAY, nil or empty means the PB
+func parseRunID(payload *string) (uuid.UUID, dispatcher.DispatchReply, bool) {
+	if payload == nil || *payload == "" {
+		return uuid.Nil, dispatcher.DispatchReply{}, true
+	}
+	runID, err := uuid.Parse(*payload)
+	if err != nil {
+		msg := fmt.Sprintf("invalid run ID %q: %s", *payload, err)
+		logger.Error(logModule, msg)
+		return uuid.Nil, dispatcher.DispatchReply{Code: 1, Message: msg}, false
+	}
+	return runID, dispatcher.DispatchReply{}, true
+}
@@ -1,15 +1,29 @@
 package statemachine
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/config"
 	"github.com/zellydev-games/opensplit/dispatcher"
 	"github.com/zellydev-games/opensplit/keyinfo"
 	"github.com/zellydev-games/opensplit/logger"
 	"github.com/zellydev-games/opensplit/repo/adapters"
+	"github.com/zellydev-games/opensplit/session"
 )
 
+// hotkeySource attributes commands triggered by global hotkeys
+var hotkeySource = dispatcher.Source{Kind: dispatcher.SourceHotkey}
+
+// hotkeysLockEvent tells the frontend whether LOCK has suspended hotkeys
+const hotkeysLockEvent = "hotkeys:lock"
+
+// hotkeysErrorEvent warns that global hotkeys couldn't be hooked, it's sent empty once they are
+const hotkeysErrorEvent = "hotkeys:error"
+
 // Running represents the state where a dto has been loaded, the UI should be showing the SplitList and the timer.
 type Running struct{}
 
@@ -19,77 +33,22 @@ func NewRunningState() (*Running, error) {
 
 func (r *Running) OnEnter() error {
 	machine.saveOnWindowDimensionChanges = true
-	sessionDto := adapters.DomainToDTO(machine.sessionService)
-	if machine.hotkeyProvider != nil {
-		err := machine.hotkeyProvider.StartHook(func(data keyinfo.KeyData) {
-			if !machine.configService.GlobalHotkeysActive && !machine.windowHasFocus {
-				return
-			}
-
-			for command, keyData := range machine.configService.KeyConfig {
-				if keyData.KeyCode != data.KeyCode {
-					continue
-				}
-
-				if len(keyData.Modifiers) != len(data.Modifiers) {
-					continue
-				}
-
-				if len(keyData.Modifiers) > 0 {
-					// Build lookup of pressed modifiers
-					sent := make(map[int]struct{}, len(data.Modifiers))
-					for _, m := range data.Modifiers {
-						sent[m] = struct{}{}
-					}
-
-					// Ensure every required modifier exists
-					match := true
-					for _, required := range keyData.Modifiers {
-						if _, ok := sent[required]; !ok {
-							match = false
-							break
-						}
-					}
-
-					if !match {
-						continue
-					}
-					_, _ = machine.ReceiveDispatch(command, nil)
-					return
-				} else {
-					_, _ = machine.ReceiveDispatch(command, nil)
-					return
-				}
-			}
-		})
-
-		if err != nil {
-			logger.Error(logModule, err.Error())
-			return err
-		}
-	}
-
-	bridge.EmitUIEvent(machine.runtimeProvider, bridge.AppViewModel{
-		View:    bridge.AppViewRunning,
-		Session: sessionDto,
-		Config:  machine.configService,
-	})
+	startRunInputs()
+	emitRunningView()
 	return nil
 }
 
 func (r *Running) OnExit() error {
 	machine.saveOnWindowDimensionChanges = false
-	if machine.hotkeyProvider != nil {
-		err := machine.hotkeyProvider.Unhook()
-		if err != nil {
-			return err
-		}
-	}
+	return stopRunInputs()
+}
 
-	return nil
+func (r *Running) Receive(source dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+	return receiveGuarded(source, command, payload, true, r.receive)
 }
 
-func (r *Running) Receive(command dispatcher.Command, _ *string) (dispatcher.DispatchReply, error) {
+func (r *Running) receive(source dispatcher.Source, command dispatcher.Command,
+	payload *string) (dispatcher.DispatchReply, error) {
 	switch command {
 	case dispatcher.CLOSE:
 		logger.Debug(logModule, "Running received CLOSE command")
@@ -97,6 +56,7 @@ func (r *Running) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		if err != nil {
 			return dispatcher.DispatchReply{}, err
 		}
+		leaveRace()
 		machine.sessionService.CloseRun()
 		machine.repoService.Close()
 		machine.changeState(WELCOME, nil)
@@ -105,6 +65,9 @@ func (r *Running) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		if _, ok := machine.sessionService.Run(); ok {
 			return dispatcher.DispatchReply{Code: 1, Message: "can't edit splitfile mid run"}, nil
 		}
+		if sf, _ := machine.sessionService.SplitFile(); sf.IsMarathon() {
+			return dispatcher.DispatchReply{Code: 1, Message: "a marathon's segments come from its games, edit their split files instead"}, nil
+		}
 		machine.changeState(EDITING, nil)
 	case dispatcher.SAVE:
 		logger.Debug(logModule, "Running received SAVE command")
@@ -116,7 +79,7 @@ func (r *Running) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		}
 	case dispatcher.SPLIT:
 		logger.Debug(logModule, "Running received SPLIT command")
-		machine.sessionService.Split()
+		return splitReply(machine.sessionService.Split(splitSource(source))), nil
 	case dispatcher.UNDO:
 		machine.sessionService.Undo()
 	case dispatcher.SKIP:
@@ -129,8 +92,40 @@ func (r *Running) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		// note: promptPartialRun only adds the partial run to the session's loadedSplitFile's Runs slice.
 		// Nothing has been saved to disk at this point, so keep the file dirty if needs be.
 		machine.sessionService.Reset()
+	case dispatcher.PRACTICE:
+		logger.Debug(logModule, "Running received PRACTICE command")
+		if _, ok := machine.sessionService.Run(); ok {
+			return dispatcher.DispatchReply{Code: 1, Message: "can't start practice mid run"}, nil
+		}
+		return startPractice(payload)
+	case dispatcher.SWITCH:
+		logger.Debug(logModule, "Running received SWITCH command")
+		return switchSplitFile(payload)
+	case dispatcher.PIN:
+		logger.Debug(logModule, "Running received PIN command")
+		return pinSplitFile()
+	case dispatcher.RACE:
+		logger.Debug(logModule, "Running received RACE command")
+		return toggleRace(payload)
+	case dispatcher.READY:
+		logger.Debug(logModule, "Running received READY command")
+		return readyRace(payload)
+	case dispatcher.GHOST:
+		logger.Debug(logModule, "Running received GHOST command")
+		return setGhost(payload)
+	case dispatcher.REPLAY:
+		logger.Debug(logModule, "Running received REPLAY command")
+		if _, ok := machine.sessionService.Run(); ok {
+			return dispatcher.DispatchReply{Code: 1, Message: "can't replay mid run"}, nil
+		}
+		return startReplay(payload)
+	case dispatcher.LOCK:
+		return toggleLock(payload), nil
+	case dispatcher.PROFILE:
+		logger.Debug(logModule, "Running received PROFILE command")
+		return switchProfile(payload, false)
 	default:
-		logger.Warnf(logModule, "unhandled default case in Running: %d", command)
+		return rejectCommand(r, command), nil
 	}
 
 	return dispatcher.DispatchReply{}, nil
@@ -142,3 +137,183 @@ func (r *Running) String() string {
 func (r *Running) ID() StateID {
 	return RUNNING
 }
+
+// startRunInputs hooks global hotkeys and loads the split file's autosplitter for the states that time runs
+//
+// Neither is required to time a run, the window, the control API and the other input still work without them, so a
+// failure is logged and reported to the UI instead of keeping the user out of Running.
+func startRunInputs() {
+	if machine.hotkeyProvider != nil {
+		err := machine.hotkeyProvider.StartHook(func(data keyinfo.KeyData) {
+			// providers that can't tell when the input happened are called as it happens, which is close enough
+			if data.At.IsZero() {
+				data = data.Stamped(time.Now())
+			}
+			// the gesture engine sends commands through dispatchHotkey once their hold, double tap or sequence
+			// completes, releases always reach it so a hold ends even if focus was lost while holding
+			if machine.gestures.Handle(data) || data.Released {
+				return
+			}
+			if pinned, ok := machine.configService.MatchPinnedSplitFile(data); ok {
+				path := pinned.Path
+				// SWITCH re-enters Running, which unhooks and rehooks this provider, so it can't run in the callback
+				go dispatchHotkey(dispatcher.SWITCH, &path, data.At)
+			}
+		})
+
+		if err != nil {
+			msg := fmt.Sprintf("global hotkeys are unavailable: %s", err)
+			logger.Warn(logModule, msg)
+			machine.runtimeProvider.EventsEmit(hotkeysErrorEvent, msg)
+		} else {
+			machine.runtimeProvider.EventsEmit(hotkeysErrorEvent, "")
+		}
+	}
+
+	if machine.autosplitterRuntime != nil {
+		sf, loaded := machine.sessionService.SplitFile()
+		if loaded && sf.AutosplitterFile != "" {
+			// A broken autosplitter shouldn't stop the user from running with hotkeys
+			err := machine.autosplitterRuntime.Load(sf.AutosplitterFile)
+			if err != nil {
+				logger.Errorf(logModule, "failed to load autosplitter: %s", err)
+			}
+		}
+	}
+}
+
+// lockableCommands are the hotkeys LOCK suspends, everything that changes the timer or the loaded split file
+var lockableCommands = []dispatcher.Command{dispatcher.SPLIT, dispatcher.UNDO, dispatcher.SKIP, dispatcher.PAUSE,
+	dispatcher.RESET, dispatcher.SUCCESS, dispatcher.FAIL, dispatcher.SWITCH}
+
+// splitSource attributes a split to the source of the command that made it
+func splitSource(source dispatcher.Source) session.SplitSource {
+	return session.SplitSource{Kind: string(source.Kind), Detail: source.Detail, At: source.At}
+}
+
+// dispatchHotkey sends a command from a hotkey if its focus policy allows it and hotkeys aren't locked
+func dispatchHotkey(command dispatcher.Command, payload *string, at time.Time) {
+	switch machine.configService.HotkeyPolicy(command) {
+	case config.PolicyDisabled:
+		logger.Debugf(logModule, "hotkey for %s ignored, it is disabled", command)
+		return
+	case config.PolicyFocused:
+		if !machine.windowHasFocus {
+			return
+		}
+	}
+	if machine.hotkeysLocked.Load() && slices.Contains(lockableCommands, command) {
+		logger.Debugf(logModule, "hotkey for %s ignored, hotkeys are locked", command)
+		return
+	}
+	source := hotkeySource
+	source.At = at
+	_, _ = machine.ReceiveDispatch(source, command, payload)
+}
+
+// toggleLock suspends or resumes the hotkeys in lockableCommands, a payload of "true" or "false" sets the lock
+// instead.  The LOCK hotkey itself always works so it can unlock.
+func toggleLock(payload *string) dispatcher.DispatchReply {
+	locked := !machine.hotkeysLocked.Load()
+	if payload != nil && *payload != "" {
+		locked = *payload == "true"
+	}
+	machine.hotkeysLocked.Store(locked)
+	logger.Infof(logModule, "hotkeys locked: %t", locked)
+	machine.runtimeProvider.EventsEmit(hotkeysLockEvent, locked)
+	return dispatcher.DispatchReply{Message: fmt.Sprintf("%t", locked)}
+}
+
+// stopRunInputs undoes startRunInputs
+func stopRunInputs() error {
+	machine.gestures.Reset()
+	if machine.autosplitterRuntime != nil {
+		machine.autosplitterRuntime.Unload()
+	}
+	if machine.hotkeyProvider != nil {
+		err := machine.hotkeyProvider.Unhook()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emitRunningView sends the split file and session to the frontend for the states that time runs
+func emitRunningView() {
+	machine.emitUIEvent(bridge.AppViewModel{
+		View:    bridge.AppViewRunning,
+		Session: adapters.DomainToDTO(machine.sessionService),
+		Config:  machine.configService,
+	})
+}
+
+// switchSplitFile replaces the loaded split file with the one payload refers to and re-enters Running.
+//
+// payload is the path of a split file or the ID of a pinned one.  The new file is read before anything else so a bad
+// path leaves the current file and run alone, then the user is offered to keep a partial run and save the current file
+// as they would be on CLOSE.  A race being run with the current file is left.
+func switchSplitFile(payload *string) (dispatcher.DispatchReply, error) {
+	if payload == nil || *payload == "" {
+		return dispatcher.DispatchReply{Code: 1, Message: "SWITCH requires a split file path or pinned split file ID"}, nil
+	}
+
+	path := *payload
+	if pinned, ok := machine.configService.FindPinnedSplitFile(path); ok {
+		path = pinned.Path
+	}
+	if path == machine.repoService.LoadedFileName() {
+		return dispatcher.DispatchReply{Message: "split file already loaded"}, nil
+	}
+
+	sf, err := machine.repoService.ReadSplitFile(path)
+	if err != nil {
+		msg := fmt.Sprintf("failed to load split file %s: %s", path, err)
+		logger.Error(logModule, msg)
+		return dispatcher.DispatchReply{Code: 1, Message: msg}, nil
+	}
+
+	_ = machine.promptPartialRun()
+	err = machine.promptDirtySave()
+	if err != nil {
+		msg := fmt.Sprintf("failed to save split file before switching: %s", err)
+		logger.Error(logModule, msg)
+		return dispatcher.DispatchReply{Code: 2, Message: msg}, err
+	}
+
+	leaveRace()
+	machine.repoService.SetLoadedFileName(path)
+	useSplitFile(sf)
+	machine.changeState(RUNNING)
+	logger.Infof(logModule, "switched to split file %s", path)
+	return dispatcher.DispatchReply{}, nil
+}
+
+// pinSplitFile pins the loaded split file so it can be switched to with SWITCH, or unpins it if it's already pinned
+func pinSplitFile() (dispatcher.DispatchReply, error) {
+	path := machine.repoService.LoadedFileName()
+	sf, loaded := machine.sessionService.SplitFile()
+	if !loaded || path == "" {
+		return dispatcher.DispatchReply{Code: 1, Message: "save the split file before pinning it"}, nil
+	}
+
+	name := sf.GameName
+	if sf.GameCategory != "" {
+		name += " - " + sf.GameCategory
+	}
+	pinned := machine.configService.TogglePinnedSplitFile(config.PinnedSplitFile{
+		ID:   sf.ID.String(),
+		Name: name,
+		Path: path,
+	})
+
+	err := machine.repoService.SaveConfig(machine.configService)
+	if err != nil {
+		message := fmt.Sprintf("error saving config to repo %s", err)
+		return dispatcher.DispatchReply{Code: 4, Message: message}, errors.New(message)
+	}
+
+	emitRunningView()
+	return dispatcher.DispatchReply{Message: fmt.Sprintf("%t", pinned)}, nil
+}
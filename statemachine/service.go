@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"github.com/zellydev-games/opensplit/config"
 	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/gesture"
 	"github.com/zellydev-games/opensplit/keyinfo"
 	"github.com/zellydev-games/opensplit/logger"
 	"github.com/zellydev-games/opensplit/repo"
@@ -30,6 +33,8 @@ const (
 	EDITING
 	RUNNING
 	CONFIG
+	PRACTICE
+	REPLAY
 )
 
 // RuntimeProvider wraps Wails.runtimeProvider calls to allow for DI for testing.
@@ -45,17 +50,25 @@ type RuntimeProvider interface {
 	Quit()
 }
 
+// HotkeyProvider calls back with every key press, and with releases flagged by KeyData.Released where the platform
+// reports them.  Modifiers are never reported on their own, only as held with another key.
 type HotkeyProvider interface {
 	StartHook(func(data keyinfo.KeyData)) error
 	Unhook() error
 }
 
+// AutosplitterRuntime runs the autosplitter file referenced by a split file while it's in the Running state
+type AutosplitterRuntime interface {
+	Load(path string) error
+	Unload()
+}
+
 // state implementations can be operated by the Service and do meaningful work, and communicate state to the frontend
 // via runtime.EventsEmit
 type state interface {
 	OnEnter() error
 	OnExit() error
-	Receive(command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error)
+	Receive(source dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error)
 	String() string
 	ID() StateID
 }
@@ -69,10 +82,15 @@ type Service struct {
 	repoService                           *repo.Service
 	runtimeProvider                       RuntimeProvider
 	hotkeyProvider                        HotkeyProvider
+	autosplitterRuntime                   AutosplitterRuntime
+	raceClient                            RaceClient
 	configService                         *config.Service
+	gestures                              *gesture.Engine
 	saveOnWindowDimensionChanges          bool
 	unsubscribeFromWindowDimensionChanges func()
 	windowHasFocus                        bool
+	hotkeysLocked                         atomic.Bool
+	guard                                 commandGuard
 }
 
 // InitMachine sets the global singleton, and gives it a friendly default state
@@ -83,6 +101,9 @@ func InitMachine(runtimeProvider RuntimeProvider, repoService *repo.Service, ses
 		repoService:     repoService,
 		configService:   configService,
 	}
+	machine.gestures = gesture.NewEngine(configService, func(command dispatcher.Command, at time.Time) {
+		dispatchHotkey(command, nil, at)
+	})
 	return machine
 }
 
@@ -98,8 +119,13 @@ func (s *Service) AttachHotkeyProvider(provider HotkeyProvider) {
 	s.hotkeyProvider = provider
 }
 
+// AttachAutosplitterRuntime allows the Running state to load the split file's autosplitter
+func (s *Service) AttachAutosplitterRuntime(runtime AutosplitterRuntime) {
+	s.autosplitterRuntime = runtime
+}
+
 // ReceiveDispatch allows external facing code to send Command bytes to the state machine
-func (s *Service) ReceiveDispatch(command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
+func (s *Service) ReceiveDispatch(source dispatcher.Source, command dispatcher.Command, payload *string) (dispatcher.DispatchReply, error) {
 	if s.currentState == nil {
 		logger.Error(logModule, "command sent to state machine without a loaded state")
 		return dispatcher.DispatchReply{}, errors.New("command sent to state machine without a loaded state")
@@ -145,12 +171,25 @@ func (s *Service) ReceiveDispatch(command dispatcher.Command, payload *string) (
 		return dispatcher.DispatchReply{}, nil
 	}
 
+	if !accepts(s.currentState.ID(), command) {
+		return rejectCommand(s.currentState, command), nil
+	}
+
 	logger.Debugf(logModule, "command %d dispatched to state %s", command, s.currentState.String())
-	return s.currentState.Receive(command, payload)
+	return s.currentState.Receive(source, command, payload)
 }
 
 // changeState provides a structured way to change the current state, calling appropriate lifecycle methods along the way
 func (s *Service) changeState(newState StateID, _ ...interface{}) {
+	if _, known := transitionTable[newState]; !known {
+		logger.Errorf(logModule, "refusing to change to unknown state %d", newState)
+		return
+	}
+	if s.currentState != nil && !canTransition(s.currentState.ID(), newState) {
+		logger.Errorf(logModule, "refusing invalid transition from %s to state %d", s.currentState, newState)
+		return
+	}
+
 	if s.currentState != nil {
 		logger.Debugf(logModule, "exiting state %s", s.currentState.String())
 		err := s.currentState.OnExit()
@@ -176,8 +215,12 @@ func (s *Service) changeState(newState StateID, _ ...interface{}) {
 		logger.Debug(logModule, "entering state Config")
 		configState, _ := NewConfigState(s.currentState.ID())
 		s.currentState = configState
-	default:
-		panic("unhandled default case")
+	case PRACTICE:
+		logger.Debug(logModule, "entering state Practice")
+		s.currentState, _ = NewPracticeState()
+	case REPLAY:
+		logger.Debug(logModule, "entering state Replay")
+		s.currentState, _ = NewReplayState()
 	}
 
 	if s.currentState != nil {
@@ -201,6 +244,10 @@ func (s *Service) saveSplitFile() error {
 	if err != nil {
 		return err
 	}
+	err = machine.repoService.SaveMarathonGames(sf)
+	if err != nil {
+		return err
+	}
 
 	machine.sessionService.ClearDirty()
 	return nil
@@ -0,0 +1,671 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/keyinfo"
+	"github.com/zellydev-games/opensplit/race"
+	"github.com/zellydev-games/opensplit/repo"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+const splitFileJSON = `{
+	"id": "9a268f11-1c89-49af-ae00-a9e2246ec82d",
+	"game_name": "Test Game",
+	"game_category": "Any%",
+	"segments": [
+		{"id": "c9bc9698-0f39-488d-80c6-06308f12b03e", "name": "Level 1"},
+		{"id": "05151851-9132-498e-b70a-344ee03c9384", "name": "Level 2"}
+	],
+	"runs": [{"id": "6f3d7c52-2b1e-4f0a-9b7c-1d2e3f4a5b6c", "total_time": 3000, "completed": true, "splits": {
+		"c9bc9698-0f39-488d-80c6-06308f12b03e": {
+			"split_segment_id": "c9bc9698-0f39-488d-80c6-06308f12b03e", "current_cumulative": 1000},
+		"05151851-9132-498e-b70a-344ee03c9384": {
+			"split_segment_id": "05151851-9132-498e-b70a-344ee03c9384", "current_cumulative": 3000}
+	}, "leaf_segments": [
+		{"id": "c9bc9698-0f39-488d-80c6-06308f12b03e", "name": "Level 1"},
+		{"id": "05151851-9132-498e-b70a-344ee03c9384", "name": "Level 2"}
+	]}],
+	"pb": {"id": "6f3d7c52-2b1e-4f0a-9b7c-1d2e3f4a5b6c", "total_time": 3000, "completed": true, "splits": {
+		"c9bc9698-0f39-488d-80c6-06308f12b03e": {
+			"split_segment_id": "c9bc9698-0f39-488d-80c6-06308f12b03e", "current_cumulative": 1000},
+		"05151851-9132-498e-b70a-344ee03c9384": {
+			"split_segment_id": "05151851-9132-498e-b70a-344ee03c9384", "current_cumulative": 3000}
+	}, "leaf_segments": [
+		{"id": "c9bc9698-0f39-488d-80c6-06308f12b03e", "name": "Level 1"},
+		{"id": "05151851-9132-498e-b70a-344ee03c9384", "name": "Level 2"}
+	]}
+}`
+
+type mockRuntime struct {
+	events map[string]any
+}
+
+func (m *mockRuntime) Startup(context.Context) {}
+func (m *mockRuntime) SaveFileDialog(runtime.SaveDialogOptions) (string, error) {
+	return "test.osf", nil
+}
+func (m *mockRuntime) OpenFileDialog(runtime.OpenDialogOptions) (string, error) {
+	return "test.osf", nil
+}
+func (m *mockRuntime) MessageDialog(runtime.MessageDialogOptions) (string, error) {
+	return "No", nil
+}
+func (m *mockRuntime) EventsEmit(name string, payload ...any) {
+	if len(payload) > 0 {
+		m.events[name] = payload[0]
+	}
+}
+func (m *mockRuntime) WindowGetSize() (int, int)                  { return 350, 550 }
+func (m *mockRuntime) WindowGetPosition() (int, int)              { return 100, 100 }
+func (m *mockRuntime) EventsOn(string, func(...any)) func()       { return func() {} }
+func (m *mockRuntime) Quit()                                      {}
+func (m *mockRuntime) StartHook(func(data keyinfo.KeyData)) error { return nil }
+func (m *mockRuntime) Unhook() error                              { return nil }
+
+const otherSplitFileJSON = `{
+	"id": "3c1e0a3e-4ad1-4f52-9a3b-5f1f0b2bd7a1",
+	"game_name": "Other Game",
+	"autosplitter_file": "other.lua",
+	"segments": [{"id": "e1f0b9a4-8a5a-4c43-9d0f-0f6a2b7c1d11", "name": "Only Level"}]
+}`
+
+const profiledSplitFileJSON = `{
+	"id": "0b6f1f1c-2d7e-4a0e-8f59-7c3f7e5d9a20",
+	"game_name": "Pad Game",
+	"profile": "pad",
+	"segments": [{"id": "5a0d3c7e-1b2f-4e6a-9c8d-3f4e5a6b7c8d", "name": "Only Level"}]
+}`
+
+type mockRepository struct {
+	fileName string
+	profiles []byte
+}
+
+func (r *mockRepository) LoadSplitFile() ([]byte, error) {
+	r.fileName = "test.osf"
+	return []byte(splitFileJSON), nil
+}
+func (r *mockRepository) ReadSplitFile(path string) ([]byte, error) {
+	switch path {
+	case "test.osf":
+		return []byte(splitFileJSON), nil
+	case "other.osf":
+		return []byte(otherSplitFileJSON), nil
+	case "profiled.osf":
+		return []byte(profiledSplitFileJSON), nil
+	}
+	return nil, os.ErrNotExist
+}
+func (r *mockRepository) WriteSplitFile(string, []byte) error { return nil }
+func (r *mockRepository) LoadedFileName() string              { return r.fileName }
+func (r *mockRepository) SetLoadedFileName(path string)       { r.fileName = path }
+func (r *mockRepository) GetLoadedSplitFile() ([]byte, error) { return []byte(splitFileJSON), nil }
+func (r *mockRepository) SaveSplitFile([]byte, string) error  { return nil }
+func (r *mockRepository) SaveAs([]byte, string) error         { return nil }
+func (r *mockRepository) ClearCachedFileName()                { r.fileName = "" }
+func (r *mockRepository) SaveConfig([]byte) error             { return nil }
+func (r *mockRepository) LoadConfig() ([]byte, error)         { return nil, repo.ErrConfigMissing }
+func (r *mockRepository) BackupConfig([]byte) error           { return nil }
+func (r *mockRepository) SaveProfiles(payload []byte) error   { r.profiles = payload; return nil }
+func (r *mockRepository) LoadProfiles() ([]byte, error)       { return r.profiles, nil }
+
+type mockTimer struct {
+	running bool
+}
+
+func (t *mockTimer) Startup(context.Context)        {}
+func (t *mockTimer) IsRunning() bool                { return t.running }
+func (t *mockTimer) Run()                           {}
+func (t *mockTimer) Start()                         { t.running = true }
+func (t *mockTimer) Pause()                         { t.running = false }
+func (t *mockTimer) Reset()                         {}
+func (t *mockTimer) GetCurrentTime() time.Duration  { return time.Second }
+func (t *mockTimer) SubtractTime(time.Duration)     {}
+func (t *mockTimer) StartAt(time.Time)              { t.running = true }
+func (t *mockTimer) TimeAt(time.Time) time.Duration { return time.Second }
+
+// pathTo lists the commands that drive a fresh machine from Welcome to each state
+var pathTo = map[StateID][]dispatcher.Command{
+	WELCOME:  {},
+	NEWFILE:  {dispatcher.NEW},
+	EDITING:  {dispatcher.LOAD, dispatcher.EDIT},
+	RUNNING:  {dispatcher.LOAD},
+	CONFIG:   {dispatcher.EDIT},
+	PRACTICE: {dispatcher.LOAD, dispatcher.PRACTICE},
+	REPLAY:   {dispatcher.LOAD, dispatcher.REPLAY},
+}
+
+func newTestMachine(t *testing.T, to StateID) (*Service, *mockRuntime) {
+	t.Helper()
+	rt := &mockRuntime{events: map[string]any{}}
+	configService, _ := config.NewService()
+	sessionService, _ := session.NewService(&mockTimer{})
+	m := InitMachine(rt, repo.NewService(&mockRepository{}), sessionService, configService)
+	m.AttachHotkeyProvider(rt)
+	m.Startup(context.Background())
+
+	for _, command := range pathTo[to] {
+		if reply, err := m.ReceiveDispatch(dispatcher.Source{}, command, nil); err != nil || reply.Code != 0 {
+			t.Fatalf("driving to state %d: %s returned %v (%v)", to, command, reply, err)
+		}
+	}
+	if m.currentState.ID() != to {
+		t.Fatalf("driving to state %d ended in %s", to, m.currentState)
+	}
+	return m, rt
+}
+
+// TestEveryCommandInEveryState sends every command, and one that doesn't exist, to every state.  Nothing may panic,
+// commands outside the table must be rejected, and any state change must be in the transition table.
+func TestEveryCommandInEveryState(t *testing.T) {
+	commands := append(dispatcher.Commands(), dispatcher.Command(255))
+	for state := range transitionTable {
+		for _, command := range commands {
+			m, _ := newTestMachine(t, state)
+			valid := slices.Contains(m.ValidCommands(), command)
+			reply, _ := m.ReceiveDispatch(dispatcher.Source{}, command, nil)
+
+			if valid != accepts(state, command) {
+				t.Fatalf("ValidCommands() in %d disagrees with the table about %s", state, command)
+			}
+			if !valid && reply.Code != dispatcher.CodeRejected {
+				t.Fatalf("%s in state %d want code %d, got %v", command, state, dispatcher.CodeRejected, reply)
+			}
+			if valid && reply.Code == dispatcher.CodeRejected {
+				t.Fatalf("%s in state %d was rejected: %s", command, state, reply.Message)
+			}
+
+			if next := m.currentState.ID(); next != state && !canTransition(state, next) {
+				t.Fatalf("%s in state %d moved to %d, which isn't in the transition table", command, state, next)
+			}
+		}
+	}
+}
+
+func TestTransitionTableReachable(t *testing.T) {
+	for state := range transitionTable {
+		if _, ok := pathTo[state]; !ok {
+			t.Fatalf("state %d has no path in the test, add one so it's covered", state)
+		}
+		if _, ok := commandTable[state]; !ok {
+			t.Fatalf("state %d has no command table entry", state)
+		}
+	}
+}
+
+func TestInvalidTransition(t *testing.T) {
+	m, _ := newTestMachine(t, WELCOME)
+	m.changeState(EDITING)
+	if m.currentState.ID() != WELCOME {
+		t.Fatalf("changeState(EDITING) from Welcome want to stay in Welcome, got %s", m.currentState)
+	}
+	m.changeState(StateID(255))
+	if m.currentState.ID() != WELCOME {
+		t.Fatalf("changeState() to an unknown state want to stay in Welcome, got %s", m.currentState)
+	}
+}
+
+func TestValidCommandsSentToFrontend(t *testing.T) {
+	_, rt := newTestMachine(t, RUNNING)
+	model, ok := rt.events["ui:model"]
+	if !ok {
+		t.Fatalf("no ui:model event emitted")
+	}
+	names := model.(bridge.AppViewModel).ValidCommands
+	if !slices.Contains(names, "SPLIT") || slices.Contains(names, "SUBMIT") {
+		t.Fatalf("ui:model in Running want SPLIT and not SUBMIT, got %v", names)
+	}
+}
+
+func TestSwitchSplitFile(t *testing.T) {
+	m, _ := newTestMachine(t, RUNNING)
+	path := "missing.osf"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &path); reply.Code == 0 {
+		t.Fatalf("SWITCH to a missing file want an error code, got %v", reply)
+	}
+	if sf, _ := m.sessionService.SplitFile(); sf.GameName != "Test Game" || m.repoService.LoadedFileName() != "test.osf" {
+		t.Fatalf("failed SWITCH changed the loaded split file to %s (%s)", sf.GameName, m.repoService.LoadedFileName())
+	}
+
+	client := &mockRaceClient{address: race.DefaultAddress}
+	m.AttachRaceClient(client)
+	path = "other.osf"
+	if reply, err := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &path); err != nil || reply.Code != 0 {
+		t.Fatalf("SWITCH to %s returned %v (%v)", path, reply, err)
+	}
+	if client.InRace() {
+		t.Fatalf("SWITCH want the race run with the old file left")
+	}
+	if sf, _ := m.sessionService.SplitFile(); sf.GameName != "Other Game" || m.repoService.LoadedFileName() != path {
+		t.Fatalf("SWITCH want Other Game from %s, got %s from %s", path, sf.GameName, m.repoService.LoadedFileName())
+	}
+	if m.currentState.ID() != RUNNING {
+		t.Fatalf("SWITCH want to end in Running, got %s", m.currentState)
+	}
+}
+
+// failingHotkeyProvider can't hook global hotkeys, like evdev without permission to read input devices
+type failingHotkeyProvider struct{}
+
+func (failingHotkeyProvider) StartHook(func(keyinfo.KeyData)) error {
+	return errors.New("no readable input devices")
+}
+func (failingHotkeyProvider) Unhook() error { return nil }
+
+type mockAutosplitter struct {
+	loaded string
+}
+
+func (a *mockAutosplitter) Load(path string) error { a.loaded = path; return nil }
+func (a *mockAutosplitter) Unload()                { a.loaded = "" }
+
+func TestRunningWithoutHotkeys(t *testing.T) {
+	m, rt := newTestMachine(t, WELCOME)
+	autosplitter := &mockAutosplitter{}
+	m.AttachHotkeyProvider(failingHotkeyProvider{})
+	m.AttachAutosplitterRuntime(autosplitter)
+
+	if reply, err := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.LOAD, nil); err != nil || reply.Code != 0 {
+		t.Fatalf("LOAD without hotkeys want success, got %v (%v)", reply, err)
+	}
+	if m.currentState.ID() != RUNNING {
+		t.Fatalf("LOAD without hotkeys want Running, got %s", m.currentState)
+	}
+	if msg, _ := rt.events[hotkeysErrorEvent].(string); msg == "" {
+		t.Fatalf("want a %s warning, got %v", hotkeysErrorEvent, rt.events[hotkeysErrorEvent])
+	}
+	if model, _ := rt.events["ui:model"].(bridge.AppViewModel); model.View != bridge.AppViewRunning {
+		t.Fatalf("want the running view emitted, got %v", rt.events["ui:model"])
+	}
+
+	path := "other.osf"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &path); reply.Code != 0 {
+		t.Fatalf("SWITCH without hotkeys want success, got %v", reply)
+	}
+	if autosplitter.loaded != "other.lua" {
+		t.Fatalf("want the autosplitter loaded without hotkeys, got %q", autosplitter.loaded)
+	}
+}
+
+func TestPinSplitFile(t *testing.T) {
+	m, _ := newTestMachine(t, RUNNING)
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.PIN, nil); reply.Message != "true" {
+		t.Fatalf("PIN want the file pinned, got %v", reply)
+	}
+	pinned := m.configService.PinnedSplitFiles
+	if len(pinned) != 1 || pinned[0].Path != "test.osf" || pinned[0].Name != "Test Game - Any%" {
+		t.Fatalf("PIN want test.osf pinned as Test Game - Any%%, got %v", pinned)
+	}
+
+	other := "other.osf"
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &other)
+	id := pinned[0].ID
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &id); reply.Code != 0 {
+		t.Fatalf("SWITCH to pinned ID %s returned %v", id, reply)
+	}
+	if m.repoService.LoadedFileName() != "test.osf" {
+		t.Fatalf("SWITCH to pinned ID want test.osf, got %s", m.repoService.LoadedFileName())
+	}
+
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.PIN, nil); reply.Message != "false" {
+		t.Fatalf("PIN of a pinned file want it unpinned, got %v", reply)
+	}
+	if len(m.configService.PinnedSplitFiles) != 0 {
+		t.Fatalf("PIN left %d pinned split files", len(m.configService.PinnedSplitFiles))
+	}
+}
+
+func TestRecordHotkeys(t *testing.T) {
+	m, rt := newTestMachine(t, CONFIG)
+	record := func(command dispatcher.Command, key keyinfo.KeyData) {
+		t.Helper()
+		if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, command, nil); reply.Code != RecordingArmed {
+			t.Fatalf("%s want recording armed, got %v", command, reply)
+		}
+		m.currentState.(*Config).handleHotkey(key)
+	}
+
+	space := keyinfo.NewKeyData(32, "Space", nil, nil)
+	record(dispatcher.SPLIT, space)
+	record(dispatcher.SPLIT, keyinfo.NewButtonData(0, "Button 0"))
+	if len(m.configService.KeyConfig[dispatcher.SPLIT]) != 2 {
+		t.Fatalf("SPLIT want 2 bindings, got %v", m.configService.KeyConfig[dispatcher.SPLIT])
+	}
+
+	record(dispatcher.RESET, space)
+	if message := rt.events[configErrorEvent]; message != "Space is already bound to SPLIT" {
+		t.Fatalf("recording a bound chord want a conflict error event, got %v", message)
+	}
+	if len(m.configService.KeyConfig[dispatcher.RESET]) != 0 {
+		t.Fatalf("RESET was bound to a conflicting chord")
+	}
+
+	unbind := `{"command": 9, "index": 0}`
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.UNBIND, &unbind); reply.Code != 0 {
+		t.Fatalf("UNBIND returned %v", reply)
+	}
+	bindings := m.configService.KeyConfig[dispatcher.SPLIT]
+	if len(bindings) != 1 || bindings[0].Input != keyinfo.InputButton {
+		t.Fatalf("UNBIND want only the button left on SPLIT, got %v", bindings)
+	}
+}
+
+func TestHotkeyPolicy(t *testing.T) {
+	m, rt := newTestMachine(t, RUNNING)
+	started := func() bool { return m.sessionService.State() != session.Idle }
+
+	_ = m.configService.SetHotkeyPolicies(map[dispatcher.Command]config.HotkeyPolicy{
+		dispatcher.SPLIT: config.PolicyFocused,
+		dispatcher.PAUSE: config.PolicyDisabled,
+	})
+	m.windowHasFocus = false
+	dispatchHotkey(dispatcher.SPLIT, nil, time.Time{})
+	if started() {
+		t.Fatalf("a focused-only hotkey was sent without focus")
+	}
+	m.windowHasFocus = true
+	dispatchHotkey(dispatcher.SPLIT, nil, time.Time{})
+	if !started() {
+		t.Fatalf("a focused-only hotkey wasn't sent with focus")
+	}
+
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.LOCK, nil); reply.Message != "true" {
+		t.Fatalf("LOCK want hotkeys locked, got %v", reply)
+	}
+	if rt.events[hotkeysLockEvent] != true {
+		t.Fatalf("LOCK want %s emitted with true, got %v", hotkeysLockEvent, rt.events[hotkeysLockEvent])
+	}
+	dispatchHotkey(dispatcher.RESET, nil, time.Time{})
+	if !started() {
+		t.Fatalf("RESET hotkey was sent while hotkeys were locked")
+	}
+	dispatchHotkey(dispatcher.LOCK, nil, time.Time{})
+	if m.hotkeysLocked.Load() {
+		t.Fatalf("the LOCK hotkey didn't unlock")
+	}
+	dispatchHotkey(dispatcher.RESET, nil, time.Time{})
+	if started() {
+		t.Fatalf("RESET hotkey wasn't sent after unlocking")
+	}
+}
+
+func TestSubmitHotkeyPolicies(t *testing.T) {
+	m, _ := newTestMachine(t, CONFIG)
+	invalid := `{"hotkey_policies": {"9": "sometimes"}}`
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SUBMIT, &invalid); reply.Code == 0 {
+		t.Fatalf("SUBMIT with an unknown policy want an error reply")
+	}
+	valid := `{"hotkey_policies": {"9": "global", "7": ""}}`
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SUBMIT, &valid); reply.Code != 0 {
+		t.Fatalf("SUBMIT returned %v", reply)
+	}
+	if len(m.configService.HotkeyPolicies) != 1 || m.configService.HotkeyPolicy(dispatcher.SPLIT) != config.PolicyGlobal {
+		t.Fatalf("SUBMIT want only SPLIT's policy saved, got %v", m.configService.HotkeyPolicies)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	m, _ := newTestMachine(t, CONFIG)
+	space := keyinfo.NewKeyData(32, "Space", nil, nil)
+	button := keyinfo.NewButtonData(0, "Button 0")
+	_ = m.configService.AddKeyBinding(dispatcher.SPLIT, space)
+
+	pad := "pad"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.PROFILE, &pad); reply.Code != 0 {
+		t.Fatalf("PROFILE in Config want the profile created, got %v", reply)
+	}
+	_ = m.configService.RemoveKeyBinding(dispatcher.SPLIT, 0)
+	_ = m.configService.AddKeyBinding(dispatcher.SPLIT, button)
+	if base := m.configService.Base().KeyConfig[dispatcher.SPLIT]; len(base) != 1 || !base[0].Matches(space) {
+		t.Fatalf("editing a profile want the config's own SPLIT binding kept as Space, got %v", base)
+	}
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SUBMIT, nil); reply.Code != 0 {
+		t.Fatalf("SUBMIT returned %v", reply)
+	}
+
+	profile, err := m.repoService.LoadProfile(pad)
+	if split := profile.KeyConfig[dispatcher.SPLIT]; err != nil || len(split) != 1 || !split[0].Matches(button) {
+		t.Fatalf("SUBMIT want the profile saved with the button on SPLIT, got %v (%v)", profile.KeyConfig, err)
+	}
+	if names := m.configService.ProfileNames; len(names) != 1 || names[0] != pad {
+		t.Fatalf("want profile names [pad], got %v", names)
+	}
+
+	// SUBMIT went back to Welcome, which loaded the config's own settings from scratch
+	_ = m.configService.AddKeyBinding(dispatcher.SPLIT, space)
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.LOAD, nil)
+	if m.configService.ActiveProfile != "" {
+		t.Fatalf("loading a split file without a profile want the config's own settings, got %s",
+			m.configService.ActiveProfile)
+	}
+	missing := "missing"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.PROFILE, &missing); reply.Code != 1 {
+		t.Fatalf("PROFILE of a missing profile in Running want code 1, got %v", reply)
+	}
+
+	path := "profiled.osf"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SWITCH, &path); reply.Code != 0 {
+		t.Fatalf("SWITCH to %s returned %v", path, reply)
+	}
+	if command, ok := m.configService.MatchCommand(button); m.configService.ActiveProfile != pad || !ok ||
+		command != dispatcher.SPLIT {
+		t.Fatalf("loading a split file with a profile want it active, got %q", m.configService.ActiveProfile)
+	}
+
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.PROFILE, nil); reply.Code != 0 {
+		t.Fatalf("PROFILE without a name returned %v", reply)
+	}
+	if command, ok := m.configService.MatchCommand(space); m.configService.ActiveProfile != "" || !ok ||
+		command != dispatcher.SPLIT {
+		t.Fatalf("PROFILE without a name want the config's own settings back, got %q", m.configService.ActiveProfile)
+	}
+}
+
+func TestGuards(t *testing.T) {
+	m, rt := newTestMachine(t, RUNNING)
+	err := m.configService.SetGuards(config.GuardConfig{
+		DebounceMS:          map[dispatcher.Command]int{dispatcher.PAUSE: 500, dispatcher.EDIT: 500},
+		ResetConfirmAfterMS: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now()
+	source := func(offset time.Duration) dispatcher.Source {
+		return dispatcher.Source{Kind: dispatcher.SourceHotkey, At: at.Add(offset)}
+	}
+
+	_, _ = m.ReceiveDispatch(source(0), dispatcher.SPLIT, nil)
+	_, _ = m.ReceiveDispatch(source(0), dispatcher.PAUSE, nil)
+	reply, _ := m.ReceiveDispatch(source(200*time.Millisecond), dispatcher.PAUSE, nil)
+	if reply.Code != dispatcher.CodeGuarded || m.sessionService.State() != session.Paused {
+		t.Fatalf("PAUSE inside its debounce want guarded and the run left paused, got %v", reply)
+	}
+	if rt.events[guardEvent] == nil {
+		t.Fatalf("a guarded command want %s emitted", guardEvent)
+	}
+	if reply, _ = m.ReceiveDispatch(source(time.Second), dispatcher.PAUSE, nil); reply.Code != 0 {
+		t.Fatalf("PAUSE after its debounce returned %v", reply)
+	}
+
+	// EDIT is rejected mid run, so it never starts its debounce
+	_, _ = m.ReceiveDispatch(source(time.Second), dispatcher.EDIT, nil)
+	if reply, _ = m.ReceiveDispatch(source(time.Second+100*time.Millisecond), dispatcher.EDIT, nil); reply.Code != 1 {
+		t.Fatalf("EDIT after a rejected EDIT want rejected by the state rather than debounced, got %v", reply)
+	}
+
+	// mockTimer reads one second, past the 1ms threshold
+	if reply, _ = m.ReceiveDispatch(source(2*time.Second), dispatcher.RESET, nil); reply.Code != dispatcher.CodeGuarded {
+		t.Fatalf("RESET past the threshold want guarded, got %v", reply)
+	}
+	if reply, _ = m.ReceiveDispatch(source(6*time.Second), dispatcher.RESET, nil); reply.Code != dispatcher.CodeGuarded {
+		t.Fatalf("RESET after the confirmation window want guarded again, got %v", reply)
+	}
+	_, _ = m.ReceiveDispatch(source(7*time.Second), dispatcher.RESET, nil)
+	if m.sessionService.State() != session.Idle {
+		t.Fatalf("RESET sent twice want the run reset, got state %d", m.sessionService.State())
+	}
+
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.SPLIT, nil)
+	if m.sessionService.State() != session.Running {
+		t.Fatalf("SPLIT want a new run started, got state %d", m.sessionService.State())
+	}
+	confirm := dispatcher.ResetConfirmPayload
+	_, _ = m.ReceiveDispatch(source(9*time.Second), dispatcher.RESET, &confirm)
+	if m.sessionService.State() != session.Idle {
+		t.Fatalf("RESET with the confirm payload want the run reset, got state %d", m.sessionService.State())
+	}
+}
+
+type mockRaceClient struct {
+	mu      sync.Mutex
+	address string
+	name    string
+	ready   bool
+	// joining blocks Join until it is closed, when set
+	joining chan struct{}
+}
+
+func (c *mockRaceClient) Join(address string, name string) error {
+	if c.joining != nil {
+		<-c.joining
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.address, c.name = address, name
+	return nil
+}
+func (c *mockRaceClient) Leave()                 { c.mu.Lock(); defer c.mu.Unlock(); c.address = "" }
+func (c *mockRaceClient) InRace() bool           { c.mu.Lock(); defer c.mu.Unlock(); return c.address != "" }
+func (c *mockRaceClient) Ready(ready bool) error { c.ready = ready; return nil }
+
+// joined waits for the background Join started by RACE and returns who it joined as
+func (c *mockRaceClient) joined(t *testing.T) (string, string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.InRace() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for RACE to join")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.address, c.name
+}
+
+func TestRace(t *testing.T) {
+	m, _ := newTestMachine(t, RUNNING)
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.RACE, nil); reply.Code != 1 {
+		t.Fatalf("RACE without a race client want code 1, got %v", reply)
+	}
+
+	client := &mockRaceClient{}
+	m.AttachRaceClient(client)
+	m.configService.Race.Name = "runner"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.RACE, nil); reply.Code != 0 {
+		t.Fatalf("RACE returned %v", reply)
+	}
+	if address, name := client.joined(t); address != race.DefaultAddress || name != "runner" {
+		t.Fatalf("RACE want the default address as runner, got %s as %s", address, name)
+	}
+
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.READY, nil)
+	if !client.ready {
+		t.Fatalf("READY didn't ready up")
+	}
+	notReady := "false"
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.READY, &notReady)
+	if client.ready {
+		t.Fatalf("READY false didn't unready")
+	}
+
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.RACE, nil)
+	if client.InRace() {
+		t.Fatalf("RACE in a race want the race left")
+	}
+
+	join := `{"address":"10.0.0.2:6769","name":"other"}`
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.RACE, &join)
+	if address, name := client.joined(t); address != "10.0.0.2:6769" || name != "other" {
+		t.Fatalf("RACE with a payload want 10.0.0.2:6769 as other, got %s as %s", address, name)
+	}
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.CLOSE, nil)
+	if client.InRace() {
+		t.Fatalf("CLOSE want the race left")
+	}
+}
+
+func TestRaceJoinsWithoutBlocking(t *testing.T) {
+	m, _ := newTestMachine(t, RUNNING)
+	client := &mockRaceClient{joining: make(chan struct{})}
+	m.AttachRaceClient(client)
+
+	// a coordinator that takes its time must not hold up the dispatcher
+	replied := make(chan dispatcher.DispatchReply, 1)
+	go func() {
+		reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.RACE, nil)
+		replied <- reply
+	}()
+	select {
+	case reply := <-replied:
+		if reply.Code != 0 {
+			t.Fatalf("RACE returned %v", reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RACE waited for the join to finish")
+	}
+
+	close(client.joining)
+	client.joined(t)
+}
+
+func TestReplay(t *testing.T) {
+	m, _ := newTestMachine(t, REPLAY)
+	if _, ok := m.sessionService.Replay(); !ok {
+		t.Fatalf("REPLAY didn't start a replay")
+	}
+
+	// the mock timer is always at 1s, so the replay splits Level 1 and waits on Level 2
+	deadline := time.Now().Add(time.Second)
+	for m.sessionService.Index() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("replay never split Level 1")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	_, _ = m.ReceiveDispatch(dispatcher.Source{}, dispatcher.CANCEL, nil)
+	if _, ok := m.sessionService.Replay(); ok || m.currentState.ID() != RUNNING {
+		t.Fatalf("CANCEL want the replay stopped in Running, got %s", m.currentState)
+	}
+	if _, ok := m.sessionService.Run(); ok {
+		t.Fatalf("CANCEL left the replayed run in the session")
+	}
+}
+
+func TestGhost(t *testing.T) {
+	m, _ := newTestMachine(t, RUNNING)
+	bad := "not a run"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.GHOST, &bad); reply.Code != 1 {
+		t.Fatalf("GHOST with an invalid run ID want code 1, got %v", reply)
+	}
+	runID := "6f3d7c52-2b1e-4f0a-9b7c-1d2e3f4a5b6c"
+	if reply, _ := m.ReceiveDispatch(dispatcher.Source{}, dispatcher.GHOST, &runID); reply.Code != 0 {
+		t.Fatalf("GHOST returned %v", reply)
+	}
+	if ghost, ok := m.sessionService.Ghost(2 * time.Second); !ok || ghost.SegmentIndex != 1 || ghost.Progress != 0.5 {
+		t.Fatalf("ghost at 2s want halfway through Level 2, got %+v", ghost)
+	}
+}
@@ -0,0 +1,99 @@
+package statemachine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+// globalCommands are handled by Service.ReceiveDispatch whatever the current state
+var globalCommands = []dispatcher.Command{
+	dispatcher.QUIT,
+	dispatcher.HELLO,
+	dispatcher.TOGGLEGLOBAL,
+	dispatcher.FOCUS,
+}
+
+// commandTable lists the commands each state's Receive accepts.
+//
+// Service.ReceiveDispatch rejects anything else with dispatcher.CodeRejected before it reaches the state, so a stray
+// hotkey or packet can't drive a state into a case it doesn't handle.
+var commandTable = map[StateID][]dispatcher.Command{
+	WELCOME: {dispatcher.LOAD, dispatcher.NEW, dispatcher.EDIT},
+	NEWFILE: {dispatcher.CANCEL, dispatcher.SUBMIT},
+	EDITING: {dispatcher.CANCEL, dispatcher.SUBMIT},
+	RUNNING: {dispatcher.CLOSE, dispatcher.EDIT, dispatcher.SAVE, dispatcher.SPLIT, dispatcher.UNDO, dispatcher.SKIP,
+		dispatcher.PAUSE, dispatcher.RESET, dispatcher.PRACTICE, dispatcher.SWITCH, dispatcher.PIN,
+		dispatcher.RACE, dispatcher.READY, dispatcher.GHOST, dispatcher.REPLAY, dispatcher.LOCK, dispatcher.PROFILE},
+	// Config arms hotkey recording for the bindable commands, SWITCH records the hotkey of a pinned split file,
+	// UNBIND removes a command's hotkey and PROFILE picks, or adds, the profile being edited
+	CONFIG: {dispatcher.CANCEL, dispatcher.SUBMIT, dispatcher.SPLIT, dispatcher.UNDO, dispatcher.SKIP, dispatcher.PAUSE,
+		dispatcher.RESET, dispatcher.SUCCESS, dispatcher.FAIL, dispatcher.LOCK, dispatcher.SWITCH, dispatcher.UNBIND,
+		dispatcher.PROFILE},
+	PRACTICE: {dispatcher.CLOSE, dispatcher.SAVE, dispatcher.SPLIT, dispatcher.UNDO, dispatcher.SKIP, dispatcher.PAUSE,
+		dispatcher.RESET, dispatcher.PRACTICE, dispatcher.CANCEL, dispatcher.SUCCESS, dispatcher.FAIL, dispatcher.SWITCH,
+		dispatcher.PIN, dispatcher.LOCK, dispatcher.PROFILE},
+	REPLAY: {dispatcher.PAUSE, dispatcher.CANCEL, dispatcher.REPLAY, dispatcher.GHOST},
+}
+
+// transitionTable lists the states each state may change to
+var transitionTable = map[StateID][]StateID{
+	WELCOME: {RUNNING, NEWFILE, CONFIG},
+	NEWFILE: {WELCOME, RUNNING},
+	EDITING: {RUNNING},
+	// Running re-enters itself when SWITCH loads another split file
+	RUNNING: {WELCOME, EDITING, PRACTICE, RUNNING, REPLAY},
+	// Config returns to whichever state opened it
+	CONFIG:   {WELCOME, RUNNING, PRACTICE},
+	PRACTICE: {WELCOME, RUNNING},
+	REPLAY:   {RUNNING},
+}
+
+// accepts reports whether command is valid in state
+func accepts(state StateID, command dispatcher.Command) bool {
+	return slices.Contains(globalCommands, command) || slices.Contains(commandTable[state], command)
+}
+
+// canTransition reports whether the state machine may change from one state to another
+func canTransition(from StateID, to StateID) bool {
+	return slices.Contains(transitionTable[from], to)
+}
+
+// ValidCommands returns the commands the current state accepts, including the global ones, in order
+func (s *Service) ValidCommands() []dispatcher.Command {
+	if s.currentState == nil {
+		return nil
+	}
+	var commands []dispatcher.Command
+	for _, command := range dispatcher.Commands() {
+		if accepts(s.currentState.ID(), command) {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// validCommandNames is ValidCommands as names, so they serialize readably for the frontend and remote clients
+func (s *Service) validCommandNames() []string {
+	var names []string
+	for _, command := range s.ValidCommands() {
+		names = append(names, command.String())
+	}
+	return names
+}
+
+// emitUIEvent sends model to the frontend along with the commands the current state accepts
+func (s *Service) emitUIEvent(model bridge.AppViewModel) {
+	model.ValidCommands = s.validCommandNames()
+	bridge.EmitUIEvent(s.runtimeProvider, model)
+}
+
+// rejectCommand is the reply to a command that isn't valid in the current state
+func rejectCommand(current state, command dispatcher.Command) dispatcher.DispatchReply {
+	message := fmt.Sprintf("%s is not valid in state %s", command, current)
+	logger.Warn(logModule, message)
+	return dispatcher.DispatchReply{Code: dispatcher.CodeRejected, Message: message}
+}
@@ -2,7 +2,6 @@ package statemachine
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/zellydev-games/opensplit/bridge"
 	"github.com/zellydev-games/opensplit/dispatcher"
@@ -42,14 +41,20 @@ func (w *Welcome) OnEnter() error {
 			return err
 		}
 	}
+	for _, conflict := range machine.configService.Conflicts() {
+		logger.Warnf(logModule, "hotkey conflict in config: %s", conflict)
+	}
+	for _, err := range machine.configService.GestureErrors() {
+		logger.Warnf(logModule, "invalid hotkey gesture in config, it will be sent on a press: %s", err)
+	}
 
-	bridge.EmitUIEvent(machine.runtimeProvider, bridge.AppViewModel{
+	machine.emitUIEvent(bridge.AppViewModel{
 		View: bridge.AppViewWelcome,
 	})
 	return nil
 }
 func (w *Welcome) OnExit() error { return nil }
-func (w *Welcome) Receive(command dispatcher.Command, _ *string) (dispatcher.DispatchReply, error) {
+func (w *Welcome) Receive(_ dispatcher.Source, command dispatcher.Command, _ *string) (dispatcher.DispatchReply, error) {
 	switch command {
 	case dispatcher.LOAD:
 		logger.Debug(logModule, "Welcome received command LOAD")
@@ -57,7 +62,7 @@ func (w *Welcome) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		if err != nil {
 			return dispatcher.DispatchReply{Code: 1, Message: "failed to load dto: " + err.Error()}, err
 		}
-		machine.sessionService.SetLoadedSplitFile(sf)
+		useSplitFile(sf)
 		machine.changeState(RUNNING)
 		return dispatcher.DispatchReply{}, nil
 	case dispatcher.NEW:
@@ -69,6 +74,6 @@ func (w *Welcome) Receive(command dispatcher.Command, _ *string) (dispatcher.Dis
 		machine.changeState(CONFIG)
 		return dispatcher.DispatchReply{}, nil
 	default:
-		return dispatcher.DispatchReply{}, fmt.Errorf("invalid command %d for state Welcome", command)
+		return rejectCommand(w, command), nil
 	}
 }
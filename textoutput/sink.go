@@ -0,0 +1,315 @@
+package textoutput
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/logger"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+const logModule = "textoutput"
+
+// DefaultTemplates are the files written when the user hasn't configured a template for them.
+//
+// Keys are file names relative to the output directory, values are text/template sources rendered against Fields.
+var DefaultTemplates = map[string]string{
+	"current_time.txt":     "{{.CurrentTime}}",
+	"segment.txt":          "{{.Segment}}",
+	"delta.txt":            "{{.Delta}}",
+	"previous_segment.txt": "{{.PreviousSegment}} {{.PreviousSegmentDelta}}",
+	"pb.txt":               "{{.PB}}",
+	"sob.txt":              "{{.SOB}}",
+	"attempts.txt":         "{{.Attempts}}",
+}
+
+// ConfigProvider supplies the current text output settings, in production this is *config.Service
+type ConfigProvider interface {
+	GetTextOutputConfig() config.TextOutputConfig
+}
+
+// FileProvider wraps os file operations to allow DI for testing.
+type FileProvider interface {
+	WriteFile(string, []byte, os.FileMode) error
+	MkdirAll(string, os.FileMode) error
+}
+
+// Fields is the data made available to text output templates.
+//
+// Delta is the live delta, the current time against the PB's time at the end of the current segment.
+// PreviousSplitDelta is the delta at the last split and PreviousSegmentDelta that segment's time against the PB's.
+type Fields struct {
+	GameName             string
+	GameCategory         string
+	CurrentTime          string
+	Segment              string
+	Delta                string
+	PreviousSegment      string
+	PreviousSplitDelta   string
+	PreviousSegmentDelta string
+	PB                   string
+	SOB                  string
+	Attempts             int
+}
+
+// Sink keeps a set of text files up to date with the timer and session so streaming software can display them.
+//
+// Sink listens to the timer and session update channels and keeps the latest values in memory, then renders and
+// writes the configured files at most once per configured write interval, and only when something has changed.
+type Sink struct {
+	mu                   sync.Mutex
+	configProvider       ConfigProvider
+	fileProvider         FileProvider
+	defaultDirectory     string
+	timerUpdateChannel   <-chan time.Duration
+	sessionUpdateChannel <-chan *session.Service
+	currentTime          time.Duration
+	splitFile            *session.SplitFile
+	run                  *session.Run
+	index                int
+	dirty                bool
+	lastWritten          map[string]string
+	templates            map[string]*template.Template
+	createdDirectory     string
+}
+
+// NewSink creates a Sink that reads from the given update channels.
+//
+// defaultDirectory is used when the configured output directory is empty.
+func NewSink(timerUpdateChannel <-chan time.Duration, sessionUpdateChannel <-chan *session.Service,
+	configProvider ConfigProvider, fileProvider FileProvider, defaultDirectory string) *Sink {
+	return &Sink{
+		configProvider:       configProvider,
+		fileProvider:         fileProvider,
+		defaultDirectory:     defaultDirectory,
+		timerUpdateChannel:   timerUpdateChannel,
+		sessionUpdateChannel: sessionUpdateChannel,
+		index:                -1,
+		lastWritten:          map[string]string{},
+		templates:            map[string]*template.Template{},
+	}
+}
+
+// Start begins consuming updates and writing files until ctx is done.
+func (s *Sink) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case currentTime, ok := <-s.timerUpdateChannel:
+				if !ok {
+					return
+				}
+				s.setCurrentTime(currentTime)
+			case sessionService, ok := <-s.sessionUpdateChannel:
+				if !ok {
+					return
+				}
+				s.setSession(sessionService)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			interval := s.configProvider.GetTextOutputConfig().WriteInterval()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+				s.flush()
+			}
+		}
+	}()
+	logger.Debug(logModule, "text output sink started")
+}
+
+func (s *Sink) setCurrentTime(currentTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.currentTime != currentTime {
+		s.currentTime = currentTime
+		s.dirty = true
+	}
+}
+
+func (s *Sink) setSession(sessionService *session.Service) {
+	sf, loaded := sessionService.SplitFile()
+	run, running := sessionService.Run()
+	index := sessionService.Index()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.splitFile = nil
+	if loaded {
+		s.splitFile = &sf
+	}
+	s.run = nil
+	if running {
+		s.run = &run
+	}
+	s.index = index
+	s.dirty = true
+}
+
+// flush renders every configured file and writes the ones whose contents changed since the last write.
+func (s *Sink) flush() {
+	cfg := s.configProvider.GetTextOutputConfig()
+	if !cfg.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return
+	}
+	s.dirty = false
+
+	directory := cfg.Directory
+	if directory == "" {
+		directory = s.defaultDirectory
+	}
+	if s.createdDirectory != directory {
+		if err := s.fileProvider.MkdirAll(directory, 0755); err != nil {
+			logger.Errorf(logModule, "failed to create text output directory: %s", err.Error())
+			return
+		}
+		s.createdDirectory = directory
+		s.lastWritten = map[string]string{}
+	}
+
+	fields := buildFields(s.splitFile, s.run, s.index, s.currentTime)
+	for name, source := range mergeTemplates(cfg.Templates) {
+		tmpl, err := s.parse(source)
+		if err != nil {
+			logger.Errorf(logModule, "invalid template for %s: %s", name, err.Error())
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, fields); err != nil {
+			logger.Errorf(logModule, "failed to render %s: %s", name, err.Error())
+			continue
+		}
+
+		contents := buf.String()
+		if last, ok := s.lastWritten[name]; ok && last == contents {
+			continue
+		}
+
+		if err = s.fileProvider.WriteFile(filepath.Join(directory, name), buf.Bytes(), 0644); err != nil {
+			logger.Errorf(logModule, "failed to write %s: %s", name, err.Error())
+			continue
+		}
+		s.lastWritten[name] = contents
+	}
+}
+
+// parse caches parsed templates by their source so they aren't reparsed on every write.
+func (s *Sink) parse(source string) (*template.Template, error) {
+	if tmpl, ok := s.templates[source]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	s.templates[source] = tmpl
+	return tmpl, nil
+}
+
+// mergeTemplates overlays user configured templates on DefaultTemplates.
+//
+// An empty user template disables that file.
+func mergeTemplates(configured map[string]string) map[string]string {
+	out := make(map[string]string, len(DefaultTemplates)+len(configured))
+	for name, source := range DefaultTemplates {
+		out[name] = source
+	}
+	for name, source := range configured {
+		if source == "" {
+			delete(out, name)
+			continue
+		}
+		out[name] = source
+	}
+	return out
+}
+
+func buildFields(sf *session.SplitFile, run *session.Run, index int, currentTime time.Duration) Fields {
+	fields := Fields{CurrentTime: timer.FormatTimeToString(currentTime)}
+	if sf == nil {
+		return fields
+	}
+
+	fields.GameName = sf.GameName
+	fields.GameCategory = sf.GameCategory
+	fields.Attempts = sf.Attempts
+	fields.SOB = timer.FormatTimeToString(sf.SOB)
+	if sf.PB != nil {
+		fields.PB = timer.FormatTimeToString(sf.PB.TotalTime)
+	}
+
+	leafSegments := sf.DeepCopyLeafSegments()
+	if index >= 0 && index < len(leafSegments) {
+		fields.Segment = leafSegments[index].Name
+		if run != nil && sf.PB != nil {
+			if pbSplit, ok := sf.PB.Splits[leafSegments[index].ID]; ok {
+				fields.Delta = FormatDelta(currentTime - pbSplit.CurrentCumulative)
+			}
+		}
+	}
+
+	if run == nil || index <= 0 {
+		return fields
+	}
+
+	// Find the most recent split in this run, skipped segments don't have one
+	for i := min(index, len(leafSegments)) - 1; i >= 0; i-- {
+		segment := leafSegments[i]
+		split, ok := run.Splits[segment.ID]
+		if !ok {
+			continue
+		}
+
+		fields.PreviousSegment = segment.Name
+		if sf.PB == nil {
+			break
+		}
+		if pbSplit, ok := sf.PB.Splits[segment.ID]; ok {
+			fields.PreviousSplitDelta = FormatDelta(split.CurrentCumulative - pbSplit.CurrentCumulative)
+			fields.PreviousSegmentDelta = FormatDelta(split.CurrentDuration - pbSplit.CurrentDuration)
+		}
+		break
+	}
+
+	return fields
+}
+
+// FormatDelta formats a comparison as a signed, compact string (e.g. +1.25, -1:02.50)
+func FormatDelta(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	m := d / time.Minute
+	d -= m * time.Minute
+	sec := d / time.Second
+	cs := (d - sec*time.Second) / (10 * time.Millisecond)
+
+	if m > 0 {
+		return fmt.Sprintf("%s%d:%02d.%02d", sign, m, sec, cs)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, sec, cs)
+}
@@ -0,0 +1,155 @@
+package textoutput
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zellydev-games/opensplit/config"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+var seg1 = uuid.MustParse("c9bc9698-0f39-488d-80c6-06308f12b03e")
+var seg2 = uuid.MustParse("05151851-9132-498e-b70a-344ee03c9384")
+
+type mockConfigProvider struct {
+	cfg config.TextOutputConfig
+}
+
+func (m *mockConfigProvider) GetTextOutputConfig() config.TextOutputConfig {
+	return m.cfg
+}
+
+type mockFileProvider struct {
+	written      map[string]string
+	writeCalled  int
+	mkdirAllPath string
+}
+
+func (m *mockFileProvider) WriteFile(name string, data []byte, _ os.FileMode) error {
+	m.writeCalled++
+	m.written[name] = string(data)
+	return nil
+}
+
+func (m *mockFileProvider) MkdirAll(path string, _ os.FileMode) error {
+	m.mkdirAllPath = path
+	return nil
+}
+
+func getSplitFile() *session.SplitFile {
+	return &session.SplitFile{
+		GameName:     "Test Game",
+		GameCategory: "Any%",
+		Attempts:     12,
+		SOB:          50 * time.Second,
+		Segments:     []session.Segment{{ID: seg1, Name: "Level 1"}, {ID: seg2, Name: "Level 2"}},
+		PB: &session.Run{
+			TotalTime: 60 * time.Second,
+			Splits: map[uuid.UUID]session.Split{
+				seg1: {SplitSegmentID: seg1, CurrentCumulative: 30 * time.Second, CurrentDuration: 30 * time.Second},
+				seg2: {SplitSegmentID: seg2, CurrentCumulative: 60 * time.Second, CurrentDuration: 30 * time.Second},
+			},
+		},
+	}
+}
+
+func TestBuildFields(t *testing.T) {
+	sf := getSplitFile()
+	run := &session.Run{Splits: map[uuid.UUID]session.Split{
+		seg1: {SplitSegmentID: seg1, CurrentCumulative: 28500 * time.Millisecond, CurrentDuration: 28500 * time.Millisecond},
+	}}
+
+	fields := buildFields(sf, run, 1, 40*time.Second)
+	if fields.Segment != "Level 2" {
+		t.Fatalf("buildFields() Segment want %q, got %q", "Level 2", fields.Segment)
+	}
+	if fields.PreviousSegment != "Level 1" {
+		t.Fatalf("buildFields() PreviousSegment want %q, got %q", "Level 1", fields.PreviousSegment)
+	}
+	if fields.Delta != "-20.00" {
+		t.Fatalf("buildFields() live Delta want %q, got %q", "-20.00", fields.Delta)
+	}
+	if fields.PreviousSplitDelta != "-1.50" {
+		t.Fatalf("buildFields() PreviousSplitDelta want %q, got %q", "-1.50", fields.PreviousSplitDelta)
+	}
+	if fields.CurrentTime != "00:00:40.00" {
+		t.Fatalf("buildFields() CurrentTime want %q, got %q", "00:00:40.00", fields.CurrentTime)
+	}
+	if fields.PB != "00:01:00.00" || fields.SOB != "00:00:50.00" || fields.Attempts != 12 {
+		t.Fatalf("buildFields() unexpected PB/SOB/Attempts: %#v", fields)
+	}
+
+	fields = buildFields(nil, nil, -1, 0)
+	if fields.Segment != "" || fields.PB != "" {
+		t.Fatalf("buildFields() with no split file should be empty, got %#v", fields)
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	cases := map[time.Duration]string{
+		1250 * time.Millisecond:  "+1.25",
+		-1250 * time.Millisecond: "-1.25",
+		62500 * time.Millisecond: "+1:02.50",
+		0:                        "+0.00",
+	}
+	for d, want := range cases {
+		if got := FormatDelta(d); got != want {
+			t.Errorf("FormatDelta(%s) want %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	cp := &mockConfigProvider{cfg: config.TextOutputConfig{
+		Enabled:   true,
+		Directory: "/tmp/out",
+		Templates: map[string]string{"segment.txt": "", "title.txt": "{{.GameName}} - {{.GameCategory}}"},
+	}}
+	fp := &mockFileProvider{written: map[string]string{}}
+	s := NewSink(nil, nil, cp, fp, "/default")
+	s.splitFile = getSplitFile()
+	s.dirty = true
+
+	s.flush()
+	if fp.mkdirAllPath != "/tmp/out" {
+		t.Fatalf("flush() directory want %q, got %q", "/tmp/out", fp.mkdirAllPath)
+	}
+	if got := fp.written[filepath.Join("/tmp/out", "title.txt")]; got != "Test Game - Any%" {
+		t.Fatalf("flush() custom template want %q, got %q", "Test Game - Any%", got)
+	}
+	if _, ok := fp.written[filepath.Join("/tmp/out", "segment.txt")]; ok {
+		t.Fatal("flush() wrote a file that was disabled with an empty template")
+	}
+	if got := fp.written[filepath.Join("/tmp/out", "attempts.txt")]; got != "12" {
+		t.Fatalf("flush() attempts want %q, got %q", "12", got)
+	}
+
+	// Unchanged state shouldn't touch the disk again
+	calls := fp.writeCalled
+	s.flush()
+	s.dirty = true
+	s.flush()
+	if fp.writeCalled != calls {
+		t.Fatalf("flush() rewrote unchanged files: want %d writes, got %d", calls, fp.writeCalled)
+	}
+
+	s.setCurrentTime(time.Second)
+	s.flush()
+	if fp.writeCalled != calls+1 {
+		t.Fatalf("flush() after time change want %d writes, got %d", calls+1, fp.writeCalled)
+	}
+}
+
+func TestFlushDisabled(t *testing.T) {
+	cp := &mockConfigProvider{}
+	fp := &mockFileProvider{written: map[string]string{}}
+	s := NewSink(nil, nil, cp, fp, "/default")
+	s.dirty = true
+	s.flush()
+	if fp.writeCalled != 0 {
+		t.Fatalf("flush() while disabled wrote %d files", fp.writeCalled)
+	}
+}
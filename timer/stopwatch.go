@@ -79,11 +79,20 @@ func (s *Stopwatch) Run() {
 
 // Start marks the current time for the monotonic clock and sets the running state to true
 func (s *Stopwatch) Start() {
+	s.StartAt(time.Now())
+}
+
+// StartAt is Start as though it had been called at the instant at, e.g. when the split that started a run was pressed
+// rather than when it was handled.  An instant in the future starts the stopwatch now.
+func (s *Stopwatch) StartAt(at time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if !s.running {
-		// mark base time relative to now
-		s.startTime = time.Now().Add(-s.currentTime)
+		if now := time.Now(); at.After(now) {
+			at = now
+		}
+		// mark base time relative to at
+		s.startTime = at.Add(-s.currentTime)
 		s.running = true
 	}
 }
@@ -117,6 +126,17 @@ func (s *Stopwatch) GetCurrentTime() time.Duration {
 	return s.currentTime
 }
 
+// TimeAt returns the time the stopwatch read, or will read, at the instant at.  Unlike GetCurrentTime it isn't limited
+// to the last tick, and a paused stopwatch reads the same at any instant.
+func (s *Stopwatch) TimeAt(at time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return s.currentTime
+	}
+	return at.Sub(s.startTime)
+}
+
 func (s *Stopwatch) SubtractTime(duration time.Duration) {
 	s.currentTime -= duration
 }
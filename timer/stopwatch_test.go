@@ -67,3 +67,24 @@ func TestSubtractTime(t *testing.T) {
 		t.Errorf("SubtractTime() got %v, want %v", s.currentTime, -10*time.Second)
 	}
 }
+
+func TestTimeAt(t *testing.T) {
+	s, _ := NewStopwatch(&mockTicker{})
+	pressed := time.Now().Add(-time.Second)
+	s.StartAt(pressed)
+	if got := s.TimeAt(pressed.Add(250 * time.Millisecond)); got != 250*time.Millisecond {
+		t.Errorf("TimeAt() got %v, want %v", got, 250*time.Millisecond)
+	}
+
+	s.Pause()
+	paused := s.GetCurrentTime()
+	if got := s.TimeAt(time.Now().Add(time.Hour)); got != paused {
+		t.Errorf("TimeAt() while paused got %v, want %v", got, paused)
+	}
+
+	s.Reset()
+	s.StartAt(time.Now().Add(time.Hour))
+	if got := s.TimeAt(time.Now()); got < 0 || got > time.Second {
+		t.Errorf("StartAt() in the future want the stopwatch started now, TimeAt() got %v", got)
+	}
+}
@@ -0,0 +1,271 @@
+// Package tui is a terminal frontend for OpenSplit.
+//
+// App runs in the same process as the backend.  It stands in for the Wails runtime, so it receives the same events
+// the bridge sends the web frontend (ui:model, session:update, timer:update), renders them as text, and dispatches
+// commands from keypresses.  Dialogs the state machine shows are answered in the terminal.
+package tui
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/zellydev-games/opensplit/dispatcher"
+	"github.com/zellydev-games/opensplit/logger"
+)
+
+const logModule = "tui"
+
+// frameInterval caps how often the screen is redrawn, timer updates arrive faster than this
+const frameInterval = time.Second / 30
+
+// Dispatcher sends commands to the state machine, in production this is *dispatcher.Service
+type Dispatcher interface {
+	DispatchFrom(dispatcher.Source, dispatcher.Command, *string) (dispatcher.DispatchReply, error)
+}
+
+// keySource attributes commands to the terminal
+var keySource = dispatcher.Source{Kind: dispatcher.SourceHotkey, Detail: "terminal"}
+
+// keyCommands maps keys to commands while the Running view is shown
+var keyCommands = map[rune]dispatcher.Command{
+	' ': dispatcher.SPLIT,
+	'u': dispatcher.UNDO,
+	's': dispatcher.SKIP,
+	'p': dispatcher.PAUSE,
+	'r': dispatcher.RESET,
+	't': dispatcher.PRACTICE,
+	'y': dispatcher.SUCCESS,
+	'n': dispatcher.FAIL,
+	'w': dispatcher.SAVE,
+	'c': dispatcher.CLOSE,
+	'l': dispatcher.LOAD,
+	'q': dispatcher.QUIT,
+}
+
+// statusEvent carries a message for the status line from a finished dispatch
+const statusEvent = "tui:status"
+
+type event struct {
+	name    string
+	payload any
+}
+
+// App draws the Model to out and reads keys from in
+type App struct {
+	dispatcher Dispatcher
+	in         io.Reader
+	out        io.Writer
+	width      func() int
+	events     chan event
+	prompts    chan *Prompt
+	quit       chan struct{}
+	quitOnce   sync.Once
+}
+
+// NewApp creates an App, width reports the terminal's current width
+func NewApp(in io.Reader, out io.Writer, width func() int) *App {
+	return &App{
+		in:      in,
+		out:     out,
+		width:   width,
+		events:  make(chan event, 256),
+		prompts: make(chan *Prompt),
+		quit:    make(chan struct{}),
+	}
+}
+
+// SetDispatcher must be called before Run, the dispatcher needs the App as its runtime so they can't be built together
+func (a *App) SetDispatcher(d Dispatcher) {
+	a.dispatcher = d
+}
+
+// Run draws the terminal until the state machine quits or ctx is cancelled
+func (a *App) Run(ctx context.Context) {
+	keys := make(chan Key)
+	go readKeys(a.in, keys)
+
+	_, _ = io.WriteString(a.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		_, _ = io.WriteString(a.out, "\x1b[?25h\x1b[?1049l")
+	}()
+
+	frames := time.NewTicker(frameInterval)
+	defer frames.Stop()
+
+	model := &Model{}
+	dirty := true
+	done := ctx.Done()
+	inputClosed := false
+	for {
+		select {
+		case <-done:
+			// QUIT may ask to save, keep drawing so the prompt can be answered
+			done = nil
+			go a.dispatchWithStatus(dispatcher.QUIT)
+		case <-a.quit:
+			return
+		case e := <-a.events:
+			model.Apply(e.name, e.payload)
+			dirty = true
+		case p := <-a.prompts:
+			if inputClosed {
+				p.reply <- p.Default
+				continue
+			}
+			model.Prompt = p
+			dirty = true
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				inputClosed = true
+				if model.Prompt != nil {
+					model.Prompt.reply <- model.Prompt.Default
+					model.Prompt = nil
+				}
+				go a.dispatchWithStatus(dispatcher.QUIT)
+				continue
+			}
+			a.handleKey(model, key)
+			dirty = true
+		case <-frames.C:
+			if dirty {
+				a.draw(model)
+				dirty = false
+			}
+		}
+	}
+}
+
+func (a *App) handleKey(model *Model, key Key) {
+	if model.Prompt != nil {
+		if answer, done := model.Prompt.handleKey(key); done {
+			model.Prompt.reply <- answer
+			model.Prompt = nil
+		}
+		return
+	}
+
+	if key.Special == KeyCtrlC {
+		go a.dispatchWithStatus(dispatcher.QUIT)
+		return
+	}
+	if key.Special == KeyEscape {
+		go a.dispatchWithStatus(dispatcher.CANCEL)
+		return
+	}
+
+	command, ok := keyCommands[key.Rune]
+	if !ok {
+		return
+	}
+	model.Status = ""
+	// Dispatch off the draw loop, the state machine may show a dialog that this loop has to answer
+	go a.dispatchWithStatus(command)
+}
+
+// dispatchWithStatus sends command and shows the reply's message, if any, on the status line
+func (a *App) dispatchWithStatus(command dispatcher.Command) {
+	reply, err := a.dispatcher.DispatchFrom(keySource, command, nil)
+	status := reply.Message
+	if err != nil {
+		logger.Warnf(logModule, "%s failed: %s", command, err)
+		status = err.Error()
+	}
+	if status == "" {
+		return
+	}
+	select {
+	case a.events <- event{name: statusEvent, payload: status}:
+	case <-a.quit:
+	}
+}
+
+func (a *App) draw(model *Model) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(strings.Join(model.Render(a.width()), "\r\n"))
+	_, _ = io.WriteString(a.out, b.String())
+}
+
+// ask shows p and waits for the user to answer it
+func (a *App) ask(p *Prompt) string {
+	p.reply = make(chan string, 1)
+	select {
+	case a.prompts <- p:
+	case <-a.quit:
+		return p.Default
+	}
+	select {
+	case answer := <-p.reply:
+		return answer
+	case <-a.quit:
+		return p.Default
+	}
+}
+
+// The methods below implement the runtime the state machine, repo and dispatcher expect
+
+func (a *App) Startup(context.Context) {}
+
+func (a *App) OpenFileDialog(options runtime.OpenDialogOptions) (string, error) {
+	return a.ask(&Prompt{Title: options.Title, Message: "Path to open:"}), nil
+}
+
+func (a *App) SaveFileDialog(options runtime.SaveDialogOptions) (string, error) {
+	return a.ask(&Prompt{Title: options.Title, Message: "Path to save to:", Input: options.DefaultFilename}), nil
+}
+
+func (a *App) MessageDialog(options runtime.MessageDialogOptions) (string, error) {
+	buttons := options.Buttons
+	if len(buttons) == 0 {
+		buttons = []string{"OK"}
+	}
+	return a.ask(&Prompt{
+		Title:   options.Title,
+		Message: options.Message,
+		Buttons: buttons,
+		Default: options.DefaultButton,
+	}), nil
+}
+
+func (a *App) EventsEmit(name string, payload ...any) {
+	e := event{name: name}
+	if len(payload) > 0 {
+		e.payload = payload[0]
+	}
+	if name == "timer:update" {
+		// Drop rather than block the timer, the next update will catch the screen up
+		select {
+		case a.events <- e:
+		default:
+		}
+		return
+	}
+	select {
+	case a.events <- e:
+	case <-a.quit:
+	}
+}
+
+// EventsOn never calls back, the terminal doesn't emit events to the backend
+func (a *App) EventsOn(string, func(...any)) func() {
+	return func() {}
+}
+
+func (a *App) WindowGetSize() (int, int) {
+	return 0, 0
+}
+
+func (a *App) WindowGetPosition() (int, int) {
+	return 0, 0
+}
+
+func (a *App) Quit() {
+	a.quitOnce.Do(func() {
+		close(a.quit)
+	})
+}
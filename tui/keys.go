@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// SpecialKey identifies keys that aren't printable runes
+type SpecialKey byte
+
+const (
+	KeyNone SpecialKey = iota
+	KeyEnter
+	KeyBackspace
+	KeyEscape
+	KeyCtrlC
+)
+
+// Key is a single keypress read from a terminal in raw mode
+type Key struct {
+	Rune    rune
+	Special SpecialKey
+}
+
+// ParseKeys splits the bytes of one read from a raw terminal into keys.
+//
+// A lone ESC byte is the escape key, longer escape sequences (arrows, function keys) are dropped.
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 {
+				keys = append(keys, Key{Special: KeyEscape})
+			}
+			return keys
+		case c == '\r' || c == '\n':
+			keys = append(keys, Key{Special: KeyEnter})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, Key{Special: KeyBackspace})
+		case c == 0x03:
+			keys = append(keys, Key{Special: KeyCtrlC})
+		case c < 0x20:
+			// other control characters
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, Key{Rune: r})
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// readKeys sends keys read from r until it fails, then closes keys
+func readKeys(r io.Reader, keys chan<- Key) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range ParseKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleKey edits the prompt's answer and reports whether the prompt is finished
+func (p *Prompt) handleKey(key Key) (string, bool) {
+	if p.Buttons != nil {
+		switch key.Special {
+		case KeyEnter:
+			return p.Default, true
+		case KeyEscape, KeyCtrlC:
+			return "", true
+		}
+		if i, err := strconv.Atoi(string(key.Rune)); err == nil && i >= 1 && i <= len(p.Buttons) {
+			return p.Buttons[i-1], true
+		}
+		// y/n shortcuts for the usual Yes/No questions
+		for _, button := range p.Buttons {
+			if r, _ := utf8.DecodeRuneInString(button); r == key.Rune || r == key.Rune-'a'+'A' {
+				return button, true
+			}
+		}
+		return "", false
+	}
+
+	switch key.Special {
+	case KeyEnter:
+		return p.Input, true
+	case KeyEscape, KeyCtrlC:
+		return "", true
+	case KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(p.Input); size > 0 {
+			p.Input = p.Input[:len(p.Input)-size]
+		}
+		return "", false
+	}
+	if key.Rune != 0 {
+		p.Input += string(key.Rune)
+	}
+	return "", false
+}
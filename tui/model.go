@@ -0,0 +1,261 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/session"
+	"github.com/zellydev-games/opensplit/textoutput"
+	"github.com/zellydev-games/opensplit/timer"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiGold  = "\x1b[33m"
+)
+
+// Model is everything the terminal shows, built from the same events the bridge sends the web frontend
+type Model struct {
+	View        bridge.View
+	Session     *dto.Session
+	CurrentTime time.Duration
+	// Status is a one line message, usually the result of the last command
+	Status string
+	// Prompt is a dialog waiting for an answer, nil if there isn't one
+	Prompt *Prompt
+}
+
+// Prompt is a MessageDialog (Buttons set) or a file dialog (Buttons nil) waiting for the user
+type Prompt struct {
+	Title   string
+	Message string
+	Buttons []string
+	Default string
+	Input   string
+	reply   chan string
+}
+
+// Apply updates the model from a bridge event, unknown events and payloads are ignored
+func (m *Model) Apply(event string, payload any) {
+	switch event {
+	case "ui:model":
+		if model, ok := payload.(bridge.AppViewModel); ok {
+			m.View = model.View
+			if model.Session != nil || model.View != bridge.AppViewRunning {
+				m.Session = model.Session
+			}
+		}
+	case "session:update":
+		if s, ok := payload.(*dto.Session); ok {
+			m.Session = s
+		}
+	case statusEvent:
+		if status, ok := payload.(string); ok {
+			m.Status = status
+		}
+	case "timer:update":
+		if ms, ok := payload.(int64); ok {
+			m.CurrentTime = time.Duration(ms) * time.Millisecond
+		}
+	}
+}
+
+// Render draws the model as lines of at most width visible characters
+func (m *Model) Render(width int) []string {
+	if width < 30 {
+		width = 30
+	}
+
+	var lines []string
+	switch m.View {
+	case bridge.AppViewRunning:
+		lines = m.renderRunning(width)
+	case bridge.AppViewWelcome, "":
+		lines = []string{
+			ansiBold + "OpenSplit" + ansiReset,
+			"",
+			"Load a split file to start timing.",
+		}
+	default:
+		lines = []string{
+			ansiBold + "OpenSplit" + ansiReset,
+			"",
+			fmt.Sprintf("The %s screen isn't available in the terminal, use the desktop app.", m.View),
+		}
+	}
+
+	lines = append(lines, "")
+	if m.Prompt != nil {
+		lines = append(lines, m.renderPrompt()...)
+	} else {
+		lines = append(lines, ansiDim+fit(m.help(), width)+ansiReset)
+	}
+	if m.Status != "" {
+		lines = append(lines, fit(m.Status, width))
+	}
+	return lines
+}
+
+func (m *Model) help() string {
+	switch m.View {
+	case bridge.AppViewRunning:
+		return "[space] split [u] undo [s] skip [p] pause [r] reset [t] practice [y/n] hit/miss [w] save [c] close [q] quit"
+	case bridge.AppViewWelcome, "":
+		return "[l] load [q] quit"
+	default:
+		return "[esc] cancel [q] quit"
+	}
+}
+
+func (m *Model) renderRunning(width int) []string {
+	if m.Session == nil || m.Session.LoadedSplitFile == nil {
+		return []string{"No split file loaded"}
+	}
+
+	sf := m.Session.LoadedSplitFile
+	title := sf.GameName
+	if sf.GameCategory != "" {
+		title += " - " + sf.GameCategory
+	}
+	info := fmt.Sprintf("attempts: %d", sf.Attempts)
+	activeIndex := m.Session.CurrentSegmentIndex
+	if p := m.Session.Practice; p != nil {
+		info = fmt.Sprintf("practice: %s - %s (attempts: %d)",
+			leafName(m.Session.LeafSegments, p.StartIndex), leafName(m.Session.LeafSegments, p.EndIndex),
+			sf.PracticeAttempts)
+		if m.Session.CurrentRun == nil {
+			// SKIP and UNDO move the start segment while practice is idle
+			activeIndex = p.StartIndex
+		}
+	}
+	lines := []string{
+		ansiBold + fit(title, width) + ansiReset,
+		ansiDim + fit(info, width) + ansiReset,
+		strings.Repeat("─", width),
+	}
+
+	const timeWidth, deltaWidth = 11, 10
+	nameWidth := width - timeWidth - deltaWidth - 3
+
+	run := m.Session.CurrentRun
+	for i, segment := range m.Session.LeafSegments {
+		marker := " "
+		if i == activeIndex && (m.Session.SessionState == dto.SessionState(session.Running) || m.Session.CurrentRun == nil) {
+			marker = ">"
+		}
+
+		pbCumulative, hasPB := pbSplit(sf, segment.ID)
+		column := "-"
+		if hasPB {
+			column = timer.FormatTimeToString(pbCumulative)
+		}
+
+		delta := ""
+		if run != nil {
+			if split, ok := run.Splits[segment.ID]; ok {
+				cumulative := time.Duration(split.CurrentCumulative) * time.Millisecond
+				column = timer.FormatTimeToString(cumulative)
+				if hasPB {
+					delta = colorDelta(cumulative-pbCumulative, isGold(segment, split), deltaWidth)
+				}
+			} else if i == m.Session.CurrentSegmentIndex && hasPB && m.CurrentTime > pbCumulative {
+				// Only show a live delta once we're behind, like most timers
+				delta = colorDelta(m.CurrentTime-pbCumulative, false, deltaWidth)
+			}
+		}
+		if delta == "" {
+			delta = strings.Repeat(" ", deltaWidth)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s%-*s %s %*s", marker, nameWidth, fit(segment.Name, nameWidth), delta,
+			timeWidth, column))
+	}
+
+	lines = append(lines, strings.Repeat("─", width))
+	timeColor := ""
+	switch m.Session.SessionState {
+	case dto.SessionState(session.Paused):
+		timeColor = ansiDim
+	case dto.SessionState(session.Finished):
+		timeColor = ansiBold
+	}
+	currentTime := timer.FormatTimeToString(m.CurrentTime)
+	lines = append(lines, strings.Repeat(" ", max(0, width-len(currentTime)))+timeColor+currentTime+ansiReset)
+	if m.Session.Dirty {
+		lines = append(lines, ansiDim+"unsaved runs"+ansiReset)
+	}
+	return lines
+}
+
+func leafName(segments []dto.Segment, index int) string {
+	if index < 0 || index >= len(segments) {
+		return "?"
+	}
+	return segments[index].Name
+}
+
+func (m *Model) renderPrompt() []string {
+	p := m.Prompt
+	lines := []string{ansiBold + p.Title + ansiReset}
+	if p.Message != "" {
+		lines = append(lines, p.Message)
+	}
+	if p.Buttons == nil {
+		return append(lines, "> "+p.Input+"█", ansiDim+"[enter] ok [esc] cancel"+ansiReset)
+	}
+
+	var choices []string
+	for i, button := range p.Buttons {
+		choice := fmt.Sprintf("[%d] %s", i+1, button)
+		if button == p.Default {
+			choice = ansiBold + choice + ansiReset
+		}
+		choices = append(choices, choice)
+	}
+	return append(lines, strings.Join(choices, "  ")+ansiDim+"  [enter] default"+ansiReset)
+}
+
+func pbSplit(sf *dto.SplitFile, segmentID string) (time.Duration, bool) {
+	if sf.PB == nil {
+		return 0, false
+	}
+	split, ok := sf.PB.Splits[segmentID]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(split.CurrentCumulative) * time.Millisecond, true
+}
+
+func isGold(segment dto.Segment, split dto.Split) bool {
+	return segment.Gold > 0 && split.CurrentDuration < segment.Gold
+}
+
+func colorDelta(d time.Duration, gold bool, width int) string {
+	color := ansiRed
+	if d < 0 {
+		color = ansiGreen
+	}
+	if gold {
+		color = ansiGold
+	}
+	return color + fmt.Sprintf("%*s", width, textoutput.FormatDelta(d)) + ansiReset
+}
+
+// fit truncates s to width runes
+func fit(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
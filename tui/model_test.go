@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zellydev-games/opensplit/bridge"
+	"github.com/zellydev-games/opensplit/dto"
+	"github.com/zellydev-games/opensplit/session"
+)
+
+func getSession() *dto.Session {
+	pb := &dto.Run{Splits: map[string]dto.Split{
+		"a": {SplitSegmentID: "a", CurrentCumulative: 10000, CurrentDuration: 10000},
+		"b": {SplitSegmentID: "b", CurrentCumulative: 30000, CurrentDuration: 20000},
+	}}
+	return &dto.Session{
+		LoadedSplitFile: &dto.SplitFile{GameName: "Game", GameCategory: "Any%", Attempts: 7, PB: pb},
+		LeafSegments: []dto.Segment{
+			{ID: "a", Name: "Level 1", Gold: 9000},
+			{ID: "b", Name: "Level 2", Gold: 19000},
+		},
+		CurrentRun: &dto.Run{Splits: map[string]dto.Split{
+			"a": {SplitSegmentID: "a", CurrentCumulative: 11500, CurrentDuration: 11500},
+		}},
+		CurrentSegmentIndex: 1,
+		SessionState:        dto.SessionState(session.Running),
+	}
+}
+
+func TestApply(t *testing.T) {
+	m := &Model{}
+	s := getSession()
+	m.Apply("ui:model", bridge.AppViewModel{View: bridge.AppViewRunning, Session: s})
+	if m.View != bridge.AppViewRunning || m.Session != s {
+		t.Fatalf("Apply(ui:model) didn't set view and session: %#v", m)
+	}
+
+	m.Apply("timer:update", int64(1500))
+	if m.CurrentTime != 1500*time.Millisecond {
+		t.Fatalf("Apply(timer:update) want %s, got %s", 1500*time.Millisecond, m.CurrentTime)
+	}
+
+	m.Apply("session:update", (*dto.Session)(nil))
+	if m.Session != nil {
+		t.Fatal("Apply(session:update) didn't replace session")
+	}
+
+	m.Apply("ui:model", bridge.AppViewModel{View: bridge.AppViewWelcome})
+	if m.View != bridge.AppViewWelcome {
+		t.Fatalf("Apply(ui:model) want view %s, got %s", bridge.AppViewWelcome, m.View)
+	}
+}
+
+func TestRenderRunning(t *testing.T) {
+	m := &Model{View: bridge.AppViewRunning, Session: getSession(), CurrentTime: 31 * time.Second}
+	out := strings.Join(m.Render(60), "\n")
+
+	for _, want := range []string{"Game - Any%", "attempts: 7", "Level 1", "+1.50", "00:00:11.50", "+1.00", "00:00:31.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, ">Level 2") {
+		t.Errorf("Render() current segment not marked:\n%s", out)
+	}
+}
+
+func TestRenderPrompt(t *testing.T) {
+	m := &Model{Prompt: &Prompt{Title: "Save?", Buttons: []string{"Yes", "No"}, Default: "Yes"}}
+	out := strings.Join(m.Render(60), "\n")
+	if !strings.Contains(out, "Save?") || !strings.Contains(out, "[2] No") {
+		t.Fatalf("Render() prompt missing:\n%s", out)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := ParseKeys([]byte(" é\r\x7f\x03"))
+	want := []Key{{Rune: ' '}, {Rune: 'é'}, {Special: KeyEnter}, {Special: KeyBackspace}, {Special: KeyCtrlC}}
+	if len(keys) != len(want) {
+		t.Fatalf("ParseKeys() want %v, got %v", want, keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("ParseKeys()[%d] want %v, got %v", i, want[i], keys[i])
+		}
+	}
+
+	if keys := ParseKeys([]byte{0x1b}); len(keys) != 1 || keys[0].Special != KeyEscape {
+		t.Fatalf("ParseKeys(ESC) want escape, got %v", keys)
+	}
+	if keys := ParseKeys([]byte("\x1b[A")); len(keys) != 0 {
+		t.Fatalf("ParseKeys(arrow) want nothing, got %v", keys)
+	}
+}
+
+func TestPromptHandleKey(t *testing.T) {
+	p := &Prompt{Buttons: []string{"Yes", "No"}, Default: "Yes"}
+	if answer, done := p.handleKey(Key{Rune: 'n'}); !done || answer != "No" {
+		t.Fatalf("handleKey(n) want No, got %q %v", answer, done)
+	}
+	if answer, done := p.handleKey(Key{Special: KeyEnter}); !done || answer != "Yes" {
+		t.Fatalf("handleKey(enter) want default Yes, got %q %v", answer, done)
+	}
+
+	p = &Prompt{}
+	for _, key := range ParseKeys([]byte("runs.osx\x7ff")) {
+		p.handleKey(key)
+	}
+	if answer, done := p.handleKey(Key{Special: KeyEnter}); !done || answer != "runs.osf" {
+		t.Fatalf("handleKey() text input want runs.osf, got %q %v", answer, done)
+	}
+}